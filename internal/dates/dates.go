@@ -0,0 +1,152 @@
+// Package dates parses the date expressions of date filters, such as
+// "2024-05-01", "7d", "yesterday" or "last monday".
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layouts are the accepted absolute date formats. Dates without a time zone
+// are in the time zone of now.
+var layouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"Jan 2 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// units are the units of relative expressions like "7d" or "2 weeks ago".
+var units = map[string]func(t time.Time, n int) time.Time{
+	"h":     func(t time.Time, n int) time.Time { return t.Add(-time.Duration(n) * time.Hour) },
+	"hour":  func(t time.Time, n int) time.Time { return t.Add(-time.Duration(n) * time.Hour) },
+	"d":     func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -n) },
+	"day":   func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -n) },
+	"w":     func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -7*n) },
+	"week":  func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -7*n) },
+	"m":     func(t time.Time, n int) time.Time { return t.AddDate(0, -n, 0) },
+	"month": func(t time.Time, n int) time.Time { return t.AddDate(0, -n, 0) },
+	"y":     func(t time.Time, n int) time.Time { return t.AddDate(-n, 0, 0) },
+	"year":  func(t time.Time, n int) time.Time { return t.AddDate(-n, 0, 0) },
+}
+
+// Parse returns the time an expression refers to, in UTC. Calendar
+// expressions refer to the start of the day, week (Monday), month or year in
+// the time zone of now. Supported expressions:
+//
+//	2024-05-01, 2024/05/01, May 1 2024   absolute dates
+//	2024-05-01T08:00:00Z                 RFC 3339 timestamps
+//	now, today, yesterday
+//	7d, 12h, 2w, 3m, 1y                  hours, days, weeks, months or years ago
+//	7 days ago, 1 week ago
+//	monday, last monday                  the last Monday before today
+//	this week, last week, this month, last month, this year, last year
+func Parse(expr string, now time.Time) (time.Time, error) {
+	e := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	if e == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(expr), now.Location()); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch e {
+	case "now":
+		return now.UTC(), nil
+	case "today":
+		return today.UTC(), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1).UTC(), nil
+	}
+
+	if t, ok := relative(e, now); ok {
+		return t.UTC(), nil
+	}
+	if t, ok := calendar(e, today); ok {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown date %q, use e.g. 2024-05-01, 7d, yesterday, last monday or this month", expr)
+}
+
+// relative parses "7d", "7 d", "7 days" and "7 days ago".
+func relative(e string, now time.Time) (time.Time, bool) {
+	e = strings.TrimSuffix(e, " ago")
+	i := 0
+	for i < len(e) && '0' <= e[i] && e[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(e[:i])
+	if err != nil {
+		return time.Time{}, false
+	}
+	unit := strings.TrimSpace(e[i:])
+	if len(unit) > 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	sub, ok := units[unit]
+	if !ok {
+		return time.Time{}, false
+	}
+	return sub(now, n), true
+}
+
+// calendar parses weekdays and "this"/"last" periods.
+func calendar(e string, today time.Time) (time.Time, bool) {
+	which, period, found := strings.Cut(e, " ")
+	if !found {
+		which, period = "last", e
+	}
+	if which != "this" && which != "last" {
+		return time.Time{}, false
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if period == strings.ToLower(d.String()) && which == "last" {
+			days := (int(today.Weekday()) - int(d) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, -days), true
+		}
+	}
+
+	var start time.Time
+	var previous func(time.Time) time.Time
+	switch period {
+	case "week":
+		days := (int(today.Weekday()) + 6) % 7 // days since Monday
+		start = today.AddDate(0, 0, -days)
+		previous = func(t time.Time) time.Time { return t.AddDate(0, 0, -7) }
+	case "month":
+		start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		previous = func(t time.Time) time.Time { return t.AddDate(0, -1, 0) }
+	case "year":
+		start = time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, today.Location())
+		previous = func(t time.Time) time.Time { return t.AddDate(-1, 0, 0) }
+	default:
+		return time.Time{}, false
+	}
+	if !found {
+		// A bare period like "week" is ambiguous
+		return time.Time{}, false
+	}
+	if which == "last" {
+		start = previous(start)
+	}
+	return start, true
+}
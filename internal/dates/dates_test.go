@@ -0,0 +1,77 @@
+package dates
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	// Wednesday, shortly after midnight in Berlin but still Tuesday in UTC
+	now := time.Date(2024, time.May, 15, 0, 30, 0, 0, berlin)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"2024-05-01", "2024-04-30T22:00:00Z"},
+		{"2024/05/01", "2024-04-30T22:00:00Z"},
+		{"May 1, 2024", "2024-04-30T22:00:00Z"},
+		{"1 may 2024", "2024-04-30T22:00:00Z"},
+		{"2024-05-01 08:15", "2024-05-01T06:15:00Z"},
+		{"2024-05-01T08:15:00Z", "2024-05-01T08:15:00Z"},
+		{"2024-05-01T08:15:00-04:00", "2024-05-01T12:15:00Z"},
+		{"now", "2024-05-14T22:30:00Z"},
+		{"today", "2024-05-14T22:00:00Z"},
+		{" Yesterday ", "2024-05-13T22:00:00Z"},
+		{"12h", "2024-05-14T10:30:00Z"},
+		{"7d", "2024-05-07T22:30:00Z"},
+		{"7 days ago", "2024-05-07T22:30:00Z"},
+		{"1 day", "2024-05-13T22:30:00Z"},
+		{"2w", "2024-04-30T22:30:00Z"},
+		{"1 week ago", "2024-05-07T22:30:00Z"},
+		{"3m", "2024-02-14T22:30:00Z"},
+		{"1y", "2023-05-14T22:30:00Z"},
+		{"monday", "2024-05-12T22:00:00Z"},
+		{"last monday", "2024-05-12T22:00:00Z"},
+		{"last wednesday", "2024-05-07T22:00:00Z"},
+		{"last sunday", "2024-05-11T22:00:00Z"},
+		{"this week", "2024-05-12T22:00:00Z"},
+		{"last week", "2024-05-05T22:00:00Z"},
+		{"this month", "2024-04-30T22:00:00Z"},
+		{"last month", "2024-03-31T22:00:00Z"},
+		{"this year", "2023-12-31T22:00:00Z"},
+		{"last year", "2022-12-31T22:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Parse(tt.expr, now)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("Parse(%q) location = %v, want UTC", tt.expr, got.Location())
+			}
+			if s := got.Format(time.RFC3339); s != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.expr, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	now := time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"", "someday", "7", "7 fortnights", "-7d", "next monday", "this monday", "week", "2024-13-01", "last"} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr, now)
+			if err == nil {
+				t.Fatalf("Parse(%q) error = nil, want error", expr)
+			}
+			if expr != "" && !strings.Contains(err.Error(), "unknown date") {
+				t.Errorf("Parse(%q) error = %v, want it to mention the accepted formats", expr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// extractCSV extracts the rows of a CSV file. The text is the file itself.
+// Unless tab separated, the delimiter is guessed from the first line, as
+// spreadsheets in many locales export CSV with semicolons.
+func extractCSV(data string, tabSeparated bool) *Result {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = guessDelimiter(data)
+	if tabSeparated {
+		r.Comma = '\t'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	result := &Result{Kind: KindCSV, Text: data}
+	table := Table{Rows: [][]string{}}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// Keep the rows read so far, the text has the rest
+			result.Truncated = true
+			break
+		}
+		if len(table.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		table.Rows = append(table.Rows, record)
+	}
+	result.Tables = []Table{table}
+	return result
+}
+
+// guessDelimiter returns the most frequent of comma, semicolon and tab in
+// the first line outside of quotes, comma if there is none.
+func guessDelimiter(data string) rune {
+	line, _, _ := strings.Cut(data, "\n")
+	counts := map[rune]int{}
+	quoted := false
+	for _, r := range line {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case ',', ';', '\t':
+			if !quoted {
+				counts[r]++
+			}
+		}
+	}
+	delimiter := ','
+	for _, r := range []rune{';', '\t'} {
+		if counts[r] > counts[delimiter] {
+			delimiter = r
+		}
+	}
+	return delimiter
+}
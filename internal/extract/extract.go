@@ -0,0 +1,342 @@
+// Package extract turns attachments into text and, where the format has
+// structure, into structured data: rows of CSV files and spreadsheets,
+// events of iCalendar files, contacts of vCard files and the header and
+// body of attached messages. Office Open XML documents (docx, xlsx and
+// pptx) are read from their zip and XML parts directly.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/ical"
+	"github.com/dastrobu/mail-mcp/internal/md"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+)
+
+// Kinds of extracted content
+const (
+	KindText         = "text"
+	KindHTML         = "html"
+	KindCSV          = "csv"
+	KindCalendar     = "calendar"
+	KindContacts     = "contacts"
+	KindMessage      = "message"
+	KindDocument     = "document"
+	KindSpreadsheet  = "spreadsheet"
+	KindPresentation = "presentation"
+)
+
+const (
+	// MaxSize is the maximum size of an attachment in bytes.
+	MaxSize = 25 << 20
+	// maxUncompressed is the maximum number of bytes read from the zip
+	// parts of an Office Open XML document, which guards against zip
+	// bombs.
+	maxUncompressed = 100 << 20
+	// maxRows is the maximum number of rows of a table in the structured
+	// data, the text has all of them.
+	maxRows = 1000
+)
+
+var (
+	// ErrUnsupported is returned for attachments of a type that cannot be
+	// extracted, e.g. images or PDFs.
+	ErrUnsupported = errors.New("unsupported attachment type")
+	// ErrTooLarge is returned for attachments larger than MaxSize, or
+	// documents that uncompress to more than maxUncompressed bytes.
+	ErrTooLarge = errors.New("attachment too large")
+	// ErrMalformed is returned for attachments that cannot be read as their
+	// type, e.g. a docx file that is no zip archive.
+	ErrMalformed = errors.New("malformed attachment")
+)
+
+// Result is the content of an attachment.
+type Result struct {
+	// Kind is the kind of content, e.g. KindCSV.
+	Kind string
+	// Text is the content as plain text, or Markdown for HTML.
+	Text string
+	// Tables are the rows of a CSV file or the sheets of a spreadsheet.
+	Tables []Table
+	// Method is the iTIP method of a calendar, e.g. "REQUEST".
+	Method   string
+	Events   []ical.Event
+	Contacts []Contact
+	// Message is the header of an attached message, its body is the Text.
+	Message *Message
+	// Truncated reports whether tables have more rows than returned.
+	Truncated bool
+}
+
+// Table is a table of cells. Rows may have different lengths.
+type Table struct {
+	// Name is the name of the sheet, or "" for CSV files.
+	Name string
+	Rows [][]string
+}
+
+// Contact is a vCard.
+type Contact struct {
+	Name         string
+	Organization string
+	Title        string
+	Emails       []string
+	Phones       []string
+}
+
+// Message is the header of an attached message.
+type Message struct {
+	From    string
+	To      string
+	Cc      string
+	Date    string
+	Subject string
+	// Attachments are the file names of the attachments of the message.
+	Attachments []string
+}
+
+// Extract extracts the content of a part. The kind is chosen by the content
+// type, falling back to the extension of the file name for generic types
+// like application/octet-stream.
+func Extract(p *mimepart.Part) (*Result, error) {
+	if len(p.Body) > MaxSize {
+		return nil, ErrTooLarge
+	}
+	switch kindOf(p.ContentType, p.Filename) {
+	case KindText:
+		return &Result{Kind: KindText, Text: text(p)}, nil
+	case KindHTML:
+		content, err := md.FromHTML(text(p))
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: KindHTML, Text: content}, nil
+	case KindCSV:
+		return extractCSV(text(p), p.ContentType == "text/tab-separated-values" || strings.EqualFold(path.Ext(p.Filename), ".tsv")), nil
+	case KindCalendar:
+		return extractCalendar(text(p))
+	case KindContacts:
+		return extractContacts(text(p))
+	case KindMessage:
+		return extractMessage(p)
+	case KindDocument:
+		return extractDocument(p.Body)
+	case KindSpreadsheet:
+		return extractSpreadsheet(p.Body)
+	case KindPresentation:
+		return extractPresentation(p.Body)
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// contentTypes maps media types to kinds.
+var contentTypes = map[string]string{
+	"text/plain":                KindText,
+	"text/markdown":             KindText,
+	"text/xml":                  KindText,
+	"application/json":          KindText,
+	"application/xml":           KindText,
+	"text/html":                 KindHTML,
+	"application/xhtml+xml":     KindHTML,
+	"text/csv":                  KindCSV,
+	"text/tab-separated-values": KindCSV,
+	"text/calendar":             KindCalendar,
+	"application/ics":           KindCalendar,
+	"text/vcard":                KindContacts,
+	"text/x-vcard":              KindContacts,
+	"text/directory":            KindContacts,
+	"message/rfc822":            KindMessage,
+	"message/global":            KindMessage,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   KindDocument,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         KindSpreadsheet,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": KindPresentation,
+}
+
+// extensions maps lower case file name extensions to kinds.
+var extensions = map[string]string{
+	".txt":  KindText,
+	".text": KindText,
+	".md":   KindText,
+	".log":  KindText,
+	".json": KindText,
+	".xml":  KindText,
+	".html": KindHTML,
+	".htm":  KindHTML,
+	".csv":  KindCSV,
+	".tsv":  KindCSV,
+	".ics":  KindCalendar,
+	".vcs":  KindCalendar,
+	".vcf":  KindContacts,
+	".eml":  KindMessage,
+	".docx": KindDocument,
+	".xlsx": KindSpreadsheet,
+	".pptx": KindPresentation,
+}
+
+// kindOf returns the kind of content of a part, or "" if it cannot be
+// extracted. Other text types are extracted as text.
+func kindOf(contentType, filename string) string {
+	if kind, ok := contentTypes[contentType]; ok {
+		return kind
+	}
+	if kind, ok := extensions[strings.ToLower(path.Ext(filename))]; ok {
+		return kind
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		return KindText
+	}
+	return ""
+}
+
+// text returns the body decoded from its charset, without byte order mark.
+func text(p *mimepart.Part) string {
+	return strings.TrimPrefix(p.Text(), "\ufeff")
+}
+
+// extractMessage extracts an attached message: its header and its body,
+// converted to Markdown if it only has an HTML body.
+func extractMessage(p *mimepart.Part) (*Result, error) {
+	var msg *mimepart.Part
+	if len(p.Parts) > 0 {
+		msg = p.Parts[0]
+	} else {
+		// An .eml file sent as application/octet-stream is not parsed as
+		// message by mimepart
+		msg = mimepart.Parse(p.Body)
+	}
+
+	m := &Message{
+		From:        msg.Header.Get("From"),
+		To:          msg.Header.Get("To"),
+		Cc:          msg.Header.Get("Cc"),
+		Date:        msg.Header.Get("Date"),
+		Subject:     msg.Header.Get("Subject"),
+		Attachments: []string{},
+	}
+	msg.Walk(func(c *mimepart.Part) {
+		if c != msg && c.IsAttachment() && !c.IsMultipart() {
+			m.Attachments = append(m.Attachments, c.Filename)
+		}
+	})
+
+	body, html := msg.Bodies()
+	if strings.TrimSpace(body) == "" && html != "" {
+		var err error
+		if body, err = md.FromHTML(html); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Kind: KindMessage, Text: body, Message: m}, nil
+}
+
+// extractCalendar extracts the events of an iCalendar file. The text lists
+// the events with their times in UTC.
+func extractCalendar(data string) (*Result, error) {
+	cal, err := ical.ParseCalendar([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	var b strings.Builder
+	for i, e := range cal.Events {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeLine(&b, "", e.Summary)
+		writeLine(&b, "Start: ", formatTime(e.Start, e.AllDay))
+		writeLine(&b, "End: ", formatTime(e.End, e.AllDay))
+		writeLine(&b, "Location: ", e.Location)
+		writeLine(&b, "Status: ", e.Status)
+		if e.Description != "" {
+			b.WriteString("\n" + strings.TrimSpace(e.Description) + "\n")
+		}
+	}
+	return &Result{Kind: KindCalendar, Text: b.String(), Method: cal.Method, Events: cal.Events}, nil
+}
+
+// formatTime formats the time of an event: a date for all-day events, RFC
+// 3339 in UTC otherwise, and "" for the zero time.
+func formatTime(t time.Time, allDay bool) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case allDay:
+		return t.Format(time.DateOnly)
+	default:
+		return t.UTC().Format(time.RFC3339)
+	}
+}
+
+// extractContacts extracts the contacts of a vCard file.
+func extractContacts(data string) (*Result, error) {
+	roots, err := ical.Parse([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	result := &Result{Kind: KindContacts, Contacts: []Contact{}}
+	var b strings.Builder
+	for _, card := range roots {
+		if card.Name != "VCARD" {
+			continue
+		}
+		c := Contact{
+			Name:   strings.TrimSpace(card.Text("FN")),
+			Title:  card.Text("TITLE"),
+			Emails: []string{},
+			Phones: []string{},
+		}
+		if c.Name == "" {
+			if n := card.Get("N"); n != nil {
+				// N is "family;given;additional;prefixes;suffixes"
+				parts := ical.SplitValue(n.Value, ';')
+				for len(parts) < 2 {
+					parts = append(parts, "")
+				}
+				c.Name = strings.TrimSpace(parts[1] + " " + parts[0])
+			}
+		}
+		if org := card.Get("ORG"); org != nil {
+			c.Organization = strings.Join(nonEmpty(ical.SplitValue(org.Value, ';')), ", ")
+		}
+		for _, p := range card.All("EMAIL") {
+			c.Emails = append(c.Emails, strings.TrimSpace(p.Text()))
+		}
+		for _, p := range card.All("TEL") {
+			c.Phones = append(c.Phones, strings.TrimPrefix(strings.TrimSpace(p.Text()), "tel:"))
+		}
+		result.Contacts = append(result.Contacts, c)
+
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		writeLine(&b, "", c.Name)
+		writeLine(&b, "Organization: ", c.Organization)
+		writeLine(&b, "Title: ", c.Title)
+		writeLine(&b, "Email: ", strings.Join(c.Emails, ", "))
+		writeLine(&b, "Phone: ", strings.Join(c.Phones, ", "))
+	}
+	result.Text = b.String()
+	return result, nil
+}
+
+// writeLine writes a line of a label and a value, nothing if the value is
+// empty.
+func writeLine(b *strings.Builder, label, value string) {
+	if value != "" {
+		b.WriteString(label + value + "\n")
+	}
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
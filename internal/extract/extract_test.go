@@ -0,0 +1,291 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+)
+
+// part returns a part with the body, as mimepart parses attachments.
+func part(contentType, filename, body string) *mimepart.Part {
+	return &mimepart.Part{ContentType: contentType, Filename: filename, Params: map[string]string{}, Body: []byte(body)}
+}
+
+// zipped returns a zip archive of the files.
+func zipped(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract_Text(t *testing.T) {
+	latin1 := &mimepart.Part{ContentType: "text/plain", Params: map[string]string{"charset": "iso-8859-1"}, Body: []byte("Gr\xfc\xdfe")}
+	tests := []struct {
+		name string
+		part *mimepart.Part
+		kind string
+		want string
+	}{
+		{name: "plain", part: part("text/plain", "notes.txt", "\ufeffHello"), kind: KindText, want: "Hello"},
+		{name: "charset", part: latin1, kind: KindText, want: "Grüße"},
+		{name: "by extension", part: part("application/octet-stream", "data.json", `{"a":1}`), kind: KindText, want: `{"a":1}`},
+		{name: "other text type", part: part("text/x-log", "", "line"), kind: KindText, want: "line"},
+		{name: "html", part: part("text/html", "page.html", "<h1>Invoice</h1><p>Total: <b>42</b></p>"), kind: KindHTML, want: "# Invoice\n\nTotal: **42**"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.kind || strings.TrimSpace(got.Text) != tt.want {
+				t.Errorf("Extract() = %s %q, want %s %q", got.Kind, got.Text, tt.kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract_CSV(t *testing.T) {
+	tests := []struct {
+		name string
+		part *mimepart.Part
+		want [][]string
+	}{
+		{
+			name: "comma",
+			part: part("text/csv", "invoice.csv", "Item,Amount\n\"Lunch, team\",42.50\n"),
+			want: [][]string{{"Item", "Amount"}, {"Lunch, team", "42.50"}},
+		},
+		{
+			name: "semicolon",
+			part: part("application/octet-stream", "rechnung.csv", "Posten;Betrag\r\nMittagessen;42,50\r\n"),
+			want: [][]string{{"Posten", "Betrag"}, {"Mittagessen", "42,50"}},
+		},
+		{
+			name: "tab separated",
+			part: part("text/tab-separated-values", "", "a,b\tc\n1\t2\n"),
+			want: [][]string{{"a,b", "c"}, {"1", "2"}},
+		},
+		{
+			name: "ragged rows",
+			part: part("text/csv", "", "a,b,c\n1\n"),
+			want: [][]string{{"a", "b", "c"}, {"1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != KindCSV || len(got.Tables) != 1 || !reflect.DeepEqual(got.Tables[0].Rows, tt.want) {
+				t.Errorf("Extract() = %s %v, want csv %v", got.Kind, got.Tables, tt.want)
+			}
+			if got.Text != string(tt.part.Body) {
+				t.Errorf("Text = %q, want the file", got.Text)
+			}
+		})
+	}
+}
+
+func TestExtract_CSVTruncated(t *testing.T) {
+	got, err := Extract(part("text/csv", "", strings.Repeat("x,y\n", maxRows+1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Tables[0].Rows) != maxRows || !got.Truncated {
+		t.Errorf("got %d rows, truncated %v, want %d rows, truncated", len(got.Tables[0].Rows), got.Truncated, maxRows)
+	}
+}
+
+func TestExtract_Calendar(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nMETHOD:REQUEST\nBEGIN:VEVENT\nSUMMARY:Kickoff\nDTSTART:20250304T090000Z\nDTEND:20250304T100000Z\nLOCATION:Room 4\nDESCRIPTION:Bring slides\nEND:VEVENT\nEND:VCALENDAR\n"
+	got, err := Extract(part("text/calendar", "invite.ics", ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != KindCalendar || got.Method != "REQUEST" || len(got.Events) != 1 || got.Events[0].Summary != "Kickoff" {
+		t.Fatalf("Extract() = %+v", got)
+	}
+	want := "Kickoff\nStart: 2025-03-04T09:00:00Z\nEnd: 2025-03-04T10:00:00Z\nLocation: Room 4\n\nBring slides\n"
+	if got.Text != want {
+		t.Errorf("Text = %q, want %q", got.Text, want)
+	}
+
+	if _, err := Extract(part("text/calendar", "", "not a calendar")); !errors.Is(err, ErrMalformed) {
+		t.Errorf("err = %v, want ErrMalformed", err)
+	}
+}
+
+func TestExtract_Contacts(t *testing.T) {
+	vcf := "BEGIN:VCARD\nVERSION:3.0\nFN:Jane Doe\nORG:Example Inc.;Sales\nTITLE:Manager\nEMAIL;TYPE=work:jane@example.com\nTEL;TYPE=cell:+1 555 0100\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:4.0\nN:Garcia;Maria;;;\nTEL;VALUE=uri:tel:+1-555-0101\nEND:VCARD\n"
+	got, err := Extract(part("text/vcard", "contacts.vcf", vcf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Contact{
+		{Name: "Jane Doe", Organization: "Example Inc., Sales", Title: "Manager", Emails: []string{"jane@example.com"}, Phones: []string{"+1 555 0100"}},
+		{Name: "Maria Garcia", Emails: []string{}, Phones: []string{"+1-555-0101"}},
+	}
+	if !reflect.DeepEqual(got.Contacts, want) {
+		t.Errorf("Contacts = %+v, want %+v", got.Contacts, want)
+	}
+	if !strings.HasPrefix(got.Text, "Jane Doe\nOrganization: Example Inc., Sales\n") {
+		t.Errorf("Text = %q", got.Text)
+	}
+}
+
+func TestExtract_Message(t *testing.T) {
+	eml := "From: Jane Doe <jane@example.com>\r\nTo: bob@example.com\r\nSubject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\nDate: Tue, 4 Mar 2025 09:00:00 +0000\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/html\r\n\r\n<p>See <i>attached</i></p>\r\n" +
+		"--b\r\nContent-Type: application/pdf; name=report.pdf\r\nContent-Disposition: attachment; filename=report.pdf\r\n\r\n%PDF\r\n--b--\r\n"
+	outer := mimepart.Parse([]byte("Content-Type: multipart/mixed; boundary=o\n\n--o\nContent-Type: message/rfc822\n\n" + eml + "\n--o--\n"))
+	parsed := outer.Parts[0]
+	for _, p := range []*mimepart.Part{parsed, part("application/octet-stream", "fwd.eml", eml)} {
+		got, err := Extract(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Message{From: "Jane Doe <jane@example.com>", To: "bob@example.com", Date: "Tue, 4 Mar 2025 09:00:00 +0000", Subject: "Grüße", Attachments: []string{"report.pdf"}}
+		if got.Kind != KindMessage || !reflect.DeepEqual(got.Message, want) {
+			t.Errorf("Message = %+v, want %+v", got.Message, want)
+		}
+		if strings.TrimSpace(got.Text) != "See *attached*" {
+			t.Errorf("Text = %q, want the HTML body as Markdown", got.Text)
+		}
+	}
+}
+
+func TestExtract_Document(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Invoice</w:t></w:r><w:r><w:t xml:space="preserve"> 2025-17</w:t></w:r></w:p>
+<w:p><w:r><w:t>Due:</w:t><w:tab/><w:t>March</w:t><w:br/><w:t>Net 30</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Item</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Amount</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Lunch</w:t></w:r></w:p><w:p><w:r><w:t>for 4</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>42.50</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:instrText>PAGE</w:instrText><w:delText>removed</w:delText></w:r></w:p>
+</w:body></w:document>`
+	data := zipped(t, map[string]string{"[Content_Types].xml": "<Types/>", "word/document.xml": doc})
+	got, err := Extract(part("application/vnd.openxmlformats-officedocument.wordprocessingml.document", "invoice.docx", string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Invoice 2025-17\nDue:\tMarch\nNet 30\nItem\tAmount\nLunch for 4\t42.50\n\n"
+	if got.Kind != KindDocument || got.Text != want {
+		t.Errorf("Extract() = %s %q, want document %q", got.Kind, got.Text, want)
+	}
+}
+
+func TestExtract_Spreadsheet(t *testing.T) {
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Summary" sheetId="2" r:id="rId2"/><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="worksheet" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Item</t></si><si><r><t>Amo</t></r><r><t>unt</t></r></si><si><t>東京</t><rPh><t>トウキョウ</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><f>SUM(1,2)</f><v>3</v></c><c r="D3" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4" t="inlineStr"><is><t>inline</t></is></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="str"><v>Total</v></c></row></sheetData></worksheet>`,
+	}
+	got, err := Extract(part("application/octet-stream", "report.xlsx", string(zipped(t, files))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Table{
+		{Name: "Summary", Rows: [][]string{{"Total"}}},
+		{Name: "Data", Rows: [][]string{{"Item", "Amount"}, {}, {"東京", "", "3", "TRUE"}, {"", "inline"}}},
+	}
+	if got.Kind != KindSpreadsheet || !reflect.DeepEqual(got.Tables, want) {
+		t.Errorf("Tables = %q, want %q", got.Tables, want)
+	}
+	wantText := "## Summary\n\nTotal\n\n## Data\n\nItem\tAmount\n\n東京\t\t3\tTRUE\n\tinline\n"
+	if got.Text != wantText {
+		t.Errorf("Text = %q, want %q", got.Text, wantText)
+	}
+}
+
+func TestExtract_Presentation(t *testing.T) {
+	slide := func(text string) string {
+		return `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree><p:sp><p:txBody>` +
+			text + `</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	files := map[string]string{
+		"ppt/presentation.xml": `<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="257" r:id="rId3"/><p:sldId id="256" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships><Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="slides/slide2.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml":           slide(`<a:p><a:r><a:t>Roadmap</a:t></a:r></a:p><a:p><a:r><a:t>Q1</a:t></a:r><a:br/><a:r><a:t>Q2</a:t></a:r></a:p>`),
+		"ppt/slides/slide2.xml":           slide(`<a:p><a:r><a:t>Agenda</a:t></a:r></a:p>`),
+	}
+	got, err := Extract(part("application/vnd.openxmlformats-officedocument.presentationml.presentation", "deck.pptx", string(zipped(t, files))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Slide 1\n\nAgenda\n\n## Slide 2\n\nRoadmap\nQ1\nQ2\n"
+	if got.Kind != KindPresentation || got.Text != want {
+		t.Errorf("Extract() = %s %q, want presentation %q", got.Kind, got.Text, want)
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	// A document part that uncompresses to more than maxUncompressed bytes
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := bytes.Repeat([]byte(" "), 1<<20)
+	for range maxUncompressed>>20 + 1 {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		part *mimepart.Part
+		want error
+	}{
+		{name: "image", part: part("image/png", "logo.png", "\x89PNG"), want: ErrUnsupported},
+		{name: "pdf", part: part("application/pdf", "menu.pdf", "%PDF"), want: ErrUnsupported},
+		{name: "unknown binary", part: part("application/octet-stream", "data.bin", "\x00"), want: ErrUnsupported},
+		{name: "too large", part: part("text/plain", "", strings.Repeat("x", MaxSize+1)), want: ErrTooLarge},
+		{name: "zip bomb", part: part("", "bomb.docx", buf.String()), want: ErrTooLarge},
+		{name: "no zip", part: part("", "broken.xlsx", "not a zip"), want: ErrMalformed},
+		{name: "no document", part: part("", "empty.docx", string(zipped(t, map[string]string{"a.xml": "<a/>"}))), want: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Extract(tt.part); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
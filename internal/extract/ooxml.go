@@ -0,0 +1,456 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Dimensions of a sheet in Excel, which bound the cells of a sheet.
+const (
+	maxColumns   = 16384
+	maxSheetRows = 1048576
+)
+
+// ooxml is an Office Open XML package, see ECMA-376 Part 2.
+type ooxml struct {
+	zr *zip.Reader
+	// budget is the number of bytes that may still be read
+	budget int64
+}
+
+func openOOXML(data []byte) (*ooxml, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return &ooxml{zr: zr, budget: maxUncompressed}, nil
+}
+
+// read returns the content of a part, nil if it does not exist. Reading
+// more than maxUncompressed bytes in total fails with ErrTooLarge.
+func (o *ooxml) read(name string) ([]byte, error) {
+	f := o.find(name)
+	if f == nil {
+		return nil, nil
+	}
+	if f.UncompressedSize64 > uint64(o.budget) {
+		return nil, ErrTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	defer func() { _ = rc.Close() }()
+	// The size in the header is not trusted
+	data, err := io.ReadAll(io.LimitReader(rc, o.budget+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if int64(len(data)) > o.budget {
+		return nil, ErrTooLarge
+	}
+	o.budget -= int64(len(data))
+	return data, nil
+}
+
+// find returns the zip file of a part. Part names are case-insensitive.
+func (o *ooxml) find(name string) *zip.File {
+	for _, f := range o.zr.File {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// relationships returns the targets of the relationships of a part by ID,
+// as part names.
+func (o *ooxml) relationships(part string) (map[string]string, error) {
+	dir, file := path.Split(part)
+	data, err := o.read(dir + "_rels/" + file + ".rels")
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	err = walkXML(data, func(start *xml.StartElement, _ *xml.EndElement, _ []byte) {
+		if start == nil || start.Name.Local != "Relationship" || attr(start, "TargetMode") == "External" {
+			return
+		}
+		target := attr(start, "Target")
+		if strings.HasPrefix(target, "/") {
+			target = target[1:]
+		} else {
+			target = path.Join(dir, target)
+		}
+		targets[attr(start, "Id")] = target
+	})
+	return targets, err
+}
+
+// walkXML calls fn for each start element, end element and character data
+// of an XML document, with the other arguments nil.
+func walkXML(data []byte, fn func(start *xml.StartElement, end *xml.EndElement, text []byte)) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			fn(&t, nil, nil)
+		case xml.EndElement:
+			fn(nil, &t, nil)
+		case xml.CharData:
+			fn(nil, nil, t)
+		}
+	}
+}
+
+// attr returns the value of the attribute with the local name, or "".
+func attr(e *xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// relationshipID returns the r:id attribute of an element, which refers to
+// a relationship of the part.
+func relationshipID(e *xml.StartElement) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == "id" && strings.HasSuffix(a.Name.Space, "/relationships") {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// extractDocument extracts the text of a docx document, the paragraphs of
+// the main document part. Table rows are lines of tab separated cells.
+func extractDocument(data []byte) (*Result, error) {
+	o, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := o.read("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: no word/document.xml", ErrMalformed)
+	}
+
+	var b strings.Builder
+	var cell *strings.Builder
+	var row []string
+	cellDepth, inText := 0, false
+	out := func() *strings.Builder {
+		if cell != nil {
+			return cell
+		}
+		return &b
+	}
+	err = walkXML(doc, func(start *xml.StartElement, end *xml.EndElement, text []byte) {
+		switch {
+		case start != nil:
+			switch start.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out().WriteString("\t")
+			case "br", "cr":
+				out().WriteString("\n")
+			case "tc":
+				// Nested tables are flattened into the cell
+				if cellDepth++; cellDepth == 1 {
+					cell = &strings.Builder{}
+				}
+			}
+		case end != nil:
+			switch end.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out().WriteString("\n")
+			case "tc":
+				if cellDepth--; cellDepth == 0 {
+					row = append(row, strings.Join(strings.Fields(cell.String()), " "))
+					cell = nil
+				}
+			case "tr":
+				if cellDepth == 0 {
+					b.WriteString(strings.Join(row, "\t") + "\n")
+					row = nil
+				}
+			}
+		case inText:
+			out().Write(text)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Kind: KindDocument, Text: b.String()}, nil
+}
+
+// extractSpreadsheet extracts the sheets of an xlsx workbook in order. Cells
+// are returned as stored: numbers, including dates, are not formatted.
+func extractSpreadsheet(data []byte) (*Result, error) {
+	o, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := o.read("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	if workbook == nil {
+		return nil, fmt.Errorf("%w: no xl/workbook.xml", ErrMalformed)
+	}
+	rels, err := o.relationships("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	shared, err := o.sharedStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	type sheet struct{ name, part string }
+	var sheets []sheet
+	err = walkXML(workbook, func(start *xml.StartElement, _ *xml.EndElement, _ []byte) {
+		if start != nil && start.Name.Local == "sheet" {
+			sheets = append(sheets, sheet{name: attr(start, "name"), part: rels[relationshipID(start)]})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Kind: KindSpreadsheet, Tables: []Table{}}
+	var b strings.Builder
+	for _, s := range sheets {
+		content, err := o.read(s.part)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			continue
+		}
+		rows, err := sheetRows(content, shared)
+		if err != nil {
+			return nil, err
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("## " + s.name + "\n\n")
+		for _, row := range rows {
+			b.WriteString(strings.Join(row, "\t") + "\n")
+		}
+		if len(rows) > maxRows {
+			rows, result.Truncated = rows[:maxRows], true
+		}
+		result.Tables = append(result.Tables, Table{Name: s.name, Rows: rows})
+	}
+	result.Text = b.String()
+	return result, nil
+}
+
+// sharedStrings returns the shared strings of a workbook. Phonetic runs are
+// skipped.
+func (o *ooxml) sharedStrings() ([]string, error) {
+	data, err := o.read("xl/sharedStrings.xml")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var shared []string
+	var b strings.Builder
+	inText, inPhonetic := false, false
+	err = walkXML(data, func(start *xml.StartElement, end *xml.EndElement, text []byte) {
+		switch {
+		case start != nil:
+			switch start.Name.Local {
+			case "si":
+				b.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case end != nil:
+			switch end.Name.Local {
+			case "si":
+				shared = append(shared, b.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case inText && !inPhonetic:
+			b.Write(text)
+		}
+	})
+	return shared, err
+}
+
+// sheetRows returns the rows of a worksheet. Empty rows and cells that are
+// left out in the sheet are kept, empty cells at the end of a row are not.
+func sheetRows(data []byte, shared []string) ([][]string, error) {
+	rows := [][]string{}
+	var row []string
+	var value strings.Builder
+	cellType, column := "", 0
+	inValue := false
+	err := walkXML(data, func(start *xml.StartElement, end *xml.EndElement, text []byte) {
+		switch {
+		case start != nil:
+			switch start.Name.Local {
+			case "row":
+				if r, err := strconv.Atoi(attr(start, "r")); err == nil {
+					for len(rows) < r-1 && len(rows) < maxSheetRows {
+						rows = append(rows, []string{})
+					}
+				}
+				row = []string{}
+			case "c":
+				cellType = attr(start, "t")
+				column = len(row)
+				if c, ok := columnIndex(attr(start, "r")); ok {
+					column = c
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case end != nil:
+			switch end.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				v := cellValue(cellType, value.String(), shared)
+				if v != "" && column < maxColumns {
+					for len(row) < column {
+						row = append(row, "")
+					}
+					row = append(row[:column], v)
+				}
+			case "row":
+				rows = append(rows, row)
+			}
+		case inValue:
+			value.Write(text)
+		}
+	})
+	return rows, err
+}
+
+// cellValue returns the text of a cell by its type.
+func cellValue(cellType, value string, shared []string) string {
+	switch cellType {
+	case "s":
+		if i, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && i >= 0 && i < len(shared) {
+			return shared[i]
+		}
+		return ""
+	case "b":
+		if strings.TrimSpace(value) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return value
+	}
+}
+
+// columnIndex returns the zero based column of a cell reference like "C7".
+func columnIndex(ref string) (int, bool) {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A'+1)
+		if column > maxColumns {
+			return 0, false
+		}
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return column - 1, true
+}
+
+// extractPresentation extracts the text of the slides of a pptx
+// presentation in order.
+func extractPresentation(data []byte) (*Result, error) {
+	o, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	presentation, err := o.read("ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	if presentation == nil {
+		return nil, fmt.Errorf("%w: no ppt/presentation.xml", ErrMalformed)
+	}
+	rels, err := o.relationships("ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	var slides []string
+	err = walkXML(presentation, func(start *xml.StartElement, _ *xml.EndElement, _ []byte) {
+		if start != nil && start.Name.Local == "sldId" {
+			slides = append(slides, rels[relationshipID(start)])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	for i, part := range slides {
+		content, err := o.read(part)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("## Slide " + strconv.Itoa(i+1) + "\n\n")
+		inText := false
+		err = walkXML(content, func(start *xml.StartElement, end *xml.EndElement, text []byte) {
+			switch {
+			case start != nil && start.Name.Local == "t":
+				inText = true
+			case start != nil && start.Name.Local == "br":
+				b.WriteString("\n")
+			case end != nil && end.Name.Local == "t":
+				inText = false
+			case end != nil && end.Name.Local == "p":
+				b.WriteString("\n")
+			case inText:
+				b.Write(text)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Kind: KindPresentation, Text: b.String()}, nil
+}
@@ -0,0 +1,544 @@
+// Package header parses RFC 5322 header blocks as returned by Mail for the
+// headers of a message. Folded lines are unfolded and RFC 2047 encoded-words
+// are decoded in any charset known to browsers. Besides the raw fields, the
+// typed values of the headers that describe the origin of a message are
+// parsed, e.g. List-Id, Authentication-Results or Received.
+package header
+
+import (
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Field is a header field. Value is unfolded and decoded.
+type Field struct {
+	Name  string
+	Value string
+
+	// raw is the unfolded value before decoding, which is parsed for the
+	// typed values
+	raw string
+}
+
+// Header is a parsed header block, the fields in order of occurrence.
+type Header []Field
+
+// Parse parses a header block. Parsing stops at the first empty line. Lines
+// that are neither fields nor continuation lines, e.g. the "From " line of
+// an mbox, are skipped.
+func Parse(raw string) Header {
+	var h Header
+	var lines []string
+	for line := range strings.Lines(raw) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		name, value, ok := strings.Cut(lines[i], ":")
+		// Obsolete syntax allows white space before the colon
+		name = strings.TrimRight(name, " \t")
+		if !ok || !validName(name) {
+			continue
+		}
+		// Unfolding removes the line breaks before continuation lines
+		for i+1 < len(lines) && isContinuation(lines[i+1]) {
+			i++
+			value += lines[i]
+		}
+		value = strings.TrimSpace(value)
+		h = append(h, Field{Name: name, Value: Decode(value), raw: value})
+	}
+	return h
+}
+
+// validName reports whether name is a field name of printable ASCII
+// characters except the colon.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < 33 || name[i] > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func isContinuation(line string) bool {
+	return line[0] == ' ' || line[0] == '\t'
+}
+
+// Get returns the value of the first field with the name, which is case
+// insensitive, or "" if there is none.
+func (h Header) Get(name string) string {
+	if f := h.field(name); f != nil {
+		return f.Value
+	}
+	return ""
+}
+
+// Values returns the values of all fields with the name, which is case
+// insensitive, in order of occurrence.
+func (h Header) Values(name string) []string {
+	var values []string
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// Map returns the values of the fields by their canonical name, e.g.
+// "Message-Id" for "Message-ID", in order of occurrence.
+func (h Header) Map() map[string][]string {
+	m := make(map[string][]string)
+	for _, f := range h {
+		name := textproto.CanonicalMIMEHeaderKey(f.Name)
+		m[name] = append(m[name], f.Value)
+	}
+	return m
+}
+
+func (h Header) field(name string) *Field {
+	for i := range h {
+		if strings.EqualFold(h[i].Name, name) {
+			return &h[i]
+		}
+	}
+	return nil
+}
+
+// rawValues returns the undecoded values of all fields with the name.
+func (h Header) rawValues(name string) []string {
+	var values []string
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			values = append(values, f.raw)
+		}
+	}
+	return values
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// Decode decodes the RFC 2047 encoded-words of a header value, e.g.
+// "=?ISO-8859-1?Q?Caf=E9?=" becomes "Café". White space between adjacent
+// encoded-words is dropped. If a word cannot be decoded, e.g. because of an
+// unknown charset, the value is returned as is.
+func Decode(value string) string {
+	if !strings.Contains(value, "=?") {
+		return value
+	}
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// MessageIDs returns the message IDs in angle brackets of a header value,
+// without the brackets. Duplicates are dropped.
+func MessageIDs(value string) []string {
+	var ids []string
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			return ids
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			return ids
+		}
+		id := strings.TrimSpace(value[start+1 : start+end])
+		if id != "" && !strings.ContainsAny(id, " \t\r\n") && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+		value = value[start+end+1:]
+	}
+}
+
+// InReplyTo returns the message IDs of the In-Reply-To header.
+func (h Header) InReplyTo() []string {
+	return MessageIDs(strings.Join(h.rawValues("In-Reply-To"), " "))
+}
+
+// References returns the message IDs of the References header, oldest
+// first.
+func (h Header) References() []string {
+	return MessageIDs(strings.Join(h.rawValues("References"), " "))
+}
+
+// ListID is the identifier of a mailing list, see RFC 2919.
+type ListID struct {
+	ID          string `json:"id" jsonschema:"Identifier of the list, e.g. dev.lists.example.com"`
+	Description string `json:"description,omitempty" jsonschema:"Description of the list"`
+}
+
+// ListID returns the List-Id header, or nil if there is none.
+func (h Header) ListID() *ListID {
+	f := h.field("List-Id")
+	if f == nil {
+		return nil
+	}
+	value := stripComments(f.raw)
+	start := strings.LastIndexByte(value, '<')
+	end := strings.LastIndexByte(value, '>')
+	if start < 0 || end < start {
+		// Obsolete lists have an identifier without brackets
+		id := strings.TrimSpace(value)
+		if id == "" || strings.ContainsAny(id, " \t") {
+			return nil
+		}
+		return &ListID{ID: id}
+	}
+	id := strings.TrimSpace(value[start+1 : end])
+	if id == "" {
+		return nil
+	}
+	description := strings.TrimSpace(Decode(strings.TrimSpace(value[:start])))
+	return &ListID{ID: id, Description: unquote(description)}
+}
+
+// ListUnsubscribe are the ways to unsubscribe from a mailing list, see
+// RFC 2369 and RFC 8058.
+type ListUnsubscribe struct {
+	URIs     []string `json:"uris" jsonschema:"URIs to unsubscribe, in order of preference, e.g. mailto: or https: URIs"`
+	OneClick bool     `json:"oneClick" jsonschema:"Whether a POST to the https URI unsubscribes without further interaction (RFC 8058)"`
+}
+
+// ListUnsubscribe returns the List-Unsubscribe header, or nil if there is
+// none.
+func (h Header) ListUnsubscribe() *ListUnsubscribe {
+	var uris []string
+	for _, value := range h.rawValues("List-Unsubscribe") {
+		for {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			// White space within the brackets is ignored
+			uri := strings.Join(strings.Fields(value[start+1:end]), "")
+			if uri != "" {
+				uris = append(uris, uri)
+			}
+			value = value[end+1:]
+		}
+	}
+	if len(uris) == 0 {
+		return nil
+	}
+
+	u := &ListUnsubscribe{URIs: uris}
+	post := strings.Join(strings.Fields(h.Get("List-Unsubscribe-Post")), "")
+	if strings.EqualFold(post, "List-Unsubscribe=One-Click") {
+		u.OneClick = slices.ContainsFunc(uris, func(uri string) bool {
+			return strings.HasPrefix(strings.ToLower(uri), "https:")
+		})
+	}
+	return u
+}
+
+// AutoSubmitted returns the lower case value of the Auto-Submitted header
+// without parameters, e.g. "auto-generated" or "auto-replied", see
+// RFC 3834. It is "" if there is no such header.
+func (h Header) AutoSubmitted() string {
+	f := h.field("Auto-Submitted")
+	if f == nil {
+		return ""
+	}
+	value, _, _ := strings.Cut(stripComments(f.raw), ";")
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// AuthenticationResults are the results of the checks of the sender of a
+// message by a server, see RFC 8601.
+type AuthenticationResults struct {
+	AuthServID string       `json:"authservId" jsonschema:"Server that performed the checks"`
+	Results    []AuthResult `json:"results" jsonschema:"Results of the checks"`
+}
+
+// AuthResult is the result of a single check, e.g. SPF, DKIM or DMARC.
+type AuthResult struct {
+	Method     string            `json:"method" jsonschema:"Method of the check, e.g. spf, dkim or dmarc"`
+	Result     string            `json:"result" jsonschema:"Result of the check, e.g. pass, fail, softfail, neutral or none"`
+	Reason     string            `json:"reason,omitempty" jsonschema:"Reason for the result"`
+	Properties map[string]string `json:"properties,omitempty" jsonschema:"Properties of the message that were checked, e.g. smtp.mailfrom or header.d"`
+}
+
+// AuthenticationResults returns the Authentication-Results headers, the
+// results of the server that received the message last first. Headers
+// without authserv-id are skipped.
+func (h Header) AuthenticationResults() []AuthenticationResults {
+	var all []AuthenticationResults
+	for _, value := range h.rawValues("Authentication-Results") {
+		parts := splitUnquoted(stripComments(value), ';')
+		// The authserv-id may be followed by a version
+		fields := strings.Fields(parts[0])
+		if len(fields) == 0 {
+			continue
+		}
+		ar := AuthenticationResults{AuthServID: fields[0], Results: []AuthResult{}}
+		for _, part := range parts[1:] {
+			pairs := parsePairs(part)
+			if len(pairs) == 0 {
+				// "none" or an empty result
+				continue
+			}
+			method, _, _ := strings.Cut(pairs[0][0], "/")
+			r := AuthResult{Method: strings.ToLower(method), Result: strings.ToLower(pairs[0][1])}
+			for _, p := range pairs[1:] {
+				if strings.EqualFold(p[0], "reason") {
+					r.Reason = p[1]
+					continue
+				}
+				if r.Properties == nil {
+					r.Properties = make(map[string]string)
+				}
+				r.Properties[strings.ToLower(p[0])] = p[1]
+			}
+			ar.Results = append(ar.Results, r)
+		}
+		all = append(all, ar)
+	}
+	return all
+}
+
+// Received is a hop of a message from one server to the next, see
+// RFC 5321.
+type Received struct {
+	From   string `json:"from,omitempty" jsonschema:"Name the sending host gave itself"`
+	FromIP string `json:"fromIp,omitempty" jsonschema:"IP address of the sending host as seen by the receiving host"`
+	By     string `json:"by,omitempty" jsonschema:"Receiving host"`
+	Via    string `json:"via,omitempty" jsonschema:"Link the message was received over"`
+	With   string `json:"with,omitempty" jsonschema:"Protocol the message was received with, e.g. ESMTPS"`
+	ID     string `json:"id,omitempty" jsonschema:"ID of the message at the receiving host"`
+	For    string `json:"for,omitempty" jsonschema:"Recipient the message was received for"`
+	Date   string `json:"date,omitempty" jsonschema:"ISO 8601 date the message was received"`
+}
+
+// ipLiteral matches the IP address in the comment of a from clause, e.g.
+// "(mail.example.com [192.0.2.1])".
+var ipLiteral = regexp.MustCompile(`\[(?:IPv6:)?([0-9A-Fa-f:.]+)\]`)
+
+// Received returns the Received headers in order of occurrence, i.e. the
+// last hop first.
+func (h Header) Received() []Received {
+	var hops []Received
+	for _, value := range h.rawValues("Received") {
+		var hop Received
+		clauses := value
+		if i := strings.LastIndexByte(value, ';'); i >= 0 {
+			clauses = value[:i]
+			date := strings.TrimSpace(stripComments(value[i+1:]))
+			if t, err := mail.ParseDate(date); err == nil {
+				hop.Date = t.Format(time.RFC3339)
+			}
+		}
+
+		var key string
+		for _, tok := range tokenize(clauses) {
+			if strings.HasPrefix(tok, "(") {
+				if key == "from" && hop.FromIP == "" {
+					if m := ipLiteral.FindStringSubmatch(tok); m != nil {
+						hop.FromIP = m[1]
+					}
+				}
+				continue
+			}
+			var target *string
+			switch key {
+			case "from":
+				target = &hop.From
+			case "by":
+				target = &hop.By
+			case "via":
+				target = &hop.Via
+			case "with":
+				target = &hop.With
+			case "id":
+				target = &hop.ID
+			case "for":
+				target = &hop.For
+			}
+			if target != nil && *target == "" {
+				*target = strings.Trim(tok, "<>")
+				continue
+			}
+			key = strings.ToLower(tok)
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// tokenize splits a value into words and comments, which keep their
+// parentheses.
+func tokenize(value string) []string {
+	var tokens []string
+	for i := 0; i < len(value); {
+		switch c := value[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(':
+			end := commentEnd(value, i)
+			tokens = append(tokens, value[i:end])
+			i = end
+		default:
+			end := i
+			for end < len(value) && !strings.ContainsRune(" \t\r\n(", rune(value[end])) {
+				end++
+			}
+			tokens = append(tokens, value[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+// commentEnd returns the index after the comment starting at i, which may
+// contain nested comments and quoted pairs.
+func commentEnd(value string, i int) int {
+	depth := 0
+	for ; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(value)
+}
+
+// stripComments replaces the comments of a value, which are not within
+// quoted strings, with a space.
+func stripComments(value string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(value):
+			b.WriteByte(c)
+			i++
+			c = value[i]
+		case c == '"':
+			quoted = !quoted
+		case c == '(' && !quoted:
+			i = commentEnd(value, i) - 1
+			c = ' '
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// splitUnquoted splits a value at sep, except within quoted strings.
+func splitUnquoted(value string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// parsePairs parses the key=value pairs of a value separated by white
+// space. Values may be quoted strings. Words without value are skipped.
+func parsePairs(value string) [][2]string {
+	var pairs [][2]string
+	i := 0
+	skipSpace := func() {
+		for i < len(value) && (value[i] == ' ' || value[i] == '\t') {
+			i++
+		}
+	}
+	for {
+		skipSpace()
+		if i >= len(value) {
+			return pairs
+		}
+		start := i
+		for i < len(value) && value[i] != '=' && value[i] != ' ' && value[i] != '\t' {
+			i++
+		}
+		key := value[start:i]
+		skipSpace()
+		if i >= len(value) || value[i] != '=' {
+			continue
+		}
+		i++
+		skipSpace()
+
+		var v strings.Builder
+		if i < len(value) && value[i] == '"' {
+			for i++; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+				}
+				v.WriteByte(value[i])
+			}
+			i++
+		} else {
+			for i < len(value) && value[i] != ' ' && value[i] != '\t' {
+				v.WriteByte(value[i])
+				i++
+			}
+		}
+		pairs = append(pairs, [2]string{key, v.String()})
+	}
+}
+
+// unquote removes the quotes of a quoted string and its quoted pairs.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,344 @@
+package header
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// gmail is the header block of a mailing list message received by Gmail.
+const gmail = "Delivered-To: jane.doe@example.com\r\n" +
+	"Received: by 2002:a05:7300:5b8a:b0:f1:3c8 with SMTP id x10csp123456dyb;\r\n" +
+	"        Tue, 4 Mar 2025 01:02:03 -0800 (PST)\r\n" +
+	"Received: from mail.lists.example.org (mail.lists.example.org. [192.0.2.25])\r\n" +
+	"        by mx.google.com with ESMTPS id a1si2345678wrb.12.2025.03.04.01.02.03\r\n" +
+	"        for <jane.doe@example.com>\r\n" +
+	"        (version=TLS1_3 cipher=TLS_AES_256_GCM_SHA384 bits=256/256);\r\n" +
+	"        Tue, 04 Mar 2025 01:02:03 -0800 (PST)\r\n" +
+	"Authentication-Results: mx.google.com;\r\n" +
+	"       dkim=pass header.i=@lists.example.org header.s=s1 header.b=AbCd;\r\n" +
+	"       spf=pass (google.com: domain of dev-bounces@lists.example.org designates 192.0.2.25 as permitted sender) smtp.mailfrom=dev-bounces@lists.example.org;\r\n" +
+	"       dmarc=pass (p=NONE sp=NONE dis=NONE) header.from=example.org\r\n" +
+	"From: =?UTF-8?Q?Jos=C3=A9_Mart=C3=ADnez?= <jose@example.org>\r\n" +
+	"To: dev@lists.example.org\r\n" +
+	"Subject: =?ISO-8859-1?Q?Re:_Caf=E9_?=\r\n" +
+	" =?ISO-8859-1?Q?planning?=\r\n" +
+	"Message-ID: <CAB123@mail.example.org>\r\n" +
+	"In-Reply-To: <CAA999@mail.example.org>\r\n" +
+	"References: <CAA000@mail.example.org>\r\n" +
+	"\t<CAA999@mail.example.org>\r\n" +
+	"List-Id: =?UTF-8?Q?Entwickler_=C3=9Cbersicht?= <dev.lists.example.org>\r\n" +
+	"List-Unsubscribe: <mailto:dev-leave@lists.example.org?subject=unsubscribe>,\r\n" +
+	" <https://lists.example.org/unsubscribe/\r\n" +
+	" dev?token=abc>\r\n" +
+	"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n" +
+	"\r\n" +
+	"Body: not a header\r\n"
+
+// notification is the header block of an automated message as Mail returns
+// it, with bare line feeds.
+const notification = "From Mailer-Daemon Tue Mar  4 09:00:00 2025\n" +
+	"Return-Path: <>\n" +
+	"Received: from localhost (localhost [IPv6:::1])\n" +
+	"\tby mail.example.com (Postfix) with ESMTP id 4Z1X2Y3W\n" +
+	"\tfor <ops@example.com>; Tue,  4 Mar 2025 09:00:00 +0000 (UTC)\n" +
+	"Authentication-Results: mail.example.com; none\n" +
+	"Authentication-Results: relay.example.net 1; spf=softfail reason=\"not permitted; but tolerated\" smtp.mailfrom=ci.example.com; dkim=fail (bad signature) header.d=ci.example.com\n" +
+	"Auto-Submitted: Auto-Generated; type=ci (build system)\n" +
+	"Subject: =?koi8-r?B?8NLJ18XU?= build =?x-unknown?Q?abc?=\n" +
+	"X-Note: one\n" +
+	"X-NOTE: two\n" +
+	"List-Id: ci.example.com\n"
+
+func TestParse(t *testing.T) {
+	h := Parse(gmail)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Subject", "Re: Café planning"},
+		{"from", "José Martínez <jose@example.org>"},
+		{"References", "<CAA000@mail.example.org>\t<CAA999@mail.example.org>"},
+		{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		{"Body", ""},
+		{"X-Missing", ""},
+	}
+	for _, tt := range tests {
+		if got := h.Get(tt.name); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := h.Values("received"); len(got) != 2 || got[0] != "by 2002:a05:7300:5b8a:b0:f1:3c8 with SMTP id x10csp123456dyb;        Tue, 4 Mar 2025 01:02:03 -0800 (PST)" {
+		t.Errorf("Values(received) = %q", got)
+	}
+
+	n := Parse(notification)
+	if n[0].Name != "Return-Path" {
+		t.Errorf("first field = %q, want the mbox line to be skipped", n[0].Name)
+	}
+	if got := n.Values("X-Note"); !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("Values(X-Note) = %q, want both fields", got)
+	}
+	m := n.Map()
+	if got := m["X-Note"]; !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("Map()[X-Note] = %q", got)
+	}
+	if got := m["Authentication-Results"]; len(got) != 2 {
+		t.Errorf("Map()[Authentication-Results] = %q, want 2 values", got)
+	}
+	// An unknown charset leaves the value undecoded
+	if got := n.Get("Subject"); got != "=?koi8-r?B?8NLJ18XU?= build =?x-unknown?Q?abc?=" {
+		t.Errorf("Get(Subject) = %q", got)
+	}
+
+	if got := Parse(""); len(got) != 0 {
+		t.Errorf("Parse(\"\") = %v, want no fields", got)
+	}
+	// A continuation line without field is skipped
+	if got := Parse(" folded\nA: b\n"); len(got) != 1 || got[0].Name != "A" || got[0].Value != "b" {
+		t.Errorf("Parse() = %v, want only field A", got)
+	}
+	// Obsolete white space before the colon
+	if got := Parse("Subject : hello\n").Get("Subject"); got != "hello" {
+		t.Errorf("Get(Subject) = %q, want hello", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain text", "plain text"},
+		{"=?UTF-8?B?SGFsbG8gV2VsdA==?=", "Hallo Welt"},
+		{"=?iso-8859-1?q?Gr=FC=DFe?=", "Grüße"},
+		{"=?windows-1252?Q?=80_100?=", "€ 100"},
+		{"=?koi8-r?B?8NLJ18XU?=", "Привет"},
+		{"=?ISO-2022-JP?B?GyRCJEYkOSRIGyhC?=", "てすと"},
+		{"=?Shift_JIS?B?g2WDWINn?=", "テスト"},
+		{"=?GB2312?B?1tDOxA==?=", "中文"},
+		{"=?UTF-8?Q?a?= =?UTF-8?Q?b?=", "ab"},
+		{"=?UTF-8?Q?a?= and =?UTF-8?Q?b?=", "a and b"},
+		{"=?x-unknown?Q?a?=", "=?x-unknown?Q?a?="},
+		{"=?UTF-8?Q?broken", "=?UTF-8?Q?broken"},
+	}
+	for _, tt := range tests {
+		if got := Decode(tt.value); got != tt.want {
+			t.Errorf("Decode(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMessageIDs(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"<a@x> <b@x>", []string{"a@x", "b@x"}},
+		{"<a@x>\r\n <b@x>", []string{"a@x", "b@x"}},
+		{"<b@x> (Jane's message of Monday)", []string{"b@x"}},
+		{"<a@x> <a@x>", []string{"a@x"}},
+		{"garbage <not an id> <ok@x>", []string{"ok@x"}},
+		{"<unterminated", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := MessageIDs(tt.value); !slices.Equal(got, tt.want) {
+			t.Errorf("MessageIDs(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	h := Parse(gmail)
+	if got := h.InReplyTo(); !slices.Equal(got, []string{"CAA999@mail.example.org"}) {
+		t.Errorf("InReplyTo() = %q", got)
+	}
+	if got := h.References(); !slices.Equal(got, []string{"CAA000@mail.example.org", "CAA999@mail.example.org"}) {
+		t.Errorf("References() = %q", got)
+	}
+	if got := Parse(notification).References(); got != nil {
+		t.Errorf("References() = %q, want none", got)
+	}
+}
+
+func TestHeader_ListID(t *testing.T) {
+	tests := []struct {
+		header string
+		want   *ListID
+	}{
+		{gmail, &ListID{ID: "dev.lists.example.org", Description: "Entwickler Übersicht"}},
+		{notification, &ListID{ID: "ci.example.com"}},
+		{"List-Id: \"The \\\"dev\\\" list\" <dev.example.com>\n", &ListID{ID: "dev.example.com", Description: `The "dev" list`}},
+		{"List-Id: <dev.example.com> (comment)\n", &ListID{ID: "dev.example.com"}},
+		{"List-Id: not an id\n", nil},
+		{"List-Id: Empty <>\n", nil},
+		{"Subject: no list\n", nil},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.header).ListID(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListID() of %q = %+v, want %+v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHeader_ListUnsubscribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   *ListUnsubscribe
+	}{
+		{
+			name:   "folded with one-click",
+			header: gmail,
+			want: &ListUnsubscribe{
+				URIs:     []string{"mailto:dev-leave@lists.example.org?subject=unsubscribe", "https://lists.example.org/unsubscribe/dev?token=abc"},
+				OneClick: true,
+			},
+		},
+		{
+			name:   "one-click requires https",
+			header: "List-Unsubscribe: <mailto:leave@example.com>\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\n",
+			want:   &ListUnsubscribe{URIs: []string{"mailto:leave@example.com"}},
+		},
+		{
+			name:   "without post",
+			header: "List-Unsubscribe: (Use this) <https://example.com/u>\n",
+			want:   &ListUnsubscribe{URIs: []string{"https://example.com/u"}},
+		},
+		{
+			name:   "none",
+			header: notification,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header).ListUnsubscribe(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListUnsubscribe() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader_AutoSubmitted(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{notification, "auto-generated"},
+		{"Auto-Submitted: auto-replied\n", "auto-replied"},
+		{"Auto-Submitted: no\n", "no"},
+		{gmail, ""},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.header).AutoSubmitted(); got != tt.want {
+			t.Errorf("AutoSubmitted() of %q = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHeader_AuthenticationResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []AuthenticationResults
+	}{
+		{
+			name:   "gmail",
+			header: gmail,
+			want: []AuthenticationResults{{
+				AuthServID: "mx.google.com",
+				Results: []AuthResult{
+					{Method: "dkim", Result: "pass", Properties: map[string]string{"header.i": "@lists.example.org", "header.s": "s1", "header.b": "AbCd"}},
+					{Method: "spf", Result: "pass", Properties: map[string]string{"smtp.mailfrom": "dev-bounces@lists.example.org"}},
+					{Method: "dmarc", Result: "pass", Properties: map[string]string{"header.from": "example.org"}},
+				},
+			}},
+		},
+		{
+			name:   "none, version, quoted reason and comments",
+			header: notification,
+			want: []AuthenticationResults{
+				{AuthServID: "mail.example.com", Results: []AuthResult{}},
+				{
+					AuthServID: "relay.example.net",
+					Results: []AuthResult{
+						{Method: "spf", Result: "softfail", Reason: "not permitted; but tolerated", Properties: map[string]string{"smtp.mailfrom": "ci.example.com"}},
+						{Method: "dkim", Result: "fail", Properties: map[string]string{"header.d": "ci.example.com"}},
+					},
+				},
+			},
+		},
+		{
+			name:   "method version and spaces around the equals sign",
+			header: "Authentication-Results: mx.example.com; DKIM/1 = Pass header.d = example.com\n",
+			want: []AuthenticationResults{{
+				AuthServID: "mx.example.com",
+				Results:    []AuthResult{{Method: "dkim", Result: "pass", Properties: map[string]string{"header.d": "example.com"}}},
+			}},
+		},
+		{
+			name:   "without authserv-id",
+			header: "Authentication-Results: ; spf=pass\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header).AuthenticationResults(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthenticationResults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader_Received(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []Received
+	}{
+		{
+			name:   "gmail",
+			header: gmail,
+			want: []Received{
+				{By: "2002:a05:7300:5b8a:b0:f1:3c8", With: "SMTP", ID: "x10csp123456dyb", Date: "2025-03-04T01:02:03-08:00"},
+				{
+					From:   "mail.lists.example.org",
+					FromIP: "192.0.2.25",
+					By:     "mx.google.com",
+					With:   "ESMTPS",
+					ID:     "a1si2345678wrb.12.2025.03.04.01.02.03",
+					For:    "jane.doe@example.com",
+					Date:   "2025-03-04T01:02:03-08:00",
+				},
+			},
+		},
+		{
+			name:   "postfix with IPv6",
+			header: notification,
+			want: []Received{{
+				From:   "localhost",
+				FromIP: "::1",
+				By:     "mail.example.com",
+				With:   "ESMTP",
+				ID:     "4Z1X2Y3W",
+				For:    "ops@example.com",
+				Date:   "2025-03-04T09:00:00Z",
+			}},
+		},
+		{
+			name:   "via and invalid date",
+			header: "Received: from a.example.com via UUCP by b.example.com; yesterday\n",
+			want:   []Received{{From: "a.example.com", Via: "UUCP", By: "b.example.com"}},
+		},
+		{
+			name:   "none",
+			header: "Subject: hello\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header).Received(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Received() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
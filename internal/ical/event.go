@@ -0,0 +1,199 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar is a VCALENDAR with its events.
+type Calendar struct {
+	// Method is the upper case iTIP method, e.g. "REQUEST" or "CANCEL", or
+	// "" for a published calendar, see RFC 5546.
+	Method string
+	Events []Event
+}
+
+// Event is a VEVENT.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	// Status is the upper case status, e.g. "CONFIRMED" or "CANCELLED".
+	Status string
+	Start  time.Time
+	// End is the end of the event, derived from DTSTART and DURATION if
+	// there is no DTEND. It is the zero time if neither is given.
+	End time.Time
+	// AllDay reports whether the event lasts whole days. Start and End are
+	// then midnight UTC of the first day and of the day after the last.
+	AllDay bool
+	// TimeZone is the TZID of DTSTART, "" for UTC, floating times and
+	// all-day events.
+	TimeZone  string
+	Organizer *Participant
+	Attendees []Participant
+	// Recurrence is the RRULE of a recurring event, or nil.
+	Recurrence *Recurrence
+	// RecurrenceID is the start of the occurrence of a recurring event that
+	// the event replaces, the zero time for other events.
+	RecurrenceID time.Time
+}
+
+// Participant is the ORGANIZER or an ATTENDEE of an event.
+type Participant struct {
+	// Name is the common name, the CN parameter.
+	Name string
+	// Email is the address of a mailto: URI, or the URI itself.
+	Email string
+	// Role is the upper case role, e.g. "REQ-PARTICIPANT" or "CHAIR".
+	Role string
+	// Status is the upper case participation status, e.g. "NEEDS-ACTION"
+	// or "ACCEPTED".
+	Status string
+	// RSVP reports whether a reply is expected.
+	RSVP bool
+}
+
+// ParseCalendar parses the VCALENDARs of iCalendar data. The events of all
+// calendars are returned in order, the method is the method of the first
+// calendar that has one.
+func ParseCalendar(data []byte) (*Calendar, error) {
+	roots, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	cal := &Calendar{}
+	found := false
+	for _, root := range roots {
+		if root.Name != "VCALENDAR" {
+			continue
+		}
+		found = true
+		if cal.Method == "" {
+			cal.Method = strings.ToUpper(strings.TrimSpace(root.Text("METHOD")))
+		}
+		zones := newTimezones(root.Children("VTIMEZONE"))
+		for _, c := range root.Children("VEVENT") {
+			cal.Events = append(cal.Events, parseEvent(c, zones))
+		}
+	}
+	if !found {
+		return nil, errors.New("no VCALENDAR found")
+	}
+	return cal, nil
+}
+
+func parseEvent(c *Component, zones timezones) Event {
+	e := Event{
+		UID:         c.Text("UID"),
+		Summary:     c.Text("SUMMARY"),
+		Description: c.Text("DESCRIPTION"),
+		Location:    c.Text("LOCATION"),
+		Status:      strings.ToUpper(c.Text("STATUS")),
+	}
+	if p := c.Get("DTSTART"); p != nil {
+		e.Start, e.AllDay, _ = zones.parseTime(*p)
+		if !e.AllDay && !strings.HasSuffix(p.Value, "Z") {
+			e.TimeZone = p.Param("TZID")
+		}
+	}
+	if p := c.Get("DTEND"); p != nil {
+		e.End, _, _ = zones.parseTime(*p)
+	} else if p := c.Get("DURATION"); p != nil && !e.Start.IsZero() {
+		if d, err := ParseDuration(p.Value); err == nil {
+			e.End = e.Start.Add(d)
+		}
+	} else if e.AllDay {
+		// An all-day event without end lasts one day
+		e.End = e.Start.AddDate(0, 0, 1)
+	}
+	if p := c.Get("RECURRENCE-ID"); p != nil {
+		e.RecurrenceID, _, _ = zones.parseTime(*p)
+	}
+	if p := c.Get("RRULE"); p != nil {
+		e.Recurrence, _ = ParseRecurrence(p.Value)
+	}
+	if p := c.Get("ORGANIZER"); p != nil {
+		organizer := parseParticipant(*p)
+		e.Organizer = &organizer
+	}
+	for _, p := range c.All("ATTENDEE") {
+		e.Attendees = append(e.Attendees, parseParticipant(p))
+	}
+	return e
+}
+
+func parseParticipant(p Property) Participant {
+	email := strings.TrimSpace(p.Value)
+	if len(email) > len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+		email = email[len("mailto:"):]
+	}
+	return Participant{
+		Name:   p.Param("CN"),
+		Email:  email,
+		Role:   strings.ToUpper(p.Param("ROLE")),
+		Status: strings.ToUpper(p.Param("PARTSTAT")),
+		RSVP:   strings.EqualFold(p.Param("RSVP"), "TRUE"),
+	}
+}
+
+// ParseDuration parses a DURATION value, e.g. "PT1H30M", "P1D" or "-P1W".
+func ParseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		s, sign = rest, -1
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+	s, ok := strings.CutPrefix(s, "P")
+	if !ok || s == "" {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+
+	var d time.Duration
+	inTime, units := false, 0
+	for s != "" {
+		if rest, ok := strings.CutPrefix(s, "T"); ok {
+			s, inTime = rest, true
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		var unit time.Duration
+		switch {
+		case !inTime && s[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && s[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && s[i] == 'H':
+			unit = time.Hour
+		case inTime && s[i] == 'M':
+			unit = time.Minute
+		case inTime && s[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		d += time.Duration(n) * unit
+		s = s[i+1:]
+		units++
+	}
+	if units == 0 {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+	return sign * d, nil
+}
@@ -0,0 +1,243 @@
+// Package ical parses iCalendar data, see RFC 5545, into its tree of
+// components. vCard data, see RFC 6350, shares the content line syntax and is
+// parsed with it, too. Parsing is lenient: lines that are not content lines
+// are skipped and components that are not closed end with the data.
+package ical
+
+import (
+	"errors"
+	"strings"
+)
+
+// maxDepth bounds the nesting of components, deeper components are skipped.
+const maxDepth = 16
+
+// Property is a content line, e.g. "DTSTART;TZID=Europe/Berlin:20250301T100000".
+type Property struct {
+	// Name is the upper case name, e.g. "DTSTART".
+	Name string
+	// Params are the parameters with upper case names. Parameters without
+	// a value, as in vCard 2.1 "TEL;WORK:...", are kept as values of TYPE.
+	Params map[string][]string
+	// Value is the raw value, escaped text values are not unescaped.
+	Value string
+}
+
+// Param returns the first value of the parameter, or "".
+func (p Property) Param(name string) string {
+	if values := p.Params[strings.ToUpper(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Text returns the value unescaped as a TEXT value.
+func (p Property) Text() string {
+	return Unescape(p.Value)
+}
+
+// Component is a component, e.g. a VCALENDAR, VEVENT or VCARD.
+type Component struct {
+	// Name is the upper case name, e.g. "VEVENT".
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// Get returns the first property with the name, or nil.
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped value of the first property with the name, or
+// "".
+func (c *Component) Text(name string) string {
+	if p := c.Get(name); p != nil {
+		return p.Text()
+	}
+	return ""
+}
+
+// All returns the properties with the name.
+func (c *Component) All(name string) []Property {
+	var all []Property
+	for _, p := range c.Properties {
+		if p.Name == name {
+			all = append(all, p)
+		}
+	}
+	return all
+}
+
+// Children returns the child components with the name.
+func (c *Component) Children(name string) []*Component {
+	var children []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Parse parses the components of iCalendar or vCard data, e.g. the
+// VCALENDAR of an .ics file or the VCARDs of a .vcf file.
+func Parse(data []byte) ([]*Component, error) {
+	var roots []*Component
+	var stack []*Component
+	for _, line := range unfold(strings.TrimPrefix(string(data), "\ufeff")) {
+		p, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		switch p.Name {
+		case "BEGIN":
+			c := &Component{Name: strings.ToUpper(strings.TrimSpace(p.Value))}
+			switch {
+			case len(stack) == 0:
+				roots = append(roots, c)
+			case len(stack) < maxDepth:
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			// END closes the innermost component of the name, unclosed
+			// components inside it end with it
+			name := strings.ToUpper(strings.TrimSpace(p.Value))
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].Name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			if len(stack) > 0 {
+				c := stack[len(stack)-1]
+				c.Properties = append(c.Properties, p)
+			}
+		}
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("no iCalendar or vCard components found")
+	}
+	return roots, nil
+}
+
+// unfold splits data into content lines, joining folded lines, which start
+// with a space or tab.
+func unfold(data string) []string {
+	var lines []string
+	for line := range strings.Lines(data) {
+		line = strings.TrimRight(line, "\r\n")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseLine parses a content line "name *(;param) :value". Colons and
+// semicolons in quoted parameter values do not end them.
+func parseLine(line string) (Property, bool) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return Property{}, false
+	}
+	p := Property{Name: strings.ToUpper(line[:end]), Params: map[string][]string{}}
+	// vCard groups, e.g. "item1.EMAIL", are dropped
+	if i := strings.LastIndexByte(p.Name, '.'); i >= 0 {
+		p.Name = p.Name[i+1:]
+	}
+
+	rest := line[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		i, quoted := 0, false
+		for ; i < len(rest); i++ {
+			if rest[i] == '"' {
+				quoted = !quoted
+			} else if !quoted && (rest[i] == ';' || rest[i] == ':') {
+				break
+			}
+		}
+		name, value, ok := strings.Cut(rest[:i], "=")
+		if !ok {
+			name, value = "TYPE", name
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		p.Params[name] = append(p.Params[name], splitParamValues(value)...)
+		rest = rest[i:]
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return Property{}, false
+	}
+	p.Value = rest[1:]
+	return p, true
+}
+
+// splitParamValues splits a parameter value at commas outside of quotes and
+// removes the quotes.
+func splitParamValues(value string) []string {
+	var values []string
+	start, quoted := 0, false
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) && value[i] == '"' {
+			quoted = !quoted
+		}
+		if i == len(value) || value[i] == ',' && !quoted {
+			values = append(values, strings.Trim(value[start:i], `"`))
+			start = i + 1
+		}
+	}
+	return values
+}
+
+// Unescape unescapes a TEXT value: "\n" and "\N" are line breaks, and "\,",
+// "\;" and "\\" the escaped characters.
+func Unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// SplitValue splits a structured value, e.g. N or ORG of a vCard, at
+// unescaped separators and unescapes the parts.
+func SplitValue(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, Unescape(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, Unescape(value[start:]))
+}
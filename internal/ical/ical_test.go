@@ -0,0 +1,301 @@
+package ical
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+const invite = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Calendar//EN\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:kickoff-1@example.com\r\n" +
+	"SUMMARY:Project kickoff\\, phase 1\r\n" +
+	"DESCRIPTION:Agenda:\\n1. Scope\\n2. Timeline\r\n" +
+	"LOCATION:Room 4\\; 2nd floor\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20250304T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20250304T113000\r\n" +
+	"ORGANIZER;CN=\"Doe, Jane\":mailto:jane.doe@example.com\r\n" +
+	"ATTENDEE;CN=Maria Garcia;PARTSTAT=NEEDS-ACTION:mailto:maria@exam\r\n" +
+	" ple.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:offsite@example.com\r\n" +
+	"SUMMARY:Offsite\r\n" +
+	"DTSTART;VALUE=DATE:20250310\r\n" +
+	"DTEND;VALUE=DATE:20250312\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART:20250305T083000Z\r\n" +
+	"DURATION:PT15M\r\n" +
+	"STATUS:cancelled\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	roots, err := Parse([]byte(invite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].Name != "VCALENDAR" {
+		t.Fatalf("roots = %v, want one VCALENDAR", roots)
+	}
+	events := roots[0].Children("VEVENT")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	organizer := events[0].Get("ORGANIZER")
+	if organizer == nil || organizer.Param("cn") != "Doe, Jane" || organizer.Value != "mailto:jane.doe@example.com" {
+		t.Errorf("ORGANIZER = %+v", organizer)
+	}
+	attendee := events[0].Get("ATTENDEE")
+	if attendee == nil || attendee.Value != "mailto:maria@example.com" || attendee.Param("PARTSTAT") != "NEEDS-ACTION" {
+		t.Errorf("folded ATTENDEE = %+v", attendee)
+	}
+	if got := events[0].Text("SUMMARY"); got != "Project kickoff, phase 1" {
+		t.Errorf("SUMMARY = %q", got)
+	}
+	if got := events[0].Text("DESCRIPTION"); got != "Agenda:\n1. Scope\n2. Timeline" {
+		t.Errorf("DESCRIPTION = %q", got)
+	}
+}
+
+func TestParse_VCard(t *testing.T) {
+	data := "BEGIN:VCARD\nVERSION:2.1\nN:Doe;Jane\nTEL;WORK;VOICE:+1 555 0100\nitem1.EMAIL;TYPE=INTERNET,pref:jane@example.com\nORG:Example\\, Inc.;Sales\nEND:VCARD\n"
+	roots, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	card := roots[0]
+	if tel := card.Get("TEL"); tel == nil || !slices.Equal(tel.Params["TYPE"], []string{"WORK", "VOICE"}) {
+		t.Errorf("TEL = %+v, want TYPE WORK and VOICE", tel)
+	}
+	if email := card.Get("EMAIL"); email == nil || email.Value != "jane@example.com" || !slices.Equal(email.Params["TYPE"], []string{"INTERNET", "pref"}) {
+		t.Errorf("grouped EMAIL = %+v", email)
+	}
+	if got := SplitValue(card.Get("ORG").Value, ';'); !slices.Equal(got, []string{"Example, Inc.", "Sales"}) {
+		t.Errorf("ORG = %q", got)
+	}
+}
+
+func TestParse_Lenient(t *testing.T) {
+	// Garbage lines are skipped, the unclosed VEVENT ends with the data
+	roots, err := Parse([]byte("\ufeffnot a content line\nBEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Open\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := roots[0].Children("VEVENT")[0].Text("SUMMARY"); got != "Open" {
+		t.Errorf("SUMMARY = %q, want Open", got)
+	}
+
+	if _, err := Parse([]byte("Hello, world")); err == nil {
+		t.Error("expected an error for data without components")
+	}
+}
+
+func TestParseCalendar(t *testing.T) {
+	cal, err := ParseCalendar([]byte(invite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Method != "REQUEST" {
+		t.Errorf("Method = %q, want REQUEST", cal.Method)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	want := []Event{
+		{
+			UID:         "kickoff-1@example.com",
+			Summary:     "Project kickoff, phase 1",
+			Description: "Agenda:\n1. Scope\n2. Timeline",
+			Location:    "Room 4; 2nd floor",
+			Start:       time.Date(2025, 3, 4, 10, 0, 0, 0, berlin),
+			End:         time.Date(2025, 3, 4, 11, 30, 0, 0, berlin),
+		},
+		{
+			UID:     "offsite@example.com",
+			Summary: "Offsite",
+			Start:   time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC),
+			AllDay:  true,
+		},
+		{
+			Summary: "Standup",
+			Status:  "CANCELLED",
+			Start:   time.Date(2025, 3, 5, 8, 30, 0, 0, time.UTC),
+			End:     time.Date(2025, 3, 5, 8, 45, 0, 0, time.UTC),
+		},
+	}
+	if len(cal.Events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(cal.Events), len(want))
+	}
+	for i, got := range cal.Events {
+		w := want[i]
+		if got.UID != w.UID || got.Summary != w.Summary || got.Description != w.Description || got.Location != w.Location || got.Status != w.Status || got.AllDay != w.AllDay {
+			t.Errorf("event %d = %+v, want %+v", i, got, w)
+		}
+		if !got.Start.Equal(w.Start) || !got.End.Equal(w.End) {
+			t.Errorf("event %d from %v to %v, want %v to %v", i, got.Start, got.End, w.Start, w.End)
+		}
+	}
+
+	kickoff := cal.Events[0]
+	if kickoff.TimeZone != "Europe/Berlin" {
+		t.Errorf("TimeZone = %q, want Europe/Berlin", kickoff.TimeZone)
+	}
+	if want := (Participant{Name: "Doe, Jane", Email: "jane.doe@example.com"}); kickoff.Organizer == nil || *kickoff.Organizer != want {
+		t.Errorf("Organizer = %+v, want %+v", kickoff.Organizer, want)
+	}
+	if want := []Participant{{Name: "Maria Garcia", Email: "maria@example.com", Status: "NEEDS-ACTION"}}; !slices.Equal(kickoff.Attendees, want) {
+		t.Errorf("Attendees = %+v, want %+v", kickoff.Attendees, want)
+	}
+	if tz := cal.Events[1].TimeZone + cal.Events[2].TimeZone; tz != "" {
+		t.Errorf("TimeZone of all-day and UTC events = %q, want none", tz)
+	}
+}
+
+func TestParseCalendar_TimeZones(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	const data = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VTIMEZONE\r\n" +
+		"TZID:Custom Berlin\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"DTSTART:16011028T030000\r\n" +
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+		"TZOFFSETFROM:+0200\r\n" +
+		"TZOFFSETTO:+0100\r\n" +
+		"END:STANDARD\r\n" +
+		"BEGIN:DAYLIGHT\r\n" +
+		"DTSTART:16010325T020000\r\n" +
+		"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3\r\n" +
+		"TZOFFSETFROM:+0100\r\n" +
+		"TZOFFSETTO:+0200\r\n" +
+		"END:DAYLIGHT\r\n" +
+		"END:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=W. Europe Standard Time:20250304T100000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Custom Berlin:20250304T100000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Custom Berlin:20250704T100000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Nowhere:20250304T100000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20250304T100000\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	cal, err := ParseCalendar([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2025-03-04T10:00:00+01:00",
+		"2025-03-04T10:00:00+01:00",
+		"2025-07-04T10:00:00+02:00",
+		"2025-03-04T10:00:00Z",
+		"2025-03-04T10:00:00Z",
+	}
+	if len(cal.Events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(cal.Events), len(want))
+	}
+	for i, e := range cal.Events {
+		if got := e.Start.Format(time.RFC3339); got != want[i] {
+			t.Errorf("event %d starts at %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU,-1FR;UNTIL=20250630T215959Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Freq != "MONTHLY" || r.Interval != 2 || r.Count != 0 {
+		t.Errorf("got %+v", r)
+	}
+	if want := []WeekdayNum{{2, time.Tuesday}, {-1, time.Friday}}; !slices.Equal(r.ByDay, want) {
+		t.Errorf("ByDay = %v, want %v", r.ByDay, want)
+	}
+	if want := time.Date(2025, 6, 30, 21, 59, 59, 0, time.UTC); !r.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", r.Until, want)
+	}
+
+	for _, value := range []string{"", "INTERVAL=2", "FREQ=SOMETIMES", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;COUNT=many"} {
+		if _, err := ParseRecurrence(value); err == nil {
+			t.Errorf("ParseRecurrence(%q): expected an error", value)
+		}
+	}
+}
+
+func TestRecurrence_Describe(t *testing.T) {
+	// a Tuesday
+	start := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "daily"},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "daily on Monday, Tuesday, Wednesday, Thursday and Friday"},
+		{"FREQ=WEEKLY", "weekly on Tuesday"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "every 2 weeks on Monday and Wednesday"},
+		{"FREQ=MONTHLY", "monthly on day 4"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15", "monthly on day 15"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "monthly on the last day"},
+		{"FREQ=MONTHLY;BYDAY=1TU", "monthly on the first Tuesday"},
+		{"FREQ=MONTHLY;BYDAY=FR;BYSETPOS=-1", "monthly on the last Friday"},
+		{"FREQ=YEARLY", "yearly on March 4"},
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "yearly on the fourth Thursday of November"},
+		{"FREQ=WEEKLY;COUNT=10", "weekly on Tuesday, 10 times"},
+		{"FREQ=DAILY;COUNT=1", "daily, once"},
+		{"FREQ=WEEKLY;UNTIL=20250630", "weekly on Tuesday, until 2025-06-30"},
+	}
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := r.Describe(start); got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"PT15M", 15 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"P1DT12H", 36 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"-PT10M", -10 * time.Minute},
+		{"+PT30S", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "P", "PT", "1H", "P1H", "PT1D", "PTM"} {
+		if _, err := ParseDuration(value); err == nil {
+			t.Errorf("ParseDuration(%q): expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is a recurrence rule, an RRULE value, see RFC 5545 section
+// 3.3.10. Only the parts needed to describe a rule are kept.
+type Recurrence struct {
+	// Freq is the upper case frequency, e.g. "WEEKLY".
+	Freq     string
+	Interval int
+	// Count is the number of occurrences, 0 if not limited by count.
+	Count int
+	// Until is the last occurrence, the zero time if not limited by date.
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	// Rule is the RRULE value as given.
+	Rule string
+}
+
+// WeekdayNum is a day of BYDAY: a weekday and, for monthly and yearly
+// rules, its occurrence in the month or year, e.g. -1 for the last.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// weekdays are the weekdays by their two letter codes.
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRecurrence parses an RRULE value like "FREQ=WEEKLY;BYDAY=MO,WE".
+func ParseRecurrence(value string) (*Recurrence, error) {
+	r := &Recurrence{Interval: 1, Rule: strings.TrimSpace(value)}
+	for part := range strings.SplitSeq(r.Rule, ";") {
+		name, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		name, v = strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(strings.TrimSpace(v))
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = v
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(v); err == nil && r.Interval < 1 {
+				r.Interval = 1
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(v)
+		case "UNTIL":
+			r.Until, _, err = timezones(nil).parseTime(Property{Value: v})
+		case "BYDAY":
+			for d := range strings.SplitSeq(v, ",") {
+				wd, ok := parseWeekdayNum(d)
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY in recurrence rule: %s", value)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(v)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(v)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in recurrence rule: %s", name, value)
+		}
+	}
+	switch r.Freq {
+	case "SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+		return r, nil
+	default:
+		return nil, fmt.Errorf("invalid FREQ in recurrence rule: %s", value)
+	}
+}
+
+// parseWeekdayNum parses a day of BYDAY like "MO", "2TU" or "-1FR".
+func parseWeekdayNum(value string) (WeekdayNum, bool) {
+	if len(value) < 2 {
+		return WeekdayNum{}, false
+	}
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, false
+	}
+	wd := WeekdayNum{Day: day}
+	if n := value[:len(value)-2]; n != "" {
+		var err error
+		if wd.N, err = strconv.Atoi(n); err != nil {
+			return WeekdayNum{}, false
+		}
+	}
+	return wd, true
+}
+
+func parseInts(value string) ([]int, error) {
+	var ints []int
+	for s := range strings.SplitSeq(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// yearlyOnset returns the occurrence of a yearly time zone rule in year,
+// e.g. "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU" for the last Sunday of March, at
+// the wall clock time of start.
+func (r *Recurrence) yearlyOnset(year int, start time.Time) (time.Time, bool) {
+	month := start.Month()
+	if len(r.ByMonth) > 0 {
+		month = r.ByMonth[0]
+	}
+	var day int
+	switch {
+	case len(r.ByDay) > 0:
+		n := r.ByDay[0].N
+		if n == 0 && len(r.ByMonthDay) > 0 {
+			// "BYDAY=SU;BYMONTHDAY=8,9,10,11,12,13,14" is the second Sunday
+			n = (r.ByMonthDay[0]-1)/7 + 1
+		}
+		var ok bool
+		if day, ok = nthWeekday(year, month, n, r.ByDay[0].Day); !ok {
+			return time.Time{}, false
+		}
+	case len(r.ByMonthDay) > 0:
+		day = r.ByMonthDay[0]
+	default:
+		day = start.Day()
+	}
+	return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, time.UTC), true
+}
+
+// nthWeekday returns the day of the month of the nth weekday of a month,
+// counted from the end if n is negative.
+func nthWeekday(year int, month time.Month, n int, weekday time.Weekday) (int, bool) {
+	days := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	switch {
+	case n > 0:
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+		day := 1 + (int(weekday)-int(first)+7)%7 + (n-1)*7
+		return day, day <= days
+	case n < 0:
+		last := time.Date(year, month, days, 0, 0, 0, 0, time.UTC).Weekday()
+		day := days - (int(last)-int(weekday)+7)%7 + (n+1)*7
+		return day, day >= 1
+	default:
+		return 0, false
+	}
+}
+
+// frequencies are the units of the frequencies, singular and plural.
+var frequencies = map[string][3]string{
+	"SECONDLY": {"every second", "second", "seconds"},
+	"MINUTELY": {"every minute", "minute", "minutes"},
+	"HOURLY":   {"hourly", "hour", "hours"},
+	"DAILY":    {"daily", "day", "days"},
+	"WEEKLY":   {"weekly", "week", "weeks"},
+	"MONTHLY":  {"monthly", "month", "months"},
+	"YEARLY":   {"yearly", "year", "years"},
+}
+
+// ordinals are the words of the occurrences of BYDAY and BYSETPOS.
+var ordinals = map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth", -1: "last", -2: "second to last"}
+
+// Describe describes the rule in English, e.g. "every 2 weeks on Monday and
+// Wednesday, until 2025-06-30". The days of rules that do not name them
+// are those of start, the first occurrence.
+func (r *Recurrence) Describe(start time.Time) string {
+	f := frequencies[r.Freq]
+	s := f[0]
+	if r.Interval > 1 {
+		s = "every " + strconv.Itoa(r.Interval) + " " + f[2]
+	}
+
+	switch r.Freq {
+	case "WEEKLY":
+		days := []string{}
+		for _, d := range r.ByDay {
+			days = append(days, d.Day.String())
+		}
+		if len(days) == 0 && !start.IsZero() {
+			days = append(days, start.Weekday().String())
+		}
+		if len(days) > 0 {
+			s += " on " + joinAnd(days)
+		}
+	case "MONTHLY":
+		if on := r.describeDays(start, false); on != "" {
+			s += " on " + on
+		}
+	case "YEARLY":
+		if on := r.describeDays(start, true); on != "" {
+			s += " on " + on
+		}
+	case "DAILY":
+		if len(r.ByDay) > 0 {
+			days := []string{}
+			for _, d := range r.ByDay {
+				days = append(days, d.Day.String())
+			}
+			s += " on " + joinAnd(days)
+		}
+	}
+
+	switch {
+	case r.Count == 1:
+		s += ", once"
+	case r.Count > 1:
+		s += ", " + strconv.Itoa(r.Count) + " times"
+	case !r.Until.IsZero():
+		until := r.Until
+		if !start.IsZero() {
+			until = until.In(start.Location())
+		}
+		s += ", until " + until.Format(time.DateOnly)
+	}
+	return s
+}
+
+// describeDays describes the days of a monthly or yearly rule, e.g. "the
+// second Tuesday", "day 15" or, for yearly rules, "March 4".
+func (r *Recurrence) describeDays(start time.Time, yearly bool) string {
+	month := ""
+	if yearly {
+		months := []string{}
+		for _, m := range r.ByMonth {
+			months = append(months, m.String())
+		}
+		if len(months) == 0 && !start.IsZero() {
+			months = append(months, start.Month().String())
+		}
+		month = joinAnd(months)
+	}
+
+	if len(r.ByDay) > 0 {
+		days := []string{}
+		for _, d := range r.ByDay {
+			n := d.N
+			if n == 0 && len(r.ByDay) == 1 && len(r.BySetPos) == 1 {
+				n = r.BySetPos[0]
+			}
+			if ordinal, ok := ordinals[n]; ok {
+				days = append(days, "the "+ordinal+" "+d.Day.String())
+			} else {
+				days = append(days, d.Day.String())
+			}
+		}
+		if month != "" {
+			return joinAnd(days) + " of " + month
+		}
+		return joinAnd(days)
+	}
+
+	monthDays := []string{}
+	for _, d := range r.ByMonthDay {
+		if d == -1 {
+			monthDays = append(monthDays, "the last day")
+		} else {
+			monthDays = append(monthDays, strconv.Itoa(d))
+		}
+	}
+	if len(monthDays) == 0 && !start.IsZero() {
+		monthDays = append(monthDays, strconv.Itoa(start.Day()))
+	}
+	switch {
+	case len(monthDays) == 0:
+		return month
+	case month != "":
+		return month + " " + joinAnd(monthDays)
+	case monthDays[0] == "the last day":
+		return joinAnd(monthDays)
+	default:
+		return "day " + joinAnd(monthDays)
+	}
+}
+
+// joinAnd joins words like "Monday, Tuesday and Friday".
+func joinAnd(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
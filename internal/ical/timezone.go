@@ -0,0 +1,240 @@
+package ical
+
+import (
+	"strings"
+	"time"
+)
+
+// windowsZones maps the Windows time zone names Outlook and Exchange use as
+// TZID to IANA time zones, see the CLDR windowsZones table.
+var windowsZones = map[string]string{
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"Romance Standard Time":           "Europe/Paris",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"India Standard Time":             "Asia/Kolkata",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Eastern Standard Time":           "America/New_York",
+	"Central Standard Time":           "America/Chicago",
+	"Mountain Standard Time":          "America/Denver",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Canada Central Standard Time":    "America/Regina",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+}
+
+// timezones are the VTIMEZONEs of a calendar by TZID.
+type timezones map[string]*timezone
+
+func newTimezones(components []*Component) timezones {
+	zones := timezones{}
+	for _, c := range components {
+		if tzid := c.Text("TZID"); tzid != "" {
+			zones[tzid] = newTimezone(c)
+		}
+	}
+	return zones
+}
+
+// parseTime parses a DATE or DATE-TIME value. Times in UTC end with "Z",
+// times with a TZID parameter are in the named time zone, other times are
+// floating and returned in UTC. allDay reports a DATE value.
+//
+// A TZID is looked up as IANA time zone, as Windows time zone and as
+// VTIMEZONE of the calendar, in this order: the time zone database knows
+// the historic offsets, a VTIMEZONE often only the current rules. Times in
+// a time zone that is not found are returned in UTC.
+func (z timezones) parseTime(p Property) (t time.Time, allDay bool, err error) {
+	value := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Param("VALUE"), "DATE") || len(value) == len("20060102") {
+		t, err = time.Parse("20060102", value)
+		return t, true, err
+	}
+	if utc, ok := strings.CutSuffix(value, "Z"); ok {
+		t, err = time.Parse("20060102T150405", utc)
+		return t, false, err
+	}
+	if t, err = time.Parse("20060102T150405", value); err != nil {
+		return t, false, err
+	}
+
+	tzid := strings.TrimPrefix(p.Param("TZID"), "/")
+	if tzid == "" {
+		return t, false, nil
+	}
+	if loc, err := time.LoadLocation(tzid); err == nil && tzid != "Local" {
+		return inLocation(t, loc), false, nil
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return inLocation(t, loc), false, nil
+		}
+	}
+	if tz, ok := z[p.Param("TZID")]; ok {
+		if offset, ok := tz.offset(t); ok {
+			return inLocation(t, time.FixedZone(tzid, offset)), false, nil
+		}
+	}
+	return t, false, nil
+}
+
+// inLocation returns the time in loc with the wall clock of t.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// timezone is a VTIMEZONE: the STANDARD and DAYLIGHT observances, each
+// starting at an onset and, if recurring, again by its RRULE.
+type timezone struct {
+	observances []observance
+}
+
+type observance struct {
+	// start is the wall clock of the first onset, in UTC
+	start    time.Time
+	offset   int
+	rule     *Recurrence
+	rdates   []time.Time
+	standard bool
+}
+
+func newTimezone(c *Component) *timezone {
+	tz := &timezone{}
+	for _, o := range c.Components {
+		if o.Name != "STANDARD" && o.Name != "DAYLIGHT" {
+			continue
+		}
+		start, err := time.Parse("20060102T150405", strings.TrimSpace(o.Text("DTSTART")))
+		if err != nil {
+			continue
+		}
+		offset, ok := parseOffset(o.Text("TZOFFSETTO"))
+		if !ok {
+			continue
+		}
+		obs := observance{start: start, offset: offset, standard: o.Name == "STANDARD"}
+		if p := o.Get("RRULE"); p != nil {
+			obs.rule, _ = ParseRecurrence(p.Value)
+		}
+		for _, p := range o.All("RDATE") {
+			for _, v := range strings.Split(p.Value, ",") {
+				if t, err := time.Parse("20060102T150405", strings.TrimSpace(v)); err == nil {
+					obs.rdates = append(obs.rdates, t)
+				}
+			}
+		}
+		tz.observances = append(tz.observances, obs)
+	}
+	return tz
+}
+
+// offset returns the UTC offset in seconds at the wall clock of t, given in
+// UTC: the offset of the observance with the latest onset before t, or of
+// the first standard observance if t is before all onsets.
+func (tz *timezone) offset(t time.Time) (int, bool) {
+	var latest time.Time
+	offset, found := 0, false
+	for _, o := range tz.observances {
+		onset, ok := o.lastOnset(t)
+		if ok && (!found || onset.After(latest)) {
+			latest, offset, found = onset, o.offset, true
+		}
+	}
+	if found {
+		return offset, true
+	}
+	for _, o := range tz.observances {
+		if o.standard {
+			return o.offset, true
+		}
+	}
+	if len(tz.observances) > 0 {
+		return tz.observances[0].offset, true
+	}
+	return 0, false
+}
+
+// lastOnset returns the latest onset of the observance not after t.
+func (o observance) lastOnset(t time.Time) (time.Time, bool) {
+	if o.start.After(t) {
+		return time.Time{}, false
+	}
+	latest := o.start
+	for _, d := range o.rdates {
+		if !d.After(t) && d.After(latest) {
+			latest = d
+		}
+	}
+	if o.rule == nil || o.rule.Freq != "YEARLY" {
+		return latest, true
+	}
+	// Time zone rules recur yearly in a month, e.g. on the last Sunday of
+	// March, so the last onset is in the year of t or the year before
+	for _, year := range []int{t.Year(), t.Year() - 1} {
+		onset, ok := o.rule.yearlyOnset(year, o.start)
+		if !ok || onset.After(t) || onset.Before(o.start) {
+			continue
+		}
+		if !o.rule.Until.IsZero() && onset.After(o.rule.Until) {
+			continue
+		}
+		if onset.After(latest) {
+			latest = onset
+		}
+		break
+	}
+	return latest, true
+}
+
+// parseOffset parses a UTC offset like "+0100" or "-053000" into seconds.
+func parseOffset(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 && len(value) != 7 || value[0] != '+' && value[0] != '-' {
+		return 0, false
+	}
+	for _, c := range value[1:] {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	digits := func(i int) int { return int(value[i]-'0')*10 + int(value[i+1]-'0') }
+	n := digits(1)*3600 + digits(3)*60
+	if len(value) == 7 {
+		n += digits(5)
+	}
+	if value[0] == '-' {
+		n = -n
+	}
+	return n, true
+}
@@ -0,0 +1,444 @@
+// Package index is an on-disk full-text index of messages. Messages are
+// keyed by account, mailbox path and message ID, and search results are
+// ranked with BM25.
+package index
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the index file in the index directory.
+const FileName = "index.gob"
+
+// lockFileName is the name of the lock file in the index directory, see
+// Index.Lock.
+const lockFileName = "index.lock"
+
+var (
+	// ErrLocked is returned by Lock if another process holds the lock of the
+	// index directory.
+	ErrLocked = errors.New("index is in use by another process")
+	// ErrNotLocked is returned by Save if the index is not locked.
+	ErrNotLocked = errors.New("index is not locked")
+)
+
+// appDir is the name of the directory of the application in the user's cache
+// directory.
+const appDir = "com.github.dastrobu.mail-mcp"
+
+// version is the version of the file format. Files of other versions are
+// discarded and the index is rebuilt.
+const version = 1
+
+// Message is an indexed message.
+type Message struct {
+	Account      string
+	MailboxPath  []string
+	ID           int
+	Subject      string
+	Sender       string
+	Recipients   []string
+	Body         string
+	DateReceived time.Time
+}
+
+// MailboxState records how far a mailbox has been synchronized.
+type MailboxState struct {
+	// MessageCount is the number of messages of the mailbox at the last sync.
+	MessageCount int
+	// Newest is the date the newest indexed message was received.
+	Newest time.Time
+	// SyncedAt is the time of the last sync.
+	SyncedAt time.Time
+}
+
+// Posting is an occurrence of a term in a document.
+type Posting struct {
+	Doc  int32
+	Freq int32
+}
+
+// data is the persisted state of an index.
+type data struct {
+	Version int
+	// Docs holds the indexed messages by document number. Postings of a
+	// term are sorted by document number.
+	Docs     map[int32]*Message
+	Lengths  map[int32]int32
+	NextDoc  int32
+	Postings map[string][]Posting
+	// Mailboxes is keyed by mailboxKey.
+	Mailboxes map[string]*MailboxState
+	SyncedAt  time.Time
+}
+
+// Index is a full-text index of messages. It is safe for concurrent use.
+type Index struct {
+	path string
+
+	mu          sync.RWMutex
+	lock        *os.File // lock file while the index is locked
+	d           data
+	byKey       map[string]int32
+	count       int
+	totalLength int
+}
+
+// DefaultDir returns the default index directory in the user's cache
+// directory, i.e. ~/Library/Caches/com.github.dastrobu.mail-mcp/index on
+// macOS.
+func DefaultDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %w", err)
+	}
+	return filepath.Join(cache, appDir, "index"), nil
+}
+
+// Open loads the index of the directory. A missing index is created empty
+// and written by the first Save.
+func Open(dir string) (*Index, error) {
+	x := &Index{path: filepath.Join(dir, FileName)}
+	x.reset()
+
+	raw, err := os.ReadFile(x.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	var d data
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("failed to decode index %s: %w", x.path, err)
+	}
+	if d.Version != version {
+		return x, nil
+	}
+
+	// gob omits empty maps
+	x.d = d
+	if x.d.Docs == nil {
+		x.d.Docs = make(map[int32]*Message)
+		x.d.Lengths = make(map[int32]int32)
+	}
+	if x.d.Postings == nil {
+		x.d.Postings = make(map[string][]Posting)
+	}
+	if x.d.Mailboxes == nil {
+		x.d.Mailboxes = make(map[string]*MailboxState)
+	}
+	for doc, m := range d.Docs {
+		x.byKey[messageKey(m.Account, m.MailboxPath, m.ID)] = doc
+		x.count++
+		x.totalLength += int(d.Lengths[doc])
+	}
+	return x, nil
+}
+
+// Path returns the path of the index file.
+func (x *Index) Path() string {
+	return x.path
+}
+
+// Lock takes an exclusive advisory lock on the index directory, which is
+// held until Close. Only the process holding the lock may Save the index, so
+// that a process does not replace the index written by another one with its
+// own stale copy, as a running server would after the index rebuild command.
+// Lock returns ErrLocked if another process holds the lock.
+func (x *Index) Lock() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.lock != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(x.path), lockFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open index lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		if errors.Is(err, ErrLocked) {
+			return fmt.Errorf("%w: %s", ErrLocked, filepath.Dir(x.path))
+		}
+		return fmt.Errorf("failed to lock index: %w", err)
+	}
+	x.lock = f
+	return nil
+}
+
+// Close releases the lock taken by Lock.
+func (x *Index) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.lock == nil {
+		return nil
+	}
+	err := x.lock.Close()
+	x.lock = nil
+	return err
+}
+
+// Save writes the index to disk. The index must be locked, see Lock. The
+// file is replaced atomically, so that a crash does not leave a corrupt
+// index.
+func (x *Index) Save() error {
+	x.mu.RLock()
+	if x.lock == nil {
+		x.mu.RUnlock()
+		return ErrNotLocked
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&x.d)
+	x.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(x.path), filepath.Base(x.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), x.path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// Reset removes all messages from the index.
+func (x *Index) Reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.reset()
+}
+
+func (x *Index) reset() {
+	x.d = data{
+		Version:   version,
+		Docs:      make(map[int32]*Message),
+		Lengths:   make(map[int32]int32),
+		Postings:  make(map[string][]Posting),
+		Mailboxes: make(map[string]*MailboxState),
+	}
+	x.byKey = make(map[string]int32)
+	x.count = 0
+	x.totalLength = 0
+}
+
+// Add indexes a message, replacing a message with the same key.
+func (x *Index) Add(m Message) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	key := messageKey(m.Account, m.MailboxPath, m.ID)
+	if doc, ok := x.byKey[key]; ok {
+		x.remove(doc)
+	}
+
+	doc := x.d.NextDoc
+	x.d.NextDoc++
+	freqs := make(map[string]int32)
+	length := int32(0)
+	for _, t := range documentTerms(&m) {
+		freqs[t]++
+		length++
+	}
+	for t, f := range freqs {
+		x.d.Postings[t] = append(x.d.Postings[t], Posting{Doc: doc, Freq: f})
+	}
+	x.d.Docs[doc] = &m
+	x.d.Lengths[doc] = length
+	x.byKey[key] = doc
+	x.count++
+	x.totalLength += int(length)
+}
+
+// Remove removes a message from the index.
+func (x *Index) Remove(account string, mailboxPath []string, id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if doc, ok := x.byKey[messageKey(account, mailboxPath, id)]; ok {
+		x.remove(doc)
+	}
+}
+
+func (x *Index) remove(doc int32) {
+	m := x.d.Docs[doc]
+	for _, t := range documentTerms(m) {
+		postings := x.d.Postings[t]
+		i, found := slices.BinarySearchFunc(postings, doc, func(p Posting, doc int32) int { return cmp.Compare(p.Doc, doc) })
+		if !found {
+			continue // the term occurs more than once and is already removed
+		}
+		if postings = slices.Delete(postings, i, i+1); len(postings) == 0 {
+			delete(x.d.Postings, t)
+		} else {
+			x.d.Postings[t] = postings
+		}
+	}
+	delete(x.byKey, messageKey(m.Account, m.MailboxPath, m.ID))
+	x.count--
+	x.totalLength -= int(x.d.Lengths[doc])
+	delete(x.d.Docs, doc)
+	delete(x.d.Lengths, doc)
+}
+
+// Contains reports whether the message is indexed.
+func (x *Index) Contains(account string, mailboxPath []string, id int) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, ok := x.byKey[messageKey(account, mailboxPath, id)]
+	return ok
+}
+
+// IDs returns the IDs of the indexed messages of a mailbox.
+func (x *Index) IDs(account string, mailboxPath []string) []int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var ids []int
+	for _, m := range x.d.Docs {
+		if m.Account == account && slices.Equal(m.MailboxPath, mailboxPath) {
+			ids = append(ids, m.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Mailbox returns the sync state of a mailbox.
+func (x *Index) Mailbox(account string, mailboxPath []string) (MailboxState, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	s, ok := x.d.Mailboxes[mailboxKey(account, mailboxPath)]
+	if !ok {
+		return MailboxState{}, false
+	}
+	return *s, true
+}
+
+// SetMailbox records the sync state of a mailbox.
+func (x *Index) SetMailbox(account string, mailboxPath []string, s MailboxState) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.d.Mailboxes[mailboxKey(account, mailboxPath)] = &s
+}
+
+// RemoveMailboxes removes the mailboxes for which keep returns false,
+// together with their messages.
+func (x *Index) RemoveMailboxes(keep func(account string, mailboxPath []string) bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for key := range x.d.Mailboxes {
+		account, mailboxPath := splitMailboxKey(key)
+		if !keep(account, mailboxPath) {
+			delete(x.d.Mailboxes, key)
+		}
+	}
+	for doc, m := range x.d.Docs {
+		if x.d.Mailboxes[mailboxKey(m.Account, m.MailboxPath)] == nil {
+			x.remove(doc)
+		}
+	}
+}
+
+// SetSyncedAt records the time of the last complete sync.
+func (x *Index) SetSyncedAt(t time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.d.SyncedAt = t
+}
+
+// SyncedAt returns the time of the last complete sync, the zero time if the
+// index has never been synced.
+func (x *Index) SyncedAt() time.Time {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.d.SyncedAt
+}
+
+// Count returns the number of indexed messages.
+func (x *Index) Count() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.count
+}
+
+// MailboxStatus is the status of an indexed mailbox.
+type MailboxStatus struct {
+	Account     string
+	MailboxPath []string
+	Messages    int
+	SyncedAt    time.Time
+}
+
+// Status describes the content of an index.
+type Status struct {
+	Path      string
+	Messages  int
+	Terms     int
+	Mailboxes []MailboxStatus
+	SyncedAt  time.Time
+}
+
+// Status returns the number of indexed messages per mailbox.
+func (x *Index) Status() Status {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, m := range x.d.Docs {
+		counts[mailboxKey(m.Account, m.MailboxPath)]++
+	}
+	s := Status{Path: x.path, Messages: x.count, Terms: len(x.d.Postings), SyncedAt: x.d.SyncedAt}
+	for key, state := range x.d.Mailboxes {
+		account, mailboxPath := splitMailboxKey(key)
+		s.Mailboxes = append(s.Mailboxes, MailboxStatus{
+			Account: account, MailboxPath: mailboxPath, Messages: counts[key], SyncedAt: state.SyncedAt,
+		})
+	}
+	slices.SortFunc(s.Mailboxes, func(a, b MailboxStatus) int {
+		if c := strings.Compare(a.Account, b.Account); c != 0 {
+			return c
+		}
+		return slices.Compare(a.MailboxPath, b.MailboxPath)
+	})
+	return s
+}
+
+// mailboxKey identifies a mailbox. Mailbox names may contain any character
+// except NUL.
+func mailboxKey(account string, mailboxPath []string) string {
+	return account + "\x00" + strings.Join(mailboxPath, "\x00")
+}
+
+func splitMailboxKey(key string) (string, []string) {
+	account, path, _ := strings.Cut(key, "\x00")
+	return account, strings.Split(path, "\x00")
+}
+
+func messageKey(account string, mailboxPath []string, id int) string {
+	return mailboxKey(account, mailboxPath) + "\x00\x00" + strconv.Itoa(id)
+}
@@ -0,0 +1,319 @@
+package index
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testMessages() []Message {
+	inbox := []string{"INBOX"}
+	return []Message{
+		{Account: "Work", MailboxPath: inbox, ID: 1, Subject: "Quarterly report", Sender: "Alex <alex@example.com>",
+			Recipients: []string{"jane@example.com"}, Body: "Please send the figures for the report.", DateReceived: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Account: "Work", MailboxPath: inbox, ID: 2, Subject: "Lunch", Sender: "Sam <sam@example.com>",
+			Recipients: []string{"jane@example.com"}, Body: "Lunch at noon? The report can wait.", DateReceived: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{Account: "Work", MailboxPath: []string{"INBOX", "Billing"}, ID: 3, Subject: "Invoice 42", Sender: "billing@shop.example.com",
+			Recipients: []string{"billing@example.com"}, Body: "Your invoice is attached.", DateReceived: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{Account: "Personal", MailboxPath: inbox, ID: 1, Subject: "Holiday photos", Sender: "mum@example.org",
+			Body: "Here are the photos from our holiday.", DateReceived: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func newTestIndex(t *testing.T) *Index {
+	t.Helper()
+	x, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, m := range testMessages() {
+		x.Add(m)
+	}
+	return x
+}
+
+func hitIDs(hits []Hit) []string {
+	var ids []string
+	for _, h := range hits {
+		ids = append(ids, h.Message.Account+"/"+h.Message.Subject)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	x := newTestIndex(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		opts      SearchOptions
+		want      []string
+		wantTotal int
+	}{
+		{
+			name:      "subject ranks before body",
+			query:     "report",
+			want:      []string{"Work/Quarterly report", "Work/Lunch"},
+			wantTotal: 2,
+		},
+		{
+			name:      "any term matches",
+			query:     "Invoice photos",
+			want:      []string{"Personal/Holiday photos", "Work/Invoice 42"},
+			wantTotal: 2,
+		},
+		{
+			name:      "recipient",
+			query:     "billing",
+			want:      []string{"Work/Invoice 42"},
+			wantTotal: 1,
+		},
+		{
+			name:      "account",
+			query:     "example",
+			opts:      SearchOptions{Account: "Personal"},
+			want:      []string{"Personal/Holiday photos"},
+			wantTotal: 1,
+		},
+		{
+			name:      "mailbox",
+			query:     "example",
+			opts:      SearchOptions{MailboxPath: []string{"INBOX", "Billing"}},
+			want:      []string{"Work/Invoice 42"},
+			wantTotal: 1,
+		},
+		{
+			name:      "limit",
+			query:     "report",
+			opts:      SearchOptions{Limit: 1},
+			want:      []string{"Work/Quarterly report"},
+			wantTotal: 2,
+		},
+		{
+			name:  "no match",
+			query: "kangaroo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total := x.Search(tt.query, tt.opts)
+			if got := hitIDs(hits); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("Search(%q) total = %d, want %d", tt.query, total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestIndex_AddReplacesAndRemoves(t *testing.T) {
+	x := newTestIndex(t)
+
+	updated := testMessages()[1]
+	updated.Body = "Lunch at noon?"
+	x.Add(updated)
+	if hits, _ := x.Search("report", SearchOptions{}); !slices.Equal(hitIDs(hits), []string{"Work/Quarterly report"}) {
+		t.Errorf("after replacing, Search(report) = %v", hitIDs(hits))
+	}
+
+	x.Remove("Work", []string{"INBOX"}, 1)
+	if hits, _ := x.Search("report", SearchOptions{}); len(hits) != 0 {
+		t.Errorf("after removing, Search(report) = %v, want none", hitIDs(hits))
+	}
+	if got := x.IDs("Work", []string{"INBOX"}); !slices.Equal(got, []int{2}) {
+		t.Errorf("IDs() = %v, want [2]", got)
+	}
+	if got := x.Status().Messages; got != 3 {
+		t.Errorf("Status().Messages = %d, want 3", got)
+	}
+	if got := x.Count(); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+}
+
+func TestIndex_SaveAndOpen(t *testing.T) {
+	dir := t.TempDir()
+	x, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, m := range testMessages() {
+		x.Add(m)
+	}
+	x.Remove("Personal", []string{"INBOX"}, 1)
+	synced := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+	x.SetMailbox("Work", []string{"INBOX"}, MailboxState{MessageCount: 2, SyncedAt: synced})
+	x.SetSyncedAt(synced)
+	if err := x.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer func() { _ = x.Close() }()
+	if err := x.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if hits, total := loaded.Search("report invoice photos", SearchOptions{}); total != 3 {
+		t.Errorf("Search() after Open = %v, want 3 hits", hitIDs(hits))
+	}
+	if s, ok := loaded.Mailbox("Work", []string{"INBOX"}); !ok || s.MessageCount != 2 || !s.SyncedAt.Equal(synced) {
+		t.Errorf("Mailbox() = %+v, %v", s, ok)
+	}
+	if !loaded.SyncedAt().Equal(synced) {
+		t.Errorf("SyncedAt() = %v, want %v", loaded.SyncedAt(), synced)
+	}
+	status := loaded.Status()
+	if status.Messages != 3 || !status.SyncedAt.Equal(synced) || status.Path != x.Path() {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	// Messages added after loading get new document numbers
+	loaded.Add(Message{Account: "Work", MailboxPath: []string{"INBOX"}, ID: 9, Subject: "Another report"})
+	if _, total := loaded.Search("report", SearchOptions{}); total != 3 {
+		t.Errorf("Search(report) total = %d, want 3", total)
+	}
+}
+
+func TestIndex_ConcurrentSave(t *testing.T) {
+	dir := t.TempDir()
+	x, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := x.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer func() { _ = x.Close() }()
+	for _, m := range testMessages() {
+		x.Add(m)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() { errs <- x.Save() })
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Save() error = %v", err)
+		}
+	}
+
+	loaded, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := loaded.Status().Messages; got != 4 {
+		t.Errorf("Status().Messages = %d, want 4", got)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temporary file %s was left behind", e.Name())
+		}
+	}
+}
+
+func TestIndex_Lock(t *testing.T) {
+	dir := t.TempDir()
+	server, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := server.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	server.Add(testMessages()[0])
+	if err := server.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A rebuild while the server holds the lock must not replace its index
+	rebuild, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := rebuild.Lock(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lock() error = %v, want ErrLocked", err)
+	}
+	rebuild.Reset()
+	rebuild.Add(testMessages()[1])
+	if err := rebuild.Save(); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("Save() error = %v, want ErrNotLocked", err)
+	}
+	loaded, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !loaded.Contains("Work", []string{"INBOX"}, 1) || loaded.Contains("Work", []string{"INBOX"}, 2) {
+		t.Errorf("index after the refused save = %+v, want the index of the server", loaded.Status())
+	}
+
+	// Once the server is stopped, the rebuild takes over
+	if err := server.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := rebuild.Lock(); err != nil {
+		t.Fatalf("Lock() after Close() error = %v", err)
+	}
+	defer func() { _ = rebuild.Close() }()
+	if err := rebuild.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if loaded, err = Open(dir); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if loaded.Contains("Work", []string{"INBOX"}, 1) || !loaded.Contains("Work", []string{"INBOX"}, 2) {
+		t.Errorf("index after the rebuild = %+v, want the index of the rebuild", loaded.Status())
+	}
+}
+
+func TestIndex_RemoveMailboxes(t *testing.T) {
+	x := newTestIndex(t)
+	for _, m := range testMessages() {
+		x.SetMailbox(m.Account, m.MailboxPath, MailboxState{})
+	}
+
+	x.RemoveMailboxes(func(account string, mailboxPath []string) bool {
+		return account == "Work"
+	})
+	status := x.Status()
+	if status.Messages != 3 || len(status.Mailboxes) != 2 {
+		t.Errorf("Status() = %+v, want 3 messages in 2 mailboxes", status)
+	}
+	if _, ok := x.Mailbox("Personal", []string{"INBOX"}); ok {
+		t.Error("Mailbox(Personal, INBOX) still exists")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	body := "Hello Jane,\n\nthe build of main failed again, see the logs of the integration tests for details."
+	tests := []struct {
+		query string
+		width int
+		want  string
+	}{
+		{"integration", 30, "...gs of the integration tests fo..."},
+		{"kangaroo", 20, "Hello Jane, the buil..."},
+		{"hello", 200, "Hello Jane, the build of main failed again, see the logs of the integration tests for details."},
+	}
+	for _, tt := range tests {
+		if got := Snippet(body, tt.query, tt.width); got != tt.want {
+			t.Errorf("Snippet(%q, %d) = %q, want %q", tt.query, tt.width, got, tt.want)
+		}
+	}
+}
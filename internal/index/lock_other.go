@@ -0,0 +1,11 @@
+//go:build !unix
+
+package index
+
+import "os"
+
+// lockFile does nothing on platforms without flock, where concurrent
+// writers are not detected.
+func lockFile(f *os.File) error {
+	return nil
+}
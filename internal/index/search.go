@@ -0,0 +1,157 @@
+package index
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BM25 parameters, see https://en.wikipedia.org/wiki/Okapi_BM25.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// subjectWeight is the number of times subject terms are counted, so that a
+// match in the subject ranks higher than one in the body.
+const subjectWeight = 2
+
+// maxTermLength bounds the length of indexed terms, longer ones are mostly
+// encoded data.
+const maxTermLength = 64
+
+// SearchOptions restrict a search.
+type SearchOptions struct {
+	// Account restricts the search to an account, if set.
+	Account string
+	// MailboxPath restricts the search to a mailbox, if set.
+	MailboxPath []string
+	// Limit is the maximum number of hits returned.
+	Limit int
+}
+
+// Hit is a message matching a search.
+type Hit struct {
+	Message Message
+	Score   float64
+}
+
+// Search returns the messages matching any term of the query, best match
+// first, and the total number of matching messages.
+func (x *Index) Search(query string, opts SearchOptions) ([]Hit, int) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if x.count == 0 {
+		return nil, 0
+	}
+	avgLength := float64(x.totalLength) / float64(x.count)
+
+	scores := make(map[int32]float64)
+	for _, t := range uniqueTerms(query) {
+		postings := x.d.Postings[t]
+		if len(postings) == 0 {
+			continue
+		}
+		n := float64(len(postings))
+		idf := math.Log(1 + (float64(x.count)-n+0.5)/(n+0.5))
+		for _, p := range postings {
+			tf := float64(p.Freq)
+			norm := 1 - b + b*float64(x.d.Lengths[p.Doc])/avgLength
+			scores[p.Doc] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	var hits []Hit
+	for doc, score := range scores {
+		m := x.d.Docs[doc]
+		if opts.Account != "" && m.Account != opts.Account {
+			continue
+		}
+		if len(opts.MailboxPath) > 0 && !slices.Equal(m.MailboxPath, opts.MailboxPath) {
+			continue
+		}
+		hits = append(hits, Hit{Message: *m, Score: score})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return b.Message.DateReceived.Compare(a.Message.DateReceived)
+	})
+
+	total := len(hits)
+	if opts.Limit > 0 && len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits, total
+}
+
+// Snippet returns an excerpt of about width characters of the body around
+// the first term of the query it contains, or the start of the body.
+func Snippet(body, query string, width int) string {
+	body = strings.Join(strings.Fields(body), " ")
+	runes := []rune(body)
+	if len(runes) <= width {
+		return body
+	}
+
+	start := 0
+	lower := strings.ToLower(body)
+	for _, t := range uniqueTerms(query) {
+		if i := strings.Index(lower, t); i >= 0 {
+			// Center the term, lower-casing does not change the rune count of
+			// the prefix for most scripts
+			start = max(0, utf8.RuneCountInString(lower[:i])-width/3)
+			break
+		}
+	}
+	start = min(start, len(runes)-width)
+
+	snippet := string(runes[start : start+width])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if start+width < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// documentTerms returns the terms of a message in order of occurrence.
+func documentTerms(m *Message) []string {
+	var terms []string
+	subject := tokenize(m.Subject)
+	for range subjectWeight {
+		terms = append(terms, subject...)
+	}
+	terms = append(terms, tokenize(m.Sender)...)
+	for _, r := range m.Recipients {
+		terms = append(terms, tokenize(r)...)
+	}
+	return append(terms, tokenize(m.Body)...)
+}
+
+// uniqueTerms returns the distinct terms of a query.
+func uniqueTerms(query string) []string {
+	terms := tokenize(query)
+	slices.Sort(terms)
+	return slices.Compact(terms)
+}
+
+// tokenize splits text into lower-case words of letters and digits. Single
+// characters are dropped.
+func tokenize(text string) []string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if n := utf8.RuneCountInString(w); n > 1 && n <= maxTermLength {
+			terms = append(terms, strings.ToLower(w))
+		}
+	}
+	return terms
+}
@@ -0,0 +1,72 @@
+package jxa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes returned by JXA scripts
+const (
+	ErrorCodeMailAppNotRunning       = "MAIL_APP_NOT_RUNNING"
+	ErrorCodeMailAppNoPermissions    = "MAIL_APP_NO_PERMISSIONS"
+	ErrorCodeMissingParameters       = "MISSING_PARAMETERS"
+	ErrorCodeInvalidParameters       = "INVALID_PARAMETERS"
+	ErrorCodeAccountNotFound         = "ACCOUNT_NOT_FOUND"
+	ErrorCodeInvalidMailboxPath      = "INVALID_MAILBOX_PATH"
+	ErrorCodeMailboxNotFound         = "MAILBOX_NOT_FOUND"
+	ErrorCodeMessageNotFound         = "MESSAGE_NOT_FOUND"
+	ErrorCodeAttachmentNotFound      = "ATTACHMENT_NOT_FOUND"
+	ErrorCodeAttachmentNotDownloaded = "ATTACHMENT_NOT_DOWNLOADED"
+	ErrorCodeAttachmentTooLarge      = "ATTACHMENT_TOO_LARGE"
+	ErrorCodeUnsupportedAttachment   = "UNSUPPORTED_ATTACHMENT"
+	ErrorCodeNoViewerWindow          = "NO_VIEWER_WINDOW"
+	ErrorCodeTimeout                 = "TIMEOUT"
+	ErrorCodeIndexNotReady           = "INDEX_NOT_READY"
+	ErrorCodeUnknown                 = "UNKNOWN_ERROR"
+)
+
+// retryableCodes lists the error codes of failures that may succeed when the
+// same call is repeated later without changing its arguments.
+var retryableCodes = map[string]bool{
+	ErrorCodeMailAppNotRunning:       true,
+	ErrorCodeNoViewerWindow:          true,
+	ErrorCodeTimeout:                 true,
+	ErrorCodeIndexNotReady:           true,
+	ErrorCodeAttachmentNotDownloaded: true,
+}
+
+// Error is a failure reported by a JXA script. Its fields are meant to be
+// passed on to MCP clients, so that they can act on the code instead of
+// parsing the message.
+type Error struct {
+	Code      string `json:"code" jsonschema:"Machine-readable error code, e.g. ACCOUNT_NOT_FOUND"`
+	Message   string `json:"message" jsonschema:"Human-readable error message"`
+	Logs      string `json:"logs,omitempty" jsonschema:"Logs of the script, if any"`
+	Retryable bool   `json:"retryable" jsonschema:"Whether repeating the call later may succeed"`
+}
+
+// NewError creates an error with the given code. An empty code is treated as
+// UNKNOWN_ERROR.
+func NewError(code string, format string, args ...any) *Error {
+	if code == "" {
+		code = ErrorCodeUnknown
+	}
+	return &Error{
+		Code:      code,
+		Message:   fmt.Sprintf(format, args...),
+		Retryable: retryableCodes[code],
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Logs != "" {
+		return fmt.Sprintf("%s\nLogs:\n%s", e.Message, e.Logs)
+	}
+	return e.Message
+}
+
+// HasCode reports whether err is an *Error with the given code.
+func HasCode(err error, code string) bool {
+	var jxaErr *Error
+	return errors.As(err, &jxaErr) && jxaErr.Code == code
+}
@@ -0,0 +1,78 @@
+package jxa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseOutput_ScriptError(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		wantCode      string
+		wantMessage   string
+		wantLogs      string
+		wantRetryable bool
+	}{
+		{
+			name:        "error without code",
+			output:      `{"success":false,"error":"Something went wrong"}`,
+			wantCode:    ErrorCodeUnknown,
+			wantMessage: "Something went wrong",
+		},
+		{
+			name:        "error without message",
+			output:      `{"success":false}`,
+			wantCode:    ErrorCodeUnknown,
+			wantMessage: "unknown error (script returned success=false with no error message)",
+		},
+		{
+			name:        "account not found with logs",
+			output:      `{"success":false,"error":"Account 'Work' not found.","errorCode":"ACCOUNT_NOT_FOUND","logs":"looking up Work"}`,
+			wantCode:    ErrorCodeAccountNotFound,
+			wantMessage: "Account 'Work' not found.",
+			wantLogs:    "looking up Work",
+		},
+		{
+			name:          "mail app not running is retryable",
+			output:        `{"success":false,"error":"not running","errorCode":"MAIL_APP_NOT_RUNNING"}`,
+			wantCode:      ErrorCodeMailAppNotRunning,
+			wantMessage:   "Mail.app is not running. Please start Mail.app and try again",
+			wantRetryable: true,
+		},
+		{
+			name:        "no permissions",
+			output:      `{"success":false,"error":"denied","errorCode":"MAIL_APP_NO_PERMISSIONS"}`,
+			wantCode:    ErrorCodeMailAppNoPermissions,
+			wantMessage: "Mail.app automation permission denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOutput(context.Background(), []byte(tt.output), nil)
+
+			var jxaErr *Error
+			if !errors.As(err, &jxaErr) {
+				t.Fatalf("ParseOutput() error = %v (%T), want *Error", err, err)
+			}
+			if jxaErr.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", jxaErr.Code, tt.wantCode)
+			}
+			if !strings.HasPrefix(jxaErr.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want prefix %q", jxaErr.Message, tt.wantMessage)
+			}
+			if jxaErr.Logs != tt.wantLogs {
+				t.Errorf("Logs = %q, want %q", jxaErr.Logs, tt.wantLogs)
+			}
+			if jxaErr.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", jxaErr.Retryable, tt.wantRetryable)
+			}
+			if !HasCode(err, tt.wantCode) {
+				t.Errorf("HasCode(err, %s) = false, want true", tt.wantCode)
+			}
+		})
+	}
+}
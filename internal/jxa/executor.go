@@ -16,13 +16,33 @@ type Result struct {
 	Data      map[string]any `json:"data,omitempty"`
 	Error     string         `json:"error,omitempty"`
 	ErrorCode string         `json:"errorCode,omitempty"`
+	Logs      string         `json:"logs,omitempty"`
 }
 
-// Error codes returned by JXA scripts
-const (
-	ErrorCodeMailAppNotRunning    = "MAIL_APP_NOT_RUNNING"
-	ErrorCodeMailAppNoPermissions = "MAIL_APP_NO_PERMISSIONS"
-)
+// Script is a JXA script together with the name used to identify it.
+// The name matches the tool that embeds the script (e.g. "list_accounts").
+type Script struct {
+	Name   string
+	Source string
+}
+
+// Executor runs JXA scripts and returns the unwrapped data field of the
+// result envelope. Tool handlers depend on this interface so that they can be
+// exercised without Mail.app.
+type Executor interface {
+	Execute(ctx context.Context, script Script, args ...string) (any, error)
+}
+
+// OsascriptExecutor runs scripts by spawning osascript for every call.
+type OsascriptExecutor struct{}
+
+// Ensure the implementation satisfies the expected interface.
+var _ Executor = OsascriptExecutor{}
+
+// Execute runs the script via osascript.
+func (OsascriptExecutor) Execute(ctx context.Context, script Script, args ...string) (any, error) {
+	return Execute(ctx, script.Source, args...)
+}
 
 // Execute runs a JXA script with the given arguments and returns the parsed result
 func Execute(ctx context.Context, script string, args ...string) (any, error) {
@@ -40,6 +60,13 @@ func Execute(ctx context.Context, script string, args ...string) (any, error) {
 		return nil, fmt.Errorf("osascript execution failed: %w\nArguments: %v", err, args)
 	}
 
+	return ParseOutput(ctx, output, args)
+}
+
+// ParseOutput unwraps the JSON envelope printed by a script and returns its
+// data field. Script-level failures are returned as *Error. Executors that
+// do not run osascript use it to report results exactly like a real script.
+func ParseOutput(ctx context.Context, output []byte, args []string) (any, error) {
 	// Check if output is empty
 	if len(output) == 0 {
 		return nil, fmt.Errorf("osascript returned empty output (expected JSON)\nArguments: %v", args)
@@ -58,27 +85,7 @@ func Execute(ctx context.Context, script string, args ...string) (any, error) {
 	}
 
 	if !success {
-		errMsg := "unknown error (script returned success=false with no error message)"
-		if errVal, ok := result["error"].(string); ok && errVal != "" {
-			errMsg = errVal
-		}
-
-		// Check for specific error codes
-		if errorCode, ok := result["errorCode"].(string); ok {
-			switch errorCode {
-			case ErrorCodeMailAppNotRunning:
-				return nil, fmt.Errorf("Mail.app is not running. Please start Mail.app and try again")
-			case ErrorCodeMailAppNoPermissions:
-				return nil, fmt.Errorf("Mail.app automation permission denied. Please grant permission to %q in System Settings > Privacy & Security > Automation", os.Args[0])
-			}
-		}
-
-		// Include logs if available for better debugging
-		if logs, ok := result["logs"].(string); ok && logs != "" {
-			return nil, fmt.Errorf("JXA script error: %s\nLogs:\n%s\nArguments: %v", errMsg, logs, args)
-		}
-
-		return nil, fmt.Errorf("JXA script error: %s\nArguments: %v", errMsg, args)
+		return nil, scriptError(ctx, result, args)
 	}
 
 	// Extract and return data field
@@ -95,3 +102,27 @@ func Execute(ctx context.Context, script string, args ...string) (any, error) {
 
 	return data, nil
 }
+
+// scriptError converts the envelope of a failed script into an *Error.
+func scriptError(ctx context.Context, result map[string]any, args []string) *Error {
+	errMsg := "unknown error (script returned success=false with no error message)"
+	if errVal, ok := result["error"].(string); ok && errVal != "" {
+		errMsg = errVal
+	}
+	code, _ := result["errorCode"].(string)
+
+	// Replace the messages of well-known codes with actionable ones
+	switch code {
+	case ErrorCodeMailAppNotRunning:
+		errMsg = "Mail.app is not running. Please start Mail.app and try again"
+	case ErrorCodeMailAppNoPermissions:
+		errMsg = fmt.Sprintf("Mail.app automation permission denied. Please grant permission to %q in System Settings > Privacy & Security > Automation", os.Args[0])
+	}
+
+	jxaErr := NewError(code, "%s", errMsg)
+	if logs, ok := result["logs"].(string); ok {
+		jxaErr.Logs = logs
+	}
+	log.FromContext(ctx).Printf("[DEBUG] JXA script error %s: %s\nArguments: %v\n", jxaErr.Code, errMsg, args)
+	return jxaErr
+}
@@ -3,11 +3,23 @@ package jxa
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"os/exec"
 	"strings"
 	"testing"
 )
 
+// requireOsascript skips tests that need a real osascript binary (macOS only).
+func requireOsascript(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("osascript"); err != nil {
+		t.Skip("osascript not available")
+	}
+}
+
 func TestExecute_WrappedFormat(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns data wrapped in data field (required format)
 	script := `
 function run(argv) {
@@ -61,6 +73,8 @@ function run(argv) {
 }
 
 func TestExecute_MissingDataField(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns unwrapped format (should fail)
 	script := `
 function run(argv) {
@@ -92,6 +106,8 @@ function run(argv) {
 }
 
 func TestExecute_ScriptError(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns an error
 	script := `
 function run(argv) {
@@ -113,15 +129,21 @@ function run(argv) {
 		t.Errorf("Execute() result = %v, want nil on error", result)
 	}
 
-	// Check that error message contains the key phrase
-	if err == nil || !strings.Contains(err.Error(), "JXA script error: Something went wrong") {
-		t.Errorf("Execute() error should contain 'JXA script error: Something went wrong', got: %v", err)
+	// Check that the script error is returned as *Error
+	var jxaErr *Error
+	if !errors.As(err, &jxaErr) {
+		t.Fatalf("Execute() error type = %T, want *Error", err)
+	}
+	if jxaErr.Code != ErrorCodeUnknown || jxaErr.Message != "Something went wrong" {
+		t.Errorf("Execute() error = %+v, want code %s and message 'Something went wrong'", jxaErr, ErrorCodeUnknown)
 	}
 
 	t.Logf("Error: %v", err)
 }
 
 func TestExecute_WithArguments(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that uses arguments
 	script := `
 function run(argv) {
@@ -170,6 +192,8 @@ function run(argv) {
 }
 
 func TestExecute_InvalidJSON(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns invalid JSON
 	script := `
 function run(argv) {
@@ -192,6 +216,8 @@ function run(argv) {
 }
 
 func TestExecute_ContextCancellation(t *testing.T) {
+	requireOsascript(t)
+
 	// Test context cancellation
 	script := `
 function run(argv) {
@@ -271,6 +297,8 @@ func TestResult_JSONMarshaling(t *testing.T) {
 }
 
 func TestExecute_MailAppNotRunning(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns MAIL_APP_NOT_RUNNING error code
 	script := `
 function run(argv) {
@@ -306,6 +334,8 @@ function run(argv) {
 }
 
 func TestExecute_MailAppNoPermissions(t *testing.T) {
+	requireOsascript(t)
+
 	// Test script that returns MAIL_APP_NO_PERMISSIONS error code
 	script := `
 function run(argv) {
@@ -0,0 +1,97 @@
+package jxa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Call records a single invocation of FakeExecutor.Execute.
+type Call struct {
+	Script string
+	Args   []string
+}
+
+// FakeExecutor is an in-memory Executor for tests. It records every call and
+// answers with canned Result envelopes registered per script name. The
+// envelopes are run through the same parsing as real osascript output, so
+// script-level errors surface exactly as they would on a Mac.
+type FakeExecutor struct {
+	mu        sync.Mutex
+	responses map[string][]func(args []string) Result
+	calls     []Call
+}
+
+// Ensure the implementation satisfies the expected interface.
+var _ Executor = (*FakeExecutor)(nil)
+
+// NewFakeExecutor creates a FakeExecutor without any canned responses.
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{responses: make(map[string][]func(args []string) Result)}
+}
+
+// On queues canned results for the named script. Results are returned in
+// order; the last one is repeated once the queue is exhausted.
+func (f *FakeExecutor) On(script string, results ...Result) *FakeExecutor {
+	for _, r := range results {
+		f.OnFunc(script, func([]string) Result { return r })
+	}
+	return f
+}
+
+// OnFunc queues a function computing the result from the script arguments.
+func (f *FakeExecutor) OnFunc(script string, fn func(args []string) Result) *FakeExecutor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[script] = append(f.responses[script], fn)
+	return f
+}
+
+// Calls returns a copy of all calls recorded so far.
+func (f *FakeExecutor) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// CallsTo returns the recorded calls of the named script.
+func (f *FakeExecutor) CallsTo(script string) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if c.Script == script {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Execute records the call and returns the next canned result for the script.
+func (f *FakeExecutor) Execute(ctx context.Context, script Script, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Script: script.Name, Args: slices.Clone(args)})
+	queue := f.responses[script.Name]
+	var next func([]string) Result
+	if len(queue) > 0 {
+		next = queue[0]
+		if len(queue) > 1 {
+			f.responses[script.Name] = queue[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	if next == nil {
+		return nil, fmt.Errorf("fake executor: no response configured for script %q", script.Name)
+	}
+
+	output, err := json.Marshal(next(args))
+	if err != nil {
+		return nil, fmt.Errorf("fake executor: failed to marshal result: %w", err)
+	}
+	return ParseOutput(ctx, output, args)
+}
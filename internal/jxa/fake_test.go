@@ -0,0 +1,73 @@
+package jxa
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFakeExecutor_ReturnsQueuedResults(t *testing.T) {
+	fake := NewFakeExecutor().On("list_accounts",
+		Result{Success: true, Data: map[string]any{"count": 1}},
+		Result{Success: true, Data: map[string]any{"count": 2}},
+	)
+	script := Script{Name: "list_accounts"}
+
+	for i, want := range []float64{1, 2, 2} {
+		data, err := fake.Execute(context.Background(), script, `{"enabled":true}`)
+		if err != nil {
+			t.Fatalf("call %d: Execute() error = %v", i, err)
+		}
+		got := data.(map[string]any)["count"]
+		if got != want {
+			t.Errorf("call %d: count = %v, want %v", i, got, want)
+		}
+	}
+
+	calls := fake.CallsTo("list_accounts")
+	if len(calls) != 3 {
+		t.Fatalf("len(CallsTo()) = %d, want 3", len(calls))
+	}
+	if calls[0].Args[0] != `{"enabled":true}` {
+		t.Errorf("recorded args = %v", calls[0].Args)
+	}
+}
+
+func TestFakeExecutor_ScriptError(t *testing.T) {
+	fake := NewFakeExecutor().On("find_messages", Result{
+		Success:   false,
+		Error:     "Mail.app is not running. Please start Mail.app and try again.",
+		ErrorCode: ErrorCodeMailAppNotRunning,
+	})
+
+	_, err := fake.Execute(context.Background(), Script{Name: "find_messages"})
+	if err == nil || !strings.Contains(err.Error(), "Mail.app is not running") {
+		t.Errorf("Execute() error = %v, want Mail.app not running error", err)
+	}
+}
+
+func TestFakeExecutor_OnFunc(t *testing.T) {
+	fake := NewFakeExecutor().OnFunc("echo", func(args []string) Result {
+		return Result{Success: true, Data: map[string]any{"arg": args[0]}}
+	})
+
+	data, err := fake.Execute(context.Background(), Script{Name: "echo"}, "hello")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := data.(map[string]any)["arg"]; got != "hello" {
+		t.Errorf("arg = %v, want hello", got)
+	}
+}
+
+func TestFakeExecutor_UnknownScript(t *testing.T) {
+	fake := NewFakeExecutor()
+
+	_, err := fake.Execute(context.Background(), Script{Name: "missing"})
+	if err == nil || !strings.Contains(err.Error(), `no response configured for script "missing"`) {
+		t.Errorf("Execute() error = %v, want missing response error", err)
+	}
+	if len(fake.Calls()) != 1 {
+		t.Errorf("len(Calls()) = %d, want 1", len(fake.Calls()))
+	}
+}
@@ -0,0 +1,18 @@
+package jxa
+
+import (
+	_ "embed"
+)
+
+// Prelude is the shared JXA library prepended to every tool script. It
+// provides the result envelope, the Mail.app running check, argument parsing,
+// logging and mailbox resolution (see prelude.js).
+//
+//go:embed prelude.js
+var Prelude string
+
+// NewScript creates a script from the source of a tool script, which may use
+// all helpers defined in Prelude.
+func NewScript(name, source string) Script {
+	return Script{Name: name, Source: Prelude + "\n" + source}
+}
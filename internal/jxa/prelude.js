@@ -0,0 +1,316 @@
+// Shared library prepended to every tool script by jxa.NewScript.
+//
+// A tool script only defines run(argv) and passes its logic to runScript,
+// which checks that Mail.app is running, parses the JSON arguments, collects
+// logs and wraps the result in the envelope expected by jxa.ParseOutput:
+//   {"success": true, "data": {...}, "logs": "..."}
+//   {"success": false, "error": "...", "errorCode": "...", "logs": "..."}
+// Failures are reported by throwing a ScriptError with one of the codes
+// defined in internal/jxa/errors.go.
+
+// ScriptError is an expected failure with a machine-readable code.
+class ScriptError extends Error {
+  constructor(message, code) {
+    super(message);
+    this.code = code;
+  }
+}
+
+// runScript runs handler(Mail, args, log) and returns the JSON envelope of
+// its result. Unexpected errors are prefixed with context, e.g.
+// "Failed to list drafts".
+function runScript(argv, handler, context) {
+  const log = newLogger();
+  try {
+    const Mail = Application("Mail");
+    Mail.includeStandardAdditions = true;
+
+    // Check if running FIRST, any other Apple Event would launch Mail.app
+    if (!Mail.running()) {
+      throw new ScriptError(
+        "Mail.app is not running. Please start Mail.app and try again.",
+        "MAIL_APP_NOT_RUNNING",
+      );
+    }
+
+    const args = parseArgs(argv);
+    const data = handler(Mail, args, log);
+    return JSON.stringify({ success: true, data: data, logs: log.text() });
+  } catch (e) {
+    if (e instanceof ScriptError) {
+      return failure(e.message, e.code, log);
+    }
+    log(`Caught error: ${e.toString()}`);
+    return failure(
+      context ? `${context}: ${e.toString()}` : e.toString(),
+      errorCodeOf(e),
+      log,
+    );
+  }
+}
+
+// newLogger returns a log function collecting messages for the envelope,
+// since console.log would end up in osascript's output.
+function newLogger() {
+  const logs = [];
+  const log = (message) => {
+    logs.push(String(message));
+  };
+  log.text = () => logs.join("\n");
+  return log;
+}
+
+function failure(error, errorCode, log) {
+  return JSON.stringify({
+    success: false,
+    error: error,
+    errorCode: errorCode || "UNKNOWN_ERROR",
+    logs: log.text(),
+  });
+}
+
+// parseArgs parses the JSON object passed as first argument. Scripts without
+// input are called without arguments.
+function parseArgs(argv) {
+  if (!argv || argv.length === 0) {
+    return {};
+  }
+  try {
+    return JSON.parse(argv[0]);
+  } catch (e) {
+    throw new ScriptError(
+      "Failed to parse input arguments JSON",
+      "INVALID_PARAMETERS",
+    );
+  }
+}
+
+// errorCodeOf maps unexpected errors to an error code. Denied automation
+// permissions surface as error -1743.
+function errorCodeOf(e) {
+  if (
+    e.errorNumber === -1743 ||
+    e.toString().includes("Automation is not allowed")
+  ) {
+    return "MAIL_APP_NO_PERMISSIONS";
+  }
+  return "UNKNOWN_ERROR";
+}
+
+// findAccount returns the account with the given name.
+function findAccount(Mail, accountName) {
+  try {
+    // Name lookup does not fail for missing accounts, reading a property does
+    const account = Mail.accounts[accountName];
+    account.name();
+    return account;
+  } catch (e) {
+    throw new ScriptError(
+      `Account "${accountName}" not found. Please verify the account name is correct.`,
+      "ACCOUNT_NOT_FOUND",
+    );
+  }
+}
+
+// findMailbox returns the mailbox at mailboxPath, e.g. ["Inbox", "GitHub"].
+function findMailbox(account, mailboxPath) {
+  const mailbox = findMailboxByPath(account, mailboxPath);
+  if (!mailbox) {
+    throw new ScriptError(
+      `Mailbox path '${mailboxPath.join(" > ")}' not found in account '${account.name()}'.`,
+      "MAILBOX_NOT_FOUND",
+    );
+  }
+  return mailbox;
+}
+
+// findMailboxByPath resolves a mailbox path within an account. An empty path
+// resolves to the account itself. Returns null if the mailbox does not exist.
+function findMailboxByPath(account, targetPath) {
+  if (!targetPath || targetPath.length === 0) return account;
+
+  // Walk down the path by name, which is fast but fails for some IMAP
+  // mailboxes whose names contain the hierarchy delimiter.
+  try {
+    let current = account;
+    for (let i = 0; i < targetPath.length; i++) {
+      const part = targetPath[i];
+      let next = null;
+      try {
+        next = current.mailboxes.whose({ name: part })()[0];
+      } catch (e) {}
+
+      if (!next) {
+        try {
+          next = current.mailboxes[part];
+          next.name();
+        } catch (e) {
+          next = null;
+        }
+      }
+      if (!next) throw new Error("not found");
+      current = next;
+    }
+    return current;
+  } catch (e) {}
+
+  // Fall back to comparing the full path of every mailbox of the account.
+  try {
+    const accountName = account.name();
+    const allMailboxes = account.mailboxes();
+    for (let i = 0; i < allMailboxes.length; i++) {
+      const path = getMailboxPath(allMailboxes[i], accountName);
+      if (
+        path.length === targetPath.length &&
+        path.every((name, j) => name === targetPath[j])
+      ) {
+        return allMailboxes[i];
+      }
+    }
+  } catch (e) {}
+  return null;
+}
+
+// getMailboxPath returns the path of a mailbox within its account, e.g.
+// ["Inbox", "GitHub"].
+function getMailboxPath(mailbox, accountName) {
+  const path = [];
+  let current = mailbox;
+
+  // Walk up the mailbox tree until we reach the account
+  while (current) {
+    try {
+      const name = current.name();
+      if (name === accountName) break;
+      path.unshift(name);
+      current = current.container();
+    } catch (e) {
+      // No container, stop
+      break;
+    }
+  }
+  return path;
+}
+
+// findMessage returns the message with the given ID. whose() is used
+// because it is much faster than iterating over the mailbox.
+function findMessage(mailbox, messageId, notFoundMessage) {
+  const messages = mailbox.messages.whose({ id: messageId })();
+  if (!messages || messages.length === 0) {
+    throw new ScriptError(notFoundMessage, "MESSAGE_NOT_FOUND");
+  }
+  return messages[0];
+}
+
+// findMessagesByIds returns the messages with the given IDs. All IDs are
+// resolved before a script changes anything, so that a wrong ID does not
+// leave a partially applied change.
+function findMessagesByIds(mailbox, messageIds, mailboxPath) {
+  const messages = [];
+  const missing = [];
+  messageIds.forEach((id) => {
+    const found = mailbox.messages.whose({ id: id })();
+    if (found && found.length > 0) {
+      messages.push(found[0]);
+    } else {
+      missing.push(id);
+    }
+  });
+  if (missing.length > 0) {
+    throw new ScriptError(
+      `Messages with IDs ${missing.join(", ")} not found in mailbox '${mailboxPath.join(" > ")}'.`,
+      "MESSAGE_NOT_FOUND",
+    );
+  }
+  return messages;
+}
+
+// transferMessages moves (or copies) messages to the destination mailbox and
+// returns their new locations. Mail.app assigns new IDs to filed messages,
+// which are looked up by their Message-ID header. The lookup may fail for
+// IMAP accounts that have not synchronized yet.
+function transferMessages(Mail, messages, destination, copy, log) {
+  const accountName = destination.account().name();
+  const destinationPath = getMailboxPath(destination, accountName);
+
+  return messages.map((msg) => {
+    const id = msg.id();
+    const subject = msg.subject();
+    let headerId = "";
+    try {
+      headerId = msg.messageId();
+    } catch (e) {
+      log(`Error reading Message-ID of message ${id}: ${e.toString()}`);
+    }
+
+    if (copy) {
+      Mail.duplicate(msg, { to: destination });
+    } else {
+      Mail.move(msg, { to: destination });
+    }
+    log(`${copy ? "Copied" : "Moved"} message ${id}.`);
+
+    let newId = null;
+    if (headerId) {
+      try {
+        const filed = destination.messages.whose({ messageId: headerId })();
+        if (filed.length > 0) {
+          newId = filed[filed.length - 1].id();
+        }
+      } catch (e) {
+        log(`Error looking up message ${id} in destination: ${e.toString()}`);
+      }
+    }
+
+    return {
+      message_id: id,
+      new_message_id: newId,
+      subject: subject,
+      account: accountName,
+      mailboxPath: destinationPath,
+    };
+  });
+}
+
+// findOutgoingMessage returns the open compose window with the given ID.
+function findOutgoingMessage(Mail, outgoingId) {
+  const messages = Mail.outgoingMessages.whose({ id: outgoingId })();
+  if (messages.length === 0) {
+    throw new ScriptError(
+      `Outgoing message with ID ${outgoingId} not found.`,
+      "MESSAGE_NOT_FOUND",
+    );
+  }
+  return messages[0];
+}
+
+// recipientAddresses returns the addresses of a recipient collection such as
+// msg.toRecipients.
+function recipientAddresses(recipients, log) {
+  try {
+    return recipients().map((r) => r.address());
+  } catch (e) {
+    log(`Error reading recipients: ${e.toString()}`);
+    return [];
+  }
+}
+
+// addRecipients appends addresses to a recipient collection.
+function addRecipients(Mail, recipients, addresses) {
+  if (Array.isArray(addresses)) {
+    addresses.forEach((address) =>
+      recipients.push(Mail.Recipient({ address: address })),
+    );
+  }
+}
+
+function contentPreview(content) {
+  return content.length > 100 ? content.substring(0, 100) + "..." : content;
+}
+
+// activateMail brings Mail.app to the front and returns its PID, which the
+// Go side needs to paste content into the compose window.
+function activateMail(Mail) {
+  Mail.activate();
+  return Application("System Events").processes.byName("Mail").unixId();
+}
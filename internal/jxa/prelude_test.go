@@ -0,0 +1,42 @@
+package jxa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewScript(t *testing.T) {
+	script := NewScript("list_accounts", "function run(argv) {}")
+
+	if script.Name != "list_accounts" {
+		t.Errorf("Name = %q, want %q", script.Name, "list_accounts")
+	}
+	if !strings.HasPrefix(script.Source, Prelude) {
+		t.Error("Source does not start with Prelude")
+	}
+	if !strings.HasSuffix(script.Source, "\nfunction run(argv) {}") {
+		t.Errorf("Source does not end with the script source: %q", script.Source[len(Prelude):])
+	}
+}
+
+func TestPrelude_DefinesHelpers(t *testing.T) {
+	// Helpers that tool scripts rely on.
+	helpers := []string{
+		"class ScriptError",
+		"function runScript(",
+		"function findAccount(",
+		"function findMailbox(",
+		"function findMailboxByPath(",
+		"function getMailboxPath(",
+		"function findMessage(",
+		"function findOutgoingMessage(",
+	}
+	for _, helper := range helpers {
+		if !strings.Contains(Prelude, helper) {
+			t.Errorf("Prelude does not define %q", helper)
+		}
+	}
+	if strings.Contains(Prelude, "function run(") {
+		t.Error("Prelude must not define run, it is defined by each tool script")
+	}
+}
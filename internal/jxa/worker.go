@@ -0,0 +1,381 @@
+package jxa
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/dastrobu/mail-mcp/internal/log"
+)
+
+//go:embed worker.js
+var workerSource string
+
+const (
+	// DefaultWorkerTimeout is the default time a single script may run in the worker.
+	DefaultWorkerTimeout = 2 * time.Minute
+
+	// maxRestartDelay caps the backoff between restarts of a crashing worker.
+	maxRestartDelay = 5 * time.Second
+
+	// maxResponseSize is the maximum size of a single response line.
+	maxResponseSize = 64 << 20
+
+	// stderrTailSize is the amount of worker stderr kept for error messages.
+	stderrTailSize = 4 << 10
+)
+
+// errWorkerTimeout is the cause of the context of a request whose worker
+// timeout expired.
+var errWorkerTimeout = errors.New("jxa worker: timeout")
+
+// WorkerOptions configures a WorkerExecutor.
+type WorkerOptions struct {
+	// Command starts the worker process. It defaults to osascript running the
+	// embedded worker script. Tests use a stand-in binary speaking the same
+	// protocol.
+	Command []string
+
+	// Timeout limits the time of a single request. When it expires, the
+	// worker is killed and restarted for the next request. Defaults to
+	// DefaultWorkerTimeout.
+	Timeout time.Duration
+}
+
+// WorkerExecutor runs scripts in a long-lived worker process instead of
+// spawning osascript for every call. Requests and responses are exchanged as
+// newline-delimited JSON over the worker's stdin and stdout (see worker.js).
+//
+// Requests are processed one at a time. A worker that crashes or times out is
+// restarted on the next request, with an increasing delay if it keeps
+// crashing.
+type WorkerExecutor struct {
+	command []string
+	timeout time.Duration
+
+	mu       sync.Mutex // serializes requests and guards the fields below
+	proc     *workerProcess
+	nextID   int64
+	failures int // consecutive failed requests, used for the restart backoff
+	closed   bool
+}
+
+// Ensure the implementation satisfies the expected interface.
+var _ Executor = (*WorkerExecutor)(nil)
+
+// NewWorkerExecutor creates a worker executor. The worker process is started
+// lazily by the first request.
+func NewWorkerExecutor(opts WorkerOptions) *WorkerExecutor {
+	command := opts.Command
+	if len(command) == 0 {
+		command = []string{"osascript", "-l", "JavaScript", "-e", workerSource}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWorkerTimeout
+	}
+	return &WorkerExecutor{command: command, timeout: timeout}
+}
+
+type workerRequest struct {
+	ID     int64    `json:"id"`
+	Script string   `json:"script"`
+	Source *string  `json:"source,omitempty"`
+	Args   []string `json:"args"`
+}
+
+type workerResponse struct {
+	ID     int64  `json:"id"`
+	Output string `json:"output"`
+	Error  string `json:"error"`
+}
+
+// Execute sends the script to the worker and waits for its result.
+func (w *WorkerExecutor) Execute(ctx context.Context, script Script, args ...string) (any, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, errors.New("jxa worker: executor is closed")
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, w.timeout, errWorkerTimeout)
+	defer cancel()
+
+	p, err := w.process(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.nextID++
+	req := workerRequest{ID: w.nextID, Script: script.Name, Args: args}
+	if req.Args == nil {
+		req.Args = []string{}
+	}
+	if !p.loaded[script.Name] {
+		req.Source = &script.Source
+	}
+
+	resp, err := p.roundTrip(ctx, req)
+	if err != nil {
+		w.failures++
+		p.kill()
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Report the deadline that expired: the worker timeout or the
+			// deadline of the caller's context
+			timeoutErr := NewError(ErrorCodeTimeout, "script %s did not finish before the deadline of the request", script.Name)
+			if errors.Is(context.Cause(ctx), errWorkerTimeout) {
+				timeoutErr = NewError(ErrorCodeTimeout, "script %s did not finish within %v", script.Name, w.timeout)
+			}
+			log.FromContext(ctx).Printf("[DEBUG] %v, restarting JXA worker\n", timeoutErr)
+			return nil, timeoutErr
+		}
+		return nil, err
+	}
+	w.failures = 0
+
+	if resp.Error != "" {
+		// A script that failed to load is sent again with the next request.
+		delete(p.loaded, script.Name)
+		return nil, fmt.Errorf("JXA worker error in script %s: %s\nArguments: %v", script.Name, resp.Error, args)
+	}
+	p.loaded[script.Name] = true
+
+	return ParseOutput(ctx, []byte(resp.Output), args)
+}
+
+// Close stops the worker process. Subsequent calls to Execute fail.
+func (w *WorkerExecutor) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.proc != nil {
+		w.proc.stop()
+		w.proc = nil
+	}
+	return nil
+}
+
+// process returns the running worker, (re)starting it if needed.
+func (w *WorkerExecutor) process(ctx context.Context) (*workerProcess, error) {
+	if w.proc != nil && !w.proc.exited() {
+		return w.proc, nil
+	}
+
+	if w.proc != nil {
+		log.FromContext(ctx).Printf("[DEBUG] JXA worker exited (%v), restarting\n", w.proc.exitErr())
+		w.proc = nil
+	}
+
+	// Back off if the worker keeps failing, e.g. because osascript crashes on start.
+	if w.failures > 0 {
+		delay := min(100*time.Millisecond<<min(w.failures-1, 6), maxRestartDelay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	p, err := startWorker(w.command)
+	if err != nil {
+		w.failures++
+		return nil, err
+	}
+	w.proc = p
+	return p, nil
+}
+
+// workerProcess is a single run of the worker command.
+type workerProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan workerResponse
+	quit      chan struct{} // closed when the process is being killed
+	quitOnce  sync.Once
+	done      chan struct{} // closed when the process has exited
+	err       error         // result of cmd.Wait, valid after done is closed
+	stderr    *tailBuffer
+	loaded    map[string]bool // scripts whose source the worker already has
+}
+
+func startWorker(command []string) (*workerProcess, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("jxa worker: failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("jxa worker: failed to create stdout pipe: %w", err)
+	}
+	stderr := &tailBuffer{max: stderrTailSize}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("jxa worker: failed to start %s: %w", command[0], err)
+	}
+
+	p := &workerProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan workerResponse),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		stderr:    stderr,
+		loaded:    make(map[string]bool),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64<<10), maxResponseSize)
+		for scanner.Scan() {
+			var resp workerResponse
+			if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.ID == 0 {
+				continue // not a response, e.g. stray output of osascript
+			}
+			select {
+			case p.responses <- resp:
+			case <-p.quit:
+			}
+		}
+		// Drain stdout so that Wait does not block, then reap the process.
+		_, _ = io.Copy(io.Discard, stdout)
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// roundTrip writes a request and waits for the matching response. Writing is
+// bounded by ctx as well, since a wedged worker may stop reading its stdin;
+// the caller kills the worker on errors, which ends a blocked write.
+func (p *workerProcess) roundTrip(ctx context.Context, req workerRequest) (workerResponse, error) {
+	line, err := asciiJSON(req)
+	if err != nil {
+		return workerResponse{}, fmt.Errorf("jxa worker: failed to encode request: %w", err)
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.stdin.Write(append(line, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			return workerResponse{}, fmt.Errorf("jxa worker: failed to send request: %w%s", err, p.stderrSuffix())
+		}
+	case <-ctx.Done():
+		return workerResponse{}, ctx.Err()
+	}
+
+	for {
+		select {
+		case resp := <-p.responses:
+			if resp.ID == req.ID {
+				return resp, nil
+			}
+		case <-p.done:
+			return workerResponse{}, fmt.Errorf("jxa worker exited while running script %s: %v%s", req.Script, p.err, p.stderrSuffix())
+		case <-ctx.Done():
+			return workerResponse{}, ctx.Err()
+		}
+	}
+}
+
+func (p *workerProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *workerProcess) exitErr() error {
+	if p.exited() {
+		return p.err
+	}
+	return nil
+}
+
+// kill terminates the process and waits for it to be reaped.
+func (p *workerProcess) kill() {
+	p.quitOnce.Do(func() { close(p.quit) })
+	_ = p.cmd.Process.Kill()
+	<-p.done
+}
+
+// stop asks the worker to exit by closing its stdin and kills it if it does
+// not exit in time.
+func (p *workerProcess) stop() {
+	_ = p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(time.Second):
+		p.kill()
+	}
+}
+
+func (p *workerProcess) stderrSuffix() string {
+	if s := strings.TrimSpace(p.stderr.String()); s != "" {
+		return "\nStderr:\n" + s
+	}
+	return ""
+}
+
+// asciiJSON encodes v as JSON with all non-ASCII characters escaped, so that
+// the worker can split the byte stream on newlines without decoding UTF-8.
+func asciiJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, r := range string(data) {
+		if r < 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+		if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+			fmt.Fprintf(&b, `\u%04x\u%04x`, r1, r2)
+		} else {
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = append(t.data, p...)
+	if len(t.data) > t.max {
+		t.data = t.data[len(t.data)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.data)
+}
@@ -0,0 +1,69 @@
+// Persistent JXA worker used by jxa.WorkerExecutor.
+//
+// Protocol: newline-delimited JSON over stdin/stdout. Each request line is
+//   {"id": 1, "script": "list_accounts", "source": "function run(argv) {...}", "args": ["{...}"]}
+// where "source" is only sent the first time a script is used in this process.
+// Each response line is either
+//   {"id": 1, "output": "<string returned by run(argv)>"}
+// or, if the script could not be loaded or threw,
+//   {"id": 1, "error": "<message>"}
+// Requests are ASCII-only (non-ASCII characters are \u-escaped by the Go side),
+// so they can be split on newline bytes safely.
+
+ObjC.import("Foundation");
+
+function run() {
+  const stdin = $.NSFileHandle.fileHandleWithStandardInput;
+  const stdout = $.NSFileHandle.fileHandleWithStandardOutput;
+  const scripts = {};
+
+  const write = (response) => {
+    const line = $(JSON.stringify(response) + "\n");
+    stdout.writeData(line.dataUsingEncoding($.NSUTF8StringEncoding));
+  };
+
+  const handle = (request) => {
+    try {
+      if (typeof request.source === "string") {
+        // Each script defines a top-level run(argv) function. Wrapping the
+        // source keeps the helpers of different scripts apart.
+        scripts[request.script] = new Function(request.source + "\nreturn run;")();
+      }
+      const fn = scripts[request.script];
+      if (typeof fn !== "function") {
+        return { id: request.id, error: `Script ${request.script} is not loaded` };
+      }
+      const output = fn(request.args || []);
+      return { id: request.id, output: String(output) };
+    } catch (e) {
+      return { id: request.id, error: e.toString() };
+    }
+  };
+
+  let buffer = "";
+  for (;;) {
+    const data = stdin.availableData;
+    if (data.length === 0) {
+      break; // EOF: the Go side closed stdin
+    }
+    buffer += $.NSString.alloc.initWithDataEncoding(data, $.NSASCIIStringEncoding).js;
+
+    let newline;
+    while ((newline = buffer.indexOf("\n")) >= 0) {
+      const line = buffer.slice(0, newline);
+      buffer = buffer.slice(newline + 1);
+      if (line.trim() === "") {
+        continue;
+      }
+      let request;
+      try {
+        request = JSON.parse(line);
+      } catch (e) {
+        write({ id: 0, error: "Invalid request: " + e.toString() });
+        continue;
+      }
+      write(handle(request));
+    }
+  }
+  return "";
+}
@@ -0,0 +1,307 @@
+package jxa
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testWorkerEnv makes the test binary act as a stand-in for the osascript
+// worker, so that the worker protocol can be tested without macOS.
+const testWorkerEnv = "JXA_TEST_WORKER"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testWorkerEnv) {
+	case "1":
+		runTestWorker()
+		os.Exit(0)
+	case "stuck":
+		// A wedged worker that never reads its stdin
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runTestWorker implements the protocol of worker.js. Scripts are identified
+// by name:
+//   - echo returns its arguments, the worker PID and whether the source was sent
+//   - sleep blocks longer than any test timeout
+//   - crash exits the worker
+//   - throw fails like a script raising an exception
+//   - fail returns a script-level error envelope
+func runTestWorker() {
+	loaded := make(map[string]bool)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64    `json:"id"`
+			Script string   `json:"script"`
+			Source *string  `json:"source"`
+			Args   []string `json:"args"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Printf(`{"id":0,"error":%q}`+"\n", err.Error())
+			continue
+		}
+		if !isASCII(scanner.Text()) {
+			fmt.Printf(`{"id":%d,"error":"request is not ASCII"}`+"\n", req.ID)
+			continue
+		}
+
+		sourceSent := req.Source != nil
+		if sourceSent {
+			loaded[req.Script] = true
+		}
+		if !loaded[req.Script] {
+			fmt.Printf(`{"id":%d,"error":"Script %s is not loaded"}`+"\n", req.ID, req.Script)
+			continue
+		}
+
+		var output any
+		switch req.Script {
+		case "echo":
+			output = Result{Success: true, Data: map[string]any{
+				"args":       req.Args,
+				"pid":        os.Getpid(),
+				"sourceSent": sourceSent,
+			}}
+		case "sleep":
+			time.Sleep(time.Minute)
+		case "crash":
+			fmt.Fprintln(os.Stderr, "worker crashed")
+			os.Exit(3)
+		case "throw":
+			delete(loaded, req.Script)
+			fmt.Printf(`{"id":%d,"error":"Error: boom"}`+"\n", req.ID)
+			continue
+		case "fail":
+			output = Result{Success: false, Error: "Account 'X' not found.", ErrorCode: ErrorCodeAccountNotFound}
+		}
+
+		// Stray output must be ignored by the executor.
+		fmt.Println("not a response")
+		outputJSON, _ := json.Marshal(output)
+		resp, _ := json.Marshal(workerResponse{ID: req.ID, Output: string(outputJSON)})
+		fmt.Println(string(resp))
+	}
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// newTestWorker creates a worker executor running the stand-in worker.
+func newTestWorker(t *testing.T, timeout time.Duration) *WorkerExecutor {
+	t.Helper()
+	t.Setenv(testWorkerEnv, "1")
+	w := NewWorkerExecutor(WorkerOptions{
+		Command: []string{os.Args[0], "-test.run=^$"},
+		Timeout: timeout,
+	})
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func echo(t *testing.T, w *WorkerExecutor, args ...string) map[string]any {
+	t.Helper()
+	data, err := w.Execute(context.Background(), Script{Name: "echo", Source: "function run(argv) {}"}, args...)
+	if err != nil {
+		t.Fatalf("Execute(echo) error = %v", err)
+	}
+	return data.(map[string]any)
+}
+
+func TestWorkerExecutor_ReusesProcess(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+
+	first := echo(t, w, "a", "ü 😀")
+	second := echo(t, w)
+
+	if first["pid"] != second["pid"] {
+		t.Errorf("worker PID changed from %v to %v, want the same process", first["pid"], second["pid"])
+	}
+	if first["sourceSent"] != true || second["sourceSent"] != false {
+		t.Errorf("sourceSent = %v, %v, want true, false", first["sourceSent"], second["sourceSent"])
+	}
+	if got := fmt.Sprint(first["args"]); got != "[a ü 😀]" {
+		t.Errorf("args = %v, want [a ü 😀]", got)
+	}
+}
+
+func TestWorkerExecutor_ScriptError(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+
+	_, err := w.Execute(context.Background(), Script{Name: "fail"})
+	if !HasCode(err, ErrorCodeAccountNotFound) {
+		t.Errorf("Execute() error = %v, want %s", err, ErrorCodeAccountNotFound)
+	}
+}
+
+func TestWorkerExecutor_ScriptException(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+	pid := echo(t, w)["pid"]
+
+	_, err := w.Execute(context.Background(), Script{Name: "throw"})
+	if err == nil || !strings.Contains(err.Error(), "Error: boom") {
+		t.Errorf("Execute() error = %v, want the exception message", err)
+	}
+	// The source is sent again after a failure and the worker keeps running.
+	_, err = w.Execute(context.Background(), Script{Name: "throw"})
+	if err == nil || !strings.Contains(err.Error(), "Error: boom") {
+		t.Errorf("second Execute() error = %v, want the exception message", err)
+	}
+	if got := echo(t, w)["pid"]; got != pid {
+		t.Errorf("worker PID changed from %v to %v, want the same process", pid, got)
+	}
+}
+
+func TestWorkerExecutor_Timeout(t *testing.T) {
+	w := newTestWorker(t, 200*time.Millisecond)
+	pid := echo(t, w)["pid"]
+
+	start := time.Now()
+	_, err := w.Execute(context.Background(), Script{Name: "sleep"})
+	var jxaErr *Error
+	if !errors.As(err, &jxaErr) || jxaErr.Code != ErrorCodeTimeout || !jxaErr.Retryable {
+		t.Fatalf("Execute() error = %v, want retryable %s", err, ErrorCodeTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %v, want it to return after the timeout", elapsed)
+	}
+
+	// The hanging worker is replaced by a new process.
+	if got := echo(t, w)["pid"]; got == pid {
+		t.Errorf("worker PID = %v after timeout, want a new process", got)
+	}
+}
+
+func TestWorkerExecutor_TimeoutWhileSending(t *testing.T) {
+	w := newTestWorker(t, 200*time.Millisecond)
+	t.Setenv(testWorkerEnv, "stuck")
+
+	// The request is larger than the pipe buffer, so writing it blocks
+	start := time.Now()
+	_, err := w.Execute(context.Background(), Script{Name: "echo"}, strings.Repeat("x", 1<<20))
+	if !HasCode(err, ErrorCodeTimeout) || !strings.Contains(err.Error(), "within 200ms") {
+		t.Fatalf("Execute() error = %v, want %s after the worker timeout", err, ErrorCodeTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %v, want it to return after the timeout", elapsed)
+	}
+}
+
+func TestWorkerExecutor_ContextDeadline(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+	echo(t, w)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := w.Execute(ctx, Script{Name: "sleep"})
+	if !HasCode(err, ErrorCodeTimeout) || !strings.Contains(err.Error(), "deadline of the request") {
+		t.Errorf("Execute() error = %v, want %s for the deadline of the context", err, ErrorCodeTimeout)
+	}
+}
+
+func TestWorkerExecutor_RestartsAfterCrash(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+	pid := echo(t, w)["pid"]
+
+	_, err := w.Execute(context.Background(), Script{Name: "crash"})
+	if err == nil || !strings.Contains(err.Error(), "worker crashed") {
+		t.Fatalf("Execute() error = %v, want crash with stderr", err)
+	}
+
+	got := echo(t, w)
+	if got["pid"] == pid {
+		t.Errorf("worker PID = %v after crash, want a new process", got["pid"])
+	}
+	if got["sourceSent"] != true {
+		t.Errorf("sourceSent = %v after restart, want true", got["sourceSent"])
+	}
+}
+
+func TestWorkerExecutor_Concurrent(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Go(func() {
+			data, err := w.Execute(context.Background(), Script{Name: "echo"}, fmt.Sprint(i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := fmt.Sprint(data.(map[string]any)["args"]); got != fmt.Sprintf("[%d]", i) {
+				errs <- fmt.Errorf("args = %v, want [%d]", got, i)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestWorkerExecutor_Close(t *testing.T) {
+	w := newTestWorker(t, time.Minute)
+	echo(t, w)
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := w.Execute(context.Background(), Script{Name: "echo"}); err == nil {
+		t.Error("Execute() after Close() error = nil, want error")
+	}
+}
+
+func TestWorkerExecutor_StartFailure(t *testing.T) {
+	w := NewWorkerExecutor(WorkerOptions{Command: []string{"/nonexistent/worker"}})
+
+	if _, err := w.Execute(context.Background(), Script{Name: "echo"}); err == nil {
+		t.Error("Execute() error = nil, want start failure")
+	}
+}
+
+func TestAsciiJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "ascii", value: "hello", want: `"hello"`},
+		{name: "latin", value: "Grüße", want: `"Gr\u00fc\u00dfe"`},
+		{name: "emoji", value: "😀", want: `"\ud83d\ude00"`},
+		{name: "escaped html", value: "<a>", want: `"\u003ca\u003e"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := asciiJSON(tt.value)
+			if err != nil {
+				t.Fatalf("asciiJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("asciiJSON() = %s, want %s", got, tt.want)
+			}
+			var decoded string
+			if err := json.Unmarshal(got, &decoded); err != nil || decoded != tt.value {
+				t.Errorf("asciiJSON() does not round-trip: %q, %v", decoded, err)
+			}
+		})
+	}
+}
@@ -29,13 +29,16 @@ const (
 
 // Config holds the launchd service configuration
 type Config struct {
-	BinaryPath string
-	Host       string
-	Port       int
-	LogPath    string
-	ErrPath    string
-	Debug      bool
-	RunAtLoad  bool
+	BinaryPath     string
+	Host           string
+	Port           int
+	LogPath        string
+	ErrPath        string
+	Debug          bool
+	Index          bool
+	ExportDir      string
+	AttachmentDirs []string
+	RunAtLoad      bool
 }
 
 // PlistPath returns the full path to the plist file
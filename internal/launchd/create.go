@@ -117,23 +117,29 @@ func createPlist(cfg *Config) error {
 
 	// Execute template with config
 	data := struct {
-		Label      string
-		BinaryPath string
-		Host       string
-		Port       int
-		LogPath    string
-		ErrPath    string
-		Debug      bool
-		RunAtLoad  bool
+		Label          string
+		BinaryPath     string
+		Host           string
+		Port           int
+		LogPath        string
+		ErrPath        string
+		Debug          bool
+		Index          bool
+		ExportDir      string
+		AttachmentDirs []string
+		RunAtLoad      bool
 	}{
-		Label:      Label,
-		BinaryPath: cfg.BinaryPath,
-		Host:       cfg.Host,
-		Port:       cfg.Port,
-		LogPath:    cfg.LogPath,
-		ErrPath:    cfg.ErrPath,
-		Debug:      cfg.Debug,
-		RunAtLoad:  cfg.RunAtLoad,
+		Label:          Label,
+		BinaryPath:     cfg.BinaryPath,
+		Host:           cfg.Host,
+		Port:           cfg.Port,
+		LogPath:        cfg.LogPath,
+		ErrPath:        cfg.ErrPath,
+		Debug:          cfg.Debug,
+		Index:          cfg.Index,
+		ExportDir:      cfg.ExportDir,
+		AttachmentDirs: cfg.AttachmentDirs,
+		RunAtLoad:      cfg.RunAtLoad,
 	}
 
 	if err := tmpl.Execute(file, data); err != nil {
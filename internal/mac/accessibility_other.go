@@ -0,0 +1,33 @@
+//go:build !darwin
+
+package mac
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errUnsupported is returned by all accessibility functions on platforms other
+// than macOS. It allows the tool layer to be built and unit-tested on Linux.
+var errUnsupported = errors.New("accessibility API is only available on macOS")
+
+// EnsureAccessibility checks for accessibility permissions.
+func EnsureAccessibility() error {
+	return errUnsupported
+}
+
+// GetMailPID returns the PID of Mail.app.
+func GetMailPID() int {
+	return 0
+}
+
+// SetClipboard sets the system clipboard content.
+func SetClipboard(htmlContent *string, plainContent string) error {
+	return errUnsupported
+}
+
+// PasteIntoWindow performs a paste operation into a Mail.app window.
+func PasteIntoWindow(ctx context.Context, pid int, expectedTitle string, timeout time.Duration, htmlContent *string, plainContent string) error {
+	return errUnsupported
+}
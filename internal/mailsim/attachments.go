@@ -0,0 +1,68 @@
+package mailsim
+
+import (
+	"os"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+)
+
+// saveAttachments mirrors scripts/save_attachments.js. Unlike the other
+// handlers, it writes to disk: the files at the given paths.
+func (s *Sim) saveAttachments(args []string) (map[string]any, error) {
+	var in struct {
+		Account     string   `json:"account"`
+		MailboxPath []string `json:"mailboxPath"`
+		MessageID   int      `json:"message_id"`
+		Attachments []struct {
+			Index int    `json:"index"`
+			Name  string `json:"name"`
+			Path  string `json:"path"`
+		} `json:"attachments"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	msg, err := s.lookupMessage(in.Account, in.MailboxPath, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.Attachments) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Attachments are required and must be a non-empty array")
+	}
+
+	saved := []map[string]any{}
+	for _, target := range in.Attachments {
+		if target.Index < 0 || target.Index >= len(msg.Attachments) || msg.Attachments[target.Index].Name != target.Name {
+			return nil, fail(jxa.ErrorCodeAttachmentNotFound, "Attachment %d \"%s\" not found in message %d.", target.Index, target.Name, msg.ID)
+		}
+		att := msg.Attachments[target.Index]
+		if !att.Downloaded {
+			return nil, fail(jxa.ErrorCodeAttachmentNotDownloaded, "Attachment \"%s\" has not been downloaded yet. Open the message in Mail.app to download it.", att.Name)
+		}
+		if err := os.WriteFile(target.Path, attachmentData(msg, att), 0o600); err != nil {
+			return nil, fail(jxa.ErrorCodeUnknown, "Failed to save attachments: %v", err)
+		}
+		saved = append(saved, map[string]any{"index": target.Index, "path": target.Path})
+	}
+	return map[string]any{
+		"id":    msg.ID,
+		"saved": saved,
+	}, nil
+}
+
+// attachmentData returns the content of an attachment: the decoded body of
+// the part of the message source with its file name, or fileSize zero bytes
+// if the source has no such part.
+func attachmentData(msg *Message, att Attachment) []byte {
+	var data []byte
+	mimepart.Parse([]byte(messageSource(msg))).Walk(func(p *mimepart.Part) {
+		if data == nil && p.Filename == att.Name && !p.IsMultipart() {
+			data = p.Body
+		}
+	})
+	if data == nil {
+		data = make([]byte, att.FileSize)
+	}
+	return data
+}
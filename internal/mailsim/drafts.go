@@ -0,0 +1,117 @@
+package mailsim
+
+import "github.com/dastrobu/mail-mcp/internal/jxa"
+
+// draftsMailboxes returns the drafts mailbox of every account that has one.
+func (s *Sim) draftsMailboxes() []*mailbox {
+	var result []*mailbox
+	for _, a := range s.accounts {
+		if m := a.findMailbox([]string{draftsMailboxName}); m != nil {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// listDrafts mirrors scripts/list_drafts.js.
+func (s *Sim) listDrafts(args []string) (map[string]any, error) {
+	var in struct {
+		Account string `json:"account"`
+		Limit   int    `json:"limit"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Limit == 0 {
+		in.Limit = 50
+	}
+	if in.Limit < 1 || in.Limit > 1000 {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "Limit must be between 1 and 1000")
+	}
+	if in.Account != "" && s.findAccount(in.Account) == nil {
+		return nil, accountNotFound(in.Account)
+	}
+
+	drafts := []map[string]any{}
+	total := 0
+	hasMore := false
+	for _, m := range s.draftsMailboxes() {
+		for _, msg := range m.messages {
+			total++
+			if in.Account != "" && m.account.name != in.Account {
+				continue
+			}
+			if len(drafts) >= in.Limit {
+				hasMore = true
+				continue
+			}
+			to := addresses(msg.ToRecipients)
+			cc := addresses(msg.CcRecipients)
+			bcc := addresses(msg.BccRecipients)
+			drafts = append(drafts, map[string]any{
+				"draft_id":         msg.ID,
+				"subject":          msg.Subject,
+				"sender":           msg.Sender,
+				"date_received":    isoString(msg.DateReceived),
+				"date_sent":        isoStringOrNil(msg.DateSent),
+				"content_preview":  preview(msg.Content),
+				"content_length":   len([]rune(msg.Content)),
+				"to_recipients":    to,
+				"cc_recipients":    cc,
+				"bcc_recipients":   bcc,
+				"to_count":         len(to),
+				"cc_count":         len(cc),
+				"bcc_count":        len(bcc),
+				"total_recipients": len(to) + len(cc) + len(bcc),
+				"mailbox":          m.name,
+				"account":          m.account.name,
+			})
+		}
+	}
+
+	return map[string]any{
+		"drafts":       drafts,
+		"count":        len(drafts),
+		"total_drafts": total,
+		"limit":        in.Limit,
+		"has_more":     hasMore,
+	}, nil
+}
+
+// deleteDraft mirrors scripts/delete_draft.js.
+func (s *Sim) deleteDraft(args []string) (map[string]any, error) {
+	var in struct {
+		DraftID *int `json:"draft_id"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.DraftID == nil {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "draft_id is required.")
+	}
+
+	for _, m := range s.draftsMailboxes() {
+		for i, msg := range m.messages {
+			if msg.ID != *in.DraftID {
+				continue
+			}
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			return map[string]any{
+				"draft_id": msg.ID,
+				"subject":  msg.Subject,
+				"account":  m.account.name,
+				"message":  "Draft deleted successfully.",
+			}, nil
+		}
+	}
+	return nil, fail(jxa.ErrorCodeMessageNotFound, "Draft with ID %d not found in the Drafts mailbox.", *in.DraftID)
+}
+
+// addresses returns the bare addresses of the recipients.
+func addresses(rs []Recipient) []string {
+	out := []string{}
+	for _, r := range rs {
+		out = append(out, r.Address)
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package mailsim
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// archiveMailboxPaths mirrors ARCHIVE_MAILBOX_PATHS of scripts/archive_messages.js.
+var archiveMailboxPaths = [][]string{
+	{"Archive"},
+	{"Archives"},
+	{"[Gmail]", "All Mail"},
+	{"[Google Mail]", "All Mail"},
+}
+
+type fileArgs struct {
+	Account                string   `json:"account"`
+	MailboxPath            []string `json:"mailboxPath"`
+	MessageIDs             []int    `json:"message_ids"`
+	DestinationMailboxPath []string `json:"destinationMailboxPath"`
+}
+
+// moveMessages mirrors scripts/move_messages.js.
+func (s *Sim) moveMessages(args []string) (map[string]any, error) {
+	return s.fileMessages(args, false, "Moved")
+}
+
+// copyMessages mirrors scripts/copy_messages.js.
+func (s *Sim) copyMessages(args []string) (map[string]any, error) {
+	return s.fileMessages(args, true, "Copied")
+}
+
+func (s *Sim) fileMessages(args []string, duplicate bool, verb string) (map[string]any, error) {
+	var in fileArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := validateFileArgs(in); err != nil {
+		return nil, err
+	}
+	if len(in.DestinationMailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Destination mailbox path is required and must be a non-empty array")
+	}
+	if len(in.MessageIDs) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "At least one message ID is required")
+	}
+
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+	source := a.findMailbox(in.MailboxPath)
+	if source == nil {
+		return nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+	destination := a.findMailbox(in.DestinationMailboxPath)
+	if destination == nil {
+		return nil, mailboxNotFound(in.DestinationMailboxPath, in.Account)
+	}
+	messages, err := source.findMessagesByIDs(in.MessageIDs, in.MailboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	filed := s.transfer(source, messages, destination, duplicate)
+	return fileResult(in, filed, destination.path(), verb), nil
+}
+
+// archiveMessages mirrors scripts/archive_messages.js.
+func (s *Sim) archiveMessages(args []string) (map[string]any, error) {
+	var in fileArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := validateFileArgs(in); err != nil {
+		return nil, err
+	}
+	if len(in.MessageIDs) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "At least one message ID is required")
+	}
+
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+	source := a.findMailbox(in.MailboxPath)
+	if source == nil {
+		return nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+
+	var archive *mailbox
+	tried := []string{}
+	for _, path := range archiveMailboxPaths {
+		if archive = a.findMailbox(path); archive != nil {
+			break
+		}
+		tried = append(tried, strings.Join(path, " > "))
+	}
+	if archive == nil {
+		return nil, fail(jxa.ErrorCodeMailboxNotFound, "No archive mailbox found in account '%s'. Tried: %s.", in.Account, strings.Join(tried, ", "))
+	}
+	if archivePath := archive.path(); slices.Equal(in.MailboxPath, archivePath) {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "The messages are already in the archive mailbox '%s'.", strings.Join(archivePath, " > "))
+	}
+
+	messages, err := source.findMessagesByIDs(in.MessageIDs, in.MailboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	filed := s.transfer(source, messages, archive, false)
+	return fileResult(in, filed, archive.path(), "Archived"), nil
+}
+
+func validateFileArgs(in fileArgs) error {
+	if in.Account == "" {
+		return fail(jxa.ErrorCodeMissingParameters, "Account name is required")
+	}
+	if len(in.MailboxPath) == 0 {
+		return fail(jxa.ErrorCodeMissingParameters, "Mailbox path is required and must be a non-empty array")
+	}
+	return nil
+}
+
+// findMessagesByIDs mirrors findMessagesByIds of the JXA prelude.
+func (m *mailbox) findMessagesByIDs(ids []int, path []string) ([]*Message, error) {
+	var messages []*Message
+	var missing []string
+	for _, id := range ids {
+		if msg := m.findMessage(id); msg != nil {
+			messages = append(messages, msg)
+		} else {
+			missing = append(missing, fmt.Sprint(id))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fail(jxa.ErrorCodeMessageNotFound, "Messages with IDs %s not found in mailbox '%s'.", strings.Join(missing, ", "), strings.Join(path, " > "))
+	}
+	return messages, nil
+}
+
+// transfer mirrors transferMessages of the JXA prelude. Like Mail.app, it
+// assigns new IDs to the filed messages.
+func (s *Sim) transfer(source *mailbox, messages []*Message, destination *mailbox, duplicate bool) []map[string]any {
+	filed := []map[string]any{}
+	for _, msg := range messages {
+		oldID := msg.ID
+		moved := msg
+		if duplicate {
+			clone := *msg
+			moved = &clone
+		} else {
+			source.messages = removeMessage(source.messages, msg)
+		}
+		moved.ID = s.nextMessageID
+		s.nextMessageID++
+		destination.messages = append(destination.messages, moved)
+
+		filed = append(filed, map[string]any{
+			"message_id":     oldID,
+			"new_message_id": moved.ID,
+			"subject":        moved.Subject,
+			"account":        destination.account.name,
+			"mailboxPath":    destination.path(),
+		})
+	}
+	return filed
+}
+
+func removeMessage(messages []*Message, msg *Message) []*Message {
+	for i, m := range messages {
+		if m == msg {
+			return append(messages[:i], messages[i+1:]...)
+		}
+	}
+	return messages
+}
+
+func fileResult(in fileArgs, filed []map[string]any, destinationPath []string, verb string) map[string]any {
+	return map[string]any{
+		"messages":               filed,
+		"count":                  len(filed),
+		"account":                in.Account,
+		"mailboxPath":            in.MailboxPath,
+		"destinationMailboxPath": destinationPath,
+		"message":                fmt.Sprintf("%s %d message(s) to '%s'.", verb, len(filed), strings.Join(destinationPath, " > ")),
+	}
+}
@@ -0,0 +1,107 @@
+package mailsim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Fixture describes the initial state of the simulated Mail.app. Fixtures can
+// be written in JSON or YAML; both use the JSON field names below.
+type Fixture struct {
+	Accounts []Account `json:"accounts"`
+}
+
+// Account is a Mail.app account with its top-level mailboxes.
+type Account struct {
+	Name           string    `json:"name"`
+	Enabled        *bool     `json:"enabled,omitempty"` // defaults to true
+	EmailAddresses []string  `json:"emailAddresses,omitempty"`
+	Mailboxes      []Mailbox `json:"mailboxes,omitempty"`
+}
+
+// Mailbox is a (possibly nested) mailbox. A top-level mailbox named "Drafts"
+// is treated as the account's drafts mailbox.
+type Mailbox struct {
+	Name      string    `json:"name"`
+	Mailboxes []Mailbox `json:"mailboxes,omitempty"`
+	Messages  []Message `json:"messages,omitempty"`
+}
+
+// Message is a received message, mirroring the properties the scripts read.
+type Message struct {
+	ID              int          `json:"id"`
+	Subject         string       `json:"subject"`
+	Sender          string       `json:"sender"`
+	ReplyTo         string       `json:"replyTo,omitempty"`
+	DateReceived    time.Time    `json:"dateReceived"`
+	DateSent        *time.Time   `json:"dateSent,omitempty"`
+	Content         string       `json:"content,omitempty"`
+	ReadStatus      bool         `json:"readStatus,omitempty"`
+	FlaggedStatus   bool         `json:"flaggedStatus,omitempty"`
+	FlagIndex       int          `json:"flagIndex,omitempty"` // flag colour of a flagged message, defaults to 0 (red)
+	JunkMailStatus  bool         `json:"junkMailStatus,omitempty"`
+	BackgroundColor string       `json:"backgroundColor,omitempty"` // defaults to "none"
+	MessageSize     int          `json:"messageSize,omitempty"`     // defaults to the size of headers and content
+	MessageID       string       `json:"messageId,omitempty"`
+	AllHeaders      string       `json:"allHeaders,omitempty"`
+	Source          string       `json:"source,omitempty"` // defaults to the headers and content as plain text
+	ToRecipients    []Recipient  `json:"toRecipients,omitempty"`
+	CcRecipients    []Recipient  `json:"ccRecipients,omitempty"`
+	BccRecipients   []Recipient  `json:"bccRecipients,omitempty"`
+	Attachments     []Attachment `json:"attachments,omitempty"`
+
+	// Selected marks the message as selected in the frontmost viewer.
+	Selected bool `json:"selected,omitempty"`
+}
+
+// Recipient is a To, Cc or Bcc recipient of a message.
+type Recipient struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// Attachment is a mail attachment of a message.
+type Attachment struct {
+	Name       string `json:"name"`
+	FileSize   int    `json:"fileSize"`
+	Downloaded bool   `json:"downloaded"`
+}
+
+// LoadFixture reads a fixture from a .json, .yaml or .yml file.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseFixture(data)
+	case ".yaml", ".yml":
+		jsonData, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML fixture %s: %w", path, err)
+		}
+		return ParseFixture(jsonData)
+	default:
+		return nil, fmt.Errorf("unsupported fixture format %q (expected .json, .yaml or .yml)", filepath.Ext(path))
+	}
+}
+
+// ParseFixture decodes a JSON fixture. Unknown fields are rejected so that
+// typos in hand-written fixtures do not go unnoticed.
+func ParseFixture(data []byte) (*Fixture, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f Fixture
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	return &f, nil
+}
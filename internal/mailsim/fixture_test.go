@@ -0,0 +1,117 @@
+package mailsim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFixture(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			file:    "fixture.json",
+			content: `{"accounts": [{"name": "Work", "mailboxes": [{"name": "INBOX"}]}]}`,
+		},
+		{
+			name:    "yaml",
+			file:    "fixture.yaml",
+			content: "accounts:\n  - name: Work\n    mailboxes:\n      - name: INBOX\n",
+		},
+		{
+			name:    "yml",
+			file:    "fixture.yml",
+			content: "accounts:\n  - name: Work\n",
+		},
+		{
+			name:    "unknown field",
+			file:    "fixture.yaml",
+			content: "accounts:\n  - name: Work\n    mailbox: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported extension",
+			file:    "fixture.toml",
+			content: "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			f, err := LoadFixture(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFixture() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(f.Accounts) != 1 || f.Accounts[0].Name != "Work") {
+				t.Errorf("LoadFixture() = %+v, want a single account Work", f)
+			}
+		})
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			fixture: `{"accounts": [{"name": "A", "mailboxes": [{"name": "INBOX", "messages": [{"id": 1}]}]}]}`,
+		},
+		{
+			name:    "missing account name",
+			fixture: `{"accounts": [{"name": ""}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate account",
+			fixture: `{"accounts": [{"name": "A"}, {"name": "A"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate mailbox",
+			fixture: `{"accounts": [{"name": "A", "mailboxes": [{"name": "INBOX"}, {"name": "INBOX"}]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate message ID across accounts",
+			fixture: `{"accounts": [{"name": "A", "mailboxes": [{"name": "INBOX", "messages": [{"id": 1}]}]}, {"name": "B", "mailboxes": [{"name": "INBOX", "messages": [{"id": 1}]}]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "non-positive message ID",
+			fixture: `{"accounts": [{"name": "A", "mailboxes": [{"name": "INBOX", "messages": [{"id": 0}]}]}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFixture([]byte(tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseFixture() error = %v", err)
+			}
+			_, err = New(f)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDemo(t *testing.T) {
+	if _, err := New(Demo()); err != nil {
+		t.Fatalf("New(Demo()) error = %v", err)
+	}
+}
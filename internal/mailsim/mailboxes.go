@@ -0,0 +1,89 @@
+package mailsim
+
+import (
+	"slices"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// listAccounts mirrors scripts/list_accounts.js.
+func (s *Sim) listAccounts(args []string) (map[string]any, error) {
+	var in struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	accounts := []map[string]any{}
+	for _, a := range s.accounts {
+		if in.Enabled && !a.enabled {
+			continue
+		}
+		addresses := slices.Clone(a.emailAddresses)
+		if addresses == nil {
+			addresses = []string{}
+		}
+		accounts = append(accounts, map[string]any{
+			"name":           a.name,
+			"enabled":        a.enabled,
+			"emailAddresses": addresses,
+			"mailboxCount":   len(a.mailboxes),
+		})
+	}
+
+	return map[string]any{
+		"accounts": accounts,
+		"count":    len(accounts),
+	}, nil
+}
+
+// listMailboxes mirrors scripts/list_mailboxes.js.
+func (s *Sim) listMailboxes(args []string) (map[string]any, error) {
+	var in struct {
+		Account     string   `json:"account"`
+		MailboxPath []string `json:"mailboxPath"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Account == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account name is required")
+	}
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+
+	source := a.mailboxes
+	if len(in.MailboxPath) > 0 {
+		parent := a.findMailbox(in.MailboxPath)
+		if parent == nil {
+			return nil, mailboxNotFound(in.MailboxPath, in.Account)
+		}
+		source = parent.children
+	}
+
+	mailboxes := []map[string]any{}
+	for _, m := range source {
+		mailboxes = append(mailboxes, map[string]any{
+			"name":            m.name,
+			"mailboxPath":     m.path(),
+			"account":         a.name,
+			"unreadCount":     m.unreadCount(),
+			"messageCount":    len(m.messages),
+			"hasSubMailboxes": len(m.children) > 0,
+			"subMailboxCount": len(m.children),
+		})
+	}
+
+	var parentPath any
+	if len(in.MailboxPath) > 0 {
+		parentPath = in.MailboxPath
+	}
+	return map[string]any{
+		"mailboxes":         mailboxes,
+		"count":             len(mailboxes),
+		"parentMailboxPath": parentPath,
+	}, nil
+}
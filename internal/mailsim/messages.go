@@ -0,0 +1,444 @@
+package mailsim
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// getMessageContent mirrors scripts/get_message_content.js.
+func (s *Sim) getMessageContent(args []string) (map[string]any, error) {
+	var in struct {
+		Account     string   `json:"account"`
+		MailboxPath []string `json:"mailboxPath"`
+		MessageID   int      `json:"message_id"`
+		PartTypes   bool     `json:"partTypes"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	msg, err := s.lookupMessage(in.Account, in.MailboxPath, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	partTypes := []string{}
+	if in.PartTypes && multipartHeader.MatchString(msg.AllHeaders) {
+		partTypes = mediaTypes(messageSource(msg))
+	}
+
+	attachments := []map[string]any{}
+	for _, att := range msg.Attachments {
+		attachments = append(attachments, map[string]any{
+			"name":       att.Name,
+			"fileSize":   att.FileSize,
+			"downloaded": att.Downloaded,
+		})
+	}
+
+	return map[string]any{
+		"message": map[string]any{
+			"id":            msg.ID,
+			"subject":       msg.Subject,
+			"sender":        msg.Sender,
+			"replyTo":       msg.ReplyTo,
+			"dateReceived":  isoString(msg.DateReceived),
+			"dateSent":      isoStringOrNil(msg.DateSent),
+			"content":       msg.Content,
+			"readStatus":    msg.ReadStatus,
+			"flaggedStatus": msg.FlaggedStatus,
+			"messageSize":   messageSize(msg),
+			"messageId":     msg.MessageID,
+			"allHeaders":    msg.AllHeaders,
+			"toRecipients":  recipientsJSON(msg.ToRecipients),
+			"ccRecipients":  recipientsJSON(msg.CcRecipients),
+			"bccRecipients": recipientsJSON(msg.BccRecipients),
+			"attachments":   attachments,
+		},
+		"partTypes": partTypes,
+	}, nil
+}
+
+var (
+	multipartHeader  = regexp.MustCompile(`(?im)^content-type:[ \t]*multipart/`)
+	contentTypeField = regexp.MustCompile(`(?im)^content-type:[ \t]*([\w.+-]+/[\w.+-]+)`)
+)
+
+// mediaTypes mirrors mediaTypes of scripts/get_message_content.js.
+func mediaTypes(source string) []string {
+	types := []string{}
+	for _, m := range contentTypeField.FindAllStringSubmatch(source, -1) {
+		if t := strings.ToLower(m[1]); !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// getMessageSource mirrors scripts/get_message_source.js.
+func (s *Sim) getMessageSource(args []string) (map[string]any, error) {
+	var in struct {
+		Account     string   `json:"account"`
+		MailboxPath []string `json:"mailboxPath"`
+		MessageID   int      `json:"message_id"`
+		MaxSize     int      `json:"maxSize"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	msg, err := s.lookupMessage(in.Account, in.MailboxPath, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	source := ""
+	if in.MaxSize == 0 || messageSize(msg) <= in.MaxSize {
+		source = messageSource(msg)
+	}
+	return map[string]any{
+		"id":          msg.ID,
+		"source":      source,
+		"messageSize": messageSize(msg),
+	}, nil
+}
+
+// lookupMessage validates the arguments identifying a message and finds it,
+// like the scripts reading a single message.
+func (s *Sim) lookupMessage(account string, mailboxPath []string, id int) (*Message, error) {
+	if account == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account name is required")
+	}
+	if len(mailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Mailbox path is required and must be a non-empty array")
+	}
+	if id < 1 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Message ID is required and must be a positive integer")
+	}
+
+	a := s.findAccount(account)
+	if a == nil {
+		return nil, accountNotFound(account)
+	}
+	m := a.findMailbox(mailboxPath)
+	if m == nil {
+		return nil, mailboxNotFound(mailboxPath, account)
+	}
+	msg := m.findMessage(id)
+	if msg == nil {
+		return nil, fail(jxa.ErrorCodeMessageNotFound, "Message with ID %d not found in mailbox %q. The message may have been deleted or moved.", id, strings.Join(mailboxPath, " > "))
+	}
+	return msg, nil
+}
+
+// getSelectedMessages mirrors scripts/get_selected_messages.js.
+func (s *Sim) getSelectedMessages(args []string) (map[string]any, error) {
+	var in struct {
+		Limit int `json:"limit"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Limit == 0 {
+		in.Limit = 5
+	}
+	if in.Limit < 1 {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "Limit must be at least 1")
+	}
+	if in.Limit > 100 {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "Limit cannot exceed 100")
+	}
+
+	messages := []map[string]any{}
+	for _, a := range s.accounts {
+		a.walk(func(m *mailbox) {
+			for _, msg := range m.messages {
+				if !msg.Selected || len(messages) >= in.Limit {
+					continue
+				}
+				messages = append(messages, map[string]any{
+					"id":             msg.ID,
+					"subject":        msg.Subject,
+					"sender":         msg.Sender,
+					"dateReceived":   isoString(msg.DateReceived),
+					"dateSent":       isoStringOrNil(msg.DateSent),
+					"readStatus":     msg.ReadStatus,
+					"flaggedStatus":  msg.FlaggedStatus,
+					"junkMailStatus": msg.JunkMailStatus,
+					"mailbox":        m.name,
+					"mailboxPath":    m.path(),
+					"account":        a.name,
+				})
+			}
+		})
+	}
+
+	if len(messages) == 0 {
+		return map[string]any{
+			"selectedMessagesCount": 0,
+			"messages":              messages,
+		}, nil
+	}
+	return map[string]any{
+		"messages": messages,
+		"count":    len(messages),
+	}, nil
+}
+
+// findMessages mirrors scripts/find_messages.js.
+func (s *Sim) findMessages(args []string) (map[string]any, error) {
+	var in struct {
+		Account        string   `json:"account"`
+		MailboxPath    []string `json:"mailboxPath"`
+		Limit          *int     `json:"limit"`
+		Subject        string   `json:"subject"`
+		Sender         string   `json:"sender"`
+		ReadStatus     *bool    `json:"readStatus"`
+		FlaggedOnly    bool     `json:"flaggedOnly"`
+		DateAfter      string   `json:"dateAfter"`
+		DateBefore     string   `json:"dateBefore"`
+		Body           string   `json:"body"`
+		Recipient      string   `json:"recipient"`
+		Cc             string   `json:"cc"`
+		ReplyTo        string   `json:"replyTo"`
+		HasAttachments *bool    `json:"hasAttachments"`
+		AttachmentName string   `json:"attachmentName"`
+		MinSize        int      `json:"minSize"`
+		MaxSize        int      `json:"maxSize"`
+		Sort           *string  `json:"sort"`
+		SkipContent    bool     `json:"skipContent"`
+		After          *struct {
+			DateReceived time.Time `json:"date_received"`
+			Sender       string    `json:"sender"`
+			Subject      string    `json:"subject"`
+			Account      string    `json:"account"`
+			MailboxPath  []string  `json:"mailbox_path"`
+			ID           int       `json:"id"`
+		} `json:"after"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	limit := 50
+	if in.Limit != nil {
+		limit = *in.Limit
+	}
+	if in.Account == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account name is required")
+	}
+	if len(in.MailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Mailbox path required")
+	}
+	if limit < 1 || limit > 1000 {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "Limit must be between 1 and 1000")
+	}
+	sort := "date_desc"
+	if in.Sort != nil {
+		sort = *in.Sort
+	}
+	if !slices.Contains(sortOrders, sort) {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "Unknown sort order '%s'. Valid: %s", sort, strings.Join(sortOrders, ", "))
+	}
+
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+	m := a.findMailbox(in.MailboxPath)
+	if m == nil {
+		return nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+
+	// Invalid dates behave like JavaScript's Invalid Date: comparisons are
+	// always false, so they do not filter anything.
+	dateAfter, afterErr := time.Parse(time.RFC3339, in.DateAfter)
+	dateBefore, beforeErr := time.Parse(time.RFC3339, in.DateBefore)
+	subject := strings.ToLower(in.Subject)
+	sender := strings.ToLower(in.Sender)
+	containsText := func(value, text string) bool {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(text))
+	}
+
+	var matches []*Message
+	for _, msg := range m.messages {
+		if subject != "" && !strings.Contains(strings.ToLower(msg.Subject), subject) {
+			continue
+		}
+		if sender != "" && !strings.Contains(strings.ToLower(msg.Sender), sender) {
+			continue
+		}
+		if in.ReadStatus != nil && msg.ReadStatus != *in.ReadStatus {
+			continue
+		}
+		if in.FlaggedOnly && !msg.FlaggedStatus {
+			continue
+		}
+		if in.DateAfter != "" && afterErr == nil && !msg.DateReceived.After(dateAfter) {
+			continue
+		}
+		if in.DateBefore != "" && beforeErr == nil && !msg.DateReceived.Before(dateBefore) {
+			continue
+		}
+		if in.MinSize > 0 && messageSize(msg) < in.MinSize {
+			continue
+		}
+		if in.MaxSize > 0 && messageSize(msg) > in.MaxSize {
+			continue
+		}
+		if in.ReplyTo != "" && (msg.ReplyTo == "" || !containsText(msg.ReplyTo, in.ReplyTo)) {
+			continue
+		}
+		if in.Recipient != "" && !slices.ContainsFunc(slices.Concat(msg.ToRecipients, msg.CcRecipients), func(r Recipient) bool {
+			return containsText(r.Address, in.Recipient)
+		}) {
+			continue
+		}
+		if in.Cc != "" && !slices.ContainsFunc(msg.CcRecipients, func(r Recipient) bool {
+			return containsText(r.Address, in.Cc)
+		}) {
+			continue
+		}
+		if in.HasAttachments != nil && *in.HasAttachments != (len(msg.Attachments) > 0) {
+			continue
+		}
+		if in.AttachmentName != "" && !slices.ContainsFunc(msg.Attachments, func(a Attachment) bool {
+			return containsText(a.Name, in.AttachmentName)
+		}) {
+			continue
+		}
+		if in.Body != "" && !containsText(msg.Content, in.Body) {
+			continue
+		}
+		matches = append(matches, msg)
+	}
+
+	key := func(msg *Message) sortKey {
+		return sortKey{msg.DateReceived, msg.Sender, msg.Subject, in.Account, in.MailboxPath, msg.ID}
+	}
+	slices.SortFunc(matches, func(a, b *Message) int {
+		return compareMatches(sort, key(a), key(b))
+	})
+	remaining := matches
+	if a := in.After; a != nil {
+		after := sortKey{a.DateReceived, a.Sender, a.Subject, a.Account, a.MailboxPath, a.ID}
+		remaining = slices.DeleteFunc(slices.Clone(matches), func(msg *Message) bool {
+			return compareMatches(sort, key(msg), after) <= 0
+		})
+	}
+
+	messages := []map[string]any{}
+	for _, msg := range remaining[:min(len(remaining), limit)] {
+		// The body filter has read the content even if it is skipped
+		content := msg.Content
+		if in.SkipContent && in.Body == "" {
+			content = ""
+		}
+		messages = append(messages, map[string]any{
+			"id":              msg.ID,
+			"subject":         msg.Subject,
+			"sender":          msg.Sender,
+			"date_received":   isoString(msg.DateReceived),
+			"date_sent":       isoStringOrNil(msg.DateSent),
+			"read_status":     msg.ReadStatus,
+			"flagged_status":  msg.FlaggedStatus,
+			"message_size":    messageSize(msg),
+			"content_preview": preview(content),
+			"content_length":  len([]rune(content)),
+			"mailbox_path":    in.MailboxPath,
+			"account":         in.Account,
+		})
+	}
+
+	return map[string]any{
+		"messages":      messages,
+		"count":         len(messages),
+		"total_matches": len(matches),
+		"limit":         limit,
+		"has_more":      len(remaining) > limit,
+		"filters_applied": map[string]any{
+			"subject":         nilIfEmpty(in.Subject),
+			"sender":          nilIfEmpty(in.Sender),
+			"read_status":     in.ReadStatus,
+			"flagged_only":    in.FlaggedOnly,
+			"date_after":      nilIfEmpty(in.DateAfter),
+			"date_before":     nilIfEmpty(in.DateBefore),
+			"body":            nilIfEmpty(in.Body),
+			"recipient":       nilIfEmpty(in.Recipient),
+			"cc":              nilIfEmpty(in.Cc),
+			"reply_to":        nilIfEmpty(in.ReplyTo),
+			"has_attachments": in.HasAttachments,
+			"attachment_name": nilIfEmpty(in.AttachmentName),
+			"min_size":        nilIfZero(in.MinSize),
+			"max_size":        nilIfZero(in.MaxSize),
+		},
+		"sort": sort,
+	}, nil
+}
+
+// sortOrders mirrors SORT_ORDERS of scripts/find_messages.js.
+var sortOrders = []string{"date_desc", "date_asc", "sender", "subject"}
+
+// sortKey holds the properties find_messages sorts by.
+type sortKey struct {
+	date        time.Time
+	sender      string
+	subject     string
+	account     string
+	mailboxPath []string
+	id          int
+}
+
+// compareMatches mirrors compareMatches of scripts/find_messages.js.
+func compareMatches(sort string, a, b sortKey) int {
+	var c int
+	switch sort {
+	case "date_asc":
+		c = a.date.Compare(b.date)
+	case "sender":
+		c = strings.Compare(foldCase(a.sender), foldCase(b.sender))
+	case "subject":
+		c = strings.Compare(foldCase(a.subject), foldCase(b.subject))
+	}
+	if c == 0 && sort != "date_asc" {
+		c = b.date.Compare(a.date)
+	}
+	if c == 0 {
+		c = strings.Compare(a.account, b.account)
+	}
+	if c == 0 {
+		c = slices.Compare(a.mailboxPath, b.mailboxPath)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.id, b.id)
+	}
+	return c
+}
+
+// foldCase mirrors foldCase of scripts/find_messages.js.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\u0130' {
+			return r
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// nilIfEmpty mirrors the `value || null` idiom of the scripts.
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nilIfZero mirrors the `value || null` idiom of the scripts for numbers.
+func nilIfZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
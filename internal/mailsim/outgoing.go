@@ -0,0 +1,378 @@
+package mailsim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// content returns the full body of an outgoing message, i.e. the pasted text
+// followed by the quoted original for replies.
+func (o *outgoingMessage) content() string {
+	if o.quoted == "" {
+		return o.body
+	}
+	return o.body + "\n\n" + o.quoted
+}
+
+func (s *Sim) newOutgoing(a *account, subject string) *outgoingMessage {
+	o := &outgoingMessage{id: s.nextOutgoingID, account: a, subject: subject}
+	if len(a.emailAddresses) > 0 {
+		o.sender = a.emailAddresses[0]
+	}
+	s.nextOutgoingID++
+	s.outgoing = append(s.outgoing, o)
+	return o
+}
+
+func (s *Sim) findOutgoing(id int) (int, *outgoingMessage) {
+	for i, o := range s.outgoing {
+		if o.id == id {
+			return i, o
+		}
+	}
+	return -1, nil
+}
+
+func (s *Sim) deleteOutgoing(id int) *outgoingMessage {
+	i, o := s.findOutgoing(id)
+	if o != nil {
+		s.outgoing = slices.Delete(s.outgoing, i, i+1)
+	}
+	return o
+}
+
+// composeResult is the data returned by all scripts opening a compose window.
+func composeResult(o *outgoingMessage, message string) map[string]any {
+	return map[string]any{
+		"outgoing_id": o.id,
+		"subject":     o.subject,
+		"pid":         pid(),
+		"message":     message,
+	}
+}
+
+// reply opens a reply to msg like Message.reply() in Mail.app.
+func (s *Sim) reply(a *account, msg *Message, replyToAll bool) *outgoingMessage {
+	subject := msg.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	o := s.newOutgoing(a, subject)
+
+	target := msg.ReplyTo
+	if target == "" {
+		target = msg.Sender
+	}
+	o.to = []string{address(target)}
+	if replyToAll {
+		own := func(addr string) bool {
+			return slices.Contains(a.emailAddresses, addr) || slices.Contains(o.to, addr)
+		}
+		for _, r := range msg.ToRecipients {
+			if !own(r.Address) {
+				o.to = append(o.to, r.Address)
+			}
+		}
+		for _, r := range msg.CcRecipients {
+			if !own(r.Address) && !slices.Contains(o.cc, r.Address) {
+				o.cc = append(o.cc, r.Address)
+			}
+		}
+	}
+
+	var quoted strings.Builder
+	fmt.Fprintf(&quoted, "On %s, %s wrote:\n\n", msg.DateReceived.UTC().Format("Jan 2, 2006, at 15:04"), msg.Sender)
+	for _, line := range strings.Split(msg.Content, "\n") {
+		quoted.WriteString("> " + line + "\n")
+	}
+	o.quoted = strings.TrimRight(quoted.String(), "\n")
+	return o
+}
+
+// replyArgs are the arguments shared by create_reply and replace_reply.
+type replyArgs struct {
+	OutgoingID    int       `json:"outgoing_id"`
+	MessageID     int       `json:"message_id"`
+	Account       string    `json:"account"`
+	MailboxPath   []string  `json:"mailbox_path"`
+	ReplyToAll    bool      `json:"reply_to_all"`
+	Subject       *string   `json:"subject"`
+	ToRecipients  *[]string `json:"to_recipients"`
+	CcRecipients  *[]string `json:"cc_recipients"`
+	BccRecipients *[]string `json:"bcc_recipients"`
+}
+
+// findOriginal resolves the message a reply refers to. notFound prefixes the
+// error message when the message does not exist, as the scripts differ there.
+func (s *Sim) findOriginal(in replyArgs, notFound string) (*account, *Message, error) {
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, nil, accountNotFound(in.Account)
+	}
+	m := a.findMailbox(in.MailboxPath)
+	if m == nil {
+		return nil, nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+	msg := m.findMessage(in.MessageID)
+	if msg == nil {
+		return nil, nil, fail(jxa.ErrorCodeMessageNotFound, "%s with ID %d not found in mailbox '%s'.", notFound, in.MessageID, strings.Join(in.MailboxPath, " > "))
+	}
+	return a, msg, nil
+}
+
+// createReply mirrors scripts/create_reply.js.
+func (s *Sim) createReply(args []string) (map[string]any, error) {
+	var in replyArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Account == "" || in.MessageID == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account name and message ID are required.")
+	}
+	if len(in.MailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeInvalidMailboxPath, "Mailbox path must be a non-empty array.")
+	}
+
+	a, msg, err := s.findOriginal(in, "Message")
+	if err != nil {
+		return nil, err
+	}
+	o := s.reply(a, msg, in.ReplyToAll)
+	return composeResult(o, "Reply message created successfully."), nil
+}
+
+// replaceReply mirrors scripts/replace_reply.js.
+func (s *Sim) replaceReply(args []string) (map[string]any, error) {
+	var in replyArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.OutgoingID == 0 || in.MessageID == 0 || in.Account == "" || len(in.MailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "outgoing_id, message_id, account, and mailbox_path are required.")
+	}
+
+	// Like the script, a missing old reply is not an error.
+	s.deleteOutgoing(in.OutgoingID)
+
+	a, msg, err := s.findOriginal(in, "Original message")
+	if err != nil {
+		return nil, err
+	}
+	o := s.reply(a, msg, in.ReplyToAll)
+	if in.Subject != nil {
+		o.subject = *in.Subject
+	}
+	if in.ToRecipients != nil {
+		o.to = slices.Clone(*in.ToRecipients)
+	}
+	if in.CcRecipients != nil {
+		o.cc = slices.Clone(*in.CcRecipients)
+	}
+	if in.BccRecipients != nil {
+		o.bcc = slices.Clone(*in.BccRecipients)
+	}
+	return composeResult(o, "Reply was successfully replaced."), nil
+}
+
+// outgoingArgs are the arguments of create_outgoing_message and replace_outgoing_message.
+type outgoingArgs struct {
+	OutgoingID    *int      `json:"outgoing_id"`
+	Account       string    `json:"account"`
+	Subject       *string   `json:"subject"`
+	Sender        *string   `json:"sender"`
+	ToRecipients  *[]string `json:"to_recipients"`
+	CcRecipients  *[]string `json:"cc_recipients"`
+	BccRecipients *[]string `json:"bcc_recipients"`
+	AttachmentDir string    `json:"attachment_dir"`
+}
+
+// createOutgoingMessage mirrors scripts/create_outgoing_message.js.
+func (s *Sim) createOutgoingMessage(args []string) (map[string]any, error) {
+	var in outgoingArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Account == "" || in.Subject == nil || *in.Subject == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account and Subject are required parameters.")
+	}
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+
+	o := s.newOutgoing(a, *in.Subject)
+	if in.ToRecipients != nil {
+		o.to = slices.Clone(*in.ToRecipients)
+	}
+	if in.CcRecipients != nil {
+		o.cc = slices.Clone(*in.CcRecipients)
+	}
+	if in.BccRecipients != nil {
+		o.bcc = slices.Clone(*in.BccRecipients)
+	}
+	return composeResult(o, "Outgoing message created successfully. Window opened for content pasting."), nil
+}
+
+// listOutgoingMessages mirrors scripts/list_outgoing_messages.js.
+func (s *Sim) listOutgoingMessages(args []string) (map[string]any, error) {
+	messages := []map[string]any{}
+	for _, o := range s.outgoing {
+		content := o.content()
+		messages = append(messages, map[string]any{
+			"outgoing_id":      o.id,
+			"subject":          o.subject,
+			"sender":           o.sender,
+			"content_preview":  preview(content),
+			"content_length":   len([]rune(content)),
+			"to_recipients":    nonNil(o.to),
+			"cc_recipients":    nonNil(o.cc),
+			"bcc_recipients":   nonNil(o.bcc),
+			"to_count":         len(o.to),
+			"cc_count":         len(o.cc),
+			"bcc_count":        len(o.bcc),
+			"total_recipients": len(o.to) + len(o.cc) + len(o.bcc),
+		})
+	}
+	return map[string]any{
+		"messages":       messages,
+		"count":          len(messages),
+		"total_outgoing": len(s.outgoing),
+	}, nil
+}
+
+// replaceOutgoingMessage mirrors scripts/replace_outgoing_message.js.
+func (s *Sim) replaceOutgoingMessage(args []string) (map[string]any, error) {
+	var in outgoingArgs
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.OutgoingID == nil {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "A valid outgoing_id is required.")
+	}
+	_, old := s.findOutgoing(*in.OutgoingID)
+	if old == nil {
+		return nil, fail(jxa.ErrorCodeMessageNotFound, "Outgoing message with ID %d not found.", *in.OutgoingID)
+	}
+	kept, err := keepAttachments(old, in.AttachmentDir)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteOutgoing(old.id)
+
+	o := s.newOutgoing(old.account, old.subject)
+	o.sender, o.to, o.cc, o.bcc = old.sender, old.to, old.cc, old.bcc
+	if in.Subject != nil {
+		o.subject = *in.Subject
+	}
+	if in.Sender != nil && *in.Sender != "" {
+		o.sender = *in.Sender
+	}
+	if in.ToRecipients != nil {
+		o.to = slices.Clone(*in.ToRecipients)
+	}
+	if in.CcRecipients != nil {
+		o.cc = slices.Clone(*in.CcRecipients)
+	}
+	if in.BccRecipients != nil {
+		o.bcc = slices.Clone(*in.BccRecipients)
+	}
+	result := composeResult(o, "Outgoing message was successfully replaced.")
+	result["kept_attachments"] = kept
+	return result, nil
+}
+
+// keepAttachments mirrors keepAttachments of
+// scripts/replace_outgoing_message.js: the attached files are copied to dir,
+// each to its own numbered subdirectory.
+func keepAttachments(o *outgoingMessage, dir string) ([]map[string]any, error) {
+	kept := []map[string]any{}
+	if len(o.attachments) == 0 {
+		return kept, nil
+	}
+	if dir == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "attachment_dir is required to keep the attachments.")
+	}
+	for i, path := range o.attachments {
+		name := filepath.Base(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fail(jxa.ErrorCodeAttachmentNotFound, "The file of attachment %s cannot be read, the outgoing message was not replaced.", name)
+		}
+		target := filepath.Join(dir, fmt.Sprint(i+1), name)
+		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return nil, fail(jxa.ErrorCodeUnknown, "Failed to replace outgoing message: %v", err)
+		}
+		if err := os.WriteFile(target, data, 0o600); err != nil {
+			return nil, fail(jxa.ErrorCodeUnknown, "Failed to replace outgoing message: %v", err)
+		}
+		kept = append(kept, map[string]any{"name": name, "path": target})
+	}
+	return kept, nil
+}
+
+// addAttachments mirrors scripts/add_attachments.js. Like Mail.app, it
+// fails for files that do not exist.
+func (s *Sim) addAttachments(args []string) (map[string]any, error) {
+	var in struct {
+		OutgoingID *int     `json:"outgoing_id"`
+		Paths      []string `json:"paths"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.OutgoingID == nil {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "outgoing_id is required.")
+	}
+	if len(in.Paths) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Paths are required and must be a non-empty array")
+	}
+	_, o := s.findOutgoing(*in.OutgoingID)
+	if o == nil {
+		return nil, fail(jxa.ErrorCodeMessageNotFound, "Outgoing message with ID %d not found.", *in.OutgoingID)
+	}
+	for _, path := range in.Paths {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fail(jxa.ErrorCodeUnknown, "Failed to attach files: %v", err)
+		}
+	}
+	o.attachments = append(o.attachments, in.Paths...)
+	return map[string]any{
+		"outgoing_id": o.id,
+		"attached":    in.Paths,
+	}, nil
+}
+
+// deleteOutgoingMessage mirrors scripts/delete_outgoing_message.js.
+func (s *Sim) deleteOutgoingMessage(args []string) (map[string]any, error) {
+	var in struct {
+		OutgoingID *int `json:"outgoing_id"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.OutgoingID == nil {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "outgoing_id is required.")
+	}
+	o := s.deleteOutgoing(*in.OutgoingID)
+	if o == nil {
+		return nil, fail(jxa.ErrorCodeMessageNotFound, "Outgoing message with ID %d not found.", *in.OutgoingID)
+	}
+	return map[string]any{
+		"deleted_id": o.id,
+		"subject":    o.subject,
+		"message":    "Outgoing message deleted successfully.",
+	}, nil
+}
+
+// nonNil returns an empty slice instead of nil so that it encodes as [].
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
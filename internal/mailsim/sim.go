@@ -0,0 +1,407 @@
+// Package mailsim implements an in-memory simulation of Mail.app for
+// end-to-end testing and demos. It answers the embedded tool scripts by name
+// with the same JSON contract as the real JXA scripts, so the MCP server can
+// run full sessions on machines without Mail.app.
+package mailsim
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed demo.yaml
+var demoFixture []byte
+
+// draftsMailboxName is the name of the top-level mailbox holding an account's drafts.
+const draftsMailboxName = "Drafts"
+
+// Sim is a simulated Mail.app. It implements jxa.Executor and, so that the
+// compose tools can fill in message bodies, the tools.Paster interface.
+type Sim struct {
+	mu             sync.Mutex
+	running        bool
+	accounts       []*account
+	outgoing       []*outgoingMessage
+	nextMessageID  int
+	nextOutgoingID int
+}
+
+type account struct {
+	name           string
+	enabled        bool
+	emailAddresses []string
+	mailboxes      []*mailbox
+}
+
+type mailbox struct {
+	name     string
+	account  *account
+	parent   *mailbox
+	children []*mailbox
+	messages []*Message
+}
+
+type outgoingMessage struct {
+	id          int
+	account     *account
+	subject     string
+	sender      string
+	to          []string
+	cc          []string
+	bcc         []string
+	body        string
+	quoted      string
+	attachments []string // paths of the attached files
+}
+
+// Ensure the implementation satisfies the expected interface.
+var _ jxa.Executor = (*Sim)(nil)
+
+// New creates a simulator seeded with the given fixture.
+func New(f *Fixture) (*Sim, error) {
+	s := &Sim{running: true, nextOutgoingID: 1}
+	seenIDs := make(map[int]bool)
+	maxID := 0
+
+	var addMailboxes func(a *account, parent *mailbox, fixtures []Mailbox) ([]*mailbox, error)
+	addMailboxes = func(a *account, parent *mailbox, fixtures []Mailbox) ([]*mailbox, error) {
+		var result []*mailbox
+		seenNames := make(map[string]bool)
+		for _, mf := range fixtures {
+			if mf.Name == "" {
+				return nil, fmt.Errorf("account %q: mailbox name is required", a.name)
+			}
+			if seenNames[mf.Name] {
+				return nil, fmt.Errorf("account %q: duplicate mailbox %q", a.name, mf.Name)
+			}
+			seenNames[mf.Name] = true
+
+			mbx := &mailbox{name: mf.Name, account: a, parent: parent}
+			for i := range mf.Messages {
+				msg := mf.Messages[i]
+				if msg.ID < 1 {
+					return nil, fmt.Errorf("mailbox %q: message ID must be positive", strings.Join(mbx.path(), " > "))
+				}
+				if seenIDs[msg.ID] {
+					return nil, fmt.Errorf("duplicate message ID %d", msg.ID)
+				}
+				seenIDs[msg.ID] = true
+				maxID = max(maxID, msg.ID)
+				mbx.messages = append(mbx.messages, &msg)
+			}
+			children, err := addMailboxes(a, mbx, mf.Mailboxes)
+			if err != nil {
+				return nil, err
+			}
+			mbx.children = children
+			result = append(result, mbx)
+		}
+		return result, nil
+	}
+
+	for _, af := range f.Accounts {
+		if af.Name == "" {
+			return nil, fmt.Errorf("account name is required")
+		}
+		if s.findAccount(af.Name) != nil {
+			return nil, fmt.Errorf("duplicate account %q", af.Name)
+		}
+		a := &account{
+			name:           af.Name,
+			enabled:        af.Enabled == nil || *af.Enabled,
+			emailAddresses: slices.Clone(af.EmailAddresses),
+		}
+		mailboxes, err := addMailboxes(a, nil, af.Mailboxes)
+		if err != nil {
+			return nil, err
+		}
+		a.mailboxes = mailboxes
+		s.accounts = append(s.accounts, a)
+	}
+
+	s.nextMessageID = maxID + 1
+	return s, nil
+}
+
+// Demo returns the built-in demo fixture used when no fixture file is given.
+func Demo() *Fixture {
+	// The demo fixture is embedded and covered by tests, so errors are bugs.
+	data, err := yaml.YAMLToJSON(demoFixture)
+	if err != nil {
+		panic(err)
+	}
+	f, err := ParseFixture(data)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// SetRunning simulates starting or quitting Mail.app. While not running, every
+// script fails with MAIL_APP_NOT_RUNNING.
+func (s *Sim) SetRunning(running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+}
+
+// scriptError is a script-level failure reported in the result envelope.
+type scriptError struct {
+	code    string
+	message string
+}
+
+func (e *scriptError) Error() string {
+	return e.message
+}
+
+// fail creates a script-level error with an optional error code.
+func fail(code string, format string, args ...any) error {
+	return &scriptError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// accountNotFound mirrors findAccount of the JXA prelude.
+func accountNotFound(name string) error {
+	return fail(jxa.ErrorCodeAccountNotFound, "Account %q not found. Please verify the account name is correct.", name)
+}
+
+// mailboxNotFound mirrors findMailbox of the JXA prelude.
+func mailboxNotFound(path []string, account string) error {
+	return fail(jxa.ErrorCodeMailboxNotFound, "Mailbox path '%s' not found in account '%s'.", strings.Join(path, " > "), account)
+}
+
+type handler func(s *Sim, args []string) (map[string]any, error)
+
+var handlers = map[string]handler{
+	"list_accounts":            (*Sim).listAccounts,
+	"list_mailboxes":           (*Sim).listMailboxes,
+	"get_message_content":      (*Sim).getMessageContent,
+	"get_message_source":       (*Sim).getMessageSource,
+	"get_selected_messages":    (*Sim).getSelectedMessages,
+	"find_messages":            (*Sim).findMessages,
+	"list_drafts":              (*Sim).listDrafts,
+	"delete_draft":             (*Sim).deleteDraft,
+	"create_reply":             (*Sim).createReply,
+	"replace_reply":            (*Sim).replaceReply,
+	"create_outgoing_message":  (*Sim).createOutgoingMessage,
+	"list_outgoing_messages":   (*Sim).listOutgoingMessages,
+	"replace_outgoing_message": (*Sim).replaceOutgoingMessage,
+	"delete_outgoing_message":  (*Sim).deleteOutgoingMessage,
+	"move_messages":            (*Sim).moveMessages,
+	"copy_messages":            (*Sim).copyMessages,
+	"archive_messages":         (*Sim).archiveMessages,
+	"update_messages":          (*Sim).updateMessages,
+	"trash_messages":           (*Sim).trashMessages,
+	"save_attachments":         (*Sim).saveAttachments,
+	"add_attachments":          (*Sim).addAttachments,
+}
+
+// Execute answers the named script against the simulated state.
+func (s *Sim) Execute(ctx context.Context, script jxa.Script, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	h, ok := handlers[script.Name]
+	if !ok {
+		return nil, fmt.Errorf("mailsim: script %q is not simulated", script.Name)
+	}
+
+	s.mu.Lock()
+	var (
+		data map[string]any
+		err  error
+	)
+	if s.running {
+		data, err = h(s, args)
+	} else {
+		err = fail(jxa.ErrorCodeMailAppNotRunning, "Mail.app is not running. Please start Mail.app and try again.")
+	}
+	s.mu.Unlock()
+
+	result := jxa.Result{Success: true, Data: data}
+	if err != nil {
+		result = jxa.Result{Success: false, Error: err.Error()}
+		if se, ok := err.(*scriptError); ok {
+			result.ErrorCode = se.code
+		}
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("mailsim: failed to marshal result: %w", err)
+	}
+	return jxa.ParseOutput(ctx, output, args)
+}
+
+// EnsureAccessibility always succeeds; the simulator needs no permissions.
+func (s *Sim) EnsureAccessibility() error {
+	return nil
+}
+
+// PasteIntoWindow sets the body of the most recent outgoing message whose
+// subject matches the window title, like pasting into its compose window.
+func (s *Sim) PasteIntoWindow(ctx context.Context, pid int, expectedTitle string, timeout time.Duration, htmlContent *string, plainContent string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.outgoing) - 1; i >= 0; i-- {
+		if s.outgoing[i].subject == expectedTitle {
+			s.outgoing[i].body = plainContent
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to find, focus, or paste into window with title '%s' within %v", expectedTitle, timeout)
+}
+
+// decodeArgs parses the JSON argument passed to every script.
+func decodeArgs(args []string, v any) error {
+	if len(args) == 0 || json.Unmarshal([]byte(args[0]), v) != nil {
+		return fail(jxa.ErrorCodeInvalidParameters, "Failed to parse input arguments JSON")
+	}
+	return nil
+}
+
+func (s *Sim) findAccount(name string) *account {
+	for _, a := range s.accounts {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// findMailbox resolves a mailbox path like ["Inbox", "GitHub"].
+func (a *account) findMailbox(path []string) *mailbox {
+	children := a.mailboxes
+	var current *mailbox
+	for _, name := range path {
+		current = nil
+		for _, m := range children {
+			if m.name == name {
+				current = m
+				break
+			}
+		}
+		if current == nil {
+			return nil
+		}
+		children = current.children
+	}
+	return current
+}
+
+// path returns the mailbox path from the account root.
+func (m *mailbox) path() []string {
+	var path []string
+	for c := m; c != nil; c = c.parent {
+		path = append([]string{c.name}, path...)
+	}
+	return path
+}
+
+// walk visits the mailbox tree of the account depth-first.
+func (a *account) walk(fn func(m *mailbox)) {
+	var visit func(ms []*mailbox)
+	visit = func(ms []*mailbox) {
+		for _, m := range ms {
+			fn(m)
+			visit(m.children)
+		}
+	}
+	visit(a.mailboxes)
+}
+
+func (m *mailbox) findMessage(id int) *Message {
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (m *mailbox) unreadCount() int {
+	n := 0
+	for _, msg := range m.messages {
+		if !msg.ReadStatus {
+			n++
+		}
+	}
+	return n
+}
+
+// isoString formats a time like JavaScript's Date.prototype.toISOString.
+func isoString(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// isoStringOrNil formats an optional time, returning nil if absent.
+func isoStringOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return isoString(*t)
+}
+
+// preview shortens content to 100 characters like the scripts do.
+func preview(content string) string {
+	r := []rune(content)
+	if len(r) > 100 {
+		return string(r[:100]) + "..."
+	}
+	return content
+}
+
+// messageSize returns the configured size or estimates it from the message.
+func messageSize(msg *Message) int {
+	if msg.MessageSize > 0 {
+		return msg.MessageSize
+	}
+	return len(msg.AllHeaders) + len(msg.Content)
+}
+
+// messageSource returns the configured source or builds a plain text
+// message from the headers and content.
+func messageSource(msg *Message) string {
+	if msg.Source != "" {
+		return msg.Source
+	}
+	headers := msg.AllHeaders
+	if headers == "" {
+		headers = "From: " + msg.Sender + "\nSubject: " + msg.Subject + "\n"
+		if msg.MessageID != "" {
+			headers += "Message-ID: " + msg.MessageID + "\n"
+		}
+	}
+	return strings.TrimRight(headers, "\n") + "\nContent-Type: text/plain; charset=utf-8\n\n" + msg.Content
+}
+
+// address extracts the bare address from a sender like "Jane <jane@example.com>".
+func address(sender string) string {
+	if a, err := mail.ParseAddress(sender); err == nil {
+		return a.Address
+	}
+	return sender
+}
+
+func recipientsJSON(rs []Recipient) []map[string]any {
+	out := []map[string]any{}
+	for _, r := range rs {
+		out = append(out, map[string]any{"name": r.Name, "address": r.Address})
+	}
+	return out
+}
+
+func pid() int {
+	return os.Getpid()
+}
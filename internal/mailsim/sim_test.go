@@ -0,0 +1,1319 @@
+package mailsim_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/index"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/mailsim"
+	"github.com/dastrobu/mail-mcp/internal/tools"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connect serves all tools backed by sim and returns a connected client session.
+func connect(t *testing.T, sim *mailsim.Sim, register ...func(*mcp.Server)) *mcp.ClientSession {
+	t.Helper()
+	return connectConfig(t, sim, tools.Config{}, register...)
+}
+
+// connectConfig is like connect, with the tools configured by cfg.
+func connectConfig(t *testing.T, sim *mailsim.Sim, cfg tools.Config, register ...func(*mcp.Server)) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	srv := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	tools.RegisterAll(srv, sim, cfg)
+	for _, r := range register {
+		r(srv)
+	}
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := srv.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server Connect() error = %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+func newDemo(t *testing.T) *mailsim.Sim {
+	t.Helper()
+	sim, err := mailsim.New(mailsim.Demo())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return sim
+}
+
+// callTool calls a tool and decodes its structured result into a map.
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args map[string]any) map[string]any {
+	t.Helper()
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s) error = %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("CallTool(%s) returned tool error: %v", name, res.Content[0].(*mcp.TextContent).Text)
+	}
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("CallTool(%s) structured content is not an object: %s", name, data)
+	}
+	return m
+}
+
+// callToolError calls a tool that is expected to fail and returns its error.
+func callToolError(t *testing.T, session *mcp.ClientSession, name string, args map[string]any) *jxa.Error {
+	t.Helper()
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s) error = %v", name, err)
+	}
+	if !res.IsError {
+		t.Fatalf("CallTool(%s) IsError = false, want true", name)
+	}
+	if res.StructuredContent != nil {
+		t.Errorf("CallTool(%s) error has structured content %v, want none", name, res.StructuredContent)
+	}
+	text := res.Content[len(res.Content)-1].(*mcp.TextContent).Text
+	var got tools.ErrorOutput
+	if err := json.Unmarshal([]byte(text), &got); err != nil || got.Error == nil {
+		t.Fatalf("CallTool(%s) text content %s is not an error: %v", name, text, err)
+	}
+	return got.Error
+}
+
+func TestSim_ListAccounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		wantCount float64
+	}{
+		{name: "all accounts", enabled: false, wantCount: 3},
+		{name: "enabled accounts", enabled: true, wantCount: 2},
+	}
+
+	session := connect(t, newDemo(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := callTool(t, session, "list_accounts", map[string]any{"enabled": tt.enabled})
+			if got["count"] != tt.wantCount {
+				t.Errorf("list_accounts count = %v, want %v", got["count"], tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestSim_FindMessages(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]any
+		wantSubject []string
+	}{
+		{
+			name:        "received after",
+			args:        map[string]any{"dateAfter": "2025-01-01T00:00:00Z"},
+			wantSubject: []string{"Quarterly planning", "Build failed on main", "Lunch on Thursday?"},
+		},
+		{
+			name:        "unread only",
+			args:        map[string]any{"readStatus": false},
+			wantSubject: []string{"Quarterly planning", "Build failed on main"},
+		},
+		{
+			name:        "flagged only",
+			args:        map[string]any{"flaggedOnly": true},
+			wantSubject: []string{"Quarterly planning"},
+		},
+		{
+			name:        "sender substring",
+			args:        map[string]any{"sender": "sam.lee"},
+			wantSubject: []string{"Lunch on Thursday?"},
+		},
+		{
+			name:        "limit",
+			args:        map[string]any{"dateAfter": "2025-01-01T00:00:00Z", "limit": 1},
+			wantSubject: []string{"Quarterly planning"},
+		},
+		{
+			name:        "body substring",
+			args:        map[string]any{"body": "INTEGRATION tests"},
+			wantSubject: []string{"Build failed on main"},
+		},
+		{
+			name:        "cc recipient",
+			args:        map[string]any{"recipient": "sam.lee@"},
+			wantSubject: []string{"Quarterly planning"},
+		},
+		{
+			name:        "to and cc in query",
+			args:        map[string]any{"query": "to:jane.doe@ cc:sam.lee@"},
+			wantSubject: []string{"Quarterly planning"},
+		},
+		{
+			name:        "cc only",
+			args:        map[string]any{"cc": "jane.doe@"},
+			wantSubject: nil,
+		},
+		{
+			name:        "reply to",
+			args:        map[string]any{"replyTo": "dev-team"},
+			wantSubject: []string{"Build failed on main"},
+		},
+		{
+			name:        "without attachments",
+			args:        map[string]any{"hasAttachments": false},
+			wantSubject: []string{"Quarterly planning", "Build failed on main"},
+		},
+		{
+			name:        "recipient with pdf attached",
+			args:        map[string]any{"recipient": "jane.doe@", "attachmentName": ".PDF"},
+			wantSubject: []string{"Lunch on Thursday?"},
+		},
+		{
+			name:        "size range",
+			args:        map[string]any{"minSize": 50, "maxSize": 80},
+			wantSubject: []string{"Build failed on main"},
+		},
+	}
+
+	session := connect(t, newDemo(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "sort": "date_asc"}
+			maps.Copy(args, tt.args)
+			got := callTool(t, session, "find_messages", args)
+			messages, _ := got["messages"].([]any)
+			var subjects []string
+			for _, m := range messages {
+				subjects = append(subjects, m.(map[string]any)["subject"].(string))
+			}
+			if strings.Join(subjects, "|") != strings.Join(tt.wantSubject, "|") {
+				t.Errorf("find_messages subjects = %v, want %v", subjects, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestSim_FindMessagesAcrossMailboxes(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]any
+		wantSubject []string
+		wantPaths   []string
+	}{
+		{
+			name:        "all mailboxes of account",
+			args:        map[string]any{"account": "Work", "dateAfter": "2025-02-01T00:00:00Z", "exclude": []string{"Sent", "Drafts"}},
+			wantSubject: []string{"Lunch on Thursday?", "Build failed on main", "Quarterly planning", "Re: Project kickoff"},
+			wantPaths:   []string{"INBOX", "INBOX", "INBOX", "INBOX > Projects"},
+		},
+		{
+			name:        "all accounts",
+			args:        map[string]any{"dateAfter": "2025-03-02T00:00:00Z", "exclude": []string{"Junk"}},
+			wantSubject: []string{"Team goals", "Lunch on Thursday?", "Build failed on main", "Quarterly planning", "Your order has shipped"},
+			wantPaths:   []string{"Drafts", "INBOX", "INBOX", "INBOX", "INBOX"},
+		},
+		{
+			name:        "query",
+			args:        map[string]any{"query": "account:Work is:unread -in:drafts"},
+			wantSubject: []string{"Build failed on main", "Quarterly planning"},
+			wantPaths:   []string{"INBOX", "INBOX"},
+		},
+		{
+			name:        "mailbox of all accounts",
+			args:        map[string]any{"mailboxPath": []string{"Junk"}, "sender": "spam"},
+			wantSubject: []string{"You have won!"},
+			wantPaths:   []string{"Junk"},
+		},
+	}
+
+	session := connect(t, newDemo(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := callTool(t, session, "find_messages", tt.args)
+			var paths []string
+			for _, m := range got["messages"].([]any) {
+				paths = append(paths, strings.Join(toStrings(m.(map[string]any)["mailbox_path"]), " > "))
+			}
+			if s := subjects(got); strings.Join(s, "|") != strings.Join(tt.wantSubject, "|") {
+				t.Errorf("find_messages subjects = %v, want %v", s, tt.wantSubject)
+			}
+			if strings.Join(paths, "|") != strings.Join(tt.wantPaths, "|") {
+				t.Errorf("find_messages mailbox paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestSim_FindMessagesPages(t *testing.T) {
+	session := connect(t, newDemo(t))
+	for _, sort := range []string{"date_desc", "date_asc", "sender", "subject"} {
+		t.Run(sort, func(t *testing.T) {
+			args := map[string]any{"account": "Work", "dateAfter": "2000-01-01T00:00:00Z", "sort": sort}
+			want := subjects(callTool(t, session, "find_messages", args))
+
+			args["limit"] = 2
+			var got []string
+			for page := 0; ; page++ {
+				if page > len(want) {
+					t.Fatalf("cursor did not terminate, got %v", got)
+				}
+				result := callTool(t, session, "find_messages", args)
+				got = append(got, subjects(result)...)
+				cursor, ok := result["cursor"].(string)
+				if !ok {
+					break
+				}
+				args["cursor"] = cursor
+			}
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("paged subjects = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSim_FindMessagesPagesNonASCII(t *testing.T) {
+	// The script sorts each mailbox and the server merges them, so both must
+	// agree on the order of senders outside ASCII and the BMP.
+	date := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	message := func(id int, sender string) mailsim.Message {
+		return mailsim.Message{
+			ID:           id,
+			Subject:      fmt.Sprintf("Message %d", id),
+			Sender:       sender,
+			DateReceived: date.Add(time.Duration(id) * time.Hour),
+		}
+	}
+	sim, err := mailsim.New(&mailsim.Fixture{Accounts: []mailsim.Account{{
+		Name: "Work",
+		Mailboxes: []mailsim.Mailbox{
+			{Name: "INBOX", Messages: []mailsim.Message{
+				message(1, "\U0001D49C Bot <bot@example.com>"),
+				message(2, "İlker <ilker@example.com>"),
+				message(3, "bob <bob@example.com>"),
+			}},
+			{Name: "Archive", Messages: []mailsim.Message{
+				message(4, "ｚ Shop <shop@example.com>"),
+				message(5, "Émile <emile@example.com>"),
+				message(6, "ilker <ilker@example.com>"),
+			}},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	session := connect(t, sim)
+
+	args := map[string]any{"account": "Work", "dateAfter": "2000-01-01T00:00:00Z", "sort": "sender", "limit": 1}
+	var got []string
+	for page := 0; page < 10; page++ {
+		result := callTool(t, session, "find_messages", args)
+		got = append(got, subjects(result)...)
+		cursor, ok := result["cursor"].(string)
+		if !ok {
+			break
+		}
+		args["cursor"] = cursor
+	}
+	want := []string{"Message 3", "Message 6", "Message 5", "Message 2", "Message 4", "Message 1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("paged subjects = %v, want %v", got, want)
+	}
+}
+
+func TestSim_CreateReplyAndPaste(t *testing.T) {
+	session := connect(t, newDemo(t))
+
+	reply := callTool(t, session, "create_reply", map[string]any{
+		"account":        "Work",
+		"mailbox_path":   []string{"INBOX"},
+		"message_id":     1001,
+		"content":        "Sure, will do.",
+		"content_format": "plain",
+		"reply_to_all":   true,
+	})
+	if reply["subject"] != "Re: Quarterly planning" {
+		t.Errorf("create_reply subject = %v, want %q", reply["subject"], "Re: Quarterly planning")
+	}
+
+	outgoing := callTool(t, session, "list_outgoing_messages", map[string]any{})
+	messages, _ := outgoing["messages"].([]any)
+	if len(messages) != 1 {
+		t.Fatalf("list_outgoing_messages returned %d messages, want 1", len(messages))
+	}
+	msg := messages[0].(map[string]any)
+	if preview, _ := msg["content_preview"].(string); !strings.HasPrefix(preview, "Sure, will do.") {
+		t.Errorf("outgoing content_preview = %q, want pasted content first", preview)
+	}
+	// Reply all keeps Cc recipients but drops the account's own address.
+	if got := msg["to_recipients"]; len(got.([]any)) != 1 || got.([]any)[0] != "alex.smith@example.com" {
+		t.Errorf("outgoing to_recipients = %v, want [alex.smith@example.com]", got)
+	}
+	if got := msg["cc_recipients"]; len(got.([]any)) != 1 || got.([]any)[0] != "sam.lee@example.com" {
+		t.Errorf("outgoing cc_recipients = %v, want [sam.lee@example.com]", got)
+	}
+}
+
+func TestSim_OutgoingAttachments(t *testing.T) {
+	// The attachments kept by replace_outgoing_message are copied to a
+	// temporary directory
+	t.Setenv("TMPDIR", t.TempDir())
+	sim := newDemo(t)
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(report, []byte("a;b\n1;2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	args := map[string]any{
+		"account":        "Work",
+		"subject":        "Weekly report",
+		"content":        "See the attached report.",
+		"content_format": "plain",
+		"to_recipients":  []string{"alex.smith@example.com"},
+		"attachments":    []string{report},
+	}
+
+	// Without attachment directories, no message is created
+	session := connect(t, sim)
+	if got := callToolError(t, session, "create_outgoing_message", args); got.Code != jxa.ErrorCodeInvalidParameters {
+		t.Errorf("create_outgoing_message without attachment directories code = %s, want %s", got.Code, jxa.ErrorCodeInvalidParameters)
+	}
+	if got := callTool(t, session, "list_outgoing_messages", map[string]any{}); got["count"] != float64(0) {
+		t.Fatalf("list_outgoing_messages count = %v, want 0", got["count"])
+	}
+
+	session = connectConfig(t, sim, tools.Config{AttachmentDirs: []string{dir}})
+	got := callTool(t, session, "create_outgoing_message", args)
+	attachments := got["attachments"].([]any)
+	if attachment := attachments[0].(map[string]any); len(attachments) != 1 || attachment["name"] != "report.csv" || attachment["size"] != float64(8) {
+		t.Errorf("attachments = %v, want report.csv with 8 bytes", attachments)
+	}
+
+	// Replacing keeps the attachments, unless a file of the same name is
+	// attached
+	got = callTool(t, session, "replace_outgoing_message", map[string]any{
+		"outgoing_id": got["outgoing_id"], "content": "See the updated report.",
+	})
+	attachments = got["attachments"].([]any)
+	kept := attachments[0].(map[string]any)
+	if len(attachments) != 1 || kept["name"] != "report.csv" || kept["kept"] != true || kept["path"] == report || kept["size"] != float64(8) {
+		t.Errorf("replace_outgoing_message attachments = %v, want a kept copy of report.csv", attachments)
+	}
+	if err := os.WriteFile(report, []byte("a;b\n3;4\n5;6\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got = callTool(t, session, "replace_outgoing_message", map[string]any{
+		"outgoing_id": got["outgoing_id"], "content": "See the updated report.", "attachments": []string{report},
+	})
+	attachments = got["attachments"].([]any)
+	if attachment := attachments[0].(map[string]any); len(attachments) != 1 || attachment["kept"] != nil || attachment["size"] != float64(12) {
+		t.Errorf("replace_outgoing_message with attachments = %v, want the new report.csv only", attachments)
+	}
+
+	// If the files of the attachments cannot be read, nothing is replaced
+	if err := os.Remove(report); err != nil {
+		t.Fatal(err)
+	}
+	if got := callToolError(t, session, "replace_outgoing_message", map[string]any{
+		"outgoing_id": got["outgoing_id"], "content": "See the updated report.",
+	}); got.Code != jxa.ErrorCodeAttachmentNotFound {
+		t.Errorf("replace_outgoing_message without attachment files code = %s, want %s", got.Code, jxa.ErrorCodeAttachmentNotFound)
+	}
+	if got := callTool(t, session, "list_outgoing_messages", map[string]any{}); got["count"] != float64(1) {
+		t.Errorf("list_outgoing_messages count = %v, want 1", got["count"])
+	}
+	if err := os.WriteFile(report, []byte("a;b\n1;2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files outside the attachment directories and missing files are refused
+	for path, code := range map[string]string{
+		filepath.Join(t.TempDir(), "report.csv"): jxa.ErrorCodeInvalidParameters,
+		filepath.Join(dir, "missing.csv"):        jxa.ErrorCodeAttachmentNotFound,
+	} {
+		args["attachments"] = []string{path}
+		if got := callToolError(t, session, "create_outgoing_message", args); got.Code != code {
+			t.Errorf("create_outgoing_message with %s code = %s, want %s", path, got.Code, code)
+		}
+	}
+}
+
+// subjects returns the subjects of the messages of a find_messages result.
+func subjects(result map[string]any) []string {
+	var out []string
+	messages, _ := result["messages"].([]any)
+	for _, m := range messages {
+		out = append(out, m.(map[string]any)["subject"].(string))
+	}
+	return out
+}
+
+func TestSim_FileMessages(t *testing.T) {
+	tests := []struct {
+		name            string
+		tool            string
+		args            map[string]any
+		wantDestination []string
+		wantInbox       []string
+		wantFiled       []string
+	}{
+		{
+			name:            "move",
+			tool:            "move_messages",
+			args:            map[string]any{"message_ids": []int{1002, 1003}, "destinationMailboxPath": []string{"INBOX", "Projects"}},
+			wantDestination: []string{"INBOX", "Projects"},
+			wantInbox:       []string{"Quarterly planning"},
+			wantFiled:       []string{"Re: Project kickoff", "Build failed on main", "Lunch on Thursday?"},
+		},
+		{
+			name:            "copy",
+			tool:            "copy_messages",
+			args:            map[string]any{"message_ids": []int{1002}, "destinationMailboxPath": []string{"INBOX", "Projects"}},
+			wantDestination: []string{"INBOX", "Projects"},
+			wantInbox:       []string{"Quarterly planning", "Build failed on main", "Lunch on Thursday?"},
+			wantFiled:       []string{"Re: Project kickoff", "Build failed on main"},
+		},
+		{
+			name:            "archive",
+			tool:            "archive_messages",
+			args:            map[string]any{"message_ids": []int{1001}},
+			wantDestination: []string{"Archive"},
+			wantInbox:       []string{"Build failed on main", "Lunch on Thursday?"},
+			wantFiled:       []string{"Quarterly planning"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := connect(t, newDemo(t))
+			args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}}
+			maps.Copy(args, tt.args)
+
+			got := callTool(t, session, tt.tool, args)
+			ids := tt.args["message_ids"].([]int)
+			if got["count"] != float64(len(ids)) {
+				t.Errorf("%s count = %v, want %d", tt.tool, got["count"], len(ids))
+			}
+			for i, m := range got["messages"].([]any) {
+				filed := m.(map[string]any)
+				if filed["message_id"] != float64(ids[i]) {
+					t.Errorf("message_id = %v, want %d", filed["message_id"], ids[i])
+				}
+				if filed["new_message_id"] == nil || filed["new_message_id"] == filed["message_id"] {
+					t.Errorf("new_message_id = %v, want a new ID", filed["new_message_id"])
+				}
+			}
+			if dest := got["destinationMailboxPath"]; strings.Join(toStrings(dest), "|") != strings.Join(tt.wantDestination, "|") {
+				t.Errorf("destinationMailboxPath = %v, want %v", dest, tt.wantDestination)
+			}
+
+			find := func(path []string) []string {
+				return subjects(callTool(t, session, "find_messages", map[string]any{
+					"account": "Work", "mailboxPath": path, "dateAfter": "2000-01-01T00:00:00Z", "sort": "date_asc",
+				}))
+			}
+			if inbox := find([]string{"INBOX"}); strings.Join(inbox, "|") != strings.Join(tt.wantInbox, "|") {
+				t.Errorf("INBOX subjects = %v, want %v", inbox, tt.wantInbox)
+			}
+			if filed := find(tt.wantDestination); strings.Join(filed, "|") != strings.Join(tt.wantFiled, "|") {
+				t.Errorf("%v subjects = %v, want %v", tt.wantDestination, filed, tt.wantFiled)
+			}
+		})
+	}
+}
+
+func toStrings(v any) []string {
+	var out []string
+	for _, s := range v.([]any) {
+		out = append(out, s.(string))
+	}
+	return out
+}
+
+func TestSim_UpdateMessages(t *testing.T) {
+	session := connect(t, newDemo(t))
+	inbox := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}}
+
+	// 1003 is already read.
+	args := maps.Clone(inbox)
+	maps.Copy(args, map[string]any{"message_ids": []int{1001, 1003}, "readStatus": true})
+	got := callTool(t, session, "update_messages", args)
+	if ids := got["changed_ids"].([]any); len(ids) != 1 || ids[0] != float64(1001) {
+		t.Errorf("changed_ids = %v, want [1001]", ids)
+	}
+	if ids := got["unchanged_ids"].([]any); len(ids) != 1 || ids[0] != float64(1003) {
+		t.Errorf("unchanged_ids = %v, want [1003]", ids)
+	}
+
+	// Setting a flag colour flags the message.
+	args = maps.Clone(inbox)
+	maps.Copy(args, map[string]any{"message_ids": []int{1002}, "flagIndex": 3})
+	got = callTool(t, session, "update_messages", args)
+	if got["count"] != float64(1) {
+		t.Errorf("count = %v, want 1", got["count"])
+	}
+
+	find := func(filter map[string]any) []string {
+		args := maps.Clone(inbox)
+		args["sort"] = "date_asc"
+		maps.Copy(args, filter)
+		return subjects(callTool(t, session, "find_messages", args))
+	}
+	if unread := find(map[string]any{"readStatus": false}); strings.Join(unread, "|") != "Build failed on main" {
+		t.Errorf("unread subjects = %v, want [Build failed on main]", unread)
+	}
+	if flagged := find(map[string]any{"flaggedOnly": true}); strings.Join(flagged, "|") != "Quarterly planning|Build failed on main" {
+		t.Errorf("flagged subjects = %v, want [Quarterly planning Build failed on main]", flagged)
+	}
+}
+
+func TestSim_TrashMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantInbox []string
+		wantTrash []string
+	}{
+		{
+			name:      "dry run",
+			args:      map[string]any{"dry_run": true},
+			wantInbox: []string{"Quarterly planning", "Build failed on main", "Lunch on Thursday?"},
+		},
+		{
+			name:      "trash",
+			args:      map[string]any{},
+			wantInbox: []string{"Quarterly planning", "Lunch on Thursday?"},
+			wantTrash: []string{"Build failed on main"},
+		},
+		{
+			name:      "permanent",
+			args:      map[string]any{"permanent": true},
+			wantInbox: []string{"Quarterly planning", "Lunch on Thursday?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := connect(t, newDemo(t))
+			args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_ids": []int{1002}}
+			maps.Copy(args, tt.args)
+
+			got := callTool(t, session, "trash_messages", args)
+			messages := got["messages"].([]any)
+			if len(messages) != 1 {
+				t.Fatalf("trash_messages returned %d messages, want 1", len(messages))
+			}
+			msg := messages[0].(map[string]any)
+			if msg["subject"] != "Build failed on main" || msg["sender"] != "CI <ci@example.com>" || msg["date_received"] != "2025-03-04T07:02:00.000Z" {
+				t.Errorf("trash_messages message = %v, want subject, sender and date of message 1002", msg)
+			}
+			_, hasNewID := msg["new_message_id"]
+			if wantNewID := len(tt.wantTrash) > 0; hasNewID != wantNewID {
+				t.Errorf("new_message_id present = %v, want %v", hasNewID, wantNewID)
+			}
+
+			find := func(path []string) []string {
+				return subjects(callTool(t, session, "find_messages", map[string]any{
+					"account": "Work", "mailboxPath": path, "dateAfter": "2000-01-01T00:00:00Z", "sort": "date_asc",
+				}))
+			}
+			if inbox := find([]string{"INBOX"}); strings.Join(inbox, "|") != strings.Join(tt.wantInbox, "|") {
+				t.Errorf("INBOX subjects = %v, want %v", inbox, tt.wantInbox)
+			}
+			if trash := find([]string{"Trash"}); strings.Join(trash, "|") != strings.Join(tt.wantTrash, "|") {
+				t.Errorf("Trash subjects = %v, want %v", trash, tt.wantTrash)
+			}
+		})
+	}
+}
+
+func TestSim_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		running       bool
+		tool          string
+		args          map[string]any
+		wantCode      string
+		wantRetryable bool
+	}{
+		{
+			name:          "not running",
+			running:       false,
+			tool:          "list_accounts",
+			args:          map[string]any{"enabled": false},
+			wantCode:      jxa.ErrorCodeMailAppNotRunning,
+			wantRetryable: true,
+		},
+		{
+			name:     "account not found",
+			running:  true,
+			tool:     "list_mailboxes",
+			args:     map[string]any{"account": "Nope"},
+			wantCode: jxa.ErrorCodeAccountNotFound,
+		},
+		{
+			name:     "invalid body format",
+			running:  true,
+			tool:     "get_message_content",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "body_format": "rtf"},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "negative max chars",
+			running:  true,
+			tool:     "get_message_content",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "max_chars": -1},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "negative offset",
+			running:  true,
+			tool:     "get_thread",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "offset": -1},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "invalid content mode",
+			running:  true,
+			tool:     "get_message_content",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "content_mode": "quotes"},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "content mode with html body",
+			running:  true,
+			tool:     "get_message_content",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "body_format": "html", "content_mode": "split"},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "unsupported attachment",
+			running:  true,
+			tool:     "get_attachment_text",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003, "name": "menu.pdf"},
+			wantCode: jxa.ErrorCodeUnsupportedAttachment,
+		},
+		{
+			name:     "attachment not found",
+			running:  true,
+			tool:     "get_attachment_text",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003, "name": "menu.docx"},
+			wantCode: jxa.ErrorCodeAttachmentNotFound,
+		},
+		{
+			name:     "attachment without name or part",
+			running:  true,
+			tool:     "get_attachment_text",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003},
+			wantCode: jxa.ErrorCodeMissingParameters,
+		},
+		{
+			name:     "mailbox not found",
+			running:  true,
+			tool:     "find_messages",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"Nope"}, "subject": "x"},
+			wantCode: jxa.ErrorCodeMailboxNotFound,
+		},
+		{
+			name:     "message not found",
+			running:  true,
+			tool:     "get_message_content",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 2001},
+			wantCode: jxa.ErrorCodeMessageNotFound,
+		},
+		{
+			name:     "move unknown message",
+			running:  true,
+			tool:     "move_messages",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_ids": []int{1001, 2001}, "destinationMailboxPath": []string{"Archive"}},
+			wantCode: jxa.ErrorCodeMessageNotFound,
+		},
+		{
+			name:     "no trash mailbox",
+			running:  true,
+			tool:     "trash_messages",
+			args:     map[string]any{"account": "Personal", "mailboxPath": []string{"INBOX"}, "message_ids": []int{2001}, "dry_run": true},
+			wantCode: jxa.ErrorCodeMailboxNotFound,
+		},
+		{
+			name:     "trash messages in trash",
+			running:  true,
+			tool:     "trash_messages",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"Trash"}, "message_ids": []int{1001}},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+		{
+			name:     "no archive mailbox",
+			running:  true,
+			tool:     "archive_messages",
+			args:     map[string]any{"account": "Personal", "mailboxPath": []string{"INBOX"}, "message_ids": []int{2001}},
+			wantCode: jxa.ErrorCodeMailboxNotFound,
+		},
+		{
+			name:     "archive messages in archive",
+			running:  true,
+			tool:     "archive_messages",
+			args:     map[string]any{"account": "Work", "mailboxPath": []string{"Archive"}, "message_ids": []int{1001}},
+			wantCode: jxa.ErrorCodeInvalidParameters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newDemo(t)
+			sim.SetRunning(tt.running)
+			session := connect(t, sim)
+
+			got := callToolError(t, session, tt.tool, tt.args)
+			if got.Code != tt.wantCode || got.Retryable != tt.wantRetryable {
+				t.Errorf("error = %+v, want code %s and retryable %v", got, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+// TestSim_ReadOnlyTools checks that the simulated results of every read-only
+// tool pass the strict result validation of the tools.
+func TestSim_ReadOnlyTools(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]any
+	}{
+		{tool: "list_mailboxes", args: map[string]any{"account": "Work"}},
+		{tool: "list_mailboxes", args: map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}}},
+		{tool: "get_message_content", args: map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003}},
+		{tool: "get_message_source", args: map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003}},
+		{tool: "get_attachment_text", args: map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003, "name": "orders.csv"}},
+		{tool: "get_selected_messages", args: map[string]any{}},
+		{tool: "list_drafts", args: map[string]any{}},
+		{tool: "list_outgoing_messages", args: map[string]any{}},
+	}
+
+	session := connect(t, newDemo(t))
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			callTool(t, session, tt.tool, tt.args)
+		})
+	}
+}
+
+func TestSim_GetMessageContentHeaders(t *testing.T) {
+	session := connect(t, newDemo(t))
+	got := callTool(t, session, "get_message_content", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101,
+	})
+	message := got["message"].(map[string]any)
+	headers := message["headers"].(map[string]any)
+	if s := toStrings(headers["Message-Id"]); len(s) != 1 || s[0] != "<kickoff-1101@example.com>" {
+		t.Errorf("headers[Message-Id] = %v", headers["Message-Id"])
+	}
+	if s := toStrings(message["inReplyTo"]); len(s) != 1 || s[0] != "kickoff-1201@example.com" {
+		t.Errorf("inReplyTo = %v, want kickoff-1201@example.com", message["inReplyTo"])
+	}
+	if s := toStrings(message["references"]); len(s) != 1 || s[0] != "kickoff-1201@example.com" {
+		t.Errorf("references = %v, want kickoff-1201@example.com", message["references"])
+	}
+	if _, ok := message["listId"]; ok {
+		t.Errorf("listId = %v, want none", message["listId"])
+	}
+}
+
+func TestSim_GetMessageContentBodyFormat(t *testing.T) {
+	session := connect(t, newDemo(t))
+	content := func(account string, mailboxPath []string, id int, format string) (string, string) {
+		t.Helper()
+		got := callTool(t, session, "get_message_content", map[string]any{
+			"account": account, "mailboxPath": mailboxPath, "message_id": id, "body_format": format,
+		})
+		message := got["message"].(map[string]any)
+		return message["content"].(string), message["bodyFormat"].(string)
+	}
+
+	got, format := content("Personal", []string{"INBOX"}, 2001, "markdown")
+	want := "![Shop](cid:logo@shop.example.com)\n\nYour order **12345** is on its way.\n\n| Total | 42,00 € |\n| --- | --- |"
+	if got != want || format != "markdown" {
+		t.Errorf("markdown content = %q (%s), want %q", got, format, want)
+	}
+	if got, format = content("Personal", []string{"INBOX"}, 2001, "html"); !strings.HasPrefix(got, "<p><img src=\"cid:logo@shop.example.com\"") || format != "html" {
+		t.Errorf("html content = %q (%s)", got, format)
+	}
+	if got, format = content("Personal", []string{"INBOX"}, 2001, ""); got != "Your order 12345 is on its way." || format != "plain" {
+		t.Errorf("default content = %q (%s)", got, format)
+	}
+
+	// Without HTML body, the plain text is returned
+	if got, format = content("Work", []string{"INBOX"}, 1002, "markdown"); got != "The nightly build of main failed in the integration tests." || format != "plain" {
+		t.Errorf("markdown content without HTML body = %q (%s)", got, format)
+	}
+}
+
+func TestSim_GetMessageContentContentMode(t *testing.T) {
+	session := connect(t, newDemo(t))
+	message := func(mode string) map[string]any {
+		t.Helper()
+		got := callTool(t, session, "get_message_content", map[string]any{
+			"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101, "content_mode": mode,
+		})
+		return got["message"].(map[string]any)
+	}
+
+	if got := message("")["content"].(string); !strings.Contains(got, "> Shall we schedule") {
+		t.Errorf("full content = %q, want the quoted message", got)
+	}
+
+	newText := "The kickoff is confirmed for Monday at 10am."
+	got := message("new_text_only")
+	if got["content"] != newText {
+		t.Errorf("new_text_only content = %q, want %q", got["content"], newText)
+	}
+	if _, ok := got["split"]; ok {
+		t.Errorf("new_text_only split = %v, want none", got["split"])
+	}
+
+	got = message("split")
+	split := got["split"].(map[string]any)
+	if got["content"] != newText || split["newText"] != newText {
+		t.Errorf("split content = %q, newText = %q, want %q", got["content"], split["newText"], newText)
+	}
+	if want := "On Thu, Feb 20, 2025 at 1:00 PM Jane Doe <jane.doe@example.com> wrote:\n> Shall we schedule the kickoff for next week?"; split["quotedText"] != want {
+		t.Errorf("quotedText = %q, want %q", split["quotedText"], want)
+	}
+	if split["signature"] != "Maria Garcia\nProject Lead" {
+		t.Errorf("signature = %q", split["signature"])
+	}
+}
+
+func TestSim_GetMessageContentChunks(t *testing.T) {
+	session := connect(t, newDemo(t))
+	call := func(offset int) map[string]any {
+		t.Helper()
+		return callTool(t, session, "get_message_content", map[string]any{
+			"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101, "max_chars": 60, "offset": offset,
+		})
+	}
+
+	got := call(0)
+	if content := got["message"].(map[string]any)["content"]; content != "The kickoff is confirmed for Monday at 10am.\n\n" {
+		t.Errorf("first chunk = %q, want the first paragraph", content)
+	}
+	if got["total_length"] != float64(193) || got["next_offset"] != float64(46) {
+		t.Errorf("total_length = %v, next_offset = %v, want 193 and 46", got["total_length"], got["next_offset"])
+	}
+
+	// Reading on until next_offset is missing returns the whole content
+	full := callTool(t, session, "get_message_content", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101,
+	})
+	if _, ok := full["next_offset"]; ok {
+		t.Errorf("next_offset = %v without max_chars, want none", full["next_offset"])
+	}
+	var b strings.Builder
+	for offset := 0; ; {
+		got := call(offset)
+		b.WriteString(got["message"].(map[string]any)["content"].(string))
+		next, ok := got["next_offset"].(float64)
+		if !ok {
+			break
+		}
+		offset = int(next)
+	}
+	if want := full["message"].(map[string]any)["content"]; b.String() != want {
+		t.Errorf("chunks = %q, want %q", b.String(), want)
+	}
+
+	// get_thread cuts the content of every message
+	thread := callTool(t, session, "get_thread", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101, "max_chars": 20,
+	})
+	for _, m := range thread["messages"].([]any) {
+		message := m.(map[string]any)
+		if content := message["content"].(string); len([]rune(content)) > 20 || message["next_offset"] == nil || message["total_length"].(float64) <= 20 {
+			t.Errorf("thread message %v: content = %q, total_length = %v, next_offset = %v", message["id"], content, message["total_length"], message["next_offset"])
+		}
+	}
+
+	// get_message_source cuts each body
+	source := callTool(t, session, "get_message_source", map[string]any{
+		"account": "Personal", "mailboxPath": []string{"INBOX"}, "message_id": 2001, "max_chars": 20, "offset": 11, "include_source": true,
+	})
+	if source["text_body"] != "12345 is on its way." || source["text_body_total_length"] != float64(31) {
+		t.Errorf("text_body = %q of %v", source["text_body"], source["text_body_total_length"])
+	}
+	if _, ok := source["text_body_next_offset"]; ok {
+		t.Errorf("text_body_next_offset = %v, want none", source["text_body_next_offset"])
+	}
+	if source["html_body_next_offset"] == nil || source["source_next_offset"] == nil || source["source_total_length"] == nil {
+		t.Errorf("html_body_next_offset = %v, source_next_offset = %v, source_total_length = %v, want all", source["html_body_next_offset"], source["source_next_offset"], source["source_total_length"])
+	}
+}
+
+func TestSim_GetMessageContentInvitation(t *testing.T) {
+	session := connect(t, newDemo(t))
+	got := callTool(t, session, "get_message_content", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101,
+	})
+	message := got["message"].(map[string]any)
+	invitation, ok := message["invitation"].(map[string]any)
+	if !ok {
+		t.Fatalf("invitation = %v", message["invitation"])
+	}
+	// The text/calendar alternative is preferred over the attachment
+	if invitation["method"] != "REQUEST" || invitation["title"] != "Project kickoff" || invitation["location"] != "Room 4" || invitation["part"] != "1.1.2" {
+		t.Errorf("invitation = %v", invitation)
+	}
+	if _, err := time.LoadLocation("Europe/Berlin"); err == nil && (invitation["start"] != "2025-02-24T10:00:00+01:00" || invitation["end"] != "2025-02-24T11:00:00+01:00") {
+		t.Errorf("invitation from %v to %v", invitation["start"], invitation["end"])
+	}
+	if organizer := invitation["organizer"].(map[string]any); organizer["email"] != "maria.garcia@example.com" {
+		t.Errorf("organizer = %v", organizer)
+	}
+	attendees := invitation["attendees"].([]any)
+	if attendee := attendees[0].(map[string]any); len(attendees) != 1 || attendee["name"] != "Jane Doe" || attendee["status"] != "NEEDS-ACTION" || attendee["rsvp"] != true {
+		t.Errorf("attendees = %v", attendees)
+	}
+	if _, ok := invitation["recurrence"]; ok {
+		t.Errorf("recurrence = %v, want none", invitation["recurrence"])
+	}
+
+	// Messages without invitation have none
+	got = callTool(t, session, "get_message_content", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003,
+	})
+	if invitation, ok := got["message"].(map[string]any)["invitation"]; ok {
+		t.Errorf("invitation = %v, want none", invitation)
+	}
+}
+
+func TestSim_GetMessageContentInvitationPart(t *testing.T) {
+	fixture, err := mailsim.LoadFixture(filepath.Join("testdata", "invitation.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFixture() error = %v", err)
+	}
+	sim, err := mailsim.New(fixture)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	session := connect(t, sim)
+
+	// Neither an attachment nor the headers announce the invitation
+	got := callTool(t, session, "get_message_content", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1,
+	})
+	message := got["message"].(map[string]any)
+	if attachments := message["attachments"].([]any); len(attachments) != 0 {
+		t.Fatalf("attachments = %v, want none", attachments)
+	}
+	invitation, ok := message["invitation"].(map[string]any)
+	if !ok {
+		t.Fatalf("invitation = %v", message["invitation"])
+	}
+	if invitation["method"] != "REQUEST" || invitation["title"] != "Design review" || invitation["start"] != "2025-02-26T13:00:00Z" || invitation["part"] != "1.2" {
+		t.Errorf("invitation = %v", invitation)
+	}
+}
+
+func TestSim_GetMessageSource(t *testing.T) {
+	session := connect(t, newDemo(t))
+
+	got := callTool(t, session, "get_message_source", map[string]any{
+		"account": "Personal", "mailboxPath": []string{"INBOX"}, "message_id": 2001,
+	})
+	var tree []string
+	for _, p := range got["parts"].([]any) {
+		part := p.(map[string]any)
+		tree = append(tree, part["path"].(string)+" "+part["content_type"].(string))
+	}
+	if want := "1 multipart/related|1.1 multipart/alternative|1.1.1 text/plain|1.1.2 text/html|1.2 image/png"; strings.Join(tree, "|") != want {
+		t.Errorf("parts = %v, want %s", tree, want)
+	}
+	if got["text_body"] != "Your order 12345 is on its way." {
+		t.Errorf("text_body = %q", got["text_body"])
+	}
+	if html := got["html_body"].(string); !strings.Contains(html, "<td>42,00 €</td>") {
+		t.Errorf("html_body = %q, want the decoded total", html)
+	}
+	images := got["inline_images"].([]any)
+	if len(images) != 1 || images[0].(map[string]any)["content_id"] != "logo@shop.example.com" || images[0].(map[string]any)["referenced"] != true {
+		t.Errorf("inline_images = %v, want the referenced logo", images)
+	}
+	if _, ok := got["source"]; ok {
+		t.Error("source returned without include_source")
+	}
+
+	// Messages without configured source are plain text
+	got = callTool(t, session, "get_message_source", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002, "include_source": true,
+	})
+	if got["text_body"] != "The nightly build of main failed in the integration tests." || got["html_body"] != "" {
+		t.Errorf("bodies = %q, %q", got["text_body"], got["html_body"])
+	}
+	if source, _ := got["source"].(string); !strings.HasPrefix(source, "From: CI <ci@example.com>") {
+		t.Errorf("source = %q", source)
+	}
+}
+
+// scriptCounter counts the scripts run through it, and the find_messages
+// calls that read the content of the messages.
+type scriptCounter struct {
+	jxa.Executor
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *scriptCounter) Execute(ctx context.Context, script jxa.Script, args ...string) (any, error) {
+	c.mu.Lock()
+	c.counts[script.Name]++
+	if script.Name == "find_messages" && !strings.Contains(args[0], `"skipContent":true`) {
+		c.counts["find_messages with content"]++
+	}
+	c.mu.Unlock()
+	return c.Executor.Execute(ctx, script, args...)
+}
+
+func TestSim_SyncIndex(t *testing.T) {
+	ctx := context.Background()
+	sim := newDemo(t)
+	dir := t.TempDir()
+	idx, err := index.Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := idx.Lock(); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	search := func(q string) []string {
+		t.Helper()
+		_, result, err := tools.HandleSearchIndex(ctx, idx, nil, tools.SearchIndexInput{Query: q})
+		if err != nil {
+			t.Fatalf("HandleSearchIndex(%q) error = %v", q, err)
+		}
+		var out []string
+		for _, m := range result.Messages {
+			out = append(out, m.Account+"/"+strings.Join(m.MailboxPath, "/")+"/"+m.Subject)
+		}
+		return out
+	}
+
+	// Every body is read once: the listing skips the content and the
+	// source is not fetched, not even of the invitation in Projects
+	counter := &scriptCounter{Executor: sim, counts: map[string]int{}}
+	got, err := tools.SyncIndex(ctx, counter, idx)
+	if err != nil {
+		t.Fatalf("SyncIndex() error = %v", err)
+	}
+	if *got != (tools.IndexSyncResult{Mailboxes: 8, Added: 8}) {
+		t.Errorf("first SyncIndex() = %+v, want 8 mailboxes and 8 added messages", *got)
+	}
+	if c := counter.counts; c["get_message_content"] != 8 || c["get_message_source"] != 0 || c["find_messages"] == 0 || c["find_messages with content"] != 0 {
+		t.Errorf("scripts run by SyncIndex() = %v, want get_message_content per message and find_messages without content", c)
+	}
+	if hits := search("kickoff"); strings.Join(hits, "|") != "Work/Sent Messages/Project kickoff|Work/INBOX/Projects/Re: Project kickoff" {
+		t.Errorf("search(kickoff) = %v", hits)
+	}
+	if hits := search("integration tests"); strings.Join(hits, "|") != "Work/INBOX/Build failed on main" {
+		t.Errorf("search(integration tests) = %v", hits)
+	}
+
+	// Nothing changed
+	if got, err = tools.SyncIndex(ctx, sim, idx); err != nil || *got != (tools.IndexSyncResult{Mailboxes: 8}) {
+		t.Errorf("second SyncIndex() = %+v, %v, want no changes", got, err)
+	}
+
+	// Trashing moves the message to a mailbox that was empty, with its
+	// original date
+	session := connect(t, sim)
+	callTool(t, session, "trash_messages", map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_ids": []int{1002}})
+	if got, err = tools.SyncIndex(ctx, sim, idx); err != nil || *got != (tools.IndexSyncResult{Mailboxes: 8, Added: 1, Removed: 1}) {
+		t.Errorf("SyncIndex() after trashing = %+v, %v, want 1 added and 1 removed message", got, err)
+	}
+	if hits := search("integration tests"); strings.Join(hits, "|") != "Work/Trash/Build failed on main" {
+		t.Errorf("search(integration tests) after trashing = %v", hits)
+	}
+
+	// The index is saved
+	loaded, err := index.Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if status := loaded.Status(); status.Messages != 8 || len(status.Mailboxes) != 8 || status.SyncedAt.IsZero() {
+		t.Errorf("Status() of saved index = %+v", status)
+	}
+}
+
+func TestSim_GetThread(t *testing.T) {
+	session := connect(t, newDemo(t))
+
+	// The reply in a sub-mailbox references the message in Sent Messages
+	got := callTool(t, session, "get_thread", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX", "Projects"}, "message_id": 1101,
+	})
+	if got["subject"] != "Project kickoff" || got["count"] != float64(2) {
+		t.Fatalf("get_thread = %v, want 2 messages with subject Project kickoff", got)
+	}
+	messages := got["messages"].([]any)
+	first, reply := messages[0].(map[string]any), messages[1].(map[string]any)
+	if first["id"] != float64(1201) || strings.Join(toStrings(first["mailbox_path"]), "/") != "Sent Messages" || first["depth"] != float64(0) {
+		t.Errorf("first message = %v, want 1201 in Sent Messages at depth 0", first)
+	}
+	if _, ok := first["in_reply_to"]; ok {
+		t.Errorf("first message in_reply_to = %v, want none", first["in_reply_to"])
+	}
+	if reply["id"] != float64(1101) || reply["depth"] != float64(1) || reply["in_reply_to"] != "kickoff-1201@example.com" || reply["internet_message_id"] != "kickoff-1101@example.com" {
+		t.Errorf("reply = %v, want 1101 replying to kickoff-1201@example.com", reply)
+	}
+	if !strings.HasPrefix(reply["content"].(string), "The kickoff is confirmed for Monday at 10am.") {
+		t.Errorf("reply content = %q", reply["content"])
+	}
+
+	// Starting from the first message finds the same conversation
+	got = callTool(t, session, "get_thread", map[string]any{
+		"account": "Work", "mailboxPath": []string{"Sent Messages"}, "message_id": 1201,
+	})
+	if s := subjects(got); strings.Join(s, "|") != "Project kickoff|Re: Project kickoff" {
+		t.Errorf("get_thread of 1201 subjects = %v", s)
+	}
+
+	// A message without replies
+	got = callTool(t, session, "get_thread", map[string]any{
+		"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1002,
+	})
+	if s := subjects(got); strings.Join(s, "|") != "Build failed on main" {
+		t.Errorf("get_thread of 1002 subjects = %v", s)
+	}
+}
+
+func TestSim_GetAttachmentText(t *testing.T) {
+	session := connect(t, newDemo(t))
+	args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003, "name": "orders.csv"}
+
+	got := callTool(t, session, "get_attachment_text", args)
+	if got["kind"] != "csv" || got["part"] != "1.3" || got["content_type"] != "text/csv" || got["size"] != float64(47) {
+		t.Errorf("get_attachment_text = %v", got)
+	}
+	tables := got["tables"].([]any)
+	rows := tables[0].(map[string]any)["rows"].([]any)
+	if len(rows) != 3 || !slices.Equal(toStrings(rows[1]), []string{"Jane", "Pasta", "12,50"}) {
+		t.Errorf("rows = %v", rows)
+	}
+	if got["total_length"] != float64(47) || got["next_offset"] != nil {
+		t.Errorf("total_length = %v, next_offset = %v, want 47 and none", got["total_length"], got["next_offset"])
+	}
+
+	// The part path reaches the same attachment, the text is cut at a line
+	delete(args, "name")
+	maps.Copy(args, map[string]any{"part": "1.3", "max_chars": 20})
+	got = callTool(t, session, "get_attachment_text", args)
+	if got["text"] != "Name;Dish;Price\n" || got["next_offset"] != float64(16) {
+		t.Errorf("text = %q, next_offset = %v, want the first line and 16", got["text"], got["next_offset"])
+	}
+}
+
+func TestSim_GetAttachmentTextLargeMessage(t *testing.T) {
+	sim, err := mailsim.New(&mailsim.Fixture{Accounts: []mailsim.Account{{
+		Name: "Work",
+		Mailboxes: []mailsim.Mailbox{{Name: "INBOX", Messages: []mailsim.Message{{
+			ID:           1,
+			Subject:      "Scans",
+			Sender:       "scanner@example.com",
+			DateReceived: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+			MessageSize:  60 << 20,
+			Attachments:  []mailsim.Attachment{{Name: "scan.txt", FileSize: 4, Downloaded: true}},
+		}}}},
+	}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1, "name": "scan.txt"}
+	if got := callToolError(t, connect(t, sim), "get_attachment_text", args); got.Code != jxa.ErrorCodeAttachmentTooLarge {
+		t.Errorf("error = %+v, want code %s", got, jxa.ErrorCodeAttachmentTooLarge)
+	}
+
+	// The script skips the source of the message, it does not transfer it
+	data, err := sim.Execute(context.Background(), jxa.Script{Name: "get_message_source"}, `{"account":"Work","mailboxPath":["INBOX"],"message_id":1,"maxSize":1024}`)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := data.(map[string]any); got["source"] != "" || got["messageSize"] != float64(60<<20) {
+		t.Errorf("get_message_source = %v, want no source and the message size", got)
+	}
+}
+
+func TestSim_SaveAttachments(t *testing.T) {
+	sim := newDemo(t)
+	exportDir := t.TempDir()
+	session := connect(t, sim, func(srv *mcp.Server) {
+		tools.RegisterSaveAttachments(srv, sim, exportDir)
+	})
+	args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003, "names": []string{"menu.pdf"}}
+
+	got := callTool(t, session, "save_attachments", args)
+	files := got["files"].([]any)
+	if len(files) != 1 {
+		t.Fatalf("files = %v, want menu.pdf", files)
+	}
+	file := files[0].(map[string]any)
+	path := file["path"].(string)
+	if want := filepath.Join(got["export_dir"].(string), "menu.pdf"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if file["index"] != float64(0) || file["size"] != float64(90) || file["sha256"] != "1876c356305e7326a61d5c18aa8fb03b2cb5874d2a83c2ffe030c81c533b13a2" {
+		t.Errorf("file = %v", file)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(data), "%PDF-1.4") {
+		t.Errorf("ReadFile() = %q, %v, want the PDF of the source", data, err)
+	}
+
+	// Existing files are not overwritten
+	args["subdirectory"] = "lunch/menus"
+	delete(args, "names")
+	callTool(t, session, "save_attachments", args)
+	got = callTool(t, session, "save_attachments", args)
+	if path := got["files"].([]any)[0].(map[string]any)["path"].(string); path != filepath.Join(got["export_dir"].(string), "menu (2).pdf") || !strings.HasSuffix(got["export_dir"].(string), filepath.Join("lunch", "menus")) {
+		t.Errorf("second path = %s, want menu (2).pdf in lunch/menus", path)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		args     map[string]any
+		wantCode string
+	}{
+		{"path traversal", map[string]any{"subdirectory": "../outside"}, jxa.ErrorCodeInvalidParameters},
+		{"absolute subdirectory", map[string]any{"subdirectory": "/tmp"}, jxa.ErrorCodeInvalidParameters},
+		{"unknown name", map[string]any{"names": []string{"../menu.pdf"}}, jxa.ErrorCodeAttachmentNotFound},
+		{"index out of range", map[string]any{"indexes": []int{2}}, jxa.ErrorCodeAttachmentNotFound},
+		{"no attachments", map[string]any{"message_id": 1002}, jxa.ErrorCodeAttachmentNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}, "message_id": 1003}
+			maps.Copy(args, tt.args)
+			if got := callToolError(t, session, "save_attachments", args); got.Code != tt.wantCode {
+				t.Errorf("error = %+v, want code %s", got, tt.wantCode)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(exportDir), "outside")); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want the directory outside the export directory not to exist", err)
+	}
+}
@@ -0,0 +1,107 @@
+package mailsim
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// trashMailboxPaths mirrors TRASH_MAILBOX_PATHS of scripts/trash_messages.js.
+var trashMailboxPaths = [][]string{
+	{"Deleted Messages"},
+	{"Trash"},
+	{"Deleted Items"},
+	{"[Gmail]", "Trash"},
+	{"[Google Mail]", "Trash"},
+}
+
+// trashMessages mirrors scripts/trash_messages.js.
+func (s *Sim) trashMessages(args []string) (map[string]any, error) {
+	var in struct {
+		fileArgs
+		DryRun    bool `json:"dry_run"`
+		Permanent bool `json:"permanent"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := validateFileArgs(in.fileArgs); err != nil {
+		return nil, err
+	}
+	if len(in.MessageIDs) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "At least one message ID is required")
+	}
+
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+	source := a.findMailbox(in.MailboxPath)
+	if source == nil {
+		return nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+
+	var trash *mailbox
+	tried := []string{}
+	for _, path := range trashMailboxPaths {
+		if trash = a.findMailbox(path); trash != nil {
+			break
+		}
+		tried = append(tried, strings.Join(path, " > "))
+	}
+	if trash == nil {
+		return nil, fail(jxa.ErrorCodeMailboxNotFound, "No trash mailbox found in account '%s'. Tried: %s.", in.Account, strings.Join(tried, ", "))
+	}
+	trashPath := trash.path()
+
+	inTrash := slices.Equal(in.MailboxPath, trashPath)
+	if inTrash && !in.Permanent {
+		return nil, fail(jxa.ErrorCodeInvalidParameters, "The messages are already in the trash. Set permanent to delete them permanently.")
+	}
+
+	messages, err := source.findMessagesByIDs(in.MessageIDs, in.MailboxPath)
+	if err != nil {
+		return nil, err
+	}
+	details := []map[string]any{}
+	for _, msg := range messages {
+		details = append(details, map[string]any{
+			"message_id":    msg.ID,
+			"subject":       msg.Subject,
+			"sender":        msg.Sender,
+			"date_received": isoString(msg.DateReceived),
+		})
+	}
+
+	var message string
+	switch {
+	case in.DryRun && in.Permanent:
+		message = fmt.Sprintf("Dry run: %d message(s) would be permanently deleted.", len(details))
+	case in.DryRun:
+		message = fmt.Sprintf("Dry run: %d message(s) would be moved to '%s'.", len(details), strings.Join(trashPath, " > "))
+	case in.Permanent:
+		for _, msg := range messages {
+			source.messages = removeMessage(source.messages, msg)
+		}
+		message = fmt.Sprintf("Permanently deleted %d message(s).", len(details))
+	default:
+		filed := s.transfer(source, messages, trash, false)
+		for i := range details {
+			details[i]["new_message_id"] = filed[i]["new_message_id"]
+		}
+		message = fmt.Sprintf("Moved %d message(s) to '%s'.", len(details), strings.Join(trashPath, " > "))
+	}
+
+	return map[string]any{
+		"messages":         details,
+		"count":            len(details),
+		"dry_run":          in.DryRun,
+		"permanent":        in.Permanent,
+		"account":          in.Account,
+		"mailboxPath":      in.MailboxPath,
+		"trashMailboxPath": trashPath,
+		"message":          message,
+	}, nil
+}
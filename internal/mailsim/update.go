@@ -0,0 +1,132 @@
+package mailsim
+
+import (
+	"fmt"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// updateMessages mirrors scripts/update_messages.js.
+func (s *Sim) updateMessages(args []string) (map[string]any, error) {
+	var in struct {
+		Account         string   `json:"account"`
+		MailboxPath     []string `json:"mailboxPath"`
+		MessageIDs      []int    `json:"message_ids"`
+		ReadStatus      *bool    `json:"readStatus"`
+		FlaggedStatus   *bool    `json:"flaggedStatus"`
+		FlagIndex       *int     `json:"flagIndex"`
+		JunkMailStatus  *bool    `json:"junkMailStatus"`
+		BackgroundColor *string  `json:"backgroundColor"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Account == "" {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Account name is required")
+	}
+	if len(in.MailboxPath) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "Mailbox path is required and must be a non-empty array")
+	}
+	if len(in.MessageIDs) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "At least one message ID is required")
+	}
+
+	updates := map[string]any{}
+	if in.ReadStatus != nil {
+		updates["readStatus"] = *in.ReadStatus
+	}
+	if in.JunkMailStatus != nil {
+		updates["junkMailStatus"] = *in.JunkMailStatus
+	}
+	if in.BackgroundColor != nil {
+		updates["backgroundColor"] = *in.BackgroundColor
+	}
+	if in.FlaggedStatus != nil {
+		updates["flaggedStatus"] = *in.FlaggedStatus
+	}
+	if in.FlagIndex != nil {
+		updates["flagIndex"] = *in.FlagIndex
+	}
+	if len(updates) == 0 {
+		return nil, fail(jxa.ErrorCodeMissingParameters, "At least one property to update is required (readStatus, junkMailStatus, backgroundColor, flaggedStatus, flagIndex)")
+	}
+
+	a := s.findAccount(in.Account)
+	if a == nil {
+		return nil, accountNotFound(in.Account)
+	}
+	m := a.findMailbox(in.MailboxPath)
+	if m == nil {
+		return nil, mailboxNotFound(in.MailboxPath, in.Account)
+	}
+	messages, err := m.findMessagesByIDs(in.MessageIDs, in.MailboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	changedIDs := []int{}
+	unchangedIDs := []int{}
+	for _, msg := range messages {
+		before := *msg
+		if in.ReadStatus != nil {
+			msg.ReadStatus = *in.ReadStatus
+		}
+		if in.JunkMailStatus != nil {
+			msg.JunkMailStatus = *in.JunkMailStatus
+		}
+		if in.BackgroundColor != nil {
+			msg.BackgroundColor = *in.BackgroundColor
+		}
+		// Like in Mail.app, the flag colour implies the flagged status.
+		switch {
+		case in.FlagIndex != nil:
+			msg.FlaggedStatus = *in.FlagIndex >= 0
+			msg.FlagIndex = max(*in.FlagIndex, 0)
+		case in.FlaggedStatus != nil && *in.FlaggedStatus != msg.FlaggedStatus:
+			msg.FlaggedStatus = *in.FlaggedStatus
+			msg.FlagIndex = 0
+		}
+
+		if statusChanged(&before, msg) {
+			changedIDs = append(changedIDs, msg.ID)
+		} else {
+			unchangedIDs = append(unchangedIDs, msg.ID)
+		}
+	}
+
+	return map[string]any{
+		"changed_ids":   changedIDs,
+		"unchanged_ids": unchangedIDs,
+		"count":         len(changedIDs),
+		"updates":       updates,
+		"account":       in.Account,
+		"mailboxPath":   in.MailboxPath,
+		"message":       fmt.Sprintf("Updated %d of %d message(s).", len(changedIDs), len(messages)),
+	}, nil
+}
+
+// flagIndex returns the flag colour like Mail.app, which reports -1 for
+// messages that are not flagged.
+func flagIndex(msg *Message) int {
+	if !msg.FlaggedStatus {
+		return -1
+	}
+	return msg.FlagIndex
+}
+
+// backgroundColor returns the background colour, defaulting to "none".
+func backgroundColor(msg *Message) string {
+	if msg.BackgroundColor == "" {
+		return "none"
+	}
+	return msg.BackgroundColor
+}
+
+// statusChanged reports whether one of the properties set by
+// update_messages differs between the messages.
+func statusChanged(a, b *Message) bool {
+	return a.ReadStatus != b.ReadStatus ||
+		a.JunkMailStatus != b.JunkMailStatus ||
+		backgroundColor(a) != backgroundColor(b) ||
+		flagIndex(a) != flagIndex(b)
+}
@@ -0,0 +1,499 @@
+package md
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Markers for white space that normalize must keep: the indentation of list
+// items and the white space of preformatted text. They are replaced at the
+// end of the conversion.
+const (
+	keepSpace   = "\uE000"
+	keepNewline = "\uE001"
+)
+
+// invisible are characters that mail templates use to pad the preview
+// text, e.g. zero width spaces or the combining grapheme joiner. Non-breaking
+// spaces are replaced by spaces.
+var invisible = strings.NewReplacer(
+	"\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "", "\u034f", "", "\u00ad", "",
+	"\u00a0", " ",
+)
+
+var (
+	spaces    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	lineSpace = regexp.MustCompile(` {2,}`)
+)
+
+// FromHTML converts the HTML body of a message to Markdown for reading.
+// Links, images with alternative text, lists, tables, headings, quotes,
+// code and emphasis are kept. Styles, scripts, hidden elements and tracking
+// pixels are dropped. Tables that contain other tables are used for layout
+// and are converted to their content.
+func FromHTML(content string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	c := &converter{}
+	out := normalize(c.children(doc))
+	out = strings.ReplaceAll(out, keepSpace, " ")
+	return strings.ReplaceAll(out, keepNewline, "\n"), nil
+}
+
+type converter struct {
+	pre    int
+	strong int
+	em     int
+}
+
+func (c *converter) children(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.render(child))
+	}
+	return b.String()
+}
+
+func (c *converter) render(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		if c.pre > 0 {
+			return preformatted(n.Data)
+		}
+		return escape(spaces.ReplaceAllString(invisible.Replace(n.Data), " "))
+	case html.ElementNode:
+	case html.DocumentNode:
+		return c.children(n)
+	default:
+		return ""
+	}
+	if hidden(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Title, atom.Noscript, atom.Template,
+		atom.Iframe, atom.Object, atom.Embed, atom.Svg, atom.Meta, atom.Link, atom.Input, atom.Select:
+		return ""
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := oneLine(c.children(n))
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+	case atom.Ul, atom.Ol:
+		return "\n\n" + c.list(n) + "\n\n"
+	case atom.Blockquote:
+		return "\n\n" + prefixLines(normalize(c.children(n)), "> ", ">") + "\n\n"
+	case atom.Pre:
+		c.pre++
+		text := strings.Trim(c.children(n), keepNewline)
+		c.pre--
+		return "\n\n```" + keepNewline + text + keepNewline + "```\n\n"
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		if c.pre > 0 {
+			return c.children(n)
+		}
+		return code(textContent(n))
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return image(n)
+	case atom.B, atom.Strong:
+		return c.emphasis(n, &c.strong, "**")
+	case atom.I, atom.Em:
+		return c.emphasis(n, &c.em, "*")
+	case atom.S, atom.Strike, atom.Del:
+		if c.pre > 0 {
+			return c.children(n)
+		}
+		return wrap(c.children(n), "~~")
+	case atom.Table:
+		return c.table(n)
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main,
+		atom.Nav, atom.Aside, atom.Center, atom.Address, atom.Figure, atom.Figcaption,
+		atom.Form, atom.Fieldset, atom.Dl, atom.Dt, atom.Dd, atom.Details, atom.Summary,
+		atom.Li, atom.Tr, atom.Td, atom.Th, atom.Caption:
+		return "\n\n" + c.children(n) + "\n\n"
+	default:
+		return c.children(n)
+	}
+}
+
+// hidden reports whether an element is not displayed, e.g. the preview text
+// of newsletters.
+func hidden(n *html.Node) bool {
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	return styleValue(n, "display") == "none" ||
+		styleValue(n, "visibility") == "hidden" ||
+		styleValue(n, "mso-hide") == "all"
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// list converts the items of a ul or ol element. Items are kept tight.
+func (c *converter) list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, ok := attr(n, "start"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(start)); err == nil {
+			number = i
+		}
+	}
+
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		var content string
+		switch {
+		case child.Type == html.ElementNode && child.DataAtom == atom.Li:
+			if hidden(child) {
+				continue
+			}
+			content = c.children(child)
+		case child.Type == html.ElementNode:
+			content = c.render(child)
+		default:
+			continue
+		}
+		content = normalize(content)
+		if content == "" {
+			continue
+		}
+		var lines []string
+		for _, line := range strings.Split(content, "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		indent := strings.Repeat(keepSpace, len(marker))
+		for i, line := range lines {
+			if i == 0 {
+				lines[i] = marker + line
+			} else {
+				lines[i] = indent + line
+			}
+		}
+		items = append(items, strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// link converts a link to a Markdown link, or to its text if it has no
+// target. Links without text, e.g. around images without alternative text,
+// are dropped.
+func (c *converter) link(n *html.Node) string {
+	text := oneLine(c.children(n))
+	href, _ := attr(n, "href")
+	href = strings.TrimSpace(href)
+	lower := strings.ToLower(href)
+	if text == "" {
+		return ""
+	}
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+		return text
+	}
+	if unescape(text) == href || "mailto:"+unescape(text) == lower {
+		return "<" + href + ">"
+	}
+	return "[" + text + "](" + linkDestination(href) + ")"
+}
+
+// linkDestination escapes the characters that would end a link destination.
+func linkDestination(href string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(href)
+}
+
+// image converts an image with alternative text. Images without, which are
+// usually decorative, and tracking pixels are dropped. Inline data is not
+// kept.
+func image(n *html.Node) string {
+	alt, _ := attr(n, "alt")
+	alt = oneLine(escape(spaces.ReplaceAllString(invisible.Replace(alt), " ")))
+	if alt == "" || trackingPixel(n) {
+		return ""
+	}
+	src, _ := attr(n, "src")
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") {
+		return alt
+	}
+	return "![" + alt + "](" + linkDestination(src) + ")"
+}
+
+// trackingPixel reports whether an image is at most one pixel wide or high.
+func trackingPixel(n *html.Node) bool {
+	for _, key := range []string{"width", "height"} {
+		v, ok := attr(n, key)
+		if style := styleValue(n, key); style != "" {
+			v, ok = style, true
+		}
+		v = strings.TrimSuffix(strings.TrimSpace(v), "px")
+		if i, err := strconv.Atoi(v); ok && err == nil && i <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// styleValue returns the lower case value of a property of the style
+// attribute, or "" if it is not set.
+func styleValue(n *html.Node, property string) string {
+	style, _ := attr(n, "style")
+	value := ""
+	for decl := range strings.SplitSeq(style, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), property) {
+			value = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important")))
+		}
+	}
+	return value
+}
+
+// emphasis wraps the content of an element in a marker, unless an
+// enclosing element of the same kind already did.
+func (c *converter) emphasis(n *html.Node, depth *int, marker string) string {
+	*depth++
+	content := c.children(n)
+	*depth--
+	if *depth > 0 || c.pre > 0 {
+		return content
+	}
+	return wrap(content, marker)
+}
+
+// wrap wraps inline content in a marker, keeping the surrounding white
+// space outside. Content spanning several blocks is not wrapped.
+func wrap(content, marker string) string {
+	core := strings.TrimSpace(content)
+	if core == "" || strings.Contains(core, "\n") {
+		return content
+	}
+	start := strings.Index(content, core)
+	return content[:start] + marker + core + marker + content[start+len(core):]
+}
+
+// code converts inline code, using a longer delimiter if the code contains
+// backticks.
+func code(text string) string {
+	text = spaces.ReplaceAllString(text, " ")
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	delimiter := "`"
+	for strings.Contains(text, delimiter) {
+		delimiter += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return delimiter + text + delimiter
+}
+
+// table converts a table with data to a Markdown table. Tables for layout,
+// which contain other tables, are marked as presentation or have a single
+// column, are converted to their content.
+func (c *converter) table(n *html.Node) string {
+	var rows [][]*html.Node
+	nested := false
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || hidden(child) {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				collect(child)
+			case atom.Tr:
+				var cells []*html.Node
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) && !hidden(cell) {
+						cells = append(cells, cell)
+						if containsTable(cell) {
+							nested = true
+						}
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	collect(n)
+
+	columns := 0
+	for _, row := range rows {
+		width := 0
+		for _, cell := range row {
+			width += colspan(cell)
+		}
+		columns = max(columns, width)
+	}
+	role, _ := attr(n, "role")
+	if nested || columns < 2 || strings.EqualFold(role, "presentation") {
+		return "\n\n" + c.children(n) + "\n\n"
+	}
+
+	var lines []string
+	for i, row := range rows {
+		var cells []string
+		for _, cell := range row {
+			text := normalize(c.children(cell))
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			for range colspan(cell) - 1 {
+				cells = append(cells, "")
+			}
+		}
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		// The first row is the header, Markdown tables require one
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	caption := ""
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Caption {
+			caption = c.render(child)
+		}
+	}
+	return "\n\n" + caption + "\n\n" + strings.Join(lines, "\n") + "\n\n"
+}
+
+func containsTable(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Table || containsTable(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func colspan(cell *html.Node) int {
+	if v, ok := attr(cell, "colspan"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && i > 1 && i <= 100 {
+			return i
+		}
+	}
+	return 1
+}
+
+// textContent is the text of an element and its descendants.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// preformatted marks the white space of preformatted text to be kept.
+func preformatted(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, " ", keepSpace)
+	text = strings.ReplaceAll(text, "\t", strings.Repeat(keepSpace, 4))
+	return strings.ReplaceAll(text, "\n", keepNewline)
+}
+
+// escape escapes the characters of text that Markdown would interpret.
+// Underscores within words are kept, as they do not mark emphasis there.
+func escape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '\\', '*', '`', '[', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '_':
+			if i > 0 && i+1 < len(text) && isWordByte(text[i-1]) && isWordByte(text[i+1]) {
+				b.WriteByte(c)
+			} else {
+				b.WriteString(`\_`)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+var unescaper = regexp.MustCompile(`\\([\\*_\x60\[\]])`)
+
+// unescape reverts escape.
+func unescape(text string) string {
+	return unescaper.ReplaceAllString(text, "$1")
+}
+
+// oneLine normalizes inline content to a single line.
+func oneLine(content string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(normalize(content), "\n", " ")), " ")
+}
+
+// normalize trims the lines of converted content, collapses spaces and
+// allows at most one empty line between blocks.
+func normalize(content string) string {
+	var lines []string
+	empty := true
+	for _, line := range strings.Split(content, "\n") {
+		line = lineSpace.ReplaceAllString(strings.TrimSpace(line), " ")
+		if line == "" {
+			if !empty {
+				lines = append(lines, "")
+			}
+			empty = true
+			continue
+		}
+		lines = append(lines, line)
+		empty = false
+	}
+	return strings.TrimSuffix(strings.Join(lines, "\n"), "\n")
+}
+
+// prefixLines prefixes the lines of content, empty lines with emptyPrefix.
+func prefixLines(content, prefix, emptyPrefix string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,151 @@
+package md
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and line breaks",
+			html: "<p>Hello   Jane,</p>\n<p>first line<br>second\nline</p><div>done</div>",
+			want: "Hello Jane,\n\nfirst line\nsecond line\n\ndone",
+		},
+		{
+			name: "emphasis",
+			html: "<p>This is <b>bold</b>, <em>italic </em>and <strong>nested <b>bold</b></strong> <del>gone</del>.</p>",
+			want: "This is **bold**, *italic* and **nested bold** ~~gone~~.",
+		},
+		{
+			name: "links",
+			html: `<p><a href="https://meet.example.com/abc?x=1">Join meeting</a>, <a href="mailto:jane@example.com">jane@example.com</a>, <a href="https://example.com">https://example.com</a>, <a href="#top">top</a>, <a href="https://example.com/a (b)">odd</a></p>`,
+			want: "[Join meeting](https://meet.example.com/abc?x=1), <mailto:jane@example.com>, <https://example.com>, top, [odd](https://example.com/a%20%28b%29)",
+		},
+		{
+			name: "link around block",
+			html: `<a href="https://example.com/track"><div>View</div><div>online</div></a>`,
+			want: "[View online](https://example.com/track)",
+		},
+		{
+			name: "headings",
+			html: "<h1>Order <i>confirmed</i></h1><h3>Details</h3><h2> </h2>",
+			want: "# Order *confirmed*\n\n### Details",
+		},
+		{
+			name: "lists",
+			html: "<ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><ol start=\"3\"><li>three</li><li><p>four</p><p>more</p></li></ol>",
+			want: "- one\n- two\n  - nested\n\n3. three\n4. four\n   more",
+		},
+		{
+			name: "blockquote",
+			html: "<p>Reply</p><blockquote><p>quoted</p><p>text</p></blockquote>",
+			want: "Reply\n\n> quoted\n>\n> text",
+		},
+		{
+			name: "code",
+			html: "<p>Run <code>go test</code> or <code>a`b</code></p><pre>func main() {\n\tfmt.Println(\"*hi*\")\n\n}</pre>",
+			want: "Run `go test` or ``a`b``\n\n```\nfunc main() {\n    fmt.Println(\"*hi*\")\n\n}\n```",
+		},
+		{
+			name: "escaping",
+			html: "<p>2*3 = [6] and snake_case but _x_ \\ `q`</p>",
+			want: "2\\*3 = \\[6\\] and snake_case but \\_x\\_ \\\\ \\`q\\`",
+		},
+		{
+			name: "data table",
+			html: `<table><thead><tr><th>Item</th><th>Qty</th><th>Price</th></tr></thead>
+				<tbody><tr><td>Coffee <b>beans</b></td><td>2</td><td>24,00 €</td></tr>
+				<tr><td colspan="2">Total</td><td>24,00&nbsp;€</td></tr>
+				<tr><td>A|B<br>C</td></tr></tbody></table>`,
+			want: "| Item | Qty | Price |\n| --- | --- | --- |\n| Coffee **beans** | 2 | 24,00 € |\n| Total | | 24,00 € |\n| A\\|B<br>C | | |",
+		},
+		{
+			name: "table without header row",
+			html: `<table><caption>Totals</caption><tr><td>Net</td><td>20</td></tr><tr><td>VAT</td><td>4</td></tr></table>`,
+			want: "Totals\n\n| Net | 20 |\n| --- | --- |\n| VAT | 4 |",
+		},
+		{
+			name: "layout tables",
+			html: `<table role="presentation"><tr><td>Header</td><td>Menu</td></tr></table>
+				<table><tr><td><p>Outer</p><table><tr><td>a</td><td>b</td></tr></table></td></tr></table>
+				<table><tr><td>single</td></tr><tr><td>column</td></tr></table>`,
+			want: "Header\n\nMenu\n\nOuter\n\n| a | b |\n| --- | --- |\n\nsingle\n\ncolumn",
+		},
+		{
+			name: "images",
+			html: `<p><img src="cid:logo@shop" alt="Shop"> <img src="https://example.com/spacer.gif"> <img src="data:image/png;base64,AAAA" alt="Inline"> <a href="https://example.com"><img src="https://example.com/b.png"></a></p>`,
+			want: "![Shop](cid:logo@shop) Inline",
+		},
+		{
+			name: "tracking pixels",
+			html: `<p>Text<img src="https://t.example.com/open.gif" alt="" width="1" height="1"><img src="https://t.example.com/o2" alt="x" style="width:1px;height:1px"><img src="https://example.com/ok.png" alt="ok" style="line-height:0; width: 200px"></p>`,
+			want: "Text![ok](https://example.com/ok.png)",
+		},
+		{
+			name: "dropped elements",
+			html: `<html><head><title>Newsletter</title><style>p { color: red }</style></head><body>
+				<div style="display: none; max-height: 0">Preview text</div>
+				<span hidden>hidden</span><div style="visibility:hidden">invisible</div>
+				<script>alert(1)</script><noscript>enable js</noscript>
+				<p>Visible</p><hr><p>Footer</p></body></html>`,
+			want: "Visible\n\n---\n\nFooter",
+		},
+		{
+			name: "invisible characters",
+			html: "<p>Hi\u200b\u034f&nbsp;&nbsp;there\u00ad</p>",
+			want: "Hi there",
+		},
+		{
+			name: "malformed",
+			html: "<p>unclosed <b>bold<p>next",
+			want: "unclosed **bold**\n\n**next**",
+		},
+		{
+			name: "empty",
+			html: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromHTML(tt.html)
+			if err != nil {
+				t.Fatalf("FromHTML() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FromHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFromHTML_RoundTrip checks that the Markdown renders to the structure of
+// the original HTML.
+func TestFromHTML_RoundTrip(t *testing.T) {
+	markdown, err := FromHTML(`<h2>Order 12345</h2><p>Thanks for your order, <a href="https://shop.example.com/orders/12345">track it here</a>.</p>
+		<table><tr><th>Item</th><th>Price</th></tr><tr><td>Mug</td><td>9 €</td></tr></table>
+		<ul><li>Ships <em>tomorrow</em></li></ul>`)
+	if err != nil {
+		t.Fatalf("FromHTML() error = %v", err)
+	}
+	rendered, err := Render(markdown)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{
+		"<h2>Order 12345</h2>",
+		`<a href="https://shop.example.com/orders/12345">track it here</a>`,
+		"<th>Item</th>",
+		"<td>9 €</td>",
+		"<li>Ships <em>tomorrow</em></li>",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("Render(FromHTML()) = %s, want it to contain %s", rendered, want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Package mimepart parses the source of a message into its tree of MIME
+// parts, see RFC 2045 and RFC 2046. Parsing is lenient: malformed parts are
+// kept with the data that could be read, as mail clients display them
+// anyway.
+package mimepart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dastrobu/mail-mcp/internal/header"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxDepth bounds the nesting of parts, deeper parts are kept undecoded.
+const maxDepth = 32
+
+// Part is a MIME part of a message. The message itself is the root part.
+type Part struct {
+	// Path is the position of the part in the tree: "1" for the root,
+	// "1.2" for the second child of the root and so on.
+	Path   string
+	Header header.Header
+	// ContentType is the lower case media type, e.g. "text/plain".
+	ContentType string
+	// Params are the parameters of the Content-Type header with lower case
+	// names, e.g. "charset".
+	Params map[string]string
+	// Disposition is the lower case Content-Disposition, e.g. "inline" or
+	// "attachment", or "" if there is none.
+	Disposition string
+	// Filename is the file name of the Content-Disposition, or the name of
+	// the Content-Type.
+	Filename string
+	// ContentID is the Content-ID without angle brackets.
+	ContentID string
+	// TransferEncoding is the lower case Content-Transfer-Encoding.
+	TransferEncoding string
+	// Body is the body with the transfer encoding decoded. For multipart
+	// and message parts, it is the encoded body of the children.
+	Body []byte
+	// Parts are the children of multipart parts, or the message of
+	// message/rfc822 parts.
+	Parts []*Part
+}
+
+// Parse parses the source of a message.
+func Parse(source []byte) *Part {
+	return parse(source, "1", "text/plain", 0)
+}
+
+func parse(raw []byte, path, defaultType string, depth int) *Part {
+	headerBlock, body := split(raw)
+	h := header.Parse(string(headerBlock))
+	p := &Part{Path: path, Header: h, ContentType: defaultType, Params: map[string]string{}}
+
+	if mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type")); err == nil {
+		p.ContentType = mediaType
+		p.Params = params
+	} else if mediaType, _, _ := strings.Cut(h.Get("Content-Type"), ";"); strings.Contains(mediaType, "/") {
+		// Keep the media type of a header with malformed parameters
+		p.ContentType = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	if disposition, params, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		p.Disposition = disposition
+		p.Filename = params["filename"]
+	}
+	if p.Filename == "" {
+		p.Filename = p.Params["name"]
+	}
+	p.Filename = header.Decode(p.Filename)
+	if ids := header.MessageIDs(h.Get("Content-Id")); len(ids) > 0 {
+		p.ContentID = ids[0]
+	}
+	p.TransferEncoding = strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding")))
+
+	switch {
+	case depth >= maxDepth:
+		p.Body = body
+	case strings.HasPrefix(p.ContentType, "multipart/") && p.Params["boundary"] != "":
+		p.Body = body
+		childType := "text/plain"
+		if p.ContentType == "multipart/digest" {
+			childType = "message/rfc822"
+		}
+		for i, child := range splitMultipart(body, p.Params["boundary"]) {
+			p.Parts = append(p.Parts, parse(child, path+"."+strconv.Itoa(i+1), childType, depth+1))
+		}
+	case p.ContentType == "message/rfc822" || p.ContentType == "message/global":
+		p.Body = decodeTransfer(body, p.TransferEncoding)
+		p.Parts = []*Part{parse(p.Body, path+".1", "text/plain", depth+1)}
+	default:
+		p.Body = decodeTransfer(body, p.TransferEncoding)
+	}
+	return p
+}
+
+// split splits an entity into its header block and body at the first empty
+// line.
+func split(raw []byte) ([]byte, []byte) {
+	if bytes.HasPrefix(raw, []byte("\r\n")) {
+		return nil, raw[2:]
+	}
+	if bytes.HasPrefix(raw, []byte("\n")) {
+		return nil, raw[1:]
+	}
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\n' {
+			continue
+		}
+		rest := raw[i+1:]
+		if bytes.HasPrefix(rest, []byte("\r\n")) {
+			return raw[:i+1], rest[2:]
+		}
+		if bytes.HasPrefix(rest, []byte("\n")) {
+			return raw[:i+1], rest[1:]
+		}
+	}
+	return raw, nil
+}
+
+// splitMultipart returns the bodies of the parts of a multipart body. The
+// preamble and epilogue are dropped. A missing close delimiter ends the last
+// part at the end of the body.
+func splitMultipart(body []byte, boundary string) [][]byte {
+	delimiter := []byte("--" + boundary)
+	var parts [][]byte
+	start := -1
+	for pos := 0; pos < len(body); {
+		end := bytes.IndexByte(body[pos:], '\n')
+		next := len(body)
+		if end >= 0 {
+			next = pos + end + 1
+		}
+		line := bytes.TrimRight(body[pos:next], " \t\r\n")
+		if bytes.HasPrefix(line, delimiter) {
+			rest := line[len(delimiter):]
+			closing := bytes.Equal(rest, []byte("--"))
+			if closing || len(rest) == 0 {
+				if start >= 0 {
+					parts = append(parts, trimLineBreak(body[start:pos]))
+				}
+				if closing {
+					return parts
+				}
+				start = next
+			}
+		}
+		pos = next
+	}
+	if start >= 0 && start < len(body) {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
+
+// trimLineBreak removes the line break before a delimiter, which belongs to
+// the delimiter.
+func trimLineBreak(b []byte) []byte {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	return bytes.TrimSuffix(b, []byte("\r"))
+}
+
+// decodeTransfer decodes a body with its Content-Transfer-Encoding. Bodies
+// that cannot be decoded completely keep what was decoded.
+func decodeTransfer(body []byte, encoding string) []byte {
+	switch encoding {
+	case "base64":
+		clean := bytes.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '/' {
+				return r
+			}
+			return -1
+		}, body)
+		// Padding is optional, as some clients omit it. A single remaining
+		// character cannot be decoded.
+		if len(clean)%4 == 1 {
+			clean = clean[:len(clean)-1]
+		}
+		decoded, _ := base64.RawStdEncoding.DecodeString(string(clean))
+		return decoded
+	case "quoted-printable":
+		decoded, _ := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		return decoded
+	default:
+		return body
+	}
+}
+
+// IsMultipart reports whether the part is a multipart part.
+func (p *Part) IsMultipart() bool {
+	return strings.HasPrefix(p.ContentType, "multipart/")
+}
+
+// IsAttachment reports whether the part is an attachment rather than part
+// of the body of the message.
+func (p *Part) IsAttachment() bool {
+	return p.Disposition == "attachment" || p.Disposition != "inline" && p.Filename != "" && !strings.HasPrefix(p.ContentType, "text/")
+}
+
+// Charset is the lower case charset of the part, or "" if there is none.
+func (p *Part) Charset() string {
+	return strings.ToLower(p.Params["charset"])
+}
+
+// Text returns the body decoded from its charset. Bodies without or with an
+// unknown charset are assumed to be UTF-8, invalid bytes are replaced.
+func (p *Part) Text() string {
+	if charset := p.Charset(); charset != "" && charset != "utf-8" && charset != "us-ascii" {
+		if enc, err := htmlindex.Get(charset); err == nil {
+			if decoded, err := enc.NewDecoder().Bytes(p.Body); err == nil {
+				return string(decoded)
+			}
+		}
+	}
+	if utf8.Valid(p.Body) {
+		return string(p.Body)
+	}
+	return strings.ToValidUTF8(string(p.Body), "�")
+}
+
+// Walk calls fn for the part and its descendants in depth-first order.
+func (p *Part) Walk(fn func(p *Part)) {
+	fn(p)
+	for _, c := range p.Parts {
+		c.Walk(fn)
+	}
+}
+
+// Find returns the part with the Content-ID, or nil.
+func (p *Part) Find(contentID string) *Part {
+	var found *Part
+	p.Walk(func(c *Part) {
+		if found == nil && c.ContentID != "" && c.ContentID == contentID {
+			found = c
+		}
+	})
+	return found
+}
+
+// Bodies returns the text/plain and text/html bodies of the message. Parts
+// of multipart/alternative parts are preferred by their kind, text parts
+// that follow each other in other multipart parts are joined. Attachments,
+// including attached messages, are skipped.
+func (p *Part) Bodies() (plain, html string) {
+	var plains, htmls []string
+	var collect func(p *Part)
+	collect = func(p *Part) {
+		switch {
+		case p.IsAttachment():
+		case p.IsMultipart():
+			for _, c := range p.Parts {
+				collect(c)
+			}
+		case p.ContentType == "text/plain":
+			plains = append(plains, p.Text())
+		case p.ContentType == "text/html":
+			htmls = append(htmls, p.Text())
+		}
+	}
+	collect(p)
+	return strings.Join(plains, "\n"), strings.Join(htmls, "\n")
+}
+
+// cidReference matches the content ID of a cid: URL in HTML, see RFC 2392.
+var cidReference = regexp.MustCompile(`(?i)\bcid:([^"'\s<>)]+)`)
+
+// CIDReferences returns the content IDs of the cid: URLs of an HTML body, in
+// order of first occurrence.
+func CIDReferences(html string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, m := range cidReference.FindAllStringSubmatch(html, -1) {
+		// Content IDs in URLs are URL encoded
+		id := m[1]
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,243 @@
+package mimepart
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newsletter is a message with an HTML body, a plain text alternative, an
+// inline image and an attached PDF.
+const newsletter = "From: Shop <news@shop.example.com>\r\n" +
+	"Subject: =?UTF-8?Q?Your_invoice_=E2=82=AC42?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"This is a multi-part message in MIME format.\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/related; boundary=\"related\"; type=\"multipart/alternative\"\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=alt\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Total: 42 =80, gr=FC=DFe=\r\n" +
+	" from the shop\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+VG90YWw6IDxiPjQyIOKCrDwvYj48L3A+PGltZyBzcmM9ImNpZDpsb2dvQHNob3AiPg==\r\n" +
+	"--alt--\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png; name=\"logo.png\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-ID: <logo@shop>\r\n" +
+	"Content-Disposition: inline; filename=\"logo.png\"\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo\r\n" +
+	"--related--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"=?UTF-8?Q?Rechnung_M=C3=A4rz.pdf?=\"\r\n" +
+	"Content-Disposition: attachment; filename*=UTF-8''Rechnung%20M%C3%A4rz.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n" +
+	"Epilogue\r\n"
+
+// forward is a message with a forwarded message attached, with bare line
+// feeds as Mail stores them.
+const forward = "Subject: Fwd: Hello\n" +
+	"Content-Type: multipart/mixed; boundary=b1\n" +
+	"\n" +
+	"--b1\n" +
+	"Content-Type: text/plain\n" +
+	"\n" +
+	"See below.\n" +
+	"--b1\n" +
+	"Content-Type: message/rfc822\n" +
+	"\n" +
+	"Subject: Hello\n" +
+	"Content-Type: text/html; charset=windows-1252\n" +
+	"\n" +
+	"<p>Caf\xe9</p>\n" +
+	"--b1\n" +
+	"Content-Type: text/plain; charset=utf-8\n" +
+	"\n" +
+	"Second text part.\n" +
+	"--b1--\n"
+
+// tree renders the parts as "path type" lines.
+func tree(p *Part) []string {
+	var lines []string
+	p.Walk(func(p *Part) {
+		lines = append(lines, p.Path+" "+p.ContentType)
+	})
+	return lines
+}
+
+func TestParse(t *testing.T) {
+	root := Parse([]byte(newsletter))
+	want := []string{
+		"1 multipart/mixed",
+		"1.1 multipart/related",
+		"1.1.1 multipart/alternative",
+		"1.1.1.1 text/plain",
+		"1.1.1.2 text/html",
+		"1.1.2 image/png",
+		"1.2 application/pdf",
+	}
+	if got := tree(root); !slices.Equal(got, want) {
+		t.Fatalf("tree = %q, want %q", got, want)
+	}
+	if got := root.Header.Get("Subject"); got != "Your invoice €42" {
+		t.Errorf("Subject = %q", got)
+	}
+
+	plain := root.Parts[0].Parts[0].Parts[0]
+	if plain.Charset() != "iso-8859-1" || plain.TransferEncoding != "quoted-printable" {
+		t.Errorf("plain part = %+v", plain)
+	}
+	if got := plain.Text(); got != "Total: 42 €, grüße from the shop" {
+		t.Errorf("plain Text() = %q", got)
+	}
+
+	image := root.Parts[0].Parts[1]
+	if image.ContentID != "logo@shop" || image.Disposition != "inline" || image.Filename != "logo.png" || image.IsAttachment() {
+		t.Errorf("image part = %+v", image)
+	}
+	if got := string(image.Body); got != "\x89PNG\r\n\x1a\n" {
+		t.Errorf("image Body = %q, want the PNG signature", got)
+	}
+	if root.Find("logo@shop") != image || root.Find("missing") != nil {
+		t.Error("Find() did not return the image part")
+	}
+
+	pdf := root.Parts[1]
+	if pdf.Filename != "Rechnung März.pdf" || !pdf.IsAttachment() || string(pdf.Body) != "%PDF-1.4\n" {
+		t.Errorf("pdf part = %+v", pdf)
+	}
+}
+
+func TestParse_Forward(t *testing.T) {
+	root := Parse([]byte(forward))
+	want := []string{
+		"1 multipart/mixed",
+		"1.1 text/plain",
+		"1.2 message/rfc822",
+		"1.2.1 text/html",
+		"1.3 text/plain",
+	}
+	if got := tree(root); !slices.Equal(got, want) {
+		t.Fatalf("tree = %q, want %q", got, want)
+	}
+	attached := root.Parts[1].Parts[0]
+	if got := attached.Header.Get("Subject"); got != "Hello" {
+		t.Errorf("attached Subject = %q", got)
+	}
+	if got := attached.Text(); got != "<p>Café</p>" {
+		t.Errorf("attached Text() = %q", got)
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+		body   string
+	}{
+		{
+			name:   "no header",
+			source: "\nJust text",
+			want:   []string{"1 text/plain"},
+			body:   "Just text",
+		},
+		{
+			name:   "no body",
+			source: "Subject: empty\n",
+			want:   []string{"1 text/plain"},
+		},
+		{
+			name:   "missing close delimiter",
+			source: "Content-Type: multipart/mixed; boundary=x\n\n--x\n\nfirst\n--x\n\nsecond\n",
+			want:   []string{"1 multipart/mixed", "1.1 text/plain", "1.2 text/plain"},
+		},
+		{
+			name:   "multipart without boundary",
+			source: "Content-Type: multipart/mixed\n\n--x\n\ntext\n",
+			want:   []string{"1 multipart/mixed"},
+			body:   "--x\n\ntext\n",
+		},
+		{
+			name:   "malformed parameters",
+			source: "Content-Type: text/HTML; charset\n\n<p>hi</p>",
+			want:   []string{"1 text/html"},
+			body:   "<p>hi</p>",
+		},
+		{
+			name:   "base64 without padding and with garbage",
+			source: "Content-Transfer-Encoding: base64\n\nSGVs*bG8\n",
+			want:   []string{"1 text/plain"},
+			body:   "Hello",
+		},
+		{
+			name:   "invalid quoted-printable escapes are kept",
+			source: "Content-Transfer-Encoding: Quoted-Printable\n\nok =ZZ rest\n",
+			want:   []string{"1 text/plain"},
+			body:   "ok =ZZ rest\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := Parse([]byte(tt.source))
+			if got := tree(root); !slices.Equal(got, tt.want) {
+				t.Errorf("tree = %q, want %q", got, tt.want)
+			}
+			if len(root.Parts) == 0 && string(root.Body) != tt.body {
+				t.Errorf("Body = %q, want %q", root.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestParse_Depth(t *testing.T) {
+	source := "Content-Type: message/rfc822\n\n"
+	nested := strings.Repeat(source, maxDepth+5) + "text"
+	depth := 0
+	Parse([]byte(nested)).Walk(func(p *Part) { depth++ })
+	if depth != maxDepth+1 {
+		t.Errorf("parsed %d parts, want %d", depth, maxDepth+1)
+	}
+}
+
+func TestPart_Bodies(t *testing.T) {
+	plain, html := Parse([]byte(newsletter)).Bodies()
+	if plain != "Total: 42 €, grüße from the shop" {
+		t.Errorf("plain = %q", plain)
+	}
+	if html != `<p>Total: <b>42 €</b></p><img src="cid:logo@shop">` {
+		t.Errorf("html = %q", html)
+	}
+
+	// The attached message is not part of the body
+	plain, html = Parse([]byte(forward)).Bodies()
+	if plain != "See below.\nSecond text part." || html != "" {
+		t.Errorf("Bodies() = %q, %q", plain, html)
+	}
+}
+
+func TestCIDReferences(t *testing.T) {
+	html := `<img src="cid:logo@shop"><img src='CID:a%40b'><div style="background:url(cid:bg)"></div><img src="cid:logo@shop">`
+	if got := CIDReferences(html); !slices.Equal(got, []string{"logo@shop", "a@b", "bg"}) {
+		t.Errorf("CIDReferences() = %q", got)
+	}
+	if got := CIDReferences("<p>no images</p>"); got != nil {
+		t.Errorf("CIDReferences() = %q, want none", got)
+	}
+}
@@ -3,6 +3,7 @@ package opts
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/opts/typed_flags"
 	"github.com/dastrobu/mail-mcp/internal/tools"
@@ -18,6 +19,7 @@ type Options struct {
 	Launchd    LaunchdCmd    `command:"launchd" description:"Manage launchd service"`
 	Completion CompletionCmd `command:"completion" description:"Generate completion scripts"`
 	Tool       ToolCmd       `command:"tool" description:"Execute a tool directly"`
+	Index      IndexCmd      `command:"index" description:"Manage the local full-text search index"`
 }
 
 // RunCmd defines the 'run' command
@@ -27,6 +29,22 @@ type RunCmd struct {
 	Host      string                `long:"host" env:"APPLE_MAIL_MCP_HOST" description:"HTTP host (only used with --transport=http)" default:"localhost"`
 	Debug     bool                  `long:"debug" env:"APPLE_MAIL_MCP_DEBUG" description:"Enable debug logging of tool calls and results to stderr"`
 
+	Backend    typed_flags.Backend `long:"backend" env:"APPLE_MAIL_MCP_BACKEND" description:"Backend executing the tools: jxa (Mail.app) or sim (in-memory simulator)" default:"jxa"`
+	SimFixture string              `long:"sim-fixture" env:"APPLE_MAIL_MCP_SIM_FIXTURE" description:"JSON or YAML fixture seeding the simulator (only used with --backend=sim, defaults to built-in demo data)"`
+
+	JXAWorker  bool          `long:"jxa-worker" env:"APPLE_MAIL_MCP_JXA_WORKER" description:"Run scripts in a persistent osascript worker instead of one osascript process per call (only used with --backend=jxa)"`
+	JXATimeout time.Duration `long:"jxa-timeout" env:"APPLE_MAIL_MCP_JXA_TIMEOUT" description:"Maximum run time of a single script in the worker before it is restarted (only used with --jxa-worker)" default:"2m"`
+
+	TimeZone string `long:"time-zone" env:"APPLE_MAIL_MCP_TIME_ZONE" description:"IANA time zone of date filters like 'yesterday' or '2024-05-01', e.g. Europe/Berlin (defaults to the system time zone)"`
+
+	Index         bool          `long:"index" env:"APPLE_MAIL_MCP_INDEX" description:"Maintain a local full-text search index of all mailboxes and provide the search_index tool"`
+	IndexDir      string        `long:"index-dir" env:"APPLE_MAIL_MCP_INDEX_DIR" description:"Directory of the search index (defaults to ~/Library/Caches/com.github.dastrobu.mail-mcp/index)"`
+	IndexInterval time.Duration `long:"index-interval" env:"APPLE_MAIL_MCP_INDEX_INTERVAL" description:"Time between syncs of the search index with Mail.app (only used with --index)" default:"15m"`
+
+	ExportDir string `long:"export-dir" env:"APPLE_MAIL_MCP_EXPORT_DIR" description:"Directory the save_attachments tool saves files in; the tool is only provided if it is set"`
+
+	AttachmentDirs []string `long:"attachment-dir" env:"APPLE_MAIL_MCP_ATTACHMENT_DIRS" env-delim:":" description:"Directory outgoing messages may attach files from, can be specified multiple times; attaching files is refused without"`
+
 	Handler func() error
 }
 
@@ -71,6 +89,11 @@ type LaunchdCreateCmd struct {
 	Port  int    `long:"port" description:"HTTP port for the service" default:"8787"`
 	Host  string `long:"host" description:"HTTP host for the service" default:"localhost"`
 	Debug bool   `long:"debug" description:"Enable debug logging for the service"`
+	Index bool   `long:"index" description:"Maintain the local full-text search index in the service"`
+
+	ExportDir string `long:"export-dir" description:"Directory the save_attachments tool of the service saves files in"`
+
+	AttachmentDirs []string `long:"attachment-dir" description:"Directory outgoing messages of the service may attach files from, can be specified multiple times"`
 
 	Handler func() error
 }
@@ -109,11 +132,50 @@ func (c *LaunchdRestartCmd) Execute(args []string) error {
 	return nil
 }
 
+// IndexCmd holds index subcommands
+type IndexCmd struct {
+	Rebuild IndexRebuildCmd `command:"rebuild" description:"Rebuild the search index from all mailboxes"`
+	Status  IndexStatusCmd  `command:"status" description:"Show the content of the search index"`
+}
+
+// IndexRebuildCmd represents the 'index rebuild' command
+type IndexRebuildCmd struct {
+	Dir string `long:"dir" env:"APPLE_MAIL_MCP_INDEX_DIR" description:"Directory of the search index (defaults to ~/Library/Caches/com.github.dastrobu.mail-mcp/index)"`
+
+	Handler func() error
+}
+
+// Execute runs the index rebuild command
+func (c *IndexRebuildCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler()
+	}
+	return nil
+}
+
+// IndexStatusCmd represents the 'index status' command
+type IndexStatusCmd struct {
+	Dir string `long:"dir" env:"APPLE_MAIL_MCP_INDEX_DIR" description:"Directory of the search index (defaults to ~/Library/Caches/com.github.dastrobu.mail-mcp/index)"`
+
+	Handler func() error
+}
+
+// Execute runs the index status command
+func (c *IndexStatusCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler()
+	}
+	return nil
+}
+
 // ToolCmd holds tool subcommands
 type ToolCmd struct {
 	ListAccounts           ListAccountsCmd           `command:"list_accounts" description:"Lists all configured email accounts"`
 	ListMailboxes          ListMailboxesCmd          `command:"list_mailboxes" description:"Lists mailboxes for a specific account"`
 	GetMessageContent      GetMessageContentCmd      `command:"get_message_content" description:"Retrieves the full content of a specific message"`
+	GetThread              GetThreadCmd              `command:"get_thread" description:"Retrieves the whole conversation of a message"`
+	GetMessageSource       GetMessageSourceCmd       `command:"get_message_source" description:"Retrieves the MIME structure and HTML body of a message"`
+	GetAttachmentText      GetAttachmentTextCmd      `command:"get_attachment_text" description:"Extracts the text of an attachment"`
 	GetSelectedMessages    GetSelectedMessagesCmd    `command:"get_selected_messages" description:"Gets the currently selected message(s)"`
 	CreateReply            CreateReplyCmd            `command:"create_reply" description:"Creates a reply to a specific message"`
 	ReplaceReply           ReplaceReplyCmd           `command:"replace_reply" description:"Replaces an existing reply"`
@@ -124,6 +186,13 @@ type ToolCmd struct {
 	ReplaceOutgoingMessage ReplaceOutgoingMessageCmd `command:"replace_outgoing_message" description:"Replaces an existing outgoing message"`
 	DeleteOutgoingMessage  DeleteOutgoingMessageCmd  `command:"delete_outgoing_message" description:"Deletes an outgoing message"`
 	FindMessages           FindMessagesCmd           `command:"find_messages" description:"Find messages in a mailbox"`
+	SearchIndex            SearchIndexCmd            `command:"search_index" description:"Full-text search of the local message index"`
+	SaveAttachments        SaveAttachmentsCmd        `command:"save_attachments" description:"Saves attachments of a message to the export directory"`
+	MoveMessages           MoveMessagesCmd           `command:"move_messages" description:"Moves messages to another mailbox"`
+	CopyMessages           CopyMessagesCmd           `command:"copy_messages" description:"Copies messages to another mailbox"`
+	ArchiveMessages        ArchiveMessagesCmd        `command:"archive_messages" description:"Moves messages to the archive mailbox"`
+	UpdateMessages         UpdateMessagesCmd         `command:"update_messages" description:"Sets the read, flagged, junk or colour status of messages"`
+	TrashMessages          TrashMessagesCmd          `command:"trash_messages" description:"Moves messages to the trash or deletes them permanently"`
 }
 
 // ListAccountsCmd represents the 'tool list_accounts' command
@@ -168,6 +237,48 @@ func (c *GetMessageContentCmd) Execute(args []string) error {
 	return nil
 }
 
+// GetThreadCmd represents the 'tool get_thread' command
+type GetThreadCmd struct {
+	tools.GetThreadInput
+	Handler func(tools.GetThreadInput) error
+}
+
+// Execute runs the get_thread tool command
+func (c *GetThreadCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.GetThreadInput)
+	}
+	return nil
+}
+
+// GetMessageSourceCmd represents the 'tool get_message_source' command
+type GetMessageSourceCmd struct {
+	tools.GetMessageSourceInput
+	Handler func(tools.GetMessageSourceInput) error
+}
+
+// Execute runs the get_message_source tool command
+func (c *GetMessageSourceCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.GetMessageSourceInput)
+	}
+	return nil
+}
+
+// GetAttachmentTextCmd represents the 'tool get_attachment_text' command
+type GetAttachmentTextCmd struct {
+	tools.GetAttachmentTextInput
+	Handler func(tools.GetAttachmentTextInput) error
+}
+
+// Execute runs the get_attachment_text tool command
+func (c *GetAttachmentTextCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.GetAttachmentTextInput)
+	}
+	return nil
+}
+
 // GetSelectedMessagesCmd represents the 'tool get_selected_messages' command
 type GetSelectedMessagesCmd struct {
 	tools.GetSelectedMessagesInput
@@ -241,7 +352,8 @@ func (c *DeleteDraftCmd) Execute(args []string) error {
 // CreateOutgoingMessageCmd represents the 'tool create_outgoing_message' command
 type CreateOutgoingMessageCmd struct {
 	tools.CreateOutgoingMessageInput
-	Handler func(tools.CreateOutgoingMessageInput) error
+	AttachmentDirs []string `long:"attachment-dir" env:"APPLE_MAIL_MCP_ATTACHMENT_DIRS" env-delim:":" description:"Directory files may be attached from, can be specified multiple times"`
+	Handler        func(tools.CreateOutgoingMessageInput) error
 }
 
 // Execute runs the create_outgoing_message tool command
@@ -268,7 +380,8 @@ func (c *ListOutgoingMessagesCmd) Execute(args []string) error {
 // ReplaceOutgoingMessageCmd represents the 'tool replace_outgoing_message' command
 type ReplaceOutgoingMessageCmd struct {
 	tools.ReplaceOutgoingMessageInput
-	Handler func(tools.ReplaceOutgoingMessageInput) error
+	AttachmentDirs []string `long:"attachment-dir" env:"APPLE_MAIL_MCP_ATTACHMENT_DIRS" env-delim:":" description:"Directory files may be attached from, can be specified multiple times"`
+	Handler        func(tools.ReplaceOutgoingMessageInput) error
 }
 
 // Execute runs the replace_outgoing_message tool command
@@ -293,6 +406,36 @@ func (c *FindMessagesCmd) Execute(args []string) error {
 	return nil
 }
 
+// SearchIndexCmd represents the 'tool search_index' command
+type SearchIndexCmd struct {
+	tools.SearchIndexInput
+	IndexDir string `long:"index-dir" env:"APPLE_MAIL_MCP_INDEX_DIR" description:"Directory of the search index (defaults to ~/Library/Caches/com.github.dastrobu.mail-mcp/index)"`
+	Handler  func(tools.SearchIndexInput) error
+}
+
+// Execute runs the search_index tool command
+func (c *SearchIndexCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.SearchIndexInput)
+	}
+	return nil
+}
+
+// SaveAttachmentsCmd represents the 'tool save_attachments' command
+type SaveAttachmentsCmd struct {
+	tools.SaveAttachmentsInput
+	ExportDir string `long:"export-dir" env:"APPLE_MAIL_MCP_EXPORT_DIR" description:"Directory to save the files in" required:"true"`
+	Handler   func(tools.SaveAttachmentsInput) error
+}
+
+// Execute runs the save_attachments tool command
+func (c *SaveAttachmentsCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.SaveAttachmentsInput)
+	}
+	return nil
+}
+
 // DeleteOutgoingMessageCmd represents the 'tool delete_outgoing_message' command
 type DeleteOutgoingMessageCmd struct {
 	tools.DeleteOutgoingMessageInput
@@ -307,6 +450,76 @@ func (c *DeleteOutgoingMessageCmd) Execute(args []string) error {
 	return nil
 }
 
+// MoveMessagesCmd represents the 'tool move_messages' command
+type MoveMessagesCmd struct {
+	tools.MoveMessagesInput
+	Handler func(tools.MoveMessagesInput) error
+}
+
+// Execute runs the move_messages tool command
+func (c *MoveMessagesCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.MoveMessagesInput)
+	}
+	return nil
+}
+
+// CopyMessagesCmd represents the 'tool copy_messages' command
+type CopyMessagesCmd struct {
+	tools.CopyMessagesInput
+	Handler func(tools.CopyMessagesInput) error
+}
+
+// Execute runs the copy_messages tool command
+func (c *CopyMessagesCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.CopyMessagesInput)
+	}
+	return nil
+}
+
+// ArchiveMessagesCmd represents the 'tool archive_messages' command
+type ArchiveMessagesCmd struct {
+	tools.ArchiveMessagesInput
+	Handler func(tools.ArchiveMessagesInput) error
+}
+
+// Execute runs the archive_messages tool command
+func (c *ArchiveMessagesCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.ArchiveMessagesInput)
+	}
+	return nil
+}
+
+// UpdateMessagesCmd represents the 'tool update_messages' command
+type UpdateMessagesCmd struct {
+	tools.UpdateMessagesInput
+	Handler func(tools.UpdateMessagesInput) error
+}
+
+// Execute runs the update_messages tool command
+func (c *UpdateMessagesCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.UpdateMessagesInput)
+	}
+	return nil
+}
+
+// TrashMessagesCmd represents the 'tool trash_messages' command
+type TrashMessagesCmd struct {
+	tools.TrashMessagesInput
+	Handler func(tools.TrashMessagesInput) error
+}
+
+// Execute runs the trash_messages tool command
+func (c *TrashMessagesCmd) Execute(args []string) error {
+	if c.Handler != nil {
+		return c.Handler(c.TrashMessagesInput)
+	}
+	return nil
+}
+
 var GlobalOpts = Options{}
 
 // Parse parses command-line arguments and environment variables
@@ -3,6 +3,7 @@ package opts
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/opts/typed_flags"
 )
@@ -237,3 +238,22 @@ func TestParse_FlagsOverrideEnvironment(t *testing.T) {
 		t.Errorf("Expected port 6000 from flag, got %d", GlobalOpts.Run.Port)
 	}
 }
+
+func TestParse_JXAWorker(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"mail-mcp", "run", "--jxa-worker", "--jxa-timeout=30s"}
+
+	_, err := Parse()
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if !GlobalOpts.Run.JXAWorker {
+		t.Error("Expected JXA worker to be enabled")
+	}
+	if GlobalOpts.Run.JXATimeout != 30*time.Second {
+		t.Errorf("Expected JXA timeout 30s, got %v", GlobalOpts.Run.JXATimeout)
+	}
+}
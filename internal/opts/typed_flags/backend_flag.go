@@ -0,0 +1,50 @@
+package typed_flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type Backend string
+
+const (
+	BackendJXA Backend = "jxa"
+	BackendSim Backend = "sim"
+)
+
+var BackendValues = []Backend{
+	BackendJXA,
+	BackendSim,
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ flags.Completer   = (*Backend)(nil)
+	_ flags.Unmarshaler = (*Backend)(nil)
+)
+
+func (t *Backend) Complete(match string) (completions []flags.Completion) {
+	for _, v := range BackendValues {
+		val := string(v)
+		if match == "" || strings.HasPrefix(val, strings.ToLower(match)) {
+			completions = append(completions, flags.Completion{
+				Item:        val,
+				Description: "",
+			})
+		}
+	}
+	return
+}
+
+// UnmarshalFlag validates the value is one of the allowed values.
+func (t *Backend) UnmarshalFlag(value string) error {
+	for _, v := range BackendValues {
+		if string(v) == value {
+			*t = v
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid backend: %s (valid: %v)", value, BackendValues)
+}
@@ -0,0 +1,95 @@
+package typed_flags
+
+import (
+	"testing"
+)
+
+func TestBackend_UnmarshalFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Backend
+		wantErr bool
+	}{
+		{
+			name:    "valid jxa",
+			value:   "jxa",
+			want:    BackendJXA,
+			wantErr: false,
+		},
+		{
+			name:    "valid sim",
+			value:   "sim",
+			want:    BackendSim,
+			wantErr: false,
+		},
+		{
+			name:    "invalid backend",
+			value:   "invalid",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "empty string",
+			value:   "",
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backend Backend
+			err := backend.UnmarshalFlag(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalFlag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && backend != tt.want {
+				t.Errorf("UnmarshalFlag() got = %v, want %v", backend, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_Complete(t *testing.T) {
+	tests := []struct {
+		name      string
+		match     string
+		wantItems []string
+	}{
+		{
+			name:      "empty match returns all",
+			match:     "",
+			wantItems: []string{"jxa", "sim"},
+		},
+		{
+			name:      "match sim",
+			match:     "s",
+			wantItems: []string{"sim"},
+		},
+		{
+			name:      "no match",
+			match:     "xyz",
+			wantItems: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backend Backend
+			completions := backend.Complete(tt.match)
+
+			if len(completions) != len(tt.wantItems) {
+				t.Fatalf("Complete() returned %d completions, want %d", len(completions), len(tt.wantItems))
+			}
+			for i, want := range tt.wantItems {
+				if completions[i].Item != want {
+					t.Errorf("Complete()[%d].Item = %v, want %v", i, completions[i].Item, want)
+				}
+			}
+		})
+	}
+}
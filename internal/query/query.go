@@ -0,0 +1,337 @@
+// Package query parses Gmail-style search queries such as
+//
+//	from:alice is:unread has:attachment after:2024-05-01 subject:"quarterly report" -in:trash
+//
+// into the filters of the find_messages tool.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dastrobu/mail-mcp/internal/dates"
+)
+
+// Filter holds the find_messages filters set by a query. Zero values are
+// not set.
+type Filter struct {
+	Account        string
+	MailboxPath    []string
+	Exclude        []string
+	Subject        string
+	Sender         string
+	Recipient      string
+	Cc             string
+	Body           string
+	ReplyTo        string
+	AttachmentName string
+	ReadStatus     *bool
+	FlaggedOnly    bool
+	HasAttachments *bool
+	DateAfter      string
+	DateBefore     string
+	MinSize        int
+	MaxSize        int
+}
+
+// Kinds of query errors, wrapped by *Error.
+var (
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	ErrMissingValue      = errors.New("missing value")
+	ErrUnknownOperator   = errors.New("unknown operator")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrDuplicate         = errors.New("filter set more than once")
+	ErrNegation          = errors.New("operator cannot be negated")
+	ErrFreeText          = errors.New("free text is not supported")
+)
+
+// Error is an error at a term of a query.
+type Error struct {
+	Pos  int    // byte offset of the term in the query
+	Term string // the term as written in the query
+	Err  error  // one of the Err* kinds
+	Hint string // optional explanation
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%v at offset %d: %s", e.Err, e.Pos, e.Term)
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// term is a single, possibly negated, `operator:value` or free text term.
+type term struct {
+	pos      int
+	text     string
+	negated  bool
+	operator string
+	value    string
+}
+
+// operator applies a term to the filter.
+type operator func(f *Filter, t term) error
+
+var operators = map[string]operator{
+	"account":  text(func(f *Filter) *string { return &f.Account }),
+	"from":     text(func(f *Filter) *string { return &f.Sender }),
+	"to":       text(func(f *Filter) *string { return &f.Recipient }),
+	"cc":       text(func(f *Filter) *string { return &f.Cc }),
+	"subject":  text(func(f *Filter) *string { return &f.Subject }),
+	"body":     text(func(f *Filter) *string { return &f.Body }),
+	"replyto":  text(func(f *Filter) *string { return &f.ReplyTo }),
+	"reply-to": text(func(f *Filter) *string { return &f.ReplyTo }),
+	"filename": text(func(f *Filter) *string { return &f.AttachmentName }),
+	"in":       in,
+	"is":       is,
+	"has":      has,
+	"after":    date(func(f *Filter) *string { return &f.DateAfter }),
+	"newer":    date(func(f *Filter) *string { return &f.DateAfter }),
+	"before":   date(func(f *Filter) *string { return &f.DateBefore }),
+	"older":    date(func(f *Filter) *string { return &f.DateBefore }),
+	"larger":   size(func(f *Filter) *int { return &f.MinSize }),
+	"smaller":  size(func(f *Filter) *int { return &f.MaxSize }),
+}
+
+// Parse parses a query. Terms are separated by white space and combined
+// with AND. Values containing spaces are quoted, e.g. subject:"weekly sync".
+// Supported operators:
+//
+//	account:NAME        account to search
+//	in:PATH             mailbox to search, nested mailboxes separated by "/"
+//	-in:NAME            mailbox to skip, may be repeated
+//	from:TEXT           sender
+//	to:TEXT             To or Cc recipient address, as in Gmail
+//	cc:TEXT             Cc recipient address
+//	subject:TEXT        subject
+//	body:TEXT           body text
+//	replyto:TEXT        Reply-To address
+//	filename:TEXT       attachment file name, e.g. filename:pdf
+//	is:unread, is:read  read status, may be negated
+//	is:flagged          flagged messages (is:starred is an alias)
+//	has:attachment      messages with attachments, may be negated
+//	after:DATE          received after the date (newer: is an alias)
+//	before:DATE         received before the date (older: is an alias)
+//	larger:SIZE         at least SIZE bytes, e.g. larger:10K or larger:5M
+//	smaller:SIZE        at most SIZE bytes
+//
+// Dates are absolute, like 2024-05-01, or relative, like 7d, yesterday or
+// "last monday", see dates.Parse.
+func Parse(q string) (*Filter, error) {
+	terms, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{}
+	for _, t := range terms {
+		if t.operator == "" {
+			return nil, &Error{Pos: t.pos, Term: t.text, Err: ErrFreeText, Hint: "use subject: or body: to search text"}
+		}
+		apply, ok := operators[strings.ToLower(t.operator)]
+		if !ok {
+			return nil, &Error{Pos: t.pos, Term: t.text, Err: ErrUnknownOperator}
+		}
+		if t.value == "" {
+			return nil, &Error{Pos: t.pos, Term: t.text, Err: ErrMissingValue}
+		}
+		if err := apply(f, t); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// lex splits a query into terms.
+func lex(q string) ([]term, error) {
+	var terms []term
+	i := 0
+	for {
+		for i < len(q) && isSpace(q[i]) {
+			i++
+		}
+		if i == len(q) {
+			return terms, nil
+		}
+
+		t := term{pos: i}
+		if q[i] == '-' {
+			t.negated = true
+			i++
+		}
+		// An operator is a word followed by a colon
+		j := i
+		for j < len(q) && (isLetter(q[j]) || q[j] == '-') {
+			j++
+		}
+		if j > i && j < len(q) && q[j] == ':' {
+			t.operator = q[i:j]
+			i = j + 1
+		}
+
+		if i < len(q) && q[i] == '"' {
+			value, end, ok := unquote(q, i)
+			if !ok {
+				return nil, &Error{Pos: t.pos, Term: q[t.pos:], Err: ErrUnterminatedQuote}
+			}
+			t.value = value
+			i = end
+		} else {
+			j := i
+			for j < len(q) && !isSpace(q[j]) {
+				j++
+			}
+			t.value = q[i:j]
+			i = j
+		}
+		t.text = q[t.pos:i]
+		terms = append(terms, t)
+	}
+}
+
+// unquote reads the quoted string starting at q[start]. A backslash escapes
+// the next character. Returns the value and the offset after the closing
+// quote.
+func unquote(q string, start int) (string, int, bool) {
+	var b strings.Builder
+	for i := start + 1; i < len(q); i++ {
+		switch q[i] {
+		case '\\':
+			if i+1 < len(q) {
+				i++
+				b.WriteByte(q[i])
+			}
+		case '"':
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(q[i])
+		}
+	}
+	return "", 0, false
+}
+
+func isSpace(c byte) bool {
+	return c < 0x80 && unicode.IsSpace(rune(c))
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// text returns an operator setting a text filter.
+func text(field func(*Filter) *string) operator {
+	return func(f *Filter, t term) error {
+		if t.negated {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrNegation}
+		}
+		return set(field(f), t.value, t)
+	}
+}
+
+// set sets a filter that must not have been set by an earlier term.
+func set[T comparable](dst *T, v T, t term) error {
+	var zero T
+	if *dst != zero {
+		return &Error{Pos: t.pos, Term: t.text, Err: ErrDuplicate}
+	}
+	*dst = v
+	return nil
+}
+
+func in(f *Filter, t term) error {
+	if t.negated {
+		f.Exclude = append(f.Exclude, t.value)
+		return nil
+	}
+	if f.MailboxPath != nil {
+		return &Error{Pos: t.pos, Term: t.text, Err: ErrDuplicate}
+	}
+	path := strings.Split(t.value, "/")
+	for _, name := range path {
+		if name == "" {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "empty mailbox name"}
+		}
+	}
+	f.MailboxPath = path
+	return nil
+}
+
+func is(f *Filter, t term) error {
+	switch strings.ToLower(t.value) {
+	case "unread":
+		return setBool(&f.ReadStatus, t.negated, t)
+	case "read":
+		return setBool(&f.ReadStatus, !t.negated, t)
+	case "flagged", "starred":
+		if t.negated {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrNegation}
+		}
+		return set(&f.FlaggedOnly, true, t)
+	}
+	return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "valid: unread, read, flagged, starred"}
+}
+
+func has(f *Filter, t term) error {
+	switch strings.ToLower(t.value) {
+	case "attachment", "attachments":
+		return setBool(&f.HasAttachments, !t.negated, t)
+	}
+	return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "valid: attachment"}
+}
+
+func setBool(dst **bool, v bool, t term) error {
+	if *dst != nil {
+		return &Error{Pos: t.pos, Term: t.text, Err: ErrDuplicate}
+	}
+	*dst = &v
+	return nil
+}
+
+// date returns an operator setting a date filter. The value is validated
+// here but kept as written, since relative dates like "7d" are resolved when
+// the filter is applied.
+func date(field func(*Filter) *string) operator {
+	return func(f *Filter, t term) error {
+		if t.negated {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrNegation}
+		}
+		if _, err := dates.Parse(t.value, time.Now()); err != nil {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "use e.g. 2024-05-01, 7d, yesterday or \"last monday\""}
+		}
+		return set(field(f), t.value, t)
+	}
+}
+
+// sizeUnits are the multipliers of the size suffixes.
+var sizeUnits = map[byte]int{'k': 1 << 10, 'm': 1 << 20, 'g': 1 << 30}
+
+// size returns an operator setting a size filter in bytes.
+func size(field func(*Filter) *int) operator {
+	return func(f *Filter, t term) error {
+		if t.negated {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrNegation}
+		}
+		value, unit := strings.ToLower(t.value), 1
+		if u, ok := sizeUnits[value[len(value)-1]]; ok {
+			value, unit = value[:len(value)-1], u
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "use a positive number of bytes, optionally followed by K, M or G"}
+		}
+		if n > math.MaxInt/unit {
+			return &Error{Pos: t.pos, Term: t.text, Err: ErrInvalidValue, Hint: "the size is too large"}
+		}
+		return set(field(f), n*unit, t)
+	}
+}
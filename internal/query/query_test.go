@@ -0,0 +1,247 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  Filter
+	}{
+		{
+			name:  "example",
+			query: `from:alice is:unread has:attachment after:2024-05-01 subject:"quarterly report" -in:trash`,
+			want: Filter{
+				Sender:         "alice",
+				ReadStatus:     new(false),
+				HasAttachments: new(true),
+				DateAfter:      "2024-05-01",
+				Subject:        "quarterly report",
+				Exclude:        []string{"trash"},
+			},
+		},
+		{
+			name:  "recipient with attachment name",
+			query: "to:billing@ filename:pdf",
+			want:  Filter{Recipient: "billing@", AttachmentName: "pdf"},
+		},
+		{
+			name:  "to and cc",
+			query: "to:alice cc:bob",
+			want:  Filter{Recipient: "alice", Cc: "bob"},
+		},
+		{
+			name:  "mailbox and account",
+			query: `account:Work in:"Inbox/Team Updates"`,
+			want:  Filter{Account: "Work", MailboxPath: []string{"Inbox", "Team Updates"}},
+		},
+		{
+			name:  "several exclusions",
+			query: "-in:trash -in:spam is:flagged",
+			want:  Filter{Exclude: []string{"trash", "spam"}, FlaggedOnly: true},
+		},
+		{
+			name:  "negated read status",
+			query: "-is:unread",
+			want:  Filter{ReadStatus: new(true)},
+		},
+		{
+			name:  "without attachments",
+			query: "-has:attachment is:starred",
+			want:  Filter{HasAttachments: new(false), FlaggedOnly: true},
+		},
+		{
+			name:  "date aliases and formats",
+			query: "newer:2024/01/31 older:2024-02-01T12:30:00+02:00",
+			want:  Filter{DateAfter: "2024/01/31", DateBefore: "2024-02-01T12:30:00+02:00"},
+		},
+		{
+			name:  "relative dates",
+			query: `after:"last monday" before:yesterday`,
+			want:  Filter{DateAfter: "last monday", DateBefore: "yesterday"},
+		},
+		{
+			name:  "sizes",
+			query: "larger:10K smaller:2m",
+			want:  Filter{MinSize: 10 << 10, MaxSize: 2 << 20},
+		},
+		{
+			name:  "case-insensitive operators",
+			query: "FROM:Alice Is:Unread",
+			want:  Filter{Sender: "Alice", ReadStatus: new(false)},
+		},
+		{
+			name:  "escaped quote and extra space",
+			query: `  body:"say \"hi\""   replyto:list@ `,
+			want:  Filter{Body: `say "hi"`, ReplyTo: "list@"},
+		},
+		{
+			name:  "value with colon",
+			query: "subject:Re:hello",
+			want:  Filter{Subject: "Re:hello"},
+		},
+		{
+			name:  "empty",
+			query: "   ",
+			want:  Filter{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.query, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantErr  error
+		wantPos  int
+		wantTerm string
+	}{
+		{
+			name:     "unterminated quote",
+			query:    `from:alice subject:"quarterly`,
+			wantErr:  ErrUnterminatedQuote,
+			wantPos:  11,
+			wantTerm: `subject:"quarterly`,
+		},
+		{
+			name:     "missing value",
+			query:    "from: is:unread",
+			wantErr:  ErrMissingValue,
+			wantPos:  0,
+			wantTerm: "from:",
+		},
+		{
+			name:     "unknown operator",
+			query:    "is:unread label:work",
+			wantErr:  ErrUnknownOperator,
+			wantPos:  10,
+			wantTerm: "label:work",
+		},
+		{
+			name:     "free text",
+			query:    "from:alice report",
+			wantErr:  ErrFreeText,
+			wantPos:  11,
+			wantTerm: "report",
+		},
+		{
+			name:     "quoted free text",
+			query:    `"quarterly report"`,
+			wantErr:  ErrFreeText,
+			wantPos:  0,
+			wantTerm: `"quarterly report"`,
+		},
+		{
+			name:     "invalid is value",
+			query:    "is:important",
+			wantErr:  ErrInvalidValue,
+			wantPos:  0,
+			wantTerm: "is:important",
+		},
+		{
+			name:     "invalid has value",
+			query:    "has:drive",
+			wantErr:  ErrInvalidValue,
+			wantTerm: "has:drive",
+		},
+		{
+			name:     "invalid date",
+			query:    "after:someday",
+			wantErr:  ErrInvalidValue,
+			wantTerm: "after:someday",
+		},
+		{
+			name:     "invalid size",
+			query:    "larger:big",
+			wantErr:  ErrInvalidValue,
+			wantTerm: "larger:big",
+		},
+		{
+			name:     "size overflow",
+			query:    "from:alice smaller:9999999999999G",
+			wantErr:  ErrInvalidValue,
+			wantPos:  11,
+			wantTerm: "smaller:9999999999999G",
+		},
+		{
+			name:     "empty mailbox name",
+			query:    "in:Inbox//GitHub",
+			wantErr:  ErrInvalidValue,
+			wantTerm: "in:Inbox//GitHub",
+		},
+		{
+			name:     "duplicate operator",
+			query:    "from:alice from:bob",
+			wantErr:  ErrDuplicate,
+			wantPos:  11,
+			wantTerm: "from:bob",
+		},
+		{
+			name:     "contradicting read status",
+			query:    "is:read is:unread",
+			wantErr:  ErrDuplicate,
+			wantPos:  8,
+			wantTerm: "is:unread",
+		},
+		{
+			name:     "duplicate cc",
+			query:    "cc:alice cc:bob",
+			wantErr:  ErrDuplicate,
+			wantPos:  9,
+			wantTerm: "cc:bob",
+		},
+		{
+			name:     "negated text",
+			query:    "-from:alice",
+			wantErr:  ErrNegation,
+			wantPos:  0,
+			wantTerm: "-from:alice",
+		},
+		{
+			name:     "negated flag",
+			query:    "-is:flagged",
+			wantErr:  ErrNegation,
+			wantTerm: "-is:flagged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var qErr *Error
+			if !errors.As(err, &qErr) {
+				t.Fatalf("Parse(%q) error = %v, want *Error", tt.query, err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.query, err, tt.wantErr)
+			}
+			if qErr.Pos != tt.wantPos || qErr.Term != tt.wantTerm {
+				t.Errorf("Parse(%q) error at %d %q, want %d %q", tt.query, qErr.Pos, qErr.Term, tt.wantPos, tt.wantTerm)
+			}
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	err := &Error{Pos: 4, Term: "larger:big", Err: ErrInvalidValue, Hint: "use a number of bytes"}
+	want := "invalid value at offset 4: larger:big (use a number of bytes)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
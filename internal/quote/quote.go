@@ -0,0 +1,234 @@
+// Package quote splits the text of a message into the text the sender wrote,
+// the quoted history of earlier messages and the signature, with heuristics
+// for the conventions of common mail clients: lines quoted with ">",
+// attribution lines like "On Mon, Mar 3, 2025, Alex wrote:" in several
+// languages, the separators and header blocks of Outlook, signature
+// delimiters ("-- ") and the signatures of mobile clients.
+package quote
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Parts are the parts of the text of a message.
+type Parts struct {
+	// New is the text the sender wrote, without quoted lines.
+	New string
+	// Quoted is the quoted history: the lines quoted with ">" and everything
+	// after an attribution line or separator.
+	Quoted string
+	// Signature is the signature of the sender.
+	Signature string
+}
+
+// maxAttributionLines is the number of lines an attribution may be wrapped
+// to, e.g. by clients that wrap long sender addresses.
+const maxAttributionLines = 3
+
+// attributions match attribution lines that introduce a quoted message,
+// which may be wrapped to several lines joined by spaces.
+var attributions = []*regexp.Regexp{
+	// English: On Mon, Mar 3, 2025 at 9:14 AM Alex Smith <alex@example.com> wrote:
+	regexp.MustCompile(`(?i)^On\s.+\swrote:$`),
+	// German: Am 03.03.2025 um 09:14 schrieb Alex Smith <alex@example.com>:
+	regexp.MustCompile(`(?i)^Am\s.+\sschrieb\s.*:$`),
+	regexp.MustCompile(`(?i)^.+\sschrieb(\sam\s.+)?:$`),
+	// French: Le lun. 3 mars 2025 à 09:14, Alex Smith <alex@example.com> a écrit :
+	regexp.MustCompile(`(?i)^Le\s.+\sa\sécrit\s?:$`),
+	// Spanish: El lun, 3 mar 2025 a las 9:14, Alex Smith (<alex@example.com>) escribió:
+	regexp.MustCompile(`(?i)^El\s.+\sescribió\s?:$`),
+	// Italian: Il giorno lun 3 mar 2025 alle ore 09:14 Alex Smith <alex@example.com> ha scritto:
+	regexp.MustCompile(`(?i)^Il\s.+\sha\sscritto\s?:$`),
+	// Dutch: Op ma 3 mrt 2025 om 09:14 schreef Alex Smith <alex@example.com>:
+	regexp.MustCompile(`(?i)^Op\s.+\sschreef\s.*:$`),
+	// Portuguese: Em seg., 3 de mar. de 2025 às 09:14, Alex Smith <alex@example.com> escreveu:
+	regexp.MustCompile(`(?i)^Em\s.+\sescreveu\s?:$`),
+	// Swedish, Danish and Norwegian: Den 3 mars 2025 kl. 09:14 skrev Alex Smith <alex@example.com>:
+	regexp.MustCompile(`(?i)^.+\sskrev(\s.*)?:$`),
+	// Polish: W dniu 3.03.2025 o 09:14, Alex Smith <alex@example.com> pisze:
+	regexp.MustCompile(`(?i)^W\sdniu\s.+\s(pisze|napisał|napisała|napisał\(a\))\s?:$`),
+	// Russian: 3 марта 2025 г., в 09:14, Alex Smith <alex@example.com> написал(а):
+	regexp.MustCompile(`(?i)^.+\sнаписал(а|\(а\))?\s?:$`),
+	// Japanese and Chinese: 2025年3月3日(月) 9:14 Alex Smith <alex@example.com>:
+	regexp.MustCompile(`^\d{4}年\d{1,2}月\d{1,2}日.+[:：]$`),
+	regexp.MustCompile(`^.+(のメッセージ|写道|寫道)[:：]?$`),
+}
+
+// separators match lines that separate the new text from the quoted message
+// in Outlook and other clients.
+var separators = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^-{2,}\s*(Original Message|Ursprüngliche Nachricht|Message d'origine|Mensaje original|Messaggio originale|Oorspronkelijk bericht|Ursprungligt meddelande|Oprindelig meddelelse|Opprinnelig melding|Wiadomość oryginalna|Исходное сообщение)\s*-{2,}$`),
+	regexp.MustCompile(`^_{20,}$`),
+}
+
+// headerField matches a field of the header block Outlook inserts above a
+// quoted message, in several languages, e.g. "From: Alex Smith" or
+// "**Von:** Alex Smith" after conversion to Markdown.
+var headerField = regexp.MustCompile(`(?i)^\**(from|sent|date|to|cc|subject|von|gesendet|datum|an|betreff|de|envoyé|à|objet|enviado|para|asunto|da|inviato|a|oggetto|van|verzonden|aan|onderwerp|från|skickat|till|ämne|fra|sendt|til|emne|od|wysłano|do|temat|от|отправлено|кому|тема)\s?:\**\s`)
+
+// fromField matches the first field of an Outlook header block.
+var fromField = regexp.MustCompile(`(?i)^\**(from|von|de|da|van|från|fra|od|от)\s?:\**\s`)
+
+// signatureDelimiter matches the signature delimiter of RFC 3676, "-- ".
+// Many clients drop the trailing space.
+var signatureDelimiter = regexp.MustCompile(`^--\s?$`)
+
+// mobileSignatures match the signatures mobile clients append.
+var mobileSignatures = regexp.MustCompile(`(?i)^(Sent from my \w+|Sent from (Mail|Outlook|Yahoo Mail) for \w+|Get Outlook for (iOS|Android)|Von meinem \w+ gesendet|Gesendet von meinem \w+|Envoyé de mon \w+|Enviado desde mi \w+|Inviato da \w+|Verzonden vanaf mijn \w+|Skickat från min \w+)\b.*$`)
+
+// Split splits the text of a message into its parts. Lines are compared
+// without surrounding white space, the parts are trimmed.
+func Split(text string) Parts {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	start := quoteStart(lines)
+	var own, quoted []string
+	inQuote := false
+	for i, line := range lines[:start] {
+		// A line like "Alex wrote:" right above quoted lines introduces them
+		introducesQuote := strings.HasSuffix(strings.TrimSpace(line), ":") && i+1 < start && isQuoted(lines[i+1])
+		if !isQuoted(line) && !introducesQuote {
+			own = append(own, line)
+			inQuote = false
+			continue
+		}
+		if !inQuote {
+			// Separate the quotes of an inline reply
+			quoted = append(quoted, "")
+		}
+		quoted = append(quoted, line)
+		inQuote = true
+	}
+	quoted = append(quoted, lines[start:]...)
+
+	own, signature := splitSignature(own)
+	return Parts{
+		New:       join(own),
+		Quoted:    join(quoted),
+		Signature: join(signature),
+	}
+}
+
+// quoteStart returns the index of the line after which the rest of the text
+// is quoted: an attribution line, a separator or an Outlook header block. It
+// is the number of lines if there is none.
+func quoteStart(lines []string) int {
+	for i := range lines {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || isQuoted(line) {
+			continue
+		}
+		if isAttribution(lines[i:]) || isSeparator(line) || isHeaderBlock(lines[i:]) {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+func isQuoted(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " \t"), ">")
+}
+
+// isAttribution reports whether the lines start with an attribution line,
+// which may be wrapped. An attribution that starts on a later line does not
+// include the lines above it, e.g. a closing "Jane" above "Alex wrote:".
+func isAttribution(lines []string) bool {
+	var joined []string
+	for i := 0; i < len(lines) && i < maxAttributionLines; i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || isQuoted(line) {
+			return false
+		}
+		joined = append(joined, line)
+		if !matchesAttribution(strings.Join(joined, " ")) {
+			continue
+		}
+		for j := 1; j <= i; j++ {
+			if matchesAttribution(strings.Join(joined[j:], " ")) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func matchesAttribution(line string) bool {
+	for _, re := range attributions {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSeparator(line string) bool {
+	for _, re := range separators {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeaderBlock reports whether the lines start with a header block of
+// Outlook: a From field followed by at least two other fields.
+func isHeaderBlock(lines []string) bool {
+	if !fromField.MatchString(strings.TrimSpace(lines[0])) {
+		return false
+	}
+	fields := 0
+	for i := 1; i < len(lines) && i <= 5; i++ {
+		line := strings.TrimSpace(lines[i])
+		if headerField.MatchString(line) {
+			fields++
+		} else if line != "" {
+			break
+		}
+	}
+	return fields >= 2
+}
+
+// splitSignature splits the signature off the end of the lines: after the
+// last signature delimiter, or from the signature of a mobile client.
+func splitSignature(lines []string) (text, signature []string) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimRight(lines[i], "\r")
+		if signatureDelimiter.MatchString(line) {
+			return lines[:i], lines[i+1:]
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if mobileSignatures.MatchString(line) {
+			return lines[:i], lines[i:]
+		}
+		break
+	}
+	return lines, nil
+}
+
+// join joins lines, collapsing runs of empty lines and trimming empty lines
+// at the start and end.
+func join(lines []string) string {
+	var out []string
+	empty := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			empty = len(out) > 0
+			continue
+		}
+		if empty {
+			out = append(out, "")
+			empty = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
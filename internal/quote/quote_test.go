@@ -0,0 +1,78 @@
+package quote
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSplit_Corpus splits the messages in testdata. Each file starts with a
+// description, followed by the sections "=== input", "=== new",
+// "=== quoted" and "=== signature".
+func TestSplit_Corpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no corpus files in testdata")
+	}
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".txt"), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sections := parseSections(t, string(data))
+			got := Split(sections["input"])
+			want := Parts{
+				New:       sections["new"],
+				Quoted:    sections["quoted"],
+				Signature: sections["signature"],
+			}
+			if got.New != want.New {
+				t.Errorf("New =\n%s\nwant\n%s", got.New, want.New)
+			}
+			if got.Quoted != want.Quoted {
+				t.Errorf("Quoted =\n%s\nwant\n%s", got.Quoted, want.Quoted)
+			}
+			if got.Signature != want.Signature {
+				t.Errorf("Signature =\n%s\nwant\n%s", got.Signature, want.Signature)
+			}
+
+			// CRLF line endings split the same way
+			if crlf := Split(strings.ReplaceAll(sections["input"], "\n", "\r\n")); crlf != got {
+				t.Errorf("Split() with CRLF = %+v, want %+v", crlf, got)
+			}
+		})
+	}
+}
+
+func parseSections(t *testing.T, data string) map[string]string {
+	t.Helper()
+	sections := map[string]string{}
+	name := ""
+	var lines []string
+	flush := func() {
+		if name != "" {
+			sections[name] = strings.Join(lines, "\n")
+		}
+	}
+	for line := range strings.Lines(data) {
+		line = strings.TrimSuffix(line, "\n")
+		if header, ok := strings.CutPrefix(line, "=== "); ok {
+			flush()
+			name, lines = header, nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	for _, want := range []string{"input", "new", "quoted", "signature"} {
+		if _, ok := sections[want]; !ok {
+			t.Fatalf("missing section %q", want)
+		}
+	}
+	return sections
+}
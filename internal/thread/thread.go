@@ -0,0 +1,314 @@
+// Package thread groups messages into conversations with the algorithm of
+// Jamie Zawinski, see https://www.jwz.org/doc/threading.html. Messages are
+// linked by their Message-ID, In-Reply-To and References headers; messages
+// whose parents are unknown are grouped by their normalized subject.
+package thread
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a message to thread.
+type Message struct {
+	// ID is the Message-ID without angle brackets. Messages without ID are
+	// only threaded by subject.
+	ID string
+	// References are the IDs of the ancestors of the message, oldest first,
+	// see References.
+	References []string
+	Subject    string
+	Date       time.Time
+}
+
+// Node is a node of a conversation tree. Nodes of messages that are only
+// known from the references of other messages have no message.
+type Node struct {
+	Message  *Message
+	Parent   *Node
+	Children []*Node
+
+	id string
+}
+
+// Walk calls fn for the node and its descendants in depth-first order. The
+// depth of the node is 0.
+func (n *Node) Walk(fn func(n *Node, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *Node) walk(fn func(n *Node, depth int), depth int) {
+	fn(n, depth)
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Find returns the node of the message in the tree of n, or nil.
+func (n *Node) Find(m *Message) *Node {
+	if n.Message == m {
+		return n
+	}
+	for _, c := range n.Children {
+		if found := c.Find(m); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// date is the date of the message of the node or, if there is none, of its
+// earliest descendant.
+func (n *Node) date() time.Time {
+	if n.Message != nil {
+		return n.Message.Date
+	}
+	var earliest time.Time
+	for _, c := range n.Children {
+		if d := c.date(); earliest.IsZero() || d.Before(earliest) {
+			earliest = d
+		}
+	}
+	return earliest
+}
+
+// subject is the subject of the message of the node or, if there is none, of
+// its first child.
+func (n *Node) subject() string {
+	if n.Message != nil {
+		return n.Message.Subject
+	}
+	if len(n.Children) > 0 {
+		return n.Children[0].subject()
+	}
+	return ""
+}
+
+// hasAncestor reports whether a is n or one of its ancestors.
+func (n *Node) hasAncestor(a *Node) bool {
+	for p := n; p != nil; p = p.Parent {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Node) addChild(c *Node) {
+	if c.Parent != nil {
+		c.Parent.removeChild(c)
+	}
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+func (n *Node) removeChild(c *Node) {
+	n.Children = slices.DeleteFunc(n.Children, func(x *Node) bool { return x == c })
+	c.Parent = nil
+}
+
+// Build threads the messages and returns the roots of the conversations,
+// sorted by date like the children of every node. A message ID that occurs
+// more than once, e.g. for copies in several mailboxes, is threaded once,
+// the later messages with that ID are added as separate roots.
+func Build(messages []*Message) []*Node {
+	nodes := make(map[string]*Node)
+	get := func(id string) *Node {
+		n, ok := nodes[id]
+		if !ok {
+			n = &Node{id: id}
+			nodes[id] = n
+		}
+		return n
+	}
+
+	var all []*Node
+	for i, m := range messages {
+		id := m.ID
+		if id == "" || nodes[id] != nil && nodes[id].Message != nil {
+			// No or duplicate ID, the message cannot be referenced
+			id = "\x00" + strconv.Itoa(i)
+		}
+		n := get(id)
+		n.Message = m
+		all = append(all, n)
+
+		// Link the references, keeping links made by earlier messages
+		var parent *Node
+		for _, ref := range m.References {
+			if ref == id {
+				continue
+			}
+			r := get(ref)
+			if parent != nil && r.Parent == nil && !parent.hasAncestor(r) {
+				parent.addChild(r)
+			}
+			parent = r
+		}
+
+		// The references of the message itself are authoritative
+		if parent != nil && parent.hasAncestor(n) {
+			parent = nil
+		}
+		if parent != nil {
+			parent.addChild(n)
+		} else if n.Parent != nil {
+			n.Parent.removeChild(n)
+		}
+	}
+
+	// Collect the roots in a stable order
+	var roots []*Node
+	seen := make(map[*Node]bool)
+	for _, n := range all {
+		r := n
+		for r.Parent != nil {
+			r = r.Parent
+		}
+		if !seen[r] {
+			seen[r] = true
+			roots = append(roots, r)
+		}
+	}
+
+	roots = prune(roots, true)
+	roots = groupBySubject(roots)
+	sortByDate(roots)
+	return roots
+}
+
+// prune removes nodes without message. Their children are moved up to their
+// parent, except at the root level, where a node without message is kept if
+// it has more than one child, so that the children stay together.
+func prune(nodes []*Node, root bool) []*Node {
+	var out []*Node
+	for _, n := range nodes {
+		n.Children = prune(n.Children, false)
+		for _, c := range n.Children {
+			c.Parent = n
+		}
+		switch {
+		case n.Message != nil:
+			out = append(out, n)
+		case len(n.Children) == 0:
+		case root && len(n.Children) > 1:
+			out = append(out, n)
+		default:
+			for _, c := range n.Children {
+				c.Parent = n.Parent
+			}
+			out = append(out, n.Children...)
+		}
+	}
+	return out
+}
+
+// groupBySubject merges roots with the same normalized subject, since
+// replies of clients that do not set References still keep the subject.
+func groupBySubject(roots []*Node) []*Node {
+	// Find the best root of every subject: one without message, or one that
+	// is not a reply itself
+	bySubject := make(map[string]*Node)
+	for _, r := range roots {
+		key := subjectKey(r.subject())
+		if key == "" {
+			continue
+		}
+		old, ok := bySubject[key]
+		if !ok ||
+			r.Message == nil && old.Message != nil ||
+			old.Message != nil && r.Message != nil && IsReply(old.Message.Subject) && !IsReply(r.Message.Subject) {
+			bySubject[key] = r
+		}
+	}
+
+	var out []*Node
+	for _, r := range roots {
+		key := subjectKey(r.subject())
+		target := bySubject[key]
+		if key == "" || target == r {
+			out = append(out, r)
+			continue
+		}
+		switch {
+		case target.Message == nil && r.Message == nil:
+			for _, c := range slices.Clone(r.Children) {
+				target.addChild(c)
+			}
+		case target.Message == nil:
+			target.addChild(r)
+		case !IsReply(target.Message.Subject) && IsReply(r.Message.Subject):
+			target.addChild(r)
+		default:
+			// Siblings, e.g. two messages with the same subject that are
+			// not replies, are joined under a new node without message
+			container := &Node{}
+			i := slices.Index(out, target)
+			container.addChild(target)
+			container.addChild(r)
+			bySubject[key] = container
+			if i >= 0 {
+				out[i] = container
+			} else {
+				// The target comes later and is added to the new node
+				out = append(out, container)
+			}
+		}
+	}
+	return out
+}
+
+// sortByDate sorts nodes and their descendants by date, oldest first.
+func sortByDate(nodes []*Node) {
+	slices.SortStableFunc(nodes, func(a, b *Node) int { return a.date().Compare(b.date()) })
+	for _, n := range nodes {
+		sortByDate(n.Children)
+	}
+}
+
+// replyPrefix matches a reply or forward prefix in the languages of common
+// mail clients, e.g. "Re:", "RE[2]:", "Fwd:", "AW:", "WG:", "SV:", "Antw:",
+// or a mailing list tag like "[dev]".
+var replyPrefix = regexp.MustCompile(`(?i)^\s*(?:(?:re|fw|fwd|aw|wg|sv|vs|antw|ref|rif|tr|odp|res|enc)\s*(?:\[\d+\]|\(\d+\))?\s*[:：]|\[[^\]]*\])\s*`)
+
+// replyOnlyPrefix matches the prefixes marking a reply or forward, but not
+// a list tag.
+var replyOnlyPrefix = regexp.MustCompile(`(?i)^\s*(?:\[[^\]]*\]\s*)*(?:re|fw|fwd|aw|wg|sv|vs|antw|ref|rif|tr|odp|res|enc)\s*(?:\[\d+\]|\(\d+\))?\s*[:：]`)
+
+// NormalizeSubject strips reply and forward prefixes and list tags from a
+// subject, e.g. "Re: [dev] Fwd: Build failed" becomes "Build failed".
+func NormalizeSubject(subject string) string {
+	for {
+		loc := replyPrefix.FindStringIndex(subject)
+		if loc == nil {
+			return strings.TrimSpace(subject)
+		}
+		subject = subject[loc[1]:]
+	}
+}
+
+// IsReply reports whether the subject starts with a reply or forward prefix.
+func IsReply(subject string) bool {
+	return replyOnlyPrefix.MatchString(subject)
+}
+
+// subjectKey is the subject by which roots are grouped.
+func subjectKey(subject string) string {
+	return strings.ToLower(strings.Join(strings.Fields(NormalizeSubject(subject)), " "))
+}
+
+// References returns the IDs of the ancestors of a message, oldest first,
+// from the message IDs of its References and In-Reply-To headers.
+// In-Reply-To is only used if it is not the last reference already, as some
+// clients set it without References.
+func References(references, inReplyTo []string) []string {
+	refs := slices.Clone(references)
+	// In-Reply-To may contain other text, the first ID is the parent
+	if len(inReplyTo) > 0 && (len(refs) == 0 || refs[len(refs)-1] != inReplyTo[0]) {
+		refs = append(refs, inReplyTo[0])
+	}
+	return refs
+}
@@ -0,0 +1,202 @@
+package thread
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// msg creates a message received on the given day of March 2025.
+func msg(id, subject string, day int, refs ...string) *Message {
+	return &Message{ID: id, Subject: subject, References: refs, Date: time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// render renders trees as "subject(child child)", nodes without message as
+// "-".
+func render(roots []*Node) string {
+	var parts []string
+	for _, r := range roots {
+		parts = append(parts, renderNode(r))
+	}
+	return strings.Join(parts, " ")
+}
+
+func renderNode(n *Node) string {
+	s := "-"
+	if n.Message != nil {
+		s = n.Message.Subject
+	}
+	if len(n.Children) > 0 {
+		s += "(" + render(n.Children) + ")"
+	}
+	return s
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*Message
+		want     string
+	}{
+		{
+			name: "references",
+			messages: []*Message{
+				msg("c", "C", 3, "a", "b"),
+				msg("a", "A", 1),
+				msg("b", "B", 2, "a"),
+				msg("d", "D", 4, "a"),
+			},
+			want: "A(B(C) D)",
+		},
+		{
+			name: "missing parent keeps siblings together",
+			messages: []*Message{
+				msg("b", "B", 2, "a"),
+				msg("c", "C", 3, "a"),
+			},
+			want: "-(B C)",
+		},
+		{
+			name: "missing parent of a single message",
+			messages: []*Message{
+				msg("b", "B", 2, "x", "a"),
+				msg("c", "C", 3, "x", "a", "b"),
+			},
+			want: "B(C)",
+		},
+		{
+			name: "in-reply-to only",
+			messages: []*Message{
+				msg("a", "Hello", 1),
+				msg("b", "Re: Hello", 2, References(nil, []string{"a"})...),
+			},
+			want: "Hello(Re: Hello)",
+		},
+		{
+			name: "subject fallback",
+			messages: []*Message{
+				msg("r", "Re: Lunch", 2),
+				msg("l", "Lunch", 1),
+				msg("o", "Other", 3),
+			},
+			want: "Lunch(Re: Lunch) Other",
+		},
+		{
+			name: "same subject without reply",
+			messages: []*Message{
+				msg("a", "Weekly report", 1),
+				msg("b", "Weekly report", 8),
+			},
+			want: "-(Weekly report Weekly report)",
+		},
+		{
+			name: "references win over subject",
+			messages: []*Message{
+				msg("a", "Plan", 1),
+				msg("b", "Re: Plan", 2, "a"),
+				msg("c", "Re: Plan", 3, "b"),
+			},
+			want: "Plan(Re: Plan(Re: Plan))",
+		},
+		{
+			// The link made first is kept
+			name: "loop",
+			messages: []*Message{
+				msg("a", "A", 1, "b"),
+				msg("b", "B", 2, "a"),
+			},
+			want: "B(A)",
+		},
+		{
+			name: "duplicate ID",
+			messages: []*Message{
+				msg("a", "A", 1),
+				msg("a", "A copy", 1),
+				msg("b", "B", 2, "a"),
+			},
+			want: "A(B) A copy",
+		},
+		{
+			name: "no ID",
+			messages: []*Message{
+				msg("", "Note", 1),
+				msg("", "Other note", 2),
+			},
+			want: "Note Other note",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(Build(tt.messages)); got != tt.want {
+				t.Errorf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_WalkAndFind(t *testing.T) {
+	b := msg("b", "B", 2, "a")
+	roots := Build([]*Message{msg("a", "A", 1), b, msg("c", "C", 3, "a", "b")})
+	if len(roots) != 1 {
+		t.Fatalf("Build() = %s, want one root", render(roots))
+	}
+
+	var got []string
+	roots[0].Walk(func(n *Node, depth int) {
+		got = append(got, strings.Repeat(" ", depth)+n.Message.Subject)
+	})
+	if want := []string{"A", " B", "  C"}; !slices.Equal(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+	if n := roots[0].Find(b); n == nil || n.Parent != roots[0] {
+		t.Errorf("Find(B) = %v", n)
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+		reply   bool
+	}{
+		{"Build failed", "Build failed", false},
+		{"Re: Build failed", "Build failed", true},
+		{"RE: re: Fwd: Build failed", "Build failed", true},
+		{"Re[2]: Build failed", "Build failed", true},
+		{"AW: WG: Angebot", "Angebot", true},
+		{"SV: Möte", "Möte", true},
+		{"[dev] Re: Build failed", "Build failed", true},
+		{"[dev] Build failed", "Build failed", false},
+		{"Regarding the build", "Regarding the build", false},
+		{"Re:", "", true},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSubject(tt.subject); got != tt.want {
+			t.Errorf("NormalizeSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+		}
+		if got := IsReply(tt.subject); got != tt.reply {
+			t.Errorf("IsReply(%q) = %v, want %v", tt.subject, got, tt.reply)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		references []string
+		inReplyTo  []string
+		want       []string
+	}{
+		{[]string{"a@x", "b@x"}, []string{"b@x"}, []string{"a@x", "b@x"}},
+		{[]string{"a@x", "b@x"}, nil, []string{"a@x", "b@x"}},
+		{nil, []string{"b@x", "other@x"}, []string{"b@x"}},
+		{[]string{"a@x"}, []string{"c@x"}, []string{"a@x", "c@x"}},
+		{nil, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := References(tt.references, tt.inReplyTo); !slices.Equal(got, tt.want) {
+			t.Errorf("References(%q, %q) = %q, want %q", tt.references, tt.inReplyTo, got, tt.want)
+		}
+	}
+}
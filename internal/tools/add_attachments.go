@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+//go:embed scripts/add_attachments.js
+var addAttachmentsSource string
+
+var addAttachmentsScript = jxa.NewScript("add_attachments", addAttachmentsSource)
+
+// addAttachmentsResult is the result of the add_attachments script.
+type addAttachmentsResult struct {
+	OutgoingID int      `json:"outgoing_id"`
+	Attached   []string `json:"attached"`
+}
+
+// resolveAttachments checks that the files exist and are inside one of the
+// attachment directories dirs, also after resolving symbolic links, and
+// returns them with their sizes. Without attachment directories, attaching
+// files is refused.
+func resolveAttachments(dirs []string, paths []string) ([]OutgoingAttachment, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	if len(dirs) == 0 {
+		return nil, invalidParameters("attaching files is disabled, start the server with --attachment-dir")
+	}
+
+	var files []OutgoingAttachment
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			return nil, invalidParameters("attachment path must be absolute: %s", path)
+		}
+		// The path is checked before it is resolved, so that errors do not
+		// reveal whether files outside the attachment directories exist
+		if !inAttachmentDir(dirs, filepath.Clean(path), false) {
+			return nil, invalidParameters("attachment is not inside an attachment directory (%v): %s", dirs, path)
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "attachment file not found: %s", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve attachment %s: %w", path, err)
+		}
+		if !inAttachmentDir(dirs, resolved, true) {
+			return nil, invalidParameters("attachment links outside the attachment directories: %s", path)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, invalidParameters("attachment is not a regular file: %s", path)
+		}
+		files = append(files, OutgoingAttachment{Name: filepath.Base(resolved), Path: resolved, Size: info.Size()})
+	}
+	return files, nil
+}
+
+// inAttachmentDir reports whether path is inside one of the directories dirs,
+// with their symbolic links resolved if resolve is set.
+func inAttachmentDir(dirs []string, path string, resolve bool) bool {
+	for _, dir := range dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if resolve {
+			if dir, err = filepath.EvalSymlinks(dir); err != nil {
+				continue
+			}
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// addAttachments attaches files to an outgoing message. The body must be
+// pasted before, as pasting replaces the content of the window. It fails
+// unless the script attached exactly the files.
+func addAttachments(ctx context.Context, executor jxa.Executor, outgoingID int, files []OutgoingAttachment) error {
+	if len(files) == 0 {
+		return nil
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	inputJSON, err := json.Marshal(map[string]any{"outgoing_id": outgoingID, "paths": paths})
+	if err != nil {
+		return fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, addAttachmentsScript, string(inputJSON))
+	if err != nil {
+		return fmt.Errorf("failed to execute add_attachments: %w", err)
+	}
+	result, err := decodeResult[addAttachmentsResult](data)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(result.Attached, paths) {
+		return fmt.Errorf("add_attachments attached %v instead of %v", result.Attached, paths)
+	}
+	return nil
+}
+
+// keptAttachments returns the attachments of a replaced message to attach
+// again, except those replaced by a file of the same name.
+func keptAttachments(kept []keptAttachment, files []OutgoingAttachment) ([]OutgoingAttachment, error) {
+	var attachments []OutgoingAttachment
+	for _, k := range kept {
+		if slices.ContainsFunc(files, func(f OutgoingAttachment) bool { return f.Name == k.Name }) {
+			continue
+		}
+		info, err := os.Stat(k.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kept attachment %s: %w", k.Name, err)
+		}
+		attachments = append(attachments, OutgoingAttachment{Name: k.Name, Path: k.Path, Size: info.Size(), Kept: true})
+	}
+	return attachments, nil
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestResolveAttachments(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	report := filepath.Join(dir, "report.pdf")
+	secret := filepath.Join(outside, "secret.txt")
+	for _, path := range []string{report, secret} {
+		if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolveAttachments(nil, []string{report}); !jxa.HasCode(err, jxa.ErrorCodeInvalidParameters) {
+		t.Errorf("resolveAttachments() without attachment directories error = %v, want INVALID_PARAMETERS", err)
+	}
+	dirs := []string{dir}
+
+	files, err := resolveAttachments(dirs, []string{report})
+	if err != nil {
+		t.Fatalf("resolveAttachments() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "report.pdf" || files[0].Size != 7 || filepath.Base(files[0].Path) != "report.pdf" || !filepath.IsAbs(files[0].Path) {
+		t.Errorf("resolveAttachments() = %+v", files)
+	}
+	if files, err := resolveAttachments(dirs, nil); files != nil || err != nil {
+		t.Errorf("resolveAttachments(nil) = %v, %v", files, err)
+	}
+
+	tests := []struct {
+		path string
+		code string
+	}{
+		{"report.pdf", jxa.ErrorCodeInvalidParameters},
+		{secret, jxa.ErrorCodeInvalidParameters},
+		{filepath.Join(dir, "..", filepath.Base(outside), "secret.txt"), jxa.ErrorCodeInvalidParameters},
+		{filepath.Join(dir, "link.txt"), jxa.ErrorCodeInvalidParameters},
+		{filepath.Join(dir, "sub"), jxa.ErrorCodeInvalidParameters},
+		{filepath.Join(dir, "missing.pdf"), jxa.ErrorCodeAttachmentNotFound},
+		// Files outside the directories are refused whether they exist or not
+		{filepath.Join(outside, "missing.pdf"), jxa.ErrorCodeInvalidParameters},
+	}
+	for _, tt := range tests {
+		if _, err := resolveAttachments(dirs, []string{report, tt.path}); !jxa.HasCode(err, tt.code) {
+			t.Errorf("resolveAttachments(%s) error = %v, want %s", tt.path, err, tt.code)
+		}
+	}
+}
+
+func TestKeptAttachments(t *testing.T) {
+	dir := t.TempDir()
+	kept := []keptAttachment{
+		{Name: "report.pdf", Path: filepath.Join(dir, "1", "report.pdf")},
+		{Name: "notes.txt", Path: filepath.Join(dir, "2", "notes.txt")},
+	}
+	for _, k := range kept {
+		if err := os.MkdirAll(filepath.Dir(k.Path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(k.Path, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := []OutgoingAttachment{{Name: "report.pdf"}}
+	got, err := keptAttachments(kept, files)
+	want := []OutgoingAttachment{{Name: "notes.txt", Path: kept[1].Path, Size: 7, Kept: true}}
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("keptAttachments() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := keptAttachments(nil, files); got != nil || err != nil {
+		t.Errorf("keptAttachments(nil) = %v, %v, want none", got, err)
+	}
+	if _, err := keptAttachments([]keptAttachment{{Name: "missing.pdf", Path: filepath.Join(dir, "missing.pdf")}}, nil); err == nil {
+		t.Error("keptAttachments() with a missing file succeeded")
+	}
+}
+
+func TestAddAttachments(t *testing.T) {
+	files := []OutgoingAttachment{{Name: "a.txt", Path: "/tmp/a.txt"}, {Name: "b.txt", Path: "/tmp/b.txt"}}
+	for _, tt := range []struct {
+		name     string
+		attached []any
+		wantErr  bool
+	}{
+		{"all attached", []any{"/tmp/a.txt", "/tmp/b.txt"}, false},
+		{"one missing", []any{"/tmp/a.txt"}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := jxa.NewFakeExecutor().On("add_attachments", jxa.Result{Success: true, Data: map[string]any{
+				"outgoing_id": 7, "attached": tt.attached,
+			}})
+			err := addAttachments(context.Background(), fake, 7, files)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("addAttachments() error = %v, want error %v", err, tt.wantErr)
+			}
+			if paths := unmarshalArg(t, fake.CallsTo("add_attachments")[0])["paths"]; len(paths.([]any)) != 2 {
+				t.Errorf("paths = %v, want both files", paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/archive_messages.js
+var archiveMessagesSource string
+
+var archiveMessagesScript = jxa.NewScript("archive_messages", archiveMessagesSource)
+
+// ArchiveMessagesInput defines input parameters for archive_messages tool
+type ArchiveMessagesInput struct {
+	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath []string `json:"mailboxPath" jsonschema:"Path of the mailbox containing the messages (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path of the mailbox containing the messages. Can be specified multiple times for nested paths."`
+	MessageIDs  []int    `json:"message_ids" jsonschema:"IDs of the messages to archive" long:"message-id" description:"ID of a message to archive. Can be specified multiple times."`
+}
+
+// RegisterArchiveMessages registers the archive_messages tool with the MCP server
+func RegisterArchiveMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "archive_messages",
+			Description:  "Moves messages to the archive mailbox of their account. The archive mailbox is resolved by name: 'Archive', 'Archives' or Gmail's 'All Mail'. Fails for messages that are already in the archive mailbox. Returns the new location of each message.",
+			InputSchema:  GenerateSchema[ArchiveMessagesInput](),
+			OutputSchema: GenerateSchema[FileMessagesOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Archive Messages",
+				ReadOnlyHint:    false,
+				IdempotentHint:  false,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input ArchiveMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+			return HandleArchiveMessages(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleArchiveMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input ArchiveMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+	if err := validateMessageIDs(input.MailboxPath, input.MessageIDs); err != nil {
+		return nil, nil, err
+	}
+	return fileMessages(ctx, executor, archiveMessagesScript, input)
+}
@@ -0,0 +1,51 @@
+package tools
+
+import "slices"
+
+// validateChunk checks the max_chars and offset inputs of the tools that
+// return content.
+func validateChunk(maxChars, offset int) error {
+	if maxChars < 0 {
+		return invalidParameters("max_chars must not be negative")
+	}
+	if offset < 0 {
+		return invalidParameters("offset must not be negative")
+	}
+	return nil
+}
+
+// chunk returns the part of content that starts at offset and has at most
+// maxChars characters, all of it if maxChars is 0. Unless the chunk reaches
+// the end of the content, it ends after the last paragraph break in its
+// second half, falling back to a line break, a space and maxChars
+// characters. The chunks of consecutive offsets concatenate to the content.
+//
+// total is the length of the content in characters. next is the offset of
+// the following chunk, nil if the chunk reaches the end.
+func chunk(content string, offset, maxChars int) (part string, total int, next *int) {
+	if offset == 0 && maxChars == 0 {
+		return content, len([]rune(content)), nil
+	}
+	runes := []rune(content)
+	total = len(runes)
+	offset = min(offset, total)
+	end := total
+	if maxChars > 0 && offset+maxChars < total {
+		end = chunkEnd(runes[offset:offset+maxChars]) + offset
+		next = &end
+	}
+	return string(runes[offset:end]), total, next
+}
+
+// chunkEnd returns the length of a chunk that is cut from window.
+func chunkEnd(window []rune) int {
+	half := len(window) / 2
+	for _, sep := range [][]rune{[]rune("\n\n"), []rune("\n"), []rune(" ")} {
+		for i := len(window) - len(sep); i >= half; i-- {
+			if slices.Equal(window[i:i+len(sep)], sep) {
+				return i + len(sep)
+			}
+		}
+	}
+	return len(window)
+}
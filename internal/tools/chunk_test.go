@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	const content = "First paragraph.\n\nSecond paragraph, a bit longer.\nStill second.\n\nThird: äöü."
+	tests := []struct {
+		name     string
+		offset   int
+		maxChars int
+		want     string
+		wantNext int // -1 for none
+	}{
+		{name: "no limit", want: content, wantNext: -1},
+		{name: "paragraph break", maxChars: 30, want: "First paragraph.\n\n", wantNext: 18},
+		{name: "line break", offset: 18, maxChars: 40, want: "Second paragraph, a bit longer.\n", wantNext: 50},
+		{name: "space", offset: 18, maxChars: 20, want: "Second paragraph, a ", wantNext: 38},
+		{name: "paragraph break in first half", maxChars: 40, want: "First paragraph.\n\nSecond paragraph, a ", wantNext: 38},
+		{name: "hard cut", maxChars: 3, want: "Fir", wantNext: 3},
+		{name: "characters, not bytes", offset: 65, maxChars: 11, want: "Third: äöü.", wantNext: -1},
+		{name: "rest", offset: 65, want: "Third: äöü.", wantNext: -1},
+		{name: "offset beyond end", offset: 1000, maxChars: 10, want: "", wantNext: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, next := chunk(content, tt.offset, tt.maxChars)
+			if got != tt.want {
+				t.Errorf("chunk() = %q, want %q", got, tt.want)
+			}
+			if total != 76 {
+				t.Errorf("total = %d, want 76", total)
+			}
+			switch {
+			case tt.wantNext < 0 && next != nil:
+				t.Errorf("next = %d, want none", *next)
+			case tt.wantNext >= 0 && (next == nil || *next != tt.wantNext):
+				t.Errorf("next = %v, want %d", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+// TestChunk_Concatenate checks that reading a content chunk by chunk returns
+// all of it.
+func TestChunk_Concatenate(t *testing.T) {
+	content := strings.Repeat("Lorem ipsum dolor sit amet, consectetur.\nAdipiscing elit.\n\n", 20) + strings.Repeat("x", 300)
+	for _, maxChars := range []int{1, 7, 50, 200, 5000} {
+		var b strings.Builder
+		offset, calls := 0, 0
+		for {
+			part, _, next := chunk(content, offset, maxChars)
+			if len([]rune(part)) > maxChars {
+				t.Fatalf("max_chars %d: chunk of %d characters", maxChars, len([]rune(part)))
+			}
+			b.WriteString(part)
+			if calls++; next == nil || calls > len(content) {
+				break
+			}
+			offset = *next
+		}
+		if b.String() != content {
+			t.Errorf("max_chars %d: concatenated chunks differ from content", maxChars)
+		}
+	}
+}
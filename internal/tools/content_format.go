@@ -33,7 +33,7 @@ func ValidateAndNormalizeContentFormat(format *string) (string, error) {
 	case ContentFormatMarkdown:
 		return ContentFormatMarkdown, nil
 	default:
-		return "", fmt.Errorf("invalid content_format: %s", normalized)
+		return "", invalidParameters("invalid content_format: %s", normalized)
 	}
 }
 
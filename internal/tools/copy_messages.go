@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/copy_messages.js
+var copyMessagesSource string
+
+var copyMessagesScript = jxa.NewScript("copy_messages", copyMessagesSource)
+
+// CopyMessagesInput defines input parameters for copy_messages tool
+type CopyMessagesInput struct {
+	Account                string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath            []string `json:"mailboxPath" jsonschema:"Path of the mailbox containing the messages (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path of the mailbox containing the messages. Can be specified multiple times for nested paths."`
+	MessageIDs             []int    `json:"message_ids" jsonschema:"IDs of the messages to copy" long:"message-id" description:"ID of a message to copy. Can be specified multiple times."`
+	DestinationMailboxPath []string `json:"destinationMailboxPath" jsonschema:"Path of the mailbox to copy the messages to, in the same account (e.g. ['Archive'] or ['Projects','2025'])" long:"destination-mailbox-path" description:"Path of the mailbox to copy the messages to. Can be specified multiple times for nested paths."`
+}
+
+// RegisterCopyMessages registers the copy_messages tool with the MCP server
+func RegisterCopyMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "copy_messages",
+			Description:  "Copies messages to another mailbox of the same account. The original messages stay in place. Returns the location of each copy. All message IDs are checked before any message is copied.",
+			InputSchema:  GenerateSchema[CopyMessagesInput](),
+			OutputSchema: GenerateSchema[FileMessagesOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Copy Messages",
+				ReadOnlyHint:    false,
+				IdempotentHint:  false,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input CopyMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+			return HandleCopyMessages(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleCopyMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input CopyMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+	if err := validateMessageIDs(input.MailboxPath, input.MessageIDs); err != nil {
+		return nil, nil, err
+	}
+	if err := validateDestination(input.MailboxPath, input.DestinationMailboxPath); err != nil {
+		return nil, nil, err
+	}
+	return fileMessages(ctx, executor, copyMessagesScript, input)
+}
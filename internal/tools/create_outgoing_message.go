@@ -10,12 +10,13 @@ import (
 	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/jxa"
-	"github.com/dastrobu/mail-mcp/internal/mac"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/create_outgoing_message.js
-var createOutgoingMessageScript string
+var createOutgoingMessageSource string
+
+var createOutgoingMessageScript = jxa.NewScript("create_outgoing_message", createOutgoingMessageSource)
 
 type CreateOutgoingMessageInput struct {
 	Account       string    `json:"account" jsonschema:"The name of the account to send from" long:"account" description:"The name of the account to send from"`
@@ -25,14 +26,18 @@ type CreateOutgoingMessageInput struct {
 	ToRecipients  *[]string `json:"to_recipients,omitempty" jsonschema:"List of To recipients" long:"to-recipients" description:"List of To recipients. Can be specified multiple times."`
 	CcRecipients  *[]string `json:"cc_recipients,omitempty" jsonschema:"List of CC recipients" long:"cc-recipients" description:"List of CC recipients. Can be specified multiple times."`
 	BccRecipients *[]string `json:"bcc_recipients,omitempty" jsonschema:"List of BCC recipients" long:"bcc-recipients" description:"List of BCC recipients. Can be specified multiple times."`
+	Attachments   []string  `json:"attachments,omitempty" jsonschema:"Absolute paths of files to attach. The files must be inside one of the attachment directories of the server." long:"attachment" description:"Absolute path of a file to attach. Can be specified multiple times."`
 }
 
-func RegisterCreateOutgoingMessage(srv *mcp.Server) {
-	mcp.AddTool(srv,
+// RegisterCreateOutgoingMessage registers the create_outgoing_message tool with the MCP server.
+// Files may only be attached from attachmentDirs.
+func RegisterCreateOutgoingMessage(srv *mcp.Server, executor jxa.Executor, attachmentDirs []string) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "create_outgoing_message",
-			Description: "Creates a new outgoing message (open window), then pastes content into its body using the Accessibility API. Returns the new Outgoing Message ID. NOTE: Mail.app may auto-save this message as a draft. If replacing this message, check for and delete the old outgoing message first.",
-			InputSchema: GenerateSchema[CreateOutgoingMessageInput](),
+			Name:         "create_outgoing_message",
+			Description:  "Creates a new outgoing message (open window), then pastes content into its body using the Accessibility API and attaches the files of attachments. Returns the new Outgoing Message ID and the attached files. NOTE: Mail.app may auto-save this message as a draft. If replacing this message, check for and delete the old outgoing message first.",
+			InputSchema:  GenerateSchema[CreateOutgoingMessageInput](),
+			OutputSchema: GenerateSchema[ComposeOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Create Outgoing Message",
 				ReadOnlyHint:    false,
@@ -41,22 +46,26 @@ func RegisterCreateOutgoingMessage(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		func(ctx context.Context, request *mcp.CallToolRequest, input CreateOutgoingMessageInput) (*mcp.CallToolResult, any, error) {
-			return HandleCreateOutgoingMessage(ctx, request, input)
+		func(ctx context.Context, request *mcp.CallToolRequest, input CreateOutgoingMessageInput) (*mcp.CallToolResult, *ComposeOutput, error) {
+			return HandleCreateOutgoingMessage(ctx, executor, attachmentDirs, request, input)
 		},
 	)
 }
 
-func HandleCreateOutgoingMessage(ctx context.Context, request *mcp.CallToolRequest, input CreateOutgoingMessageInput) (*mcp.CallToolResult, any, error) {
+func HandleCreateOutgoingMessage(ctx context.Context, executor jxa.Executor, attachmentDirs []string, request *mcp.CallToolRequest, input CreateOutgoingMessageInput) (*mcp.CallToolResult, *ComposeOutput, error) {
 	// 1. Input Validation & Setup
 	if input.Account == "" || input.Subject == "" || input.Content == "" {
-		return nil, nil, fmt.Errorf("account, subject, and content are required")
+		return nil, nil, missingParameters("account, subject, and content are required")
 	}
 	contentFormat, err := ValidateAndNormalizeContentFormat(input.ContentFormat)
 	if err != nil {
 		return nil, nil, err
 	}
-	if err := mac.EnsureAccessibility(); err != nil {
+	files, err := resolveAttachments(attachmentDirs, input.Attachments)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := pasterFor(executor).EnsureAccessibility(); err != nil {
 		return nil, nil, err
 	}
 
@@ -72,36 +81,34 @@ func HandleCreateOutgoingMessage(ctx context.Context, request *mcp.CallToolReque
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	resultAny, err := jxa.Execute(ctx, createOutgoingMessageScript, string(inputJSON))
+	resultAny, err := executor.Execute(ctx, createOutgoingMessageScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("JXA execution failed: %w", err)
 	}
 
-	resultMap, ok := resultAny.(map[string]any)
-	if !ok {
-		return nil, nil, fmt.Errorf("invalid JXA result format")
-	}
-
 	// Extract data for pasting
-	outgoingID, idOk := resultMap["outgoing_id"].(float64)
-	resultSubject, subjectOk := resultMap["subject"].(string)
-	mailPID, pidOk := resultMap["pid"].(float64)
-
-	if !idOk || !subjectOk || !pidOk {
-		return nil, nil, fmt.Errorf("JXA result is missing required fields (outgoing_id, subject, pid)")
+	result, err := decodeResult[composeScriptResult](resultAny)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// 4. Paste content
-	if err := mac.PasteIntoWindow(ctx, int(mailPID), resultSubject, 5*time.Second, htmlContent, plainContent); err != nil {
+	if err := pasterFor(executor).PasteIntoWindow(ctx, result.PID, result.Subject, 5*time.Second, htmlContent, plainContent); err != nil {
 		return nil, nil, fmt.Errorf("accessibility paste operation failed: %w", err)
 	}
 	time.Sleep(250 * time.Millisecond) // Allow Mail.app to process the paste event.
 
-	// 5. Return success
-	finalResult := map[string]any{
-		"outgoing_id": outgoingID,
-		"subject":     resultSubject,
-		"message":     "Outgoing message created and content pasted. Note: Paste success is not verified.",
+	// 5. Attach files after the paste, which replaces the content
+	if err := addAttachments(ctx, executor, result.OutgoingID, files); err != nil {
+		return nil, nil, fmt.Errorf("outgoing message %d was created, but attaching files failed: %w", result.OutgoingID, err)
+	}
+
+	// 6. Return success
+	finalResult := &ComposeOutput{
+		OutgoingID:  result.OutgoingID,
+		Subject:     result.Subject,
+		Message:     "Outgoing message created and content pasted. Note: Paste success is not verified.",
+		Attachments: files,
 	}
 
 	return nil, finalResult, nil
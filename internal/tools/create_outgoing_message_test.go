@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dastrobu/mail-mcp/internal/jxa"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -19,7 +20,7 @@ func TestHandleCreateOutgoingMessage_UnknownContentFormat(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	_, _, err := HandleCreateOutgoingMessage(ctx, &mcp.CallToolRequest{}, input)
+	_, _, err := HandleCreateOutgoingMessage(ctx, jxa.NewFakeExecutor(), nil, &mcp.CallToolRequest{}, input)
 
 	if err == nil {
 		t.Errorf("Expected error for unknown content format, but got nil")
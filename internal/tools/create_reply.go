@@ -10,12 +10,13 @@ import (
 	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/jxa"
-	"github.com/dastrobu/mail-mcp/internal/mac"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/create_reply.js
-var createReplyScript string
+var createReplySource string
+
+var createReplyScript = jxa.NewScript("create_reply", createReplySource)
 
 type CreateReplyInput struct {
 	MessageID     int      `json:"message_id" jsonschema:"The ID of the message to reply to" long:"message-id" description:"The ID of the message to reply to"`
@@ -26,12 +27,13 @@ type CreateReplyInput struct {
 	ReplyToAll    bool     `json:"reply_to_all,omitempty" jsonschema:"Reply to all recipients. Default is false." long:"reply-to-all" description:"Reply to all recipients. Default is false."`
 }
 
-func RegisterCreateReply(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterCreateReply(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "create_reply",
-			Description: "Creates a reply to a specific message, opens it as a new window, and pastes in content. Returns the new Outgoing Message ID. NOTE: Mail.app may auto-save this message as a draft. If replacing this reply, check for and delete the old outgoing message first.",
-			InputSchema: GenerateSchema[CreateReplyInput](),
+			Name:         "create_reply",
+			Description:  "Creates a reply to a specific message, opens it as a new window, and pastes in content. Returns the new Outgoing Message ID. NOTE: Mail.app may auto-save this message as a draft. If replacing this reply, check for and delete the old outgoing message first.",
+			InputSchema:  GenerateSchema[CreateReplyInput](),
+			OutputSchema: GenerateSchema[ComposeOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Create Reply",
 				ReadOnlyHint:    false,
@@ -40,22 +42,22 @@ func RegisterCreateReply(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		func(ctx context.Context, request *mcp.CallToolRequest, input CreateReplyInput) (*mcp.CallToolResult, any, error) {
-			return HandleCreateReply(ctx, request, input)
+		func(ctx context.Context, request *mcp.CallToolRequest, input CreateReplyInput) (*mcp.CallToolResult, *ComposeOutput, error) {
+			return HandleCreateReply(ctx, executor, request, input)
 		},
 	)
 }
 
-func HandleCreateReply(ctx context.Context, request *mcp.CallToolRequest, input CreateReplyInput) (*mcp.CallToolResult, any, error) {
+func HandleCreateReply(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input CreateReplyInput) (*mcp.CallToolResult, *ComposeOutput, error) {
 	// 1. Input Validation and Setup
 	if input.Account == "" || input.MessageID == 0 || input.Content == "" || len(input.MailboxPath) == 0 {
-		return nil, nil, fmt.Errorf("account, message_id, content, and mailbox_path are required")
+		return nil, nil, missingParameters("account, message_id, content, and mailbox_path are required")
 	}
 	contentFormat, err := ValidateAndNormalizeContentFormat(input.ContentFormat)
 	if err != nil {
 		return nil, nil, err
 	}
-	if err := mac.EnsureAccessibility(); err != nil {
+	if err := pasterFor(executor).EnsureAccessibility(); err != nil {
 		return nil, nil, err
 	}
 
@@ -71,36 +73,28 @@ func HandleCreateReply(ctx context.Context, request *mcp.CallToolRequest, input
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	resultAny, err := jxa.Execute(ctx, createReplyScript, string(inputJSON))
+	resultAny, err := executor.Execute(ctx, createReplyScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("JXA execution failed: %w", err)
 	}
 
-	resultMap, ok := resultAny.(map[string]any)
-	if !ok {
-		return nil, nil, fmt.Errorf("invalid JXA result format")
-	}
-
 	// 4. Extract data for pasting
-	outgoingID, idOk := resultMap["outgoing_id"].(float64)
-	resultSubject, subjectOk := resultMap["subject"].(string)
-	mailPID, pidOk := resultMap["pid"].(float64)
-
-	if !idOk || !subjectOk || !pidOk {
-		return nil, nil, fmt.Errorf("JXA result is missing required fields (outgoing_id, subject, pid)")
+	result, err := decodeResult[composeScriptResult](resultAny)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// 5. Paste content
-	if err := mac.PasteIntoWindow(ctx, int(mailPID), resultSubject, 5*time.Second, htmlContent, plainContent); err != nil {
+	if err := pasterFor(executor).PasteIntoWindow(ctx, result.PID, result.Subject, 5*time.Second, htmlContent, plainContent); err != nil {
 		return nil, nil, fmt.Errorf("accessibility paste operation failed: %w", err)
 	}
 	time.Sleep(250 * time.Millisecond)
 
 	// 6. Return success
-	finalResult := map[string]any{
-		"outgoing_id": outgoingID,
-		"subject":     resultSubject,
-		"message":     "Reply created and content pasted.",
+	finalResult := &ComposeOutput{
+		OutgoingID: result.OutgoingID,
+		Subject:    result.Subject,
+		Message:    "Reply created and content pasted.",
 	}
 
 	return nil, finalResult, nil
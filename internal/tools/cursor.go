@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// findMessagesCursor is the state behind the opaque cursor of find_messages:
+// the query it belongs to and the sort key of the last message returned.
+type findMessagesCursor struct {
+	Query string     `json:"q"`
+	After messageKey `json:"a"`
+}
+
+// messageKey holds the properties find_messages sorts by. It is passed to
+// the script, which resumes after the message with this key.
+type messageKey struct {
+	DateReceived string   `json:"date_received"`
+	Sender       string   `json:"sender,omitempty"`
+	Subject      string   `json:"subject,omitempty"`
+	Account      string   `json:"account"`
+	MailboxPath  []string `json:"mailbox_path"`
+	ID           int      `json:"id"`
+}
+
+// encodeCursor returns the cursor for the page following the message.
+func encodeCursor(input FindMessagesInput, last MessageSummary) string {
+	data, _ := json.Marshal(findMessagesCursor{
+		Query: queryFingerprint(input),
+		After: keyOf(last),
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// keyOf returns the sort key of a message.
+func keyOf(m MessageSummary) messageKey {
+	return messageKey{
+		DateReceived: m.DateReceived,
+		Sender:       m.Sender,
+		Subject:      m.Subject,
+		Account:      m.Account,
+		MailboxPath:  m.MailboxPath,
+		ID:           m.ID,
+	}
+}
+
+// decodeCursor returns the key of the message to resume after. The cursor
+// must belong to the same query, only the limit may change between pages.
+func decodeCursor(input FindMessagesInput) (*messageKey, error) {
+	data, err := base64.RawURLEncoding.DecodeString(input.Cursor)
+	if err != nil {
+		return nil, invalidParameters("invalid cursor")
+	}
+	var c findMessagesCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalidParameters("invalid cursor")
+	}
+	if c.Query != queryFingerprint(input) {
+		return nil, invalidParameters("cursor belongs to a different query; repeat the account, mailbox, filters and sort of the first page")
+	}
+	return &c.After, nil
+}
+
+// queryFingerprint identifies the query of a find_messages call, ignoring
+// the page.
+func queryFingerprint(input FindMessagesInput) string {
+	input.Cursor = ""
+	input.Limit = 0
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -13,18 +13,21 @@ import (
 )
 
 //go:embed scripts/delete_draft.js
-var deleteDraftScript string
+var deleteDraftSource string
+
+var deleteDraftScript = jxa.NewScript("delete_draft", deleteDraftSource)
 
 type DeleteDraftInput struct {
 	DraftID int `json:"draft_id" jsonschema:"The ID of the draft to delete" long:"draft-id" description:"The ID of the draft to delete"`
 }
 
-func RegisterDeleteDraft(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterDeleteDraft(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "delete_draft",
-			Description: "Deletes a draft message by its ID. This action is irreversible.",
-			InputSchema: GenerateSchema[DeleteDraftInput](),
+			Name:         "delete_draft",
+			Description:  "Deletes a draft message by its ID. This action is irreversible.",
+			InputSchema:  GenerateSchema[DeleteDraftInput](),
+			OutputSchema: GenerateSchema[DeleteDraftOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Delete Draft",
 				ReadOnlyHint:    false,
@@ -33,11 +36,13 @@ func RegisterDeleteDraft(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleDeleteDraft,
+		func(ctx context.Context, request *mcp.CallToolRequest, input DeleteDraftInput) (*mcp.CallToolResult, *DeleteDraftOutput, error) {
+			return HandleDeleteDraft(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleDeleteDraft(ctx context.Context, request *mcp.CallToolRequest, input DeleteDraftInput) (*mcp.CallToolResult, any, error) {
+func HandleDeleteDraft(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input DeleteDraftInput) (*mcp.CallToolResult, *DeleteDraftOutput, error) {
 	// Prepare arguments for JXA
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
@@ -45,10 +50,15 @@ func HandleDeleteDraft(ctx context.Context, request *mcp.CallToolRequest, input
 	}
 
 	// Execute JXA
-	data, err := jxa.Execute(ctx, deleteDraftScript, string(inputJSON))
+	data, err := executor.Execute(ctx, deleteDraftScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := decodeResult[DeleteDraftOutput](data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return nil, data, nil
+	return nil, result, nil
 }
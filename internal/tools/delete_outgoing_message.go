@@ -13,18 +13,21 @@ import (
 )
 
 //go:embed scripts/delete_outgoing_message.js
-var deleteOutgoingMessageScript string
+var deleteOutgoingMessageSource string
+
+var deleteOutgoingMessageScript = jxa.NewScript("delete_outgoing_message", deleteOutgoingMessageSource)
 
 type DeleteOutgoingMessageInput struct {
 	OutgoingID int `json:"outgoing_id" jsonschema:"The ID of the outgoing message to delete" long:"outgoing-id" description:"The ID of the outgoing message to delete"`
 }
 
-func RegisterDeleteOutgoingMessage(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterDeleteOutgoingMessage(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "delete_outgoing_message",
-			Description: "Deletes an outgoing message (draft or open composition window) by its ID. This action is irreversible.",
-			InputSchema: GenerateSchema[DeleteOutgoingMessageInput](),
+			Name:         "delete_outgoing_message",
+			Description:  "Deletes an outgoing message (draft or open composition window) by its ID. This action is irreversible.",
+			InputSchema:  GenerateSchema[DeleteOutgoingMessageInput](),
+			OutputSchema: GenerateSchema[DeleteOutgoingMessageOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Delete Outgoing Message",
 				ReadOnlyHint:    false,
@@ -33,11 +36,13 @@ func RegisterDeleteOutgoingMessage(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleDeleteOutgoingMessage,
+		func(ctx context.Context, request *mcp.CallToolRequest, input DeleteOutgoingMessageInput) (*mcp.CallToolResult, *DeleteOutgoingMessageOutput, error) {
+			return HandleDeleteOutgoingMessage(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleDeleteOutgoingMessage(ctx context.Context, request *mcp.CallToolRequest, input DeleteOutgoingMessageInput) (*mcp.CallToolResult, any, error) {
+func HandleDeleteOutgoingMessage(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input DeleteOutgoingMessageInput) (*mcp.CallToolResult, *DeleteOutgoingMessageOutput, error) {
 	// Prepare arguments for JXA
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
@@ -45,10 +50,15 @@ func HandleDeleteOutgoingMessage(ctx context.Context, request *mcp.CallToolReque
 	}
 
 	// Execute JXA
-	data, err := jxa.Execute(ctx, deleteOutgoingMessageScript, string(inputJSON))
+	data, err := executor.Execute(ctx, deleteOutgoingMessageScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := decodeResult[DeleteOutgoingMessageOutput](data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return nil, data, nil
+	return nil, result, nil
 }
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrorOutput is the machine-readable error of a failed tool call, sent as
+// JSON text content after the error message.
+type ErrorOutput struct {
+	Error *jxa.Error `json:"error"`
+}
+
+// addTool registers a typed tool handler. Unlike mcp.AddTool, errors returned by
+// the handler are reported as CallToolResult{IsError: true} carrying an
+// ErrorOutput, so that clients can branch on the error code.
+func addTool[In, Out any](srv *mcp.Server, tool *mcp.Tool, handler func(context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, *Out, error)) {
+	// The handler is registered with an untyped output so that the SDK does not
+	// add a zero Out as structured content to error results.
+	mcp.AddTool(srv, tool, func(ctx context.Context, request *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		res, out, err := handler(ctx, request, input)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return res, out, nil
+	})
+}
+
+// errorResult converts an error into a tool error result. Errors other than
+// *jxa.Error are reported as TIMEOUT or UNKNOWN_ERROR.
+//
+// The ErrorOutput is not sent as structured content, which clients validate
+// against the output schema of the tool, i.e. its result type.
+func errorResult(err error) *mcp.CallToolResult {
+	var jxaErr *jxa.Error
+	if !errors.As(err, &jxaErr) {
+		code := jxa.ErrorCodeUnknown
+		if errors.Is(err, context.DeadlineExceeded) {
+			code = jxa.ErrorCodeTimeout
+		}
+		jxaErr = jxa.NewError(code, "%s", err.Error())
+	}
+
+	res := &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+	}
+	if data, err := json.Marshal(ErrorOutput{Error: jxaErr}); err == nil {
+		res.Content = append(res.Content, &mcp.TextContent{Text: string(data)})
+	}
+	res.SetError(err)
+	return res
+}
+
+// invalidParameters reports invalid tool input with the INVALID_PARAMETERS code.
+func invalidParameters(format string, args ...any) error {
+	return jxa.NewError(jxa.ErrorCodeInvalidParameters, format, args...)
+}
+
+// missingParameters reports missing tool input with the MISSING_PARAMETERS code.
+func missingParameters(format string, args ...any) error {
+	return jxa.NewError(jxa.ErrorCodeMissingParameters, format, args...)
+}
@@ -1,38 +1,104 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/dastrobu/mail-mcp/internal/dates"
 	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/query"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/find_messages.js
-var findMessagesScript string
+var findMessagesSource string
+
+var findMessagesScript = jxa.NewScript("find_messages", findMessagesSource)
 
 // FindMessagesInput defines input parameters for find_messages tool
 type FindMessagesInput struct {
-	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
-	MailboxPath []string `json:"mailboxPath" jsonschema:"Mailbox path array (e.g., ['Inbox'] or ['Inbox', 'GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Mailbox path (can be specified multiple times for nested mailboxes). Note: Mailbox names are case-sensitive."`
-	Subject     string   `json:"subject,omitempty" jsonschema:"Filter by subject (substring match)" long:"subject" description:"Filter by subject (substring match)"`
-	Sender      string   `json:"sender,omitempty" jsonschema:"Filter by sender email address (substring match)" long:"sender" description:"Filter by sender email address (substring match)"`
-	ReadStatus  *bool    `json:"readStatus,omitempty" jsonschema:"Filter by read status (true for read, false for unread)" long:"read-status" description:"Filter by read status (true for read, false for unread)"`
-	FlaggedOnly bool     `json:"flaggedOnly,omitempty" jsonschema:"Filter for flagged messages only" long:"flagged-only" description:"Filter for flagged messages only"`
-	DateAfter   string   `json:"dateAfter,omitempty" jsonschema:"Filter for messages received after this ISO date (e.g., '2024-01-01T00:00:00Z')" long:"date-after" description:"Filter for messages received after this ISO date (e.g., '2024-01-01T00:00:00Z')"`
-	DateBefore  string   `json:"dateBefore,omitempty" jsonschema:"Filter for messages received before this ISO date (e.g., '2024-12-31T23:59:59Z')" long:"date-before" description:"Filter for messages received before this ISO date (e.g., '2024-12-31T23:59:59Z')"`
-	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum number of messages to return (1-1000, default: 50)" long:"limit" description:"Maximum number of messages to return (1-1000, default: 50)"`
-}
-
-// RegisterFindMessages registers the find_messages tool with the MCP server
-func RegisterFindMessages(srv *mcp.Server) {
-	mcp.AddTool(srv,
+	Account        string   `json:"account,omitempty" jsonschema:"Name of the email account. If omitted, all enabled accounts are searched." long:"account" description:"Name of the email account. If omitted, all enabled accounts are searched."`
+	MailboxPath    []string `json:"mailboxPath,omitempty" jsonschema:"Mailbox path array (e.g., ['Inbox'] or ['Inbox', 'GitHub']). If omitted, all mailboxes of the account are searched. Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Mailbox path (can be specified multiple times for nested mailboxes). If omitted, all mailboxes of the account are searched. Note: Mailbox names are case-sensitive."`
+	Exclude        []string `json:"exclude,omitempty" jsonschema:"Mailboxes to skip when mailboxPath is omitted, by name (case-insensitive). Sub-mailboxes are skipped too. 'Trash', 'Junk', 'Sent' and 'Archive' also match the names other providers use, e.g. 'Deleted Messages' or 'Spam'." long:"exclude" description:"Mailbox to skip when no mailbox path is given (e.g. Trash, Junk, Sent). Can be specified multiple times."`
+	Query          string   `json:"query,omitempty" jsonschema:"Gmail-style search query combining filters, e.g. 'from:alice is:unread has:attachment after:2024-05-01 subject:\"quarterly report\" -in:trash'. Supported operators: account:, in:, -in:, from:, to:, cc:, subject:, body:, replyto:, filename:, is:unread, is:read, is:flagged, has:attachment, after:, before:, larger:, smaller:. Can be combined with the other parameters as long as they do not set the same filter." long:"query" description:"Gmail-style search query, e.g. 'from:alice is:unread has:attachment after:2024-05-01'"`
+	Subject        string   `json:"subject,omitempty" jsonschema:"Filter by subject (substring match)" long:"subject" description:"Filter by subject (substring match)"`
+	Sender         string   `json:"sender,omitempty" jsonschema:"Filter by sender email address (substring match)" long:"sender" description:"Filter by sender email address (substring match)"`
+	ReadStatus     *bool    `json:"readStatus,omitempty" jsonschema:"Filter by read status (true for read, false for unread)" long:"read-status" description:"Filter by read status (true for read, false for unread)"`
+	FlaggedOnly    bool     `json:"flaggedOnly,omitempty" jsonschema:"Filter for flagged messages only" long:"flagged-only" description:"Filter for flagged messages only"`
+	DateAfter      string   `json:"dateAfter,omitempty" jsonschema:"Filter for messages received after this date: an ISO date or timestamp (e.g., '2024-01-01' or '2024-01-01T00:00:00Z'), a relative date ('7d', '2w', '3m', '12h') or 'today', 'yesterday', 'last monday', 'this week', 'last month'. Dates without time zone are in the configured time zone." long:"date-after" description:"Filter for messages received after this date (e.g., 2024-01-01, 7d, yesterday, last monday)"`
+	DateBefore     string   `json:"dateBefore,omitempty" jsonschema:"Filter for messages received before this date, in the same formats as dateAfter (e.g., '2024-12-31', 'today' or 'this month')" long:"date-before" description:"Filter for messages received before this date (e.g., 2024-12-31, today, this month)"`
+	Body           string   `json:"body,omitempty" jsonschema:"Filter by message body text (substring match). Reads the content of every message that passes the other filters, so combine it with other filters on large mailboxes." long:"body" description:"Filter by message body text (substring match)"`
+	Recipient      string   `json:"recipient,omitempty" jsonschema:"Filter by To or Cc recipient address (substring match, e.g. 'billing@')" long:"recipient" description:"Filter by To or Cc recipient address (substring match)"`
+	Cc             string   `json:"cc,omitempty" jsonschema:"Filter by Cc recipient address (substring match)" long:"cc" description:"Filter by Cc recipient address (substring match)"`
+	ReplyTo        string   `json:"replyTo,omitempty" jsonschema:"Filter by Reply-To address (substring match)" long:"reply-to" description:"Filter by Reply-To address (substring match)"`
+	HasAttachments *bool    `json:"hasAttachments,omitempty" jsonschema:"Filter by whether messages have attachments" long:"has-attachments" description:"Filter by whether messages have attachments"`
+	AttachmentName string   `json:"attachmentName,omitempty" jsonschema:"Filter for messages with an attachment whose file name contains this text (e.g. '.pdf')" long:"attachment-name" description:"Filter for messages with an attachment whose file name contains this text (e.g. .pdf)"`
+	MinSize        int      `json:"minSize,omitempty" jsonschema:"Filter for messages of at least this size in bytes" long:"min-size" description:"Filter for messages of at least this size in bytes"`
+	MaxSize        int      `json:"maxSize,omitempty" jsonschema:"Filter for messages of at most this size in bytes" long:"max-size" description:"Filter for messages of at most this size in bytes"`
+	Limit          int      `json:"limit,omitempty" jsonschema:"Maximum number of messages to return (1-1000, default: 50)" long:"limit" description:"Maximum number of messages to return (1-1000, default: 50)"`
+	Sort           string   `json:"sort,omitempty" jsonschema:"Sort order: 'date_desc' (newest first, default), 'date_asc', 'sender' or 'subject'" long:"sort" description:"Sort order: date_desc (default), date_asc, sender or subject"`
+	Cursor         string   `json:"cursor,omitempty" jsonschema:"Cursor returned by a previous call, to get the next page. The other parameters must be the same as in that call, except limit." long:"cursor" description:"Cursor returned by a previous call, to get the next page"`
+}
+
+// Sort orders of find_messages.
+const (
+	SortDateDesc = "date_desc"
+	SortDateAsc  = "date_asc"
+	SortSender   = "sender"
+	SortSubject  = "subject"
+)
+
+// SortOrders are the valid sort orders of find_messages.
+var SortOrders = []string{SortDateDesc, SortDateAsc, SortSender, SortSubject}
+
+// findMessagesWorkers bounds the number of mailboxes searched concurrently.
+const findMessagesWorkers = 4
+
+// excludeAliases maps the mailbox kinds accepted by the exclude option of
+// find_messages to the names common providers use for them.
+var excludeAliases = map[string][]string{
+	"trash":   {"deleted messages", "deleted items", "bin"},
+	"junk":    {"spam", "junk e-mail", "junk email", "bulk mail"},
+	"sent":    {"sent messages", "sent items", "sent mail"},
+	"archive": {"archives", "all mail"},
+}
+
+// mailboxScan is one mailbox searched by find_messages.
+type mailboxScan struct {
+	account     string
+	mailboxPath []string
+	// messageCount is the number of messages of the mailbox, if it was
+	// listed
+	messageCount int
+}
+
+// findMessagesScan is the input of the script for a single mailbox.
+type findMessagesScan struct {
+	FindMessagesInput
+	After *messageKey `json:"after,omitempty"`
+	// SkipContent skips reading the content of the messages, which leaves
+	// content_preview and content_length empty. The index sync lists
+	// messages with it, as it fetches the content of new messages anyway.
+	SkipContent bool `json:"skipContent,omitempty"`
+}
+
+// RegisterFindMessages registers the find_messages tool with the MCP server.
+// Date filters like "yesterday" or "2024-05-01" are interpreted in loc.
+func RegisterFindMessages(srv *mcp.Server, executor jxa.Executor, loc *time.Location) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "find_messages",
-			Description: "Find messages in a mailbox. At least one filter criterion must be specified.",
-			InputSchema: GenerateSchema[FindMessagesInput](),
+			Name:         "find_messages",
+			Description:  "Find messages in a mailbox. Omit mailboxPath to search all mailboxes of the account and account to search all enabled accounts. Results are sorted newest first by default and returned in pages; pass the returned cursor to get the next page. At least one filter criterion must be specified.",
+			InputSchema:  GenerateSchema[FindMessagesInput](),
+			OutputSchema: GenerateSchema[FindMessagesOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Find Messages",
 				ReadOnlyHint:    true,
@@ -41,11 +107,21 @@ func RegisterFindMessages(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleFindMessages,
+		func(ctx context.Context, request *mcp.CallToolRequest, input FindMessagesInput) (*mcp.CallToolResult, *FindMessagesOutput, error) {
+			return HandleFindMessages(ctx, executor, loc, request, input)
+		},
 	)
 }
 
-func HandleFindMessages(ctx context.Context, request *mcp.CallToolRequest, input FindMessagesInput) (*mcp.CallToolResult, any, error) {
+// HandleFindMessages finds messages, interpreting date filters without an
+// explicit time zone in loc, or in the local time zone if loc is nil.
+func HandleFindMessages(ctx context.Context, executor jxa.Executor, loc *time.Location, request *mcp.CallToolRequest, input FindMessagesInput) (*mcp.CallToolResult, *FindMessagesOutput, error) {
+	if input.Query != "" {
+		if err := applyQuery(&input); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Apply default limit
 	if input.Limit == 0 {
 		input.Limit = 50
@@ -53,12 +129,31 @@ func HandleFindMessages(ctx context.Context, request *mcp.CallToolRequest, input
 
 	// Validate limit
 	if input.Limit < 1 || input.Limit > 1000 {
-		return nil, nil, fmt.Errorf("limit must be between 1 and 1000")
+		return nil, nil, invalidParameters("limit must be between 1 and 1000")
 	}
 
-	// Validate mailbox path
-	if len(input.MailboxPath) == 0 {
-		return nil, nil, fmt.Errorf("mailboxPath is required")
+	// Apply default sort order
+	if input.Sort == "" {
+		input.Sort = SortDateDesc
+	}
+	if !slices.Contains(SortOrders, input.Sort) {
+		return nil, nil, invalidParameters("invalid sort: %s (valid: %v)", input.Sort, SortOrders)
+	}
+
+	// Exclusions only apply to searches over all mailboxes
+	if len(input.MailboxPath) > 0 && len(input.Exclude) > 0 {
+		return nil, nil, invalidParameters("exclude can only be used if mailboxPath is omitted")
+	}
+
+	// Validate size and attachment filters
+	if input.MinSize < 0 || input.MaxSize < 0 {
+		return nil, nil, invalidParameters("minSize and maxSize must not be negative")
+	}
+	if input.MinSize > 0 && input.MaxSize > 0 && input.MinSize > input.MaxSize {
+		return nil, nil, invalidParameters("minSize must not be greater than maxSize")
+	}
+	if input.AttachmentName != "" && input.HasAttachments != nil && !*input.HasAttachments {
+		return nil, nil, invalidParameters("attachmentName cannot be combined with hasAttachments false")
 	}
 
 	// Require at least one filter criterion
@@ -67,22 +162,350 @@ func HandleFindMessages(ctx context.Context, request *mcp.CallToolRequest, input
 		input.ReadStatus != nil ||
 		input.FlaggedOnly ||
 		input.DateAfter != "" ||
-		input.DateBefore != ""
+		input.DateBefore != "" ||
+		input.Body != "" ||
+		input.Recipient != "" ||
+		input.Cc != "" ||
+		input.ReplyTo != "" ||
+		input.HasAttachments != nil ||
+		input.AttachmentName != "" ||
+		input.MinSize > 0 ||
+		input.MaxSize > 0
 
 	if !hasFilter {
-		return nil, nil, fmt.Errorf("at least one filter criterion is required (subject, sender, readStatus, flaggedOnly, dateAfter, or dateBefore)")
+		return nil, nil, missingParameters("at least one filter criterion is required (query, subject, sender, readStatus, flaggedOnly, dateAfter, dateBefore, body, recipient, cc, replyTo, hasAttachments, attachmentName, minSize, or maxSize)")
+	}
+
+	var after *messageKey
+	if input.Cursor != "" {
+		var err error
+		if after, err = decodeCursor(input); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Cursors refer to the dates as given, since relative dates are resolved
+	// again for every page
+	page := input
+	if loc == nil {
+		loc = time.Local
+	}
+	if err := resolveDates(&input, time.Now().In(loc)); err != nil {
+		return nil, nil, err
+	}
+
+	var result *FindMessagesOutput
+	if input.Account != "" && len(input.MailboxPath) > 0 {
+		var err error
+		result, err = findMessagesIn(ctx, executor, findMessagesScan{FindMessagesInput: input, After: after})
+		if err != nil {
+			return nil, nil, err
+		}
+		result.MailboxesSearched = 1
+	} else {
+		scans, err := mailboxScans(ctx, executor, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		results := make([]*FindMessagesOutput, len(scans))
+		err = forEachParallel(ctx, len(scans), findMessagesWorkers, func(ctx context.Context, i int) error {
+			scan := findMessagesScan{FindMessagesInput: input, After: after}
+			scan.Account = scans[i].account
+			scan.MailboxPath = scans[i].mailboxPath
+			scan.Exclude = nil
+
+			result, err := findMessagesIn(ctx, executor, scan)
+			// A mailbox path given for all accounts only exists in some of them
+			var jxaErr *jxa.Error
+			if input.Account == "" && errors.As(err, &jxaErr) && jxaErr.Code == jxa.ErrorCodeMailboxNotFound {
+				return nil
+			}
+			results[i] = result
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		result = mergeFindMessagesResults(input, results)
+	}
+
+	if result.HasMore && len(result.Messages) > 0 {
+		cursor := encodeCursor(page, result.Messages[len(result.Messages)-1])
+		result.Cursor = &cursor
+	}
+	return nil, result, nil
+}
+
+// resolveDates replaces the date filters by RFC 3339 timestamps in UTC, so
+// that the script does not need to understand relative dates like "7d".
+func resolveDates(input *FindMessagesInput, now time.Time) error {
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{{"dateAfter", &input.DateAfter}, {"dateBefore", &input.DateBefore}} {
+		if *f.value == "" {
+			continue
+		}
+		t, err := dates.Parse(*f.value, now)
+		if err != nil {
+			return invalidParameters("invalid %s: %v", f.name, err)
+		}
+		*f.value = t.Format(time.RFC3339)
+	}
+	if input.DateAfter != "" && input.DateBefore != "" && input.DateAfter >= input.DateBefore {
+		return invalidParameters("dateAfter must be before dateBefore")
+	}
+	return nil
+}
+
+// applyQuery sets the filters of the query in the input. A filter set by
+// both the query and a parameter is an error unless both agree.
+func applyQuery(input *FindMessagesInput) error {
+	f, err := query.Parse(input.Query)
+	if err != nil {
+		return invalidParameters("invalid query: %v", err)
+	}
+
+	var conflicts []string
+	merge := func(name string, dst *string, v string) {
+		if v != "" && *dst != "" && *dst != v {
+			conflicts = append(conflicts, name)
+		} else if v != "" {
+			*dst = v
+		}
+	}
+	mergeInt := func(name string, dst *int, v int) {
+		if v != 0 && *dst != 0 && *dst != v {
+			conflicts = append(conflicts, name)
+		} else if v != 0 {
+			*dst = v
+		}
+	}
+	mergeBool := func(name string, dst **bool, v *bool) {
+		if v != nil && *dst != nil && **dst != *v {
+			conflicts = append(conflicts, name)
+		} else if v != nil {
+			*dst = v
+		}
+	}
+
+	merge("account", &input.Account, f.Account)
+	if len(f.MailboxPath) > 0 && len(input.MailboxPath) > 0 && !slices.Equal(input.MailboxPath, f.MailboxPath) {
+		conflicts = append(conflicts, "mailboxPath")
+	} else if len(f.MailboxPath) > 0 {
+		input.MailboxPath = f.MailboxPath
+	}
+	input.Exclude = append(input.Exclude, f.Exclude...)
+	merge("subject", &input.Subject, f.Subject)
+	merge("sender", &input.Sender, f.Sender)
+	merge("recipient", &input.Recipient, f.Recipient)
+	merge("cc", &input.Cc, f.Cc)
+	merge("body", &input.Body, f.Body)
+	merge("replyTo", &input.ReplyTo, f.ReplyTo)
+	merge("attachmentName", &input.AttachmentName, f.AttachmentName)
+	mergeBool("readStatus", &input.ReadStatus, f.ReadStatus)
+	input.FlaggedOnly = input.FlaggedOnly || f.FlaggedOnly
+	mergeBool("hasAttachments", &input.HasAttachments, f.HasAttachments)
+	merge("dateAfter", &input.DateAfter, f.DateAfter)
+	merge("dateBefore", &input.DateBefore, f.DateBefore)
+	mergeInt("minSize", &input.MinSize, f.MinSize)
+	mergeInt("maxSize", &input.MaxSize, f.MaxSize)
+
+	if len(conflicts) > 0 {
+		return invalidParameters("query conflicts with parameters: %s", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// findMessagesIn runs the find_messages script for a single mailbox.
+func findMessagesIn(ctx context.Context, executor jxa.Executor, scan findMessagesScan) (*FindMessagesOutput, error) {
+	scan.Cursor = ""
+	inputJSON, err := json.Marshal(scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	// Marshal input to JSON
-	inputJSON, err := json.Marshal(input)
+	data, err := executor.Execute(ctx, findMessagesScript, string(inputJSON))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+		return nil, fmt.Errorf("failed to execute find_messages: %w", err)
 	}
 
-	data, err := jxa.Execute(ctx, findMessagesScript, string(inputJSON))
+	return decodeResult[FindMessagesOutput](data)
+}
+
+// mailboxScans returns the mailboxes to search if the account or mailbox
+// path is omitted.
+func mailboxScans(ctx context.Context, executor jxa.Executor, input FindMessagesInput) ([]mailboxScan, error) {
+	accounts := []string{input.Account}
+	if input.Account == "" {
+		_, result, err := HandleListAccounts(ctx, executor, nil, ListAccountsInput{Enabled: true})
+		if err != nil {
+			return nil, err
+		}
+		accounts = accounts[:0]
+		for _, a := range result.Accounts {
+			accounts = append(accounts, a.Name)
+		}
+	}
+
+	if len(input.MailboxPath) > 0 {
+		var scans []mailboxScan
+		for _, a := range accounts {
+			scans = append(scans, mailboxScan{account: a, mailboxPath: input.MailboxPath})
+		}
+		return scans, nil
+	}
+
+	excluded := excludedNames(input.Exclude)
+	perAccount := make([][]mailboxScan, len(accounts))
+	err := forEachParallel(ctx, len(accounts), findMessagesWorkers, func(ctx context.Context, i int) error {
+		// Walk the mailbox tree, skipping excluded mailboxes with their sub-mailboxes
+		parents := [][]string{nil}
+		for len(parents) > 0 {
+			parent := parents[0]
+			parents = parents[1:]
+			_, result, err := HandleListMailboxes(ctx, executor, nil, ListMailboxesInput{Account: accounts[i], MailboxPath: parent})
+			if err != nil {
+				return err
+			}
+			for _, m := range result.Mailboxes {
+				if excluded[strings.ToLower(m.Name)] {
+					continue
+				}
+				perAccount[i] = append(perAccount[i], mailboxScan{account: accounts[i], mailboxPath: m.MailboxPath, messageCount: m.MessageCount})
+				if m.HasSubMailboxes {
+					parents = append(parents, m.MailboxPath)
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute find_messages: %w", err)
+		return nil, err
 	}
+	return slices.Concat(perAccount...), nil
+}
 
-	return nil, data, nil
+// excludedNames returns the lower-case mailbox names matched by the exclude
+// option of find_messages.
+func excludedNames(exclude []string) map[string]bool {
+	names := make(map[string]bool)
+	for _, e := range exclude {
+		name := strings.ToLower(e)
+		names[name] = true
+		for _, alias := range excludeAliases[name] {
+			names[alias] = true
+		}
+	}
+	return names
+}
+
+// mergeFindMessagesResults merges the results of several mailboxes in sort
+// order. Results of mailboxes that were skipped are nil.
+func mergeFindMessagesResults(input FindMessagesInput, results []*FindMessagesOutput) *FindMessagesOutput {
+	merged := &FindMessagesOutput{
+		Messages:       []MessageSummary{},
+		Limit:          input.Limit,
+		FiltersApplied: findMessagesFilters(input),
+		Sort:           input.Sort,
+	}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		merged.Messages = append(merged.Messages, r.Messages...)
+		merged.TotalMatches += r.TotalMatches
+		merged.HasMore = merged.HasMore || r.HasMore
+		merged.MailboxesSearched++
+	}
+
+	slices.SortFunc(merged.Messages, func(a, b MessageSummary) int {
+		return compareMessages(input.Sort, a, b)
+	})
+	if len(merged.Messages) > input.Limit {
+		merged.Messages = merged.Messages[:input.Limit]
+		merged.HasMore = true
+	}
+	merged.Count = len(merged.Messages)
+	return merged
+}
+
+// compareMessages orders messages by the sort order and breaks ties by date
+// (newest first), account, mailbox path and ID. Mirrors compareMatches of
+// scripts/find_messages.js.
+func compareMessages(sort string, a, b MessageSummary) int {
+	var c int
+	switch sort {
+	case SortDateAsc:
+		// ISO 8601 dates in UTC sort lexicographically
+		c = strings.Compare(a.DateReceived, b.DateReceived)
+	case SortSender:
+		c = strings.Compare(foldCase(a.Sender), foldCase(b.Sender))
+	case SortSubject:
+		c = strings.Compare(foldCase(a.Subject), foldCase(b.Subject))
+	}
+	if c == 0 && sort != SortDateAsc {
+		c = strings.Compare(b.DateReceived, a.DateReceived)
+	}
+	if c == 0 {
+		c = strings.Compare(a.Account, b.Account)
+	}
+	if c == 0 {
+		c = slices.Compare(a.MailboxPath, b.MailboxPath)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// foldCase lower-cases each rune like the script's foldCase, which keeps
+// U+0130 as its lower case in JavaScript is two code points. Strings of
+// folded runes then compare by code points on both sides, as
+// strings.Compare does for UTF-8.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\u0130' {
+			return r
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// findMessagesFilters mirrors the filters_applied echo of the script.
+func findMessagesFilters(input FindMessagesInput) FindMessagesFilters {
+	return FindMessagesFilters{
+		Subject:        nilIfEmpty(input.Subject),
+		Sender:         nilIfEmpty(input.Sender),
+		ReadStatus:     input.ReadStatus,
+		FlaggedOnly:    input.FlaggedOnly,
+		DateAfter:      nilIfEmpty(input.DateAfter),
+		DateBefore:     nilIfEmpty(input.DateBefore),
+		Body:           nilIfEmpty(input.Body),
+		Recipient:      nilIfEmpty(input.Recipient),
+		Cc:             nilIfEmpty(input.Cc),
+		ReplyTo:        nilIfEmpty(input.ReplyTo),
+		HasAttachments: input.HasAttachments,
+		AttachmentName: nilIfEmpty(input.AttachmentName),
+		MinSize:        nilIfZero(input.MinSize),
+		MaxSize:        nilIfZero(input.MaxSize),
+		Exclude:        input.Exclude,
+	}
+}
+
+// nilIfEmpty mirrors the `value || null` idiom of the scripts.
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nilIfZero mirrors the `value || null` idiom of the scripts for numbers.
+func nilIfZero(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
 }
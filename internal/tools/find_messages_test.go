@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestHandleFindMessages_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   FindMessagesInput
+		wantErr string
+	}{
+		{
+			name:    "exclude with mailbox path",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Exclude: []string{"Trash"}, Subject: "invoice"},
+			wantErr: "exclude can only be used if mailboxPath is omitted",
+		},
+		{
+			name:    "no filter",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}},
+			wantErr: "at least one filter criterion is required",
+		},
+		{
+			name:    "limit too large",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "x", Limit: 1001},
+			wantErr: "limit must be between 1 and 1000",
+		},
+		{
+			name:    "invalid query",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Query: `subject:"open`},
+			wantErr: "invalid query: unterminated quote at offset 0",
+		},
+		{
+			name:    "query conflicts with parameter",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Query: "from:alice in:Archive", Sender: "bob"},
+			wantErr: "query conflicts with parameters: mailboxPath, sender",
+		},
+		{
+			name:    "invalid date",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, DateAfter: "someday"},
+			wantErr: `invalid dateAfter: unknown date "someday"`,
+		},
+		{
+			name:    "empty date range",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, DateAfter: "yesterday", DateBefore: "7d"},
+			wantErr: "dateAfter must be before dateBefore",
+		},
+		{
+			name:    "negative size",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MinSize: -1},
+			wantErr: "minSize and maxSize must not be negative",
+		},
+		{
+			name:    "empty size range",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MinSize: 2048, MaxSize: 1024},
+			wantErr: "minSize must not be greater than maxSize",
+		},
+		{
+			name:    "attachment name without attachments",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, AttachmentName: ".pdf", HasAttachments: new(false)},
+			wantErr: "attachmentName cannot be combined with hasAttachments false",
+		},
+		{
+			name:    "invalid sort",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "x", Sort: "size"},
+			wantErr: "invalid sort: size",
+		},
+		{
+			name:    "invalid cursor",
+			input:   FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "x", Cursor: "not a cursor"},
+			wantErr: "invalid cursor",
+		},
+		{
+			name: "cursor of other query",
+			input: FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "y",
+				Cursor: encodeCursor(FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "x", Sort: SortDateDesc}, MessageSummary{ID: 1})},
+			wantErr: "cursor belongs to a different query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := jxa.NewFakeExecutor()
+			_, _, err := HandleFindMessages(context.Background(), fake, nil, nil, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("HandleFindMessages() error = %v, want %q", err, tt.wantErr)
+			}
+			if n := len(fake.Calls()); n != 0 {
+				t.Errorf("executor called %d times, want 0", n)
+			}
+		})
+	}
+}
+
+func TestHandleFindMessages_DefaultLimit(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("find_messages", jxa.Result{
+		Success: true,
+		Data: map[string]any{
+			"messages":        []any{},
+			"count":           0,
+			"total_matches":   0,
+			"limit":           50,
+			"has_more":        false,
+			"filters_applied": map[string]any{"subject": "invoice", "flagged_only": false},
+			"sort":            "date_desc",
+		},
+	})
+
+	input := FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "invoice"}
+	if _, _, err := HandleFindMessages(context.Background(), fake, nil, nil, input); err != nil {
+		t.Fatalf("HandleFindMessages() error = %v", err)
+	}
+
+	calls := fake.CallsTo("find_messages")
+	if len(calls) != 1 {
+		t.Fatalf("find_messages called %d times, want 1", len(calls))
+	}
+	if got := unmarshalArg(t, calls[0])["limit"]; got != float64(50) {
+		t.Errorf("limit = %v, want 50", got)
+	}
+}
+
+func TestHandleFindMessages_Query(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("find_messages", findMessagesResult("Work", []string{"Inbox"}))
+
+	input := FindMessagesInput{
+		Account: "Work",
+		Query:   `in:Inbox from:alice is:unread has:attachment after:2024-05-01 subject:"quarterly report"`,
+		Sender:  "alice",
+	}
+	if _, _, err := HandleFindMessages(context.Background(), fake, time.FixedZone("CEST", 2*60*60), nil, input); err != nil {
+		t.Fatalf("HandleFindMessages() error = %v", err)
+	}
+
+	args := unmarshalArg(t, fake.CallsTo("find_messages")[0])
+	want := map[string]any{
+		"mailboxPath":    []any{"Inbox"},
+		"sender":         "alice",
+		"readStatus":     false,
+		"hasAttachments": true,
+		"dateAfter":      "2024-04-30T22:00:00Z",
+		"subject":        "quarterly report",
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(args[k], v) {
+			t.Errorf("%s = %v, want %v", k, args[k], v)
+		}
+	}
+}
+
+func TestHandleFindMessages_Cursor(t *testing.T) {
+	fake := jxa.NewFakeExecutor().OnFunc("find_messages", func(args []string) jxa.Result {
+		result := findMessagesResult("Work", []string{"Inbox"}, "2025-03-01T00:00:00.000Z", "2025-02-01T00:00:00.000Z")
+		result.Data["has_more"] = true
+		return result
+	})
+
+	input := FindMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, Subject: "s", Limit: 2}
+	_, first, err := HandleFindMessages(context.Background(), fake, nil, nil, input)
+	if err != nil {
+		t.Fatalf("HandleFindMessages() error = %v", err)
+	}
+	if first.Cursor == nil {
+		t.Fatal("cursor = nil, want a cursor for the next page")
+	}
+	if after := unmarshalArg(t, fake.CallsTo("find_messages")[0])["after"]; after != nil {
+		t.Errorf("first page after = %v, want none", after)
+	}
+
+	// The limit may change between pages
+	input.Cursor = *first.Cursor
+	input.Limit = 10
+	if _, _, err := HandleFindMessages(context.Background(), fake, nil, nil, input); err != nil {
+		t.Fatalf("HandleFindMessages() with cursor error = %v", err)
+	}
+	args := unmarshalArg(t, fake.CallsTo("find_messages")[1])
+	after, _ := args["after"].(map[string]any)
+	if after["date_received"] != "2025-02-01T00:00:00.000Z" || after["id"] != float64(2) {
+		t.Errorf("after = %v, want the key of the last message of the first page", args["after"])
+	}
+	if _, ok := args["cursor"]; ok {
+		t.Errorf("cursor passed to the script: %v", args["cursor"])
+	}
+}
+
+// findMessagesResult returns a find_messages result with one message per date.
+func findMessagesResult(account string, mailboxPath []string, dates ...string) jxa.Result {
+	messages := []any{}
+	for i, d := range dates {
+		messages = append(messages, map[string]any{
+			"id": i + 1, "subject": "s", "sender": "a@example.com", "date_received": d, "date_sent": nil,
+			"read_status": false, "flagged_status": false, "message_size": 1, "content_preview": "", "content_length": 0,
+			"mailbox_path": mailboxPath, "account": account,
+		})
+	}
+	return jxa.Result{Success: true, Data: map[string]any{
+		"messages": messages, "count": len(messages), "total_matches": len(messages), "limit": 50, "has_more": false,
+		"filters_applied": map[string]any{"subject": "s", "flagged_only": false}, "sort": "date_desc",
+	}}
+}
+
+func TestHandleFindMessages_AllMailboxes(t *testing.T) {
+	mailbox := func(path []string, hasSubMailboxes bool) map[string]any {
+		return map[string]any{
+			"name": path[len(path)-1], "mailboxPath": path, "account": "Work", "unreadCount": 0,
+			"messageCount": 1, "hasSubMailboxes": hasSubMailboxes, "subMailboxCount": 0,
+		}
+	}
+	dates := map[string][]string{
+		"INBOX":            {"2025-03-01T00:00:00.000Z", "2025-01-01T00:00:00.000Z"},
+		"INBOX > Projects": {"2025-02-01T00:00:00.000Z"},
+		"Sent Messages":    {"2025-04-01T00:00:00.000Z"},
+		"Deleted Messages": {"2025-05-01T00:00:00.000Z"},
+	}
+
+	fake := jxa.NewFakeExecutor().
+		OnFunc("list_mailboxes", func(args []string) jxa.Result {
+			var in ListMailboxesInput
+			_ = json.Unmarshal([]byte(args[0]), &in)
+			mailboxes := []any{}
+			if len(in.MailboxPath) == 0 {
+				mailboxes = append(mailboxes,
+					mailbox([]string{"INBOX"}, true),
+					mailbox([]string{"Sent Messages"}, false),
+					mailbox([]string{"Deleted Messages"}, true))
+			} else if in.MailboxPath[0] == "INBOX" {
+				mailboxes = append(mailboxes, mailbox([]string{"INBOX", "Projects"}, false))
+			} else {
+				t.Errorf("list_mailboxes called for excluded mailbox %v", in.MailboxPath)
+			}
+			return jxa.Result{Success: true, Data: map[string]any{"mailboxes": mailboxes, "count": len(mailboxes)}}
+		}).
+		OnFunc("find_messages", func(args []string) jxa.Result {
+			var in findMessagesScan
+			_ = json.Unmarshal([]byte(args[0]), &in)
+			if in.Sort != "date_desc" {
+				t.Errorf("find_messages sort = %q, want date_desc", in.Sort)
+			}
+			return findMessagesResult(in.Account, in.MailboxPath, dates[strings.Join(in.MailboxPath, " > ")]...)
+		})
+
+	input := FindMessagesInput{Account: "Work", Exclude: []string{"trash"}, Subject: "s", Limit: 3}
+	_, got, err := HandleFindMessages(context.Background(), fake, nil, nil, input)
+	if err != nil {
+		t.Fatalf("HandleFindMessages() error = %v", err)
+	}
+
+	var gotDates []string
+	for _, m := range got.Messages {
+		gotDates = append(gotDates, m.DateReceived)
+	}
+	wantDates := []string{"2025-04-01T00:00:00.000Z", "2025-03-01T00:00:00.000Z", "2025-02-01T00:00:00.000Z"}
+	if !slices.Equal(gotDates, wantDates) {
+		t.Errorf("dates = %v, want %v", gotDates, wantDates)
+	}
+	if got.MailboxesSearched != 3 || got.TotalMatches != 4 || got.Count != 3 || !got.HasMore {
+		t.Errorf("got mailboxes_searched %d, total_matches %d, count %d, has_more %v, want 3, 4, 3, true",
+			got.MailboxesSearched, got.TotalMatches, got.Count, got.HasMore)
+	}
+}
+
+func TestHandleFindMessages_AllAccounts(t *testing.T) {
+	fake := jxa.NewFakeExecutor().
+		On("list_accounts", jxa.Result{Success: true, Data: map[string]any{
+			"accounts": []any{
+				map[string]any{"name": "Work", "enabled": true, "emailAddresses": []any{}, "mailboxCount": 1},
+				map[string]any{"name": "Personal", "enabled": true, "emailAddresses": []any{}, "mailboxCount": 1},
+			},
+			"count": 2,
+		}}).
+		OnFunc("find_messages", func(args []string) jxa.Result {
+			var in findMessagesScan
+			_ = json.Unmarshal([]byte(args[0]), &in)
+			if in.Account == "Personal" {
+				return jxa.Result{Success: false, Error: "Mailbox path 'Archive' not found in account 'Personal'.", ErrorCode: jxa.ErrorCodeMailboxNotFound}
+			}
+			return findMessagesResult(in.Account, in.MailboxPath, "2025-03-01T00:00:00.000Z")
+		})
+
+	input := FindMessagesInput{MailboxPath: []string{"Archive"}, Subject: "s"}
+	_, got, err := HandleFindMessages(context.Background(), fake, nil, nil, input)
+	if err != nil {
+		t.Fatalf("HandleFindMessages() error = %v", err)
+	}
+	if got.Count != 1 || got.Messages[0].Account != "Work" || got.MailboxesSearched != 1 {
+		t.Errorf("got %+v, want the message of Work only", got)
+	}
+	if args := unmarshalArg(t, fake.CallsTo("list_accounts")[0]); args["enabled"] != true {
+		t.Errorf("list_accounts enabled = %v, want true", args["enabled"])
+	}
+}
+
+func TestCompareMessages_Sender(t *testing.T) {
+	// The order must match compareMatches of the script, which compares code
+	// points of senders lower-cased one code point at a time.
+	senders := []string{"\U0001D49C Bot", "ｚ Shop", "İlker", "Émile", "ilker", "Bob"}
+	var messages []MessageSummary
+	for i, s := range senders {
+		messages = append(messages, MessageSummary{ID: i, Sender: s, DateReceived: "2025-03-01T00:00:00.000Z"})
+	}
+	slices.SortFunc(messages, func(a, b MessageSummary) int {
+		return compareMessages(SortSender, a, b)
+	})
+	var got []string
+	for _, m := range messages {
+		got = append(got, m.Sender)
+	}
+	want := []string{"Bob", "ilker", "Émile", "İlker", "ｚ Shop", "\U0001D49C Bot"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted senders = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/extract"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxAttachmentMessageSize is the maximum size in bytes of a message to
+// extract attachments from. The source of the message is transferred as a
+// whole before the attachment is found, so larger messages are rejected
+// before the transfer. Twice extract.MaxSize leaves room for the base64
+// encoding of an attachment of that size, which grows it by over a third.
+const maxAttachmentMessageSize = 2 * extract.MaxSize
+
+// GetAttachmentTextInput defines input parameters for get_attachment_text tool
+type GetAttachmentTextInput struct {
+	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath []string `json:"mailboxPath" jsonschema:"Path to the mailbox as an array (e.g. ['Inbox'] for top-level or ['Inbox','GitHub'] for nested mailbox). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path to the mailbox. Can be specified multiple times for nested paths."`
+	MessageID   int      `json:"message_id" jsonschema:"The unique ID of the message" long:"message-id" description:"The unique ID of the message"`
+	Name        string   `json:"name,omitempty" jsonschema:"Name of the attachment, as in the attachments of get_message_content" long:"name" description:"Name of the attachment"`
+	Part        string   `json:"part,omitempty" jsonschema:"Path of the MIME part instead of a name, as in the parts of get_message_source (e.g. '1.2'), for parts without a name" long:"part" description:"Path of the MIME part instead of a name"`
+	MaxChars    int      `json:"max_chars,omitempty" jsonschema:"Maximum number of characters of text to return, ending at a paragraph break if possible (default: no limit). If the text is cut, next_offset is the offset of the rest." long:"max-chars" description:"Maximum number of characters of text to return (default: no limit)"`
+	Offset      int      `json:"offset,omitempty" jsonschema:"Character offset in the text to start at, the next_offset of a previous call to continue (default: 0)" long:"offset" description:"Character offset in the text to start at (default: 0)"`
+}
+
+// RegisterGetAttachmentText registers the get_attachment_text tool with the MCP server
+func RegisterGetAttachmentText(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "get_attachment_text",
+			Description:  fmt.Sprintf("Extracts the text of an attachment, and structured data where the format has it: rows of CSV files and xlsx sheets, events of iCalendar files, contacts of vCard files and the header of attached messages. Supports text, HTML (as Markdown), CSV, ICS, VCF, attached messages (message/rfc822, .eml) and Word, Excel and PowerPoint documents (docx, xlsx, pptx). Attachments larger than %d MB, or of messages larger than %d MB, are rejected; use max_chars and offset to read long texts in chunks.", extract.MaxSize>>20, maxAttachmentMessageSize>>20),
+			InputSchema:  GenerateSchema[GetAttachmentTextInput](),
+			OutputSchema: GenerateSchema[GetAttachmentTextOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Get Attachment Text",
+				ReadOnlyHint:    true,
+				IdempotentHint:  true,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input GetAttachmentTextInput) (*mcp.CallToolResult, *GetAttachmentTextOutput, error) {
+			return HandleGetAttachmentText(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleGetAttachmentText(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input GetAttachmentTextInput) (*mcp.CallToolResult, *GetAttachmentTextOutput, error) {
+	if len(input.MailboxPath) == 0 {
+		return nil, nil, missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	if input.Name == "" && input.Part == "" {
+		return nil, nil, missingParameters("name or part is required")
+	}
+	if input.Name != "" && input.Part != "" {
+		return nil, nil, invalidParameters("only one of name and part may be given")
+	}
+	if err := validateChunk(input.MaxChars, input.Offset); err != nil {
+		return nil, nil, err
+	}
+
+	msg, err := fetchMessageSource(ctx, executor, input.Account, input.MailboxPath, input.MessageID, maxAttachmentMessageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.MessageSize > maxAttachmentMessageSize {
+		return nil, nil, jxa.NewError(jxa.ErrorCodeAttachmentTooLarge, "message is %d MB, too large to extract its attachments, the limit is %d MB; use save_attachments to save them", msg.MessageSize>>20, maxAttachmentMessageSize>>20)
+	}
+
+	p, err := findAttachmentPart(mimepart.Parse([]byte(msg.Source)), input.Name, input.Part)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := extract.Extract(p)
+	switch {
+	case errors.Is(err, extract.ErrTooLarge):
+		return nil, nil, jxa.NewError(jxa.ErrorCodeAttachmentTooLarge, "attachment %q is too large to extract, the limit is %d MB", p.Filename, extract.MaxSize>>20)
+	case errors.Is(err, extract.ErrUnsupported):
+		return nil, nil, jxa.NewError(jxa.ErrorCodeUnsupportedAttachment, "cannot extract text from attachment %q of type %s, use save_attachments to save it", p.Filename, p.ContentType)
+	case err != nil:
+		return nil, nil, jxa.NewError(jxa.ErrorCodeUnsupportedAttachment, "cannot extract text from attachment %q: %v", p.Filename, err)
+	}
+
+	result := newAttachmentTextOutput(input.MessageID, p, content)
+	result.Text, result.TotalLength, result.NextOffset = chunk(content.Text, input.Offset, input.MaxChars)
+	return nil, result, nil
+}
+
+// findAttachmentPart returns the part with the path, or the first part with
+// the file name.
+func findAttachmentPart(root *mimepart.Part, name, path string) (*mimepart.Part, error) {
+	var found *mimepart.Part
+	var available []string
+	root.Walk(func(p *mimepart.Part) {
+		if p.IsMultipart() {
+			return
+		}
+		if found == nil && (path != "" && p.Path == path || name != "" && p.Filename == name) {
+			found = p
+		}
+		if p.Filename != "" {
+			available = append(available, p.Filename)
+		}
+	})
+	switch {
+	case found != nil:
+		return found, nil
+	case path != "":
+		return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "part %s not found or multipart, use get_message_source to list the parts", path)
+	case len(available) == 0:
+		return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "the message has no attachments")
+	default:
+		return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "attachment %q not found (available: %s)", name, strings.Join(available, ", "))
+	}
+}
+
+// newAttachmentTextOutput converts the extracted content of a part, without
+// its text.
+func newAttachmentTextOutput(id int, p *mimepart.Part, content *extract.Result) *GetAttachmentTextOutput {
+	result := &GetAttachmentTextOutput{
+		ID:          id,
+		Name:        p.Filename,
+		Part:        p.Path,
+		ContentType: p.ContentType,
+		Size:        len(p.Body),
+		Kind:        content.Kind,
+		Method:      content.Method,
+		Truncated:   content.Truncated,
+	}
+	for _, t := range content.Tables {
+		result.Tables = append(result.Tables, AttachmentTable(t))
+	}
+	for _, e := range content.Events {
+		result.Events = append(result.Events, CalendarEvent{
+			UID:         e.UID,
+			Summary:     e.Summary,
+			Description: e.Description,
+			Location:    e.Location,
+			Status:      e.Status,
+			Start:       formatEventTime(e.Start, e.AllDay),
+			End:         formatEventTime(e.End, e.AllDay),
+			AllDay:      e.AllDay,
+		})
+	}
+	for _, c := range content.Contacts {
+		result.Contacts = append(result.Contacts, AttachmentContact(c))
+	}
+	if m := content.Message; m != nil {
+		result.Message = &AttachedMessage{
+			From:        m.From,
+			To:          m.To,
+			Cc:          m.Cc,
+			Date:        m.Date,
+			Subject:     m.Subject,
+			Attachments: m.Attachments,
+		}
+	}
+	return result
+}
+
+// formatEventTime formats the time of an event: a date for all-day events,
+// RFC 3339 in the time zone of the event otherwise, and "" for the zero time.
+func formatEventTime(t time.Time, allDay bool) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case allDay:
+		return t.Format(time.DateOnly)
+	default:
+		return t.Format(time.RFC3339)
+	}
+}
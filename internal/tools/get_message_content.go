@@ -5,28 +5,76 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"slices"
 
+	"github.com/dastrobu/mail-mcp/internal/header"
 	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/md"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+	"github.com/dastrobu/mail-mcp/internal/quote"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/get_message_content.js
-var getMessageContentScript string
+var getMessageContentSource string
+
+var getMessageContentScript = jxa.NewScript("get_message_content", getMessageContentSource)
 
 // GetMessageContentInput defines input parameters for get_message_content tool
 type GetMessageContentInput struct {
 	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
 	MailboxPath []string `json:"mailboxPath" jsonschema:"Path to the mailbox as an array (e.g. ['Inbox'] for top-level or ['Inbox','GitHub'] for nested mailbox). Use the mailboxPath field from get_selected_messages. Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path to the mailbox. Can be specified multiple times for nested paths."`
 	MessageID   int      `json:"message_id" jsonschema:"The unique ID of the message to retrieve" long:"message-id" description:"The unique ID of the message to retrieve"`
+	BodyFormat  string   `json:"body_format,omitempty" jsonschema:"Format of content: 'plain' (default) for the text Mail extracts, 'markdown' for the HTML body converted to Markdown, which keeps links, lists, tables and emphasis, or 'html' for the HTML body as is. Messages without HTML body are returned as plain text." long:"body-format" description:"Format of content: plain (default), markdown or html"`
+	ContentMode string   `json:"content_mode,omitempty" jsonschema:"Which text of a reply to return: 'full' (default) for the whole content, 'new_text_only' for the text the sender wrote, without the quoted history and the signature, or 'split' for the new text as content and the new text, quoted history and signature in split. Requires body_format plain or markdown." long:"content-mode" description:"Which text of a reply to return: full (default), new_text_only or split"`
+	MaxChars    int      `json:"max_chars,omitempty" jsonschema:"Maximum number of characters of content to return, ending at a paragraph break if possible (default: no limit). If content is cut, next_offset is the offset of the rest." long:"max-chars" description:"Maximum number of characters of content to return (default: no limit)"`
+	Offset      int      `json:"offset,omitempty" jsonschema:"Character offset in content to start at, the next_offset of the previous call (default: 0)" long:"offset" description:"Character offset in content to start at (default: 0)"`
+}
+
+// getMessageContentArgs is the input of the get_message_content script.
+type getMessageContentArgs struct {
+	GetMessageContentInput
+	// PartTypes asks for the media types of the MIME parts, see
+	// mayHaveInvitation.
+	PartTypes bool `json:"partTypes,omitempty"`
 }
 
+// messageContent is the result of the get_message_content script.
+type messageContent struct {
+	Message MessageDetail `json:"message"`
+	// PartTypes are the media types of the MIME parts of a multipart
+	// message, if requested.
+	PartTypes []string `json:"partTypes"`
+}
+
+// Body formats of get_message_content.
+const (
+	BodyFormatPlain    = "plain"
+	BodyFormatMarkdown = "markdown"
+	BodyFormatHTML     = "html"
+)
+
+// BodyFormats are the valid body formats of get_message_content.
+var BodyFormats = []string{BodyFormatPlain, BodyFormatMarkdown, BodyFormatHTML}
+
+// Content modes of get_message_content.
+const (
+	ContentModeFull        = "full"
+	ContentModeNewTextOnly = "new_text_only"
+	ContentModeSplit       = "split"
+)
+
+// ContentModes are the valid content modes of get_message_content.
+var ContentModes = []string{ContentModeFull, ContentModeNewTextOnly, ContentModeSplit}
+
 // RegisterGetMessageContent registers the get_message_content tool with the MCP server
-func RegisterGetMessageContent(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterGetMessageContent(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "get_message_content",
-			Description: "Retrieves the full content (body) of a specific message by its ID from a specific account and mailbox. Supports nested mailboxes via mailboxPath array. IMPORTANT: Use the mailboxPath field from get_selected_messages output, not the mailbox field.",
-			InputSchema: GenerateSchema[GetMessageContentInput](),
+			Name:         "get_message_content",
+			Description:  "Retrieves the full content (body) of a specific message by its ID from a specific account and mailbox. Supports nested mailboxes via mailboxPath array. Use body_format 'markdown' for messages like order confirmations or invitations, whose tables and link targets the plain text loses. Calendar invitations are returned as invitation with title, start, end, organizer, attendees, method and recurrence. Use content_mode 'new_text_only' for replies to skip the quoted history of earlier messages, and max_chars to read long messages in chunks. IMPORTANT: Use the mailboxPath field from get_selected_messages output, not the mailbox field.",
+			InputSchema:  GenerateSchema[GetMessageContentInput](),
+			OutputSchema: GenerateSchema[GetMessageContentOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Get Message Content",
 				ReadOnlyHint:    true,
@@ -35,27 +83,166 @@ func RegisterGetMessageContent(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleGetMessageContent,
+		func(ctx context.Context, request *mcp.CallToolRequest, input GetMessageContentInput) (*mcp.CallToolResult, *GetMessageContentOutput, error) {
+			return HandleGetMessageContent(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleGetMessageContent(ctx context.Context, request *mcp.CallToolRequest, input GetMessageContentInput) (*mcp.CallToolResult, any, error) {
+func HandleGetMessageContent(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input GetMessageContentInput) (*mcp.CallToolResult, *GetMessageContentOutput, error) {
 	// Validate mailboxPath
 	if len(input.MailboxPath) == 0 {
-		return nil, nil, fmt.Errorf("mailboxPath is required and must be a non-empty array")
+		return nil, nil, missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	if input.BodyFormat == "" {
+		input.BodyFormat = BodyFormatPlain
+	}
+	if !slices.Contains(BodyFormats, input.BodyFormat) {
+		return nil, nil, invalidParameters("invalid body_format: %s (valid: %v)", input.BodyFormat, BodyFormats)
+	}
+	if input.ContentMode == "" {
+		input.ContentMode = ContentModeFull
+	}
+	if !slices.Contains(ContentModes, input.ContentMode) {
+		return nil, nil, invalidParameters("invalid content_mode: %s (valid: %v)", input.ContentMode, ContentModes)
+	}
+	if input.ContentMode != ContentModeFull && input.BodyFormat == BodyFormatHTML {
+		return nil, nil, invalidParameters("content_mode %s requires body_format %s or %s", input.ContentMode, BodyFormatPlain, BodyFormatMarkdown)
+	}
+	if err := validateChunk(input.MaxChars, input.Offset); err != nil {
+		return nil, nil, err
 	}
 
-	// Marshal input to JSON
-	inputJSON, err := json.Marshal(input)
+	// The other body formats fetch the source anyway
+	msg, err := fetchMessageContent(ctx, executor, input, input.BodyFormat == BodyFormatPlain)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+		return nil, nil, err
+	}
+	result := &GetMessageContentOutput{Message: msg.Message}
+	parseHeaders(&result.Message)
+
+	result.Message.BodyFormat = BodyFormatPlain
+	// The source is only fetched if needed, as it may be large
+	if input.BodyFormat != BodyFormatPlain || mayHaveInvitation(&result.Message, msg.PartTypes) {
+		root, err := fetchMessagePart(ctx, executor, input.Account, input.MailboxPath, input.MessageID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := formatBody(input.BodyFormat, root, &result.Message); err != nil {
+			return nil, nil, err
+		}
+		result.Message.Invitation = findInvitation(root)
 	}
+	splitContent(input.ContentMode, &result.Message)
+	result.Message.Content, result.TotalLength, result.NextOffset = chunk(result.Message.Content, input.Offset, input.MaxChars)
 
-	// Execute JXA script with input as JSON string
-	data, err := jxa.Execute(ctx, getMessageContentScript, string(inputJSON))
+	return nil, result, nil
+}
+
+// fetchMessageContent runs the get_message_content script, which returns
+// the plain content Mail extracts, without fetching the source. With
+// partTypes, the script reads the media types of the parts from the source.
+func fetchMessageContent(ctx context.Context, executor jxa.Executor, input GetMessageContentInput, partTypes bool) (*messageContent, error) {
+	inputJSON, err := json.Marshal(getMessageContentArgs{GetMessageContentInput: input, PartTypes: partTypes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, getMessageContentScript, string(inputJSON))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute get_message_content: %w", err)
+		return nil, fmt.Errorf("failed to execute get_message_content: %w", err)
 	}
 
-	return nil, data, nil
+	return decodeResult[messageContent](data)
+}
+
+// splitContent strips the quoted history and the signature from the content
+// of a message, unless the content mode is full.
+func splitContent(mode string, m *MessageDetail) {
+	if mode == ContentModeFull {
+		return
+	}
+	parts := quote.Split(m.Content)
+	m.Content = parts.New
+	if mode == ContentModeSplit {
+		m.Split = &ContentSplit{
+			NewText:    parts.New,
+			QuotedText: parts.Quoted,
+			Signature:  parts.Signature,
+		}
+	}
+}
+
+// fetchMessagePart fetches and parses the source of a message.
+func fetchMessagePart(ctx context.Context, executor jxa.Executor, account string, mailboxPath []string, id int) (*mimepart.Part, error) {
+	msg, err := fetchMessageSource(ctx, executor, account, mailboxPath, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return mimepart.Parse([]byte(msg.Source)), nil
+}
+
+// fetchMessageSource runs the get_message_source script. The source of
+// messages larger than maxSize bytes is not fetched, unless maxSize is 0.
+func fetchMessageSource(ctx context.Context, executor jxa.Executor, account string, mailboxPath []string, id, maxSize int) (*messageSource, error) {
+	inputJSON, err := json.Marshal(getMessageSourceArgs{
+		GetMessageSourceInput: GetMessageSourceInput{
+			Account:     account,
+			MailboxPath: mailboxPath,
+			MessageID:   id,
+		},
+		MaxSize: maxSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, getMessageSourceScript, string(inputJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get_message_source: %w", err)
+	}
+
+	return decodeResult[messageSource](data)
+}
+
+// formatBody replaces the plain content of a message with its HTML body in
+// the body format, if it has one.
+func formatBody(format string, root *mimepart.Part, m *MessageDetail) error {
+	if format == BodyFormatPlain {
+		return nil
+	}
+	_, html := root.Bodies()
+	if html == "" {
+		return nil
+	}
+
+	switch format {
+	case BodyFormatMarkdown:
+		content, err := md.FromHTML(html)
+		if err != nil {
+			return err
+		}
+		m.Content = content
+	case BodyFormatHTML:
+		m.Content = html
+	}
+	m.BodyFormat = format
+	return nil
+}
+
+// parseHeaders sets the structured headers of a message from its raw
+// headers.
+func parseHeaders(m *MessageDetail) {
+	h := header.Parse(m.AllHeaders)
+	if len(h) == 0 {
+		return
+	}
+	m.Headers = h.Map()
+	m.ListID = h.ListID()
+	m.ListUnsubscribe = h.ListUnsubscribe()
+	m.AutoSubmitted = h.AutoSubmitted()
+	m.InReplyTo = h.InReplyTo()
+	m.References = h.References()
+	m.AuthenticationResults = h.AuthenticationResults()
+	m.Received = h.Received()
 }
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/get_message_source.js
+var getMessageSourceSource string
+
+var getMessageSourceScript = jxa.NewScript("get_message_source", getMessageSourceSource)
+
+// GetMessageSourceInput defines input parameters for get_message_source tool
+type GetMessageSourceInput struct {
+	Account       string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath   []string `json:"mailboxPath" jsonschema:"Path to the mailbox as an array (e.g. ['Inbox'] for top-level or ['Inbox','GitHub'] for nested mailbox). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path to the mailbox. Can be specified multiple times for nested paths."`
+	MessageID     int      `json:"message_id" jsonschema:"The unique ID of the message" long:"message-id" description:"The unique ID of the message"`
+	IncludeSource bool     `json:"include_source,omitempty" jsonschema:"Also return the raw RFC 822 source, which includes the encoded attachments and may be large (default: false)" long:"include-source" description:"Also return the raw RFC 822 source"`
+	MaxChars      int      `json:"max_chars,omitempty" jsonschema:"Maximum number of characters of text_body, html_body and source to return each, ending at a paragraph break if possible (default: no limit). If a body is cut, its next_offset is the offset of the rest." long:"max-chars" description:"Maximum number of characters of each body to return (default: no limit)"`
+	Offset        int      `json:"offset,omitempty" jsonschema:"Character offset in text_body, html_body and source to start at, the next_offset of the body to continue (default: 0)" long:"offset" description:"Character offset in the bodies to start at (default: 0)"`
+}
+
+// getMessageSourceArgs is the input of the get_message_source script.
+type getMessageSourceArgs struct {
+	GetMessageSourceInput
+	// MaxSize skips the source of larger messages, see
+	// maxAttachmentMessageSize.
+	MaxSize int `json:"maxSize,omitempty"`
+}
+
+// messageSource is the result of the get_message_source script.
+type messageSource struct {
+	ID          int    `json:"id"`
+	Source      string `json:"source"`
+	MessageSize int    `json:"messageSize"`
+}
+
+// RegisterGetMessageSource registers the get_message_source tool with the MCP server
+func RegisterGetMessageSource(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "get_message_source",
+			Description:  "Retrieves the MIME structure of a message from its raw source: the tree of parts with content types, charsets, dispositions and sizes, the decoded text/plain and text/html bodies, and the inline images the HTML body references. Use it when the plain content of get_message_content is not enough, e.g. for invoices or newsletters that only make sense in their HTML form.",
+			InputSchema:  GenerateSchema[GetMessageSourceInput](),
+			OutputSchema: GenerateSchema[GetMessageSourceOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Get Message Source",
+				ReadOnlyHint:    true,
+				IdempotentHint:  true,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input GetMessageSourceInput) (*mcp.CallToolResult, *GetMessageSourceOutput, error) {
+			return HandleGetMessageSource(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleGetMessageSource(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input GetMessageSourceInput) (*mcp.CallToolResult, *GetMessageSourceOutput, error) {
+	if len(input.MailboxPath) == 0 {
+		return nil, nil, missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	if err := validateChunk(input.MaxChars, input.Offset); err != nil {
+		return nil, nil, err
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, getMessageSourceScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute get_message_source: %w", err)
+	}
+
+	msg, err := decodeResult[messageSource](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := newMessageSourceOutput(msg.ID, mimepart.Parse([]byte(msg.Source)))
+	result.Size = len(msg.Source)
+	result.TextBody, result.TextBodyLength, result.TextBodyNextOffset = chunk(result.TextBody, input.Offset, input.MaxChars)
+	result.HTMLBody, result.HTMLBodyLength, result.HTMLBodyNextOffset = chunk(result.HTMLBody, input.Offset, input.MaxChars)
+	if input.IncludeSource {
+		source, length, next := chunk(msg.Source, input.Offset, input.MaxChars)
+		result.Source, result.SourceLength, result.SourceNextOffset = &source, &length, next
+	}
+	return nil, result, nil
+}
+
+// newMessageSourceOutput describes the parts of a message.
+func newMessageSourceOutput(id int, root *mimepart.Part) *GetMessageSourceOutput {
+	result := &GetMessageSourceOutput{ID: id, Parts: []MessagePart{}, InlineImages: []InlineImage{}}
+	root.Walk(func(p *mimepart.Part) {
+		result.Parts = append(result.Parts, MessagePart{
+			Path:             p.Path,
+			ContentType:      p.ContentType,
+			Charset:          p.Charset(),
+			Disposition:      p.Disposition,
+			Filename:         p.Filename,
+			ContentID:        p.ContentID,
+			TransferEncoding: p.TransferEncoding,
+			Size:             len(p.Body),
+		})
+	})
+	result.TextBody, result.HTMLBody = root.Bodies()
+
+	// Images referenced by the HTML body first, then the other inline
+	// images, which clients show below the body
+	refs := mimepart.CIDReferences(result.HTMLBody)
+	var images []InlineImage
+	root.Walk(func(p *mimepart.Part) {
+		if !strings.HasPrefix(p.ContentType, "image/") || p.Disposition == "attachment" || p.ContentID == "" && p.Disposition != "inline" {
+			return
+		}
+		images = append(images, InlineImage{
+			ContentID:   p.ContentID,
+			Path:        p.Path,
+			ContentType: p.ContentType,
+			Filename:    p.Filename,
+			Size:        len(p.Body),
+			Referenced:  slices.Contains(refs, p.ContentID),
+		})
+	})
+	slices.SortStableFunc(images, func(a, b InlineImage) int {
+		return refIndex(refs, a.ContentID) - refIndex(refs, b.ContentID)
+	})
+	result.InlineImages = append(result.InlineImages, images...)
+	return result
+}
+
+// refIndex is the position of the first reference to a content ID, or the
+// number of references if it is not referenced.
+func refIndex(refs []string, contentID string) int {
+	if i := slices.Index(refs, contentID); i >= 0 {
+		return i
+	}
+	return len(refs)
+}
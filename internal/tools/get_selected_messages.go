@@ -11,7 +11,9 @@ import (
 )
 
 //go:embed scripts/get_selected_messages.js
-var getSelectedMessagesScript string
+var getSelectedMessagesSource string
+
+var getSelectedMessagesScript = jxa.NewScript("get_selected_messages", getSelectedMessagesSource)
 
 // GetSelectedMessagesInput defines input parameters for get_selected_messages tool
 type GetSelectedMessagesInput struct {
@@ -19,12 +21,13 @@ type GetSelectedMessagesInput struct {
 }
 
 // RegisterGetSelectedMessages registers the get_selected_messages tool with the MCP server
-func RegisterGetSelectedMessages(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterGetSelectedMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "get_selected_messages",
-			Description: "Gets the currently selected message(s) in Mail.app.",
-			InputSchema: GenerateSchema[GetSelectedMessagesInput](),
+			Name:         "get_selected_messages",
+			Description:  "Gets the currently selected message(s) in Mail.app.",
+			InputSchema:  GenerateSchema[GetSelectedMessagesInput](),
+			OutputSchema: GenerateSchema[GetSelectedMessagesOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Get Selected Messages",
 				ReadOnlyHint:    true,
@@ -33,11 +36,13 @@ func RegisterGetSelectedMessages(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleGetSelectedMessages,
+		func(ctx context.Context, request *mcp.CallToolRequest, input GetSelectedMessagesInput) (*mcp.CallToolResult, *GetSelectedMessagesOutput, error) {
+			return HandleGetSelectedMessages(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleGetSelectedMessages(ctx context.Context, request *mcp.CallToolRequest, input GetSelectedMessagesInput) (*mcp.CallToolResult, any, error) {
+func HandleGetSelectedMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input GetSelectedMessagesInput) (*mcp.CallToolResult, *GetSelectedMessagesOutput, error) {
 	// Apply default for limit if not specified
 	if input.Limit == 0 {
 		input.Limit = 5 // default
@@ -48,10 +53,15 @@ func HandleGetSelectedMessages(ctx context.Context, request *mcp.CallToolRequest
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	data, err := jxa.Execute(ctx, getSelectedMessagesScript, string(inputJSON))
+	data, err := executor.Execute(ctx, getSelectedMessagesScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := decodeResult[GetSelectedMessagesOutput](data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return nil, data, nil
+	return nil, result, nil
 }
@@ -0,0 +1,243 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/header"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/thread"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxThreadCandidates bounds the number of messages with the subject of a
+// conversation that are fetched to thread them.
+const maxThreadCandidates = 200
+
+// threadExclude are the mailboxes that are not searched for the messages of
+// a conversation.
+var threadExclude = []string{"Trash", "Junk"}
+
+// GetThreadInput defines input parameters for get_thread tool
+type GetThreadInput struct {
+	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath []string `json:"mailboxPath" jsonschema:"Path to the mailbox of the message as an array (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path to the mailbox of the message. Can be specified multiple times for nested paths."`
+	MessageID   int      `json:"message_id" jsonschema:"The ID of any message of the conversation" long:"message-id" description:"The ID of any message of the conversation"`
+	MaxChars    int      `json:"max_chars,omitempty" jsonschema:"Maximum number of characters of content to return per message, ending at a paragraph break if possible (default: no limit). Read the rest of a message with get_message_content and its next_offset." long:"max-chars" description:"Maximum number of characters of content to return per message (default: no limit)"`
+	Offset      int      `json:"offset,omitempty" jsonschema:"Character offset in the content of each message to start at (default: 0)" long:"offset" description:"Character offset in the content of each message to start at (default: 0)"`
+}
+
+// RegisterGetThread registers the get_thread tool with the MCP server
+func RegisterGetThread(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "get_thread",
+			Description:  "Retrieves the whole conversation of a message, oldest first, with the content of every message. Messages are collected from all mailboxes of the account except Trash and Junk, including Sent, and threaded by their Message-ID, In-Reply-To and References headers, falling back to the subject. Use it to read the full back-and-forth before drafting a reply; max_chars bounds the content of each message.",
+			InputSchema:  GenerateSchema[GetThreadInput](),
+			OutputSchema: GenerateSchema[GetThreadOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Get Thread",
+				ReadOnlyHint:    true,
+				IdempotentHint:  true,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input GetThreadInput) (*mcp.CallToolResult, *GetThreadOutput, error) {
+			return HandleGetThread(ctx, executor, request, input)
+		},
+	)
+}
+
+// threadCandidate is a message that may belong to the conversation.
+type threadCandidate struct {
+	account     string
+	mailboxPath []string
+	detail      *MessageDetail
+	message     *thread.Message
+}
+
+func HandleGetThread(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input GetThreadInput) (*mcp.CallToolResult, *GetThreadOutput, error) {
+	if input.Account == "" {
+		return nil, nil, missingParameters("account is required")
+	}
+	if len(input.MailboxPath) == 0 {
+		return nil, nil, missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	if err := validateChunk(input.MaxChars, input.Offset); err != nil {
+		return nil, nil, err
+	}
+
+	_, seedResult, err := HandleGetMessageContent(ctx, executor, nil, GetMessageContentInput{
+		Account:     input.Account,
+		MailboxPath: input.MailboxPath,
+		MessageID:   input.MessageID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := newThreadCandidate(input.Account, input.MailboxPath, &seedResult.Message)
+	result := &GetThreadOutput{Subject: thread.NormalizeSubject(seed.detail.Subject)}
+
+	// Replies keep the subject, so the messages with the subject are the
+	// candidates. Without subject, only references could link messages,
+	// which cannot be searched for.
+	candidates := []*threadCandidate{seed}
+	if result.Subject != "" {
+		found, err := findThreadCandidates(ctx, executor, input.Account, result, seed)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Copies of a message, e.g. in the Inbox and an archive, are
+		// listed once
+		ids := map[string]bool{seed.message.ID: true}
+		for _, c := range found {
+			if c.message.ID == "" || !ids[c.message.ID] {
+				ids[c.message.ID] = true
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	messages := make([]*thread.Message, len(candidates))
+	byMessage := make(map[*thread.Message]*threadCandidate, len(candidates))
+	for i, c := range candidates {
+		messages[i] = c.message
+		byMessage[c.message] = c
+	}
+	var root *thread.Node
+	for _, r := range thread.Build(messages) {
+		if r.Find(seed.message) != nil {
+			root = r
+			break
+		}
+	}
+
+	result.Messages = []ThreadMessage{}
+	root.Walk(func(n *thread.Node, depth int) {
+		c := byMessage[n.Message]
+		if c == nil {
+			return
+		}
+		m := ThreadMessage{
+			ID:                c.detail.ID,
+			Account:           c.account,
+			MailboxPath:       c.mailboxPath,
+			Subject:           c.detail.Subject,
+			Sender:            c.detail.Sender,
+			ToRecipients:      c.detail.ToRecipients,
+			CcRecipients:      c.detail.CcRecipients,
+			DateReceived:      c.detail.DateReceived,
+			DateSent:          c.detail.DateSent,
+			InternetMessageID: c.message.ID,
+			Depth:             depth,
+		}
+		m.Content, m.TotalLength, m.NextOffset = chunk(c.detail.Content, input.Offset, input.MaxChars)
+		// Nodes without message of a dummy root do not count
+		if root.Message == nil {
+			m.Depth--
+		}
+		if p := n.Parent; p != nil && p.Message != nil && p.Message.ID != "" {
+			m.InReplyTo = &p.Message.ID
+		}
+		result.Messages = append(result.Messages, m)
+	})
+	slices.SortStableFunc(result.Messages, func(a, b ThreadMessage) int {
+		return threadDate(a.DateSent, a.DateReceived).Compare(threadDate(b.DateSent, b.DateReceived))
+	})
+	result.Count = len(result.Messages)
+	return nil, result, nil
+}
+
+// findThreadCandidates fetches the messages of the account with the subject
+// of the conversation, except the seed.
+func findThreadCandidates(ctx context.Context, executor jxa.Executor, account string, result *GetThreadOutput, seed *threadCandidate) ([]*threadCandidate, error) {
+	input := FindMessagesInput{
+		Account: account,
+		Exclude: threadExclude,
+		Subject: result.Subject,
+		Sort:    SortDateAsc,
+		Limit:   maxThreadCandidates,
+	}
+	var summaries []MessageSummary
+	for {
+		_, found, err := HandleFindMessages(ctx, executor, nil, nil, input)
+		if err != nil {
+			return nil, err
+		}
+		result.MailboxesSearched = found.MailboxesSearched
+		for _, m := range found.Messages {
+			if m.ID == seed.detail.ID && slices.Equal(m.MailboxPath, seed.mailboxPath) {
+				continue
+			}
+			summaries = append(summaries, m)
+		}
+		if found.Cursor == nil {
+			break
+		}
+		if len(summaries) >= maxThreadCandidates {
+			result.Truncated = true
+			break
+		}
+		input.Cursor = *found.Cursor
+	}
+	if len(summaries) > maxThreadCandidates {
+		summaries = summaries[:maxThreadCandidates]
+	}
+
+	candidates := make([]*threadCandidate, len(summaries))
+	err := forEachParallel(ctx, len(summaries), findMessagesWorkers, func(ctx context.Context, i int) error {
+		s := summaries[i]
+		_, content, err := HandleGetMessageContent(ctx, executor, nil, GetMessageContentInput{
+			Account:     s.Account,
+			MailboxPath: s.MailboxPath,
+			MessageID:   s.ID,
+		})
+		// The message may have been moved or deleted in the meantime
+		if jxa.HasCode(err, jxa.ErrorCodeMessageNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		candidates[i] = newThreadCandidate(s.Account, s.MailboxPath, &content.Message)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(candidates, func(c *threadCandidate) bool { return c == nil }), nil
+}
+
+// newThreadCandidate creates the candidate of a message from its threading
+// headers.
+func newThreadCandidate(account string, mailboxPath []string, m *MessageDetail) *threadCandidate {
+	c := &threadCandidate{account: account, mailboxPath: mailboxPath, detail: m}
+	c.message = &thread.Message{
+		Subject:    m.Subject,
+		References: thread.References(m.References, m.InReplyTo),
+		Date:       threadDate(m.DateSent, m.DateReceived),
+	}
+	if ids := header.MessageIDs("<" + strings.Trim(m.MessageID, "<>") + ">"); len(ids) > 0 {
+		c.message.ID = ids[0]
+	} else if ids := header.MessageIDs(strings.Join(m.Headers["Message-Id"], " ")); len(ids) > 0 {
+		c.message.ID = ids[0]
+	}
+	return c
+}
+
+// threadDate is the date by which the messages of a conversation are
+// ordered, preferring the date a message was sent.
+func threadDate(sent, received *string) time.Time {
+	for _, d := range []*string{sent, received} {
+		if d == nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, *d); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
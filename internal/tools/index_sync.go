@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/index"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// indexListLimit is the page size when listing the messages of a mailbox.
+const indexListLimit = 1000
+
+// maxIndexedBody bounds the number of bytes of a message body that are
+// indexed, so that huge messages do not bloat the index.
+const maxIndexedBody = 64 << 10
+
+// IndexSyncResult summarizes a sync of the index.
+type IndexSyncResult struct {
+	Mailboxes int // number of synced mailboxes
+	Added     int // number of added messages
+	Removed   int // number of removed messages
+}
+
+// SyncIndex updates the index from the mailboxes of all enabled accounts.
+// Only messages received after the newest indexed message of a mailbox are
+// fetched, unless the number of messages of the mailbox does not match the
+// index afterwards, e.g. because messages were deleted or moved in. Then all
+// messages of the mailbox are listed to find the missing and removed ones.
+// The index is saved even if the sync fails, to keep the progress.
+func SyncIndex(ctx context.Context, executor jxa.Executor, idx *index.Index) (result *IndexSyncResult, err error) {
+	defer func() {
+		if saveErr := idx.Save(); err == nil && saveErr != nil {
+			err = saveErr
+		}
+	}()
+
+	scans, err := mailboxScans(ctx, executor, FindMessagesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop mailboxes that were deleted, renamed or belong to disabled accounts
+	existing := make(map[string]bool)
+	for _, s := range scans {
+		existing[s.account+"\x00"+strings.Join(s.mailboxPath, "\x00")] = true
+	}
+	before := idx.Count()
+	idx.RemoveMailboxes(func(account string, mailboxPath []string) bool {
+		return existing[account+"\x00"+strings.Join(mailboxPath, "\x00")]
+	})
+
+	result = &IndexSyncResult{Removed: before - idx.Count()}
+	for _, s := range scans {
+		added, removed, err := syncMailbox(ctx, executor, idx, s)
+		if err != nil {
+			return result, err
+		}
+		result.Mailboxes++
+		result.Added += added
+		result.Removed += removed
+	}
+	idx.SetSyncedAt(time.Now())
+	return result, nil
+}
+
+// syncMailbox updates the index from a single mailbox and returns the number
+// of added and removed messages.
+func syncMailbox(ctx context.Context, executor jxa.Executor, idx *index.Index, s mailboxScan) (int, int, error) {
+	state, known := idx.Mailbox(s.account, s.mailboxPath)
+	since := time.Unix(0, 0)
+	if known && state.Newest.After(since) {
+		// Messages received in the same minute may have been missed
+		since = state.Newest.Add(-time.Minute)
+	}
+
+	listed, err := listMailbox(ctx, executor, s, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	added, err := indexMessages(ctx, executor, idx, s, listed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed := 0
+	if len(idx.IDs(s.account, s.mailboxPath)) != s.messageCount && known {
+		all, err := listMailbox(ctx, executor, s, time.Unix(0, 0))
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err := indexMessages(ctx, executor, idx, s, all)
+		if err != nil {
+			return 0, 0, err
+		}
+		added += n
+
+		ids := make(map[int]bool, len(all))
+		for _, m := range all {
+			ids[m.ID] = true
+		}
+		for _, id := range idx.IDs(s.account, s.mailboxPath) {
+			if !ids[id] {
+				idx.Remove(s.account, s.mailboxPath, id)
+				removed++
+			}
+		}
+		listed = all
+	}
+
+	newest := state.Newest
+	for _, m := range listed {
+		if t, err := time.Parse(time.RFC3339, m.DateReceived); err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+	idx.SetMailbox(s.account, s.mailboxPath, index.MailboxState{
+		MessageCount: s.messageCount,
+		Newest:       newest,
+		SyncedAt:     time.Now(),
+	})
+	return added, removed, nil
+}
+
+// listMailbox returns the messages of a mailbox received after a date,
+// oldest first. The content of the messages is not read, see indexMessages.
+func listMailbox(ctx context.Context, executor jxa.Executor, s mailboxScan, since time.Time) ([]MessageSummary, error) {
+	scan := findMessagesScan{
+		FindMessagesInput: FindMessagesInput{
+			Account:     s.account,
+			MailboxPath: s.mailboxPath,
+			DateAfter:   since.UTC().Format(time.RFC3339),
+			Sort:        SortDateAsc,
+			Limit:       indexListLimit,
+		},
+		SkipContent: true,
+	}
+	var messages []MessageSummary
+	for {
+		result, err := findMessagesIn(ctx, executor, scan)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, result.Messages...)
+		if !result.HasMore || len(result.Messages) == 0 {
+			return messages, nil
+		}
+		after := keyOf(result.Messages[len(result.Messages)-1])
+		scan.After = &after
+	}
+}
+
+// indexMessages fetches and indexes the listed messages that are not indexed
+// yet. Messages deleted in the meantime are skipped. Only the content Mail
+// extracts is fetched, not the source that get_message_content reads for
+// invitations and other body formats.
+func indexMessages(ctx context.Context, executor jxa.Executor, idx *index.Index, s mailboxScan, listed []MessageSummary) (int, error) {
+	var missing []int
+	for _, m := range listed {
+		if !idx.Contains(s.account, s.mailboxPath, m.ID) {
+			missing = append(missing, m.ID)
+		}
+	}
+
+	var added atomic.Int64
+	err := forEachParallel(ctx, len(missing), findMessagesWorkers, func(ctx context.Context, i int) error {
+		msg, err := fetchMessageContent(ctx, executor, GetMessageContentInput{
+			Account:     s.account,
+			MailboxPath: s.mailboxPath,
+			MessageID:   missing[i],
+		}, false)
+		if jxa.HasCode(err, jxa.ErrorCodeMessageNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		idx.Add(indexMessage(s, &msg.Message))
+		added.Add(1)
+		return nil
+	})
+	return int(added.Load()), err
+}
+
+// indexMessage converts a message to its indexed form.
+func indexMessage(s mailboxScan, m *MessageDetail) index.Message {
+	var recipients []string
+	for _, r := range slices.Concat(m.ToRecipients, m.CcRecipients) {
+		recipients = append(recipients, strings.TrimSpace(r.Name+" "+r.Address))
+	}
+	body := m.Content
+	if len(body) > maxIndexedBody {
+		body = strings.ToValidUTF8(body[:maxIndexedBody], "")
+	}
+	var received time.Time
+	if m.DateReceived != nil {
+		received, _ = time.Parse(time.RFC3339, *m.DateReceived)
+	}
+	return index.Message{
+		Account:      s.account,
+		MailboxPath:  s.mailboxPath,
+		ID:           m.ID,
+		Subject:      m.Subject,
+		Sender:       m.Sender,
+		Recipients:   recipients,
+		Body:         body,
+		DateReceived: received,
+	}
+}
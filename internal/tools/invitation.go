@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/dastrobu/mail-mcp/internal/header"
+	"github.com/dastrobu/mail-mcp/internal/ical"
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+)
+
+// mayHaveInvitation reports whether a message may have a calendar
+// invitation, so that its source is worth fetching: Mail lists an iCalendar
+// attachment, a part is iCalendar data, e.g. a text/calendar alternative of
+// the body, which Mail does not list as attachment, or the headers announce
+// a calendar message, as Outlook does. partTypes are the media types of the
+// parts reported by the get_message_content script.
+func mayHaveInvitation(m *MessageDetail, partTypes []string) bool {
+	for _, a := range m.Attachments {
+		if isCalendarFile(a.Name) {
+			return true
+		}
+	}
+	if slices.Contains(partTypes, "text/calendar") || slices.Contains(partTypes, "application/ics") {
+		return true
+	}
+	h := header.Parse(m.AllHeaders)
+	return strings.Contains(strings.ToLower(h.Get("Content-Type")), "text/calendar") ||
+		strings.Contains(strings.ToLower(h.Get("Content-Class")), "calendarmessage")
+}
+
+func isCalendarFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".ics" || ext == ".vcs"
+}
+
+// findInvitation returns the invitation of a message, or nil. A text/calendar
+// part, usually an alternative to the text body, is preferred over an
+// iCalendar attachment, as mail clients send both with the same content.
+func findInvitation(root *mimepart.Part) *Invitation {
+	var parts, attachments []*mimepart.Part
+	root.Walk(func(p *mimepart.Part) {
+		switch {
+		case p.IsMultipart():
+		case p.ContentType == "text/calendar" && !p.IsAttachment():
+			parts = append(parts, p)
+		case p.ContentType == "text/calendar" || p.ContentType == "application/ics" || isCalendarFile(p.Filename):
+			attachments = append(attachments, p)
+		}
+	})
+	for _, p := range append(parts, attachments...) {
+		if invitation := parseInvitation(p); invitation != nil {
+			return invitation
+		}
+	}
+	return nil
+}
+
+// parseInvitation returns the first event of an iCalendar part that is not
+// a changed occurrence of a recurring event, or nil if there is none.
+func parseInvitation(p *mimepart.Part) *Invitation {
+	cal, err := ical.ParseCalendar([]byte(p.Text()))
+	if err != nil || len(cal.Events) == 0 {
+		return nil
+	}
+	e := cal.Events[0]
+	for _, c := range cal.Events {
+		if c.RecurrenceID.IsZero() {
+			e = c
+			break
+		}
+	}
+
+	invitation := &Invitation{
+		Method:      cal.Method,
+		UID:         e.UID,
+		Title:       e.Summary,
+		Start:       formatEventTime(e.Start, e.AllDay),
+		End:         formatEventTime(e.End, e.AllDay),
+		AllDay:      e.AllDay,
+		TimeZone:    e.TimeZone,
+		Location:    e.Location,
+		Description: e.Description,
+		Status:      e.Status,
+		Part:        p.Path,
+	}
+	if invitation.Method == "" {
+		// The method of the part if the calendar does not have one
+		invitation.Method = strings.ToUpper(p.Params["method"])
+	}
+	if e.Organizer != nil {
+		organizer := InvitationParticipant(*e.Organizer)
+		invitation.Organizer = &organizer
+	}
+	for _, a := range e.Attendees {
+		invitation.Attendees = append(invitation.Attendees, InvitationParticipant(a))
+	}
+	if e.Recurrence != nil {
+		invitation.Recurrence = e.Recurrence.Describe(e.Start)
+		invitation.RecurrenceRule = e.Recurrence.Rule
+	}
+	if len(cal.Events) > 1 {
+		invitation.EventCount = len(cal.Events)
+	}
+	return invitation
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/mimepart"
+)
+
+const invitationEvent = "BEGIN:VCALENDAR\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@example.com\r\n" +
+	"SUMMARY:Weekly sync\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20250303T090000\r\n" +
+	"DTEND;TZID=W. Europe Standard Time:20250303T093000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10\r\n" +
+	"LOCATION:Teams\r\n" +
+	"ORGANIZER;CN=Maria Garcia:mailto:maria@example.com\r\n" +
+	"ATTENDEE;CN=Jane Doe;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:jane@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@example.com\r\n" +
+	"RECURRENCE-ID;TZID=W. Europe Standard Time:20250310T090000\r\n" +
+	"SUMMARY:Weekly sync (moved)\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20250311T090000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestFindInvitation(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	tests := []struct {
+		name     string
+		source   string
+		wantPart string
+	}{
+		{
+			name: "mime part",
+			source: "Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+				"--b\r\nContent-Type: text/plain\r\n\r\nWeekly sync\r\n" +
+				"--b\r\nContent-Type: text/calendar; charset=utf-8\r\n\r\n" + invitationEvent +
+				"--b--\r\n",
+			wantPart: "1.2",
+		},
+		{
+			name: "attachment",
+			source: "Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+				"--b\r\nContent-Type: text/plain\r\n\r\nWeekly sync\r\n" +
+				"--b\r\nContent-Type: application/octet-stream; name=invite.ics\r\nContent-Disposition: attachment; filename=invite.ics\r\n\r\n" + invitationEvent +
+				"--b--\r\n",
+			wantPart: "1.2",
+		},
+		{
+			name:     "message",
+			source:   "Content-Type: text/calendar; method=REQUEST\r\n\r\n" + invitationEvent,
+			wantPart: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findInvitation(mimepart.Parse([]byte(tt.source)))
+			if got == nil {
+				t.Fatal("findInvitation() = nil")
+			}
+			if got.Part != tt.wantPart {
+				t.Errorf("Part = %s, want %s", got.Part, tt.wantPart)
+			}
+			if got.Method != "REQUEST" || got.Title != "Weekly sync" || got.Location != "Teams" || got.EventCount != 2 {
+				t.Errorf("findInvitation() = %+v", got)
+			}
+			if got.Start != "2025-03-03T09:00:00+01:00" || got.End != "2025-03-03T09:30:00+01:00" || got.TimeZone != "W. Europe Standard Time" {
+				t.Errorf("from %s to %s in %s", got.Start, got.End, got.TimeZone)
+			}
+			if got.Recurrence != "weekly on Monday, 10 times" || got.RecurrenceRule != "FREQ=WEEKLY;BYDAY=MO;COUNT=10" {
+				t.Errorf("Recurrence = %q, %q", got.Recurrence, got.RecurrenceRule)
+			}
+			if got.Organizer == nil || *got.Organizer != (InvitationParticipant{Name: "Maria Garcia", Email: "maria@example.com"}) {
+				t.Errorf("Organizer = %+v", got.Organizer)
+			}
+			want := InvitationParticipant{Name: "Jane Doe", Email: "jane@example.com", Role: "REQ-PARTICIPANT", Status: "NEEDS-ACTION", RSVP: true}
+			if len(got.Attendees) != 1 || got.Attendees[0] != want {
+				t.Errorf("Attendees = %+v", got.Attendees)
+			}
+		})
+	}
+}
+
+func TestFindInvitation_Method(t *testing.T) {
+	// The method of the part if the calendar does not have one
+	source := "Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nCancelled\r\n" +
+		"--b\r\nContent-Type: text/calendar; method=cancel\r\n\r\n" +
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Offsite\r\nDTSTART;VALUE=DATE:20250310\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n" +
+		"--b--\r\n"
+	got := findInvitation(mimepart.Parse([]byte(source)))
+	if got == nil {
+		t.Fatal("findInvitation() = nil")
+	}
+	if got.Method != "CANCEL" || got.Status != "CANCELLED" || !got.AllDay || got.Start != "2025-03-10" || got.End != "2025-03-11" {
+		t.Errorf("findInvitation() = %+v", got)
+	}
+
+	for _, source := range []string{
+		"Content-Type: text/plain\r\n\r\nNo invitation",
+		"Content-Type: text/calendar\r\n\r\nnot a calendar",
+		"Content-Type: text/calendar\r\n\r\nBEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+	} {
+		if got := findInvitation(mimepart.Parse([]byte(source))); got != nil {
+			t.Errorf("findInvitation(%q) = %+v, want nil", source, got)
+		}
+	}
+}
+
+func TestMayHaveInvitation(t *testing.T) {
+	tests := []struct {
+		name      string
+		m         MessageDetail
+		partTypes []string
+		want      bool
+	}{
+		{"ics attachment", MessageDetail{Attachments: []Attachment{{Name: "report.pdf"}, {Name: "Invite.ICS"}}}, nil, true},
+		{"content class", MessageDetail{AllHeaders: "Subject: Sync\nContent-Class: urn:content-classes:calendarmessage\n"}, nil, true},
+		{"content type", MessageDetail{AllHeaders: "Content-Type: text/calendar; method=REQUEST\n"}, nil, true},
+		{"calendar part", MessageDetail{AllHeaders: "Content-Type: multipart/alternative; boundary=b\n"}, []string{"multipart/alternative", "text/plain", "text/calendar"}, true},
+		{"plain message", MessageDetail{AllHeaders: "Content-Type: multipart/mixed; boundary=b\n", Attachments: []Attachment{{Name: "report.pdf"}}}, []string{"multipart/mixed", "text/plain", "application/pdf"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mayHaveInvitation(&tt.m, tt.partTypes); got != tt.want {
+				t.Errorf("mayHaveInvitation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
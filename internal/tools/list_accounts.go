@@ -11,7 +11,9 @@ import (
 )
 
 //go:embed scripts/list_accounts.js
-var listAccountsScript string
+var listAccountsSource string
+
+var listAccountsScript = jxa.NewScript("list_accounts", listAccountsSource)
 
 // ListAccountsInput defines input parameters for list_accounts tool
 type ListAccountsInput struct {
@@ -19,12 +21,13 @@ type ListAccountsInput struct {
 }
 
 // RegisterListAccounts registers the list_accounts tool with the MCP server
-func RegisterListAccounts(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterListAccounts(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "list_accounts",
-			Description: "Lists all configured email accounts in Apple Mail with their properties.",
-			InputSchema: GenerateSchema[ListAccountsInput](),
+			Name:         "list_accounts",
+			Description:  "Lists all configured email accounts in Apple Mail with their properties.",
+			InputSchema:  GenerateSchema[ListAccountsInput](),
+			OutputSchema: GenerateSchema[ListAccountsOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "List Mail Accounts",
 				ReadOnlyHint:    true,
@@ -33,21 +36,28 @@ func RegisterListAccounts(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleListAccounts,
+		func(ctx context.Context, request *mcp.CallToolRequest, input ListAccountsInput) (*mcp.CallToolResult, *ListAccountsOutput, error) {
+			return HandleListAccounts(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleListAccounts(ctx context.Context, request *mcp.CallToolRequest, input ListAccountsInput) (*mcp.CallToolResult, any, error) {
+func HandleListAccounts(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input ListAccountsInput) (*mcp.CallToolResult, *ListAccountsOutput, error) {
 	// Execute JXA script with enabled filter
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	data, err := jxa.Execute(ctx, listAccountsScript, string(inputJSON))
+	data, err := executor.Execute(ctx, listAccountsScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to execute list_accounts: %w", err)
 	}
 
-	return nil, data, nil
+	result, err := decodeResult[ListAccountsOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
 }
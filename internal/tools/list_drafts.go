@@ -11,7 +11,9 @@ import (
 )
 
 //go:embed scripts/list_drafts.js
-var listDraftsScript string
+var listDraftsSource string
+
+var listDraftsScript = jxa.NewScript("list_drafts", listDraftsSource)
 
 // ListDraftsInput defines input parameters for list_drafts tool
 type ListDraftsInput struct {
@@ -20,12 +22,13 @@ type ListDraftsInput struct {
 }
 
 // RegisterListDrafts registers the list_drafts tool with the MCP server
-func RegisterListDrafts(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterListDrafts(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "list_drafts",
-			Description: "Lists draft messages from the global Drafts mailbox, optionally filtered by a specific account. Returns Message.id() values for persistent drafts saved in the Drafts mailbox. These are different from OutgoingMessage objects. Use list_outgoing_messages to see in-memory drafts instead.",
-			InputSchema: GenerateSchema[ListDraftsInput](),
+			Name:         "list_drafts",
+			Description:  "Lists draft messages from the global Drafts mailbox, optionally filtered by a specific account. Returns Message.id() values for persistent drafts saved in the Drafts mailbox. These are different from OutgoingMessage objects. Use list_outgoing_messages to see in-memory drafts instead.",
+			InputSchema:  GenerateSchema[ListDraftsInput](),
+			OutputSchema: GenerateSchema[ListDraftsOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "List Draft Messages",
 				ReadOnlyHint:    true,
@@ -34,11 +37,13 @@ func RegisterListDrafts(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleListDrafts,
+		func(ctx context.Context, request *mcp.CallToolRequest, input ListDraftsInput) (*mcp.CallToolResult, *ListDraftsOutput, error) {
+			return HandleListDrafts(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleListDrafts(ctx context.Context, request *mcp.CallToolRequest, input ListDraftsInput) (*mcp.CallToolResult, any, error) {
+func HandleListDrafts(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input ListDraftsInput) (*mcp.CallToolResult, *ListDraftsOutput, error) {
 	// Apply default limit
 	if input.Limit == 0 {
 		input.Limit = 50
@@ -46,7 +51,7 @@ func HandleListDrafts(ctx context.Context, request *mcp.CallToolRequest, input L
 
 	// Validate limit
 	if input.Limit < 1 || input.Limit > 1000 {
-		return nil, nil, fmt.Errorf("limit must be between 1 and 1000")
+		return nil, nil, invalidParameters("limit must be between 1 and 1000")
 	}
 
 	inputJSON, err := json.Marshal(input)
@@ -54,10 +59,15 @@ func HandleListDrafts(ctx context.Context, request *mcp.CallToolRequest, input L
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	data, err := jxa.Execute(ctx, listDraftsScript, string(inputJSON))
+	data, err := executor.Execute(ctx, listDraftsScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to execute list_drafts: %w", err)
 	}
 
-	return nil, data, nil
+	result, err := decodeResult[ListDraftsOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
 }
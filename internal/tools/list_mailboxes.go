@@ -11,7 +11,9 @@ import (
 )
 
 //go:embed scripts/list_mailboxes.js
-var listMailboxesScript string
+var listMailboxesSource string
+
+var listMailboxesScript = jxa.NewScript("list_mailboxes", listMailboxesSource)
 
 // ListMailboxesInput defines input parameters for list_mailboxes tool
 type ListMailboxesInput struct {
@@ -20,12 +22,13 @@ type ListMailboxesInput struct {
 }
 
 // RegisterListMailboxes registers the list_mailboxes tool with the MCP server
-func RegisterListMailboxes(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterListMailboxes(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "list_mailboxes",
-			Description: "Lists mailboxes (folders) for a specific account in Apple Mail. By default lists top-level mailboxes. Optionally provide mailboxPath to list sub-mailboxes of a specific mailbox. Returns mailboxPath for each mailbox to support nested mailbox navigation.",
-			InputSchema: GenerateSchema[ListMailboxesInput](),
+			Name:         "list_mailboxes",
+			Description:  "Lists mailboxes (folders) for a specific account in Apple Mail. By default lists top-level mailboxes. Optionally provide mailboxPath to list sub-mailboxes of a specific mailbox. Returns mailboxPath for each mailbox to support nested mailbox navigation.",
+			InputSchema:  GenerateSchema[ListMailboxesInput](),
+			OutputSchema: GenerateSchema[ListMailboxesOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "List Mailboxes",
 				ReadOnlyHint:    true,
@@ -34,20 +37,27 @@ func RegisterListMailboxes(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleListMailboxes,
+		func(ctx context.Context, request *mcp.CallToolRequest, input ListMailboxesInput) (*mcp.CallToolResult, *ListMailboxesOutput, error) {
+			return HandleListMailboxes(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleListMailboxes(ctx context.Context, request *mcp.CallToolRequest, input ListMailboxesInput) (*mcp.CallToolResult, any, error) {
+func HandleListMailboxes(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input ListMailboxesInput) (*mcp.CallToolResult, *ListMailboxesOutput, error) {
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
-	data, err := jxa.Execute(ctx, listMailboxesScript, string(inputJSON))
+	data, err := executor.Execute(ctx, listMailboxesScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to execute list_mailboxes: %w", err)
 	}
 
-	return nil, data, nil
+	result, err := decodeResult[ListMailboxesOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
 }
@@ -9,15 +9,18 @@ import (
 )
 
 //go:embed scripts/list_outgoing_messages.js
-var listOutgoingMessagesScript string
+var listOutgoingMessagesSource string
+
+var listOutgoingMessagesScript = jxa.NewScript("list_outgoing_messages", listOutgoingMessagesSource)
 
 // RegisterListOutgoingMessages registers the list_outgoing_messages tool with the MCP server
-func RegisterListOutgoingMessages(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterListOutgoingMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "list_outgoing_messages",
-			Description: "Lists all OutgoingMessage objects currently in memory in Mail.app. These are unsent messages that were created with create_outgoing_message or create_reply_draft. Returns outgoing_id for each message which can be used with replace_outgoing_message or replace_reply_draft. Note: Only shows messages in the current Mail.app session - messages are lost when Mail.app is closed or messages are sent.",
-			InputSchema: GenerateSchema[struct{}](),
+			Name:         "list_outgoing_messages",
+			Description:  "Lists all OutgoingMessage objects currently in memory in Mail.app. These are unsent messages that were created with create_outgoing_message or create_reply_draft. Returns outgoing_id for each message which can be used with replace_outgoing_message or replace_reply_draft. Note: Only shows messages in the current Mail.app session - messages are lost when Mail.app is closed or messages are sent.",
+			InputSchema:  GenerateSchema[struct{}](),
+			OutputSchema: GenerateSchema[ListOutgoingMessagesOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "List Outgoing Messages",
 				ReadOnlyHint:    true,
@@ -26,15 +29,22 @@ func RegisterListOutgoingMessages(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		HandleListOutgoingMessages,
+		func(ctx context.Context, request *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, *ListOutgoingMessagesOutput, error) {
+			return HandleListOutgoingMessages(ctx, executor, request, input)
+		},
 	)
 }
 
-func HandleListOutgoingMessages(ctx context.Context, request *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
-	data, err := jxa.Execute(ctx, listOutgoingMessagesScript)
+func HandleListOutgoingMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, *ListOutgoingMessagesOutput, error) {
+	data, err := executor.Execute(ctx, listOutgoingMessagesScript)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := decodeResult[ListOutgoingMessagesOutput](data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return nil, data, nil
+	return nil, result, nil
 }
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/move_messages.js
+var moveMessagesSource string
+
+var moveMessagesScript = jxa.NewScript("move_messages", moveMessagesSource)
+
+// MoveMessagesInput defines input parameters for move_messages tool
+type MoveMessagesInput struct {
+	Account                string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath            []string `json:"mailboxPath" jsonschema:"Path of the mailbox containing the messages (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path of the mailbox containing the messages. Can be specified multiple times for nested paths."`
+	MessageIDs             []int    `json:"message_ids" jsonschema:"IDs of the messages to move" long:"message-id" description:"ID of a message to move. Can be specified multiple times."`
+	DestinationMailboxPath []string `json:"destinationMailboxPath" jsonschema:"Path of the mailbox to move the messages to, in the same account (e.g. ['Archive'] or ['Projects','2025'])" long:"destination-mailbox-path" description:"Path of the mailbox to move the messages to. Can be specified multiple times for nested paths."`
+}
+
+// RegisterMoveMessages registers the move_messages tool with the MCP server
+func RegisterMoveMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "move_messages",
+			Description:  "Moves messages to another mailbox of the same account. Returns the new location of each message; Mail.app assigns new message IDs to moved messages. All message IDs are checked before any message is moved.",
+			InputSchema:  GenerateSchema[MoveMessagesInput](),
+			OutputSchema: GenerateSchema[FileMessagesOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Move Messages",
+				ReadOnlyHint:    false,
+				IdempotentHint:  false,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input MoveMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+			return HandleMoveMessages(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleMoveMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input MoveMessagesInput) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+	if err := validateMessageIDs(input.MailboxPath, input.MessageIDs); err != nil {
+		return nil, nil, err
+	}
+	if err := validateDestination(input.MailboxPath, input.DestinationMailboxPath); err != nil {
+		return nil, nil, err
+	}
+	return fileMessages(ctx, executor, moveMessagesScript, input)
+}
+
+// validateMessageIDs checks the mailbox and message IDs of the tools working
+// on a batch of messages.
+func validateMessageIDs(mailboxPath []string, messageIDs []int) error {
+	if len(mailboxPath) == 0 {
+		return missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	if len(messageIDs) == 0 {
+		return missingParameters("message_ids must contain at least one message ID")
+	}
+	for _, id := range messageIDs {
+		if id < 1 {
+			return invalidParameters("message_ids must be positive integers, got %d", id)
+		}
+	}
+	return nil
+}
+
+// validateDestination checks the destination of move_messages and copy_messages.
+func validateDestination(mailboxPath, destinationMailboxPath []string) error {
+	if len(destinationMailboxPath) == 0 {
+		return missingParameters("destinationMailboxPath is required and must be a non-empty array")
+	}
+	if slices.Equal(mailboxPath, destinationMailboxPath) {
+		return invalidParameters("destinationMailboxPath must differ from mailboxPath")
+	}
+	return nil
+}
+
+// fileMessages runs one of the scripts filing messages into another mailbox.
+func fileMessages(ctx context.Context, executor jxa.Executor, script jxa.Script, input any) (*mcp.CallToolResult, *FileMessagesOutput, error) {
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, script, string(inputJSON))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute %s: %w", script.Name, err)
+	}
+
+	result, err := decodeResult[FileMessagesOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
+}
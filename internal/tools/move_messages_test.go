@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestHandleMoveMessages_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   MoveMessagesInput
+		wantErr string
+	}{
+		{
+			name:    "missing mailbox path",
+			input:   MoveMessagesInput{Account: "Work", MessageIDs: []int{1}, DestinationMailboxPath: []string{"Archive"}},
+			wantErr: "mailboxPath is required",
+		},
+		{
+			name:    "no message IDs",
+			input:   MoveMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, DestinationMailboxPath: []string{"Archive"}},
+			wantErr: "message_ids must contain at least one message ID",
+		},
+		{
+			name:    "invalid message ID",
+			input:   MoveMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1, 0}, DestinationMailboxPath: []string{"Archive"}},
+			wantErr: "message_ids must be positive integers, got 0",
+		},
+		{
+			name:    "missing destination",
+			input:   MoveMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}},
+			wantErr: "destinationMailboxPath is required",
+		},
+		{
+			name:    "same mailbox",
+			input:   MoveMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, DestinationMailboxPath: []string{"Inbox"}},
+			wantErr: "destinationMailboxPath must differ from mailboxPath",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := jxa.NewFakeExecutor()
+			_, _, err := HandleMoveMessages(context.Background(), fake, nil, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("HandleMoveMessages() error = %v, want %q", err, tt.wantErr)
+			}
+			if n := len(fake.Calls()); n != 0 {
+				t.Errorf("executor called %d times, want 0", n)
+			}
+		})
+	}
+}
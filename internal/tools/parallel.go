@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachParallel calls fn for every index in [0, n) on at most workers
+// goroutines. After the first error, the context passed to fn is cancelled,
+// pending calls are skipped and the error is returned.
+func forEachParallel(ctx context.Context, n, workers int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indices := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for range min(n, workers) {
+		wg.Go(func() {
+			for i := range indices {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := fn(ctx, i); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		})
+	}
+	for i := range n {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachParallel(t *testing.T) {
+	var running, maxRunning, calls atomic.Int32
+	err := forEachParallel(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachParallel() error = %v", err)
+	}
+	if calls.Load() != 20 {
+		t.Errorf("fn called %d times, want 20", calls.Load())
+	}
+	if maxRunning.Load() > 3 {
+		t.Errorf("%d calls ran concurrently, want at most 3", maxRunning.Load())
+	}
+}
+
+func TestForEachParallel_StopsAfterError(t *testing.T) {
+	wantErr := errors.New("boom")
+	var calls atomic.Int32
+	err := forEachParallel(context.Background(), 100, 1, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 2 {
+			return wantErr
+		}
+		return nil
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("forEachParallel() error = %v, want %v", err, wantErr)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("fn called %d times, want 3", calls.Load())
+	}
+}
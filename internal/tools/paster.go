@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/dastrobu/mail-mcp/internal/mac"
+)
+
+// Paster pastes rendered content into an open compose window. Compose tools
+// create the window via JXA and then hand the body over to a Paster.
+type Paster interface {
+	EnsureAccessibility() error
+	PasteIntoWindow(ctx context.Context, pid int, expectedTitle string, timeout time.Duration, htmlContent *string, plainContent string) error
+}
+
+// macPaster pastes through the macOS Accessibility API.
+type macPaster struct{}
+
+func (macPaster) EnsureAccessibility() error {
+	return mac.EnsureAccessibility()
+}
+
+func (macPaster) PasteIntoWindow(ctx context.Context, pid int, expectedTitle string, timeout time.Duration, htmlContent *string, plainContent string) error {
+	return mac.PasteIntoWindow(ctx, pid, expectedTitle, timeout, htmlContent, plainContent)
+}
+
+// pasterFor returns the executor itself if it also implements Paster (e.g. the
+// Mail.app simulator), and the Accessibility API otherwise.
+func pasterFor(executor jxa.Executor) Paster {
+	if p, ok := executor.(Paster); ok {
+		return p
+	}
+	return macPaster{}
+}
@@ -7,15 +7,17 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/jxa"
-	"github.com/dastrobu/mail-mcp/internal/mac"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/replace_outgoing_message.js
-var replaceOutgoingMessageScript string
+var replaceOutgoingMessageSource string
+
+var replaceOutgoingMessageScript = jxa.NewScript("replace_outgoing_message", replaceOutgoingMessageSource)
 
 type ReplaceOutgoingMessageInput struct {
 	OutgoingID    int       `json:"outgoing_id" jsonschema:"The ID of the outgoing message to replace" long:"outgoing-id" description:"The ID of the outgoing message to replace"`
@@ -26,14 +28,27 @@ type ReplaceOutgoingMessageInput struct {
 	CcRecipients  *[]string `json:"cc_recipients,omitempty" jsonschema:"New list of CC recipients (optional, keeps existing if null, clears if empty array)" long:"cc-recipients" description:"New list of CC recipients (optional, keeps existing if null, clears if empty array). Can be specified multiple times."`
 	BccRecipients *[]string `json:"bcc_recipients,omitempty" jsonschema:"New list of BCC recipients (optional, keeps existing if null, clears if empty array)" long:"bcc-recipients" description:"New list of BCC recipients (optional, keeps existing if null, clears if empty array). Can be specified multiple times."`
 	Sender        *string   `json:"sender,omitempty" jsonschema:"New sender email address (optional, keeps existing if null)" long:"sender" description:"New sender email address (optional, keeps existing if null)"`
+	Attachments   []string  `json:"attachments,omitempty" jsonschema:"Absolute paths of files to attach. The files must be inside one of the attachment directories of the server. Attachments of the old message are kept, unless a file of the same name is attached." long:"attachment" description:"Absolute path of a file to attach. Can be specified multiple times."`
+}
+
+// replaceOutgoingMessageArgs is the input of the replace_outgoing_message
+// script.
+type replaceOutgoingMessageArgs struct {
+	ReplaceOutgoingMessageInput
+	// AttachmentDir is the directory the attachments of the old message are
+	// copied to.
+	AttachmentDir string `json:"attachment_dir"`
 }
 
-func RegisterReplaceOutgoingMessage(srv *mcp.Server) {
-	mcp.AddTool(srv,
+// RegisterReplaceOutgoingMessage registers the replace_outgoing_message tool with the MCP server.
+// Files may only be attached from attachmentDirs.
+func RegisterReplaceOutgoingMessage(srv *mcp.Server, executor jxa.Executor, attachmentDirs []string) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "replace_outgoing_message",
-			Description: "Replaces an outgoing message (draft or open window) with new content. Deletes the old message, creates a new one with updated properties, and pastes new content. NOTE: Mail.app may auto-save this message as a draft. If replacing this message again, check for and delete the old outgoing message first.",
-			InputSchema: GenerateSchema[ReplaceOutgoingMessageInput](),
+			Name:         "replace_outgoing_message",
+			Description:  "Replaces an outgoing message (draft or open window) with new content. Deletes the old message, creates a new one with updated properties, pastes new content and attaches the files of attachments. Attachments of the old message are attached again from a copy (marked as kept), unless a file of the same name is attached; if their files cannot be read, the message is not replaced. NOTE: Mail.app may auto-save this message as a draft. If replacing this message again, check for and delete the old outgoing message first.",
+			InputSchema:  GenerateSchema[ReplaceOutgoingMessageInput](),
+			OutputSchema: GenerateSchema[ComposeOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Replace Outgoing Message",
 				ReadOnlyHint:    false,
@@ -42,18 +57,18 @@ func RegisterReplaceOutgoingMessage(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		func(ctx context.Context, request *mcp.CallToolRequest, input ReplaceOutgoingMessageInput) (*mcp.CallToolResult, any, error) {
-			return HandleReplaceOutgoingMessage(ctx, request, input)
+		func(ctx context.Context, request *mcp.CallToolRequest, input ReplaceOutgoingMessageInput) (*mcp.CallToolResult, *ComposeOutput, error) {
+			return HandleReplaceOutgoingMessage(ctx, executor, attachmentDirs, request, input)
 		},
 	)
 }
 
-func HandleReplaceOutgoingMessage(ctx context.Context, request *mcp.CallToolRequest, input ReplaceOutgoingMessageInput) (*mcp.CallToolResult, any, error) {
+func HandleReplaceOutgoingMessage(ctx context.Context, executor jxa.Executor, attachmentDirs []string, request *mcp.CallToolRequest, input ReplaceOutgoingMessageInput) (*mcp.CallToolResult, *ComposeOutput, error) {
 	// 1. Input Validation and Setup
 	if input.OutgoingID == 0 {
-		return nil, nil, fmt.Errorf("outgoing_id is required")
+		return nil, nil, missingParameters("outgoing_id is required")
 	}
-	if err := mac.EnsureAccessibility(); err != nil {
+	if err := pasterFor(executor).EnsureAccessibility(); err != nil {
 		return nil, nil, err
 	}
 
@@ -61,49 +76,80 @@ func HandleReplaceOutgoingMessage(ctx context.Context, request *mcp.CallToolRequ
 	if err != nil {
 		return nil, nil, err
 	}
+	files, err := resolveAttachments(attachmentDirs, input.Attachments)
+	if err != nil {
+		return nil, nil, err
+	}
 	htmlContent, plainContent, err := ToClipboardContent(input.Content, contentFormat)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// 2. Prepare arguments for JXA
-	inputJSON, err := json.Marshal(input)
+	// 2. Prepare arguments for JXA. The attachments of the old message are
+	// copied to a temporary directory, as their files are deleted with it.
+	attachmentDir, err := os.MkdirTemp("", "mail-mcp-attachments-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create directory for attachments: %w", err)
+	}
+	keepAttachmentDir := false
+	defer func() {
+		if !keepAttachmentDir {
+			_ = os.RemoveAll(attachmentDir)
+		}
+	}()
+	inputJSON, err := json.Marshal(replaceOutgoingMessageArgs{ReplaceOutgoingMessageInput: input, AttachmentDir: attachmentDir})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
 	}
 
 	// 3. Execute JXA to replace the message
-	resultAny, err := jxa.Execute(ctx, replaceOutgoingMessageScript, string(inputJSON))
+	resultAny, err := executor.Execute(ctx, replaceOutgoingMessageScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("JXA execution failed: %w", err)
 	}
 
-	resultMap, ok := resultAny.(map[string]any)
-	if !ok {
-		return nil, nil, fmt.Errorf("invalid JXA result format")
-	}
-
 	// 4. Extract data for pasting
-	newOutgoingID, idOk := resultMap["outgoing_id"].(float64)
-	resultSubject, subjectOk := resultMap["subject"].(string)
-	mailPID, pidOk := resultMap["pid"].(float64)
-
-	if !idOk || !subjectOk || !pidOk {
-		return nil, nil, fmt.Errorf("JXA result is missing required fields (outgoing_id, subject, pid)")
+	result, err := decodeResult[composeScriptResult](resultAny)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The copies that are attached again are left in place, since the old
+	// message is gone and Mail.app may read the files again when the message
+	// is saved or sent. The system cleans up the temporary directory.
+	kept, err := keptAttachments(result.KeptAttachments, files)
+	if err != nil {
+		keepAttachmentDir = true
+		return nil, nil, fmt.Errorf("outgoing message %d was created, but the attachments of the old message in %s cannot be read: %w", result.OutgoingID, attachmentDir, err)
+	}
+	keepAttachmentDir = len(kept) > 0
+	files = append(kept, files...)
+	// Errors from now on tell where the attachments of the old message are
+	var keptIn string
+	if len(kept) > 0 {
+		keptIn = fmt.Sprintf(" (the attachments of the old message are in %s)", attachmentDir)
 	}
 
 	// 5. Paste content into the new message window
-	if err := mac.PasteIntoWindow(ctx, int(mailPID), resultSubject, 5*time.Second, htmlContent, plainContent); err != nil {
-		return nil, nil, fmt.Errorf("accessibility paste operation failed: %w", err)
+	if err := pasterFor(executor).PasteIntoWindow(ctx, result.PID, result.Subject, 5*time.Second, htmlContent, plainContent); err != nil {
+		return nil, nil, fmt.Errorf("accessibility paste operation failed%s: %w", keptIn, err)
 	}
 
 	time.Sleep(250 * time.Millisecond) // Allow Mail.app to process the paste event.
 
-	// 6. Return success
-	finalResult := map[string]any{
-		"outgoing_id": newOutgoingID,
-		"subject":     resultSubject,
-		"message":     "Outgoing message replaced and content pasted.",
+	// 6. Attach files after the paste, which replaces the content
+	if err := addAttachments(ctx, executor, result.OutgoingID, files); err != nil {
+		return nil, nil, fmt.Errorf("outgoing message %d was created, but attaching files failed%s: %w", result.OutgoingID, keptIn, err)
+	}
+
+	// 7. Return success
+	finalResult := &ComposeOutput{
+		OutgoingID:  result.OutgoingID,
+		Subject:     result.Subject,
+		Message:     "Outgoing message replaced and content pasted.",
+		Attachments: files,
+	}
+	if len(kept) > 0 {
+		finalResult.Message += fmt.Sprintf(" Attachments of the old message attached again: %d.", len(kept))
 	}
 
 	return nil, finalResult, nil
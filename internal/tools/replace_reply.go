@@ -10,12 +10,13 @@ import (
 	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/jxa"
-	"github.com/dastrobu/mail-mcp/internal/mac"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 //go:embed scripts/replace_reply.js
-var replaceReplyScript string
+var replaceReplySource string
+
+var replaceReplyScript = jxa.NewScript("replace_reply", replaceReplySource)
 
 type ReplaceReplyInput struct {
 	OutgoingID  int      `json:"outgoing_id" jsonschema:"The ID of the outgoing reply message to replace" long:"outgoing-id" description:"The ID of the outgoing reply message to replace"`
@@ -34,12 +35,13 @@ type ReplaceReplyInput struct {
 	BccRecipients *[]string `json:"bcc_recipients,omitempty" jsonschema:"New list of BCC recipients (optional, replaces reply recipients)" long:"bcc-recipients" description:"New list of BCC recipients (optional, replaces reply recipients). Can be specified multiple times."`
 }
 
-func RegisterReplaceReply(srv *mcp.Server) {
-	mcp.AddTool(srv,
+func RegisterReplaceReply(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
 		&mcp.Tool{
-			Name:        "replace_reply",
-			Description: "Replaces an existing reply with new content. Deletes the old reply window, creates a new one, and pastes in the new content. NOTE: Mail.app may auto-save messages as drafts. Always check for and delete the old auto-saved draft after replacing. If replacing again, use the new outgoing_id.",
-			InputSchema: GenerateSchema[ReplaceReplyInput](),
+			Name:         "replace_reply",
+			Description:  "Replaces an existing reply with new content. Deletes the old reply window, creates a new one, and pastes in the new content. NOTE: Mail.app may auto-save messages as drafts. Always check for and delete the old auto-saved draft after replacing. If replacing again, use the new outgoing_id.",
+			InputSchema:  GenerateSchema[ReplaceReplyInput](),
+			OutputSchema: GenerateSchema[ComposeOutput](),
 			Annotations: &mcp.ToolAnnotations{
 				Title:           "Replace Reply",
 				ReadOnlyHint:    false,
@@ -48,18 +50,18 @@ func RegisterReplaceReply(srv *mcp.Server) {
 				OpenWorldHint:   new(true),
 			},
 		},
-		func(ctx context.Context, request *mcp.CallToolRequest, input ReplaceReplyInput) (*mcp.CallToolResult, any, error) {
-			return HandleReplaceReply(ctx, request, input)
+		func(ctx context.Context, request *mcp.CallToolRequest, input ReplaceReplyInput) (*mcp.CallToolResult, *ComposeOutput, error) {
+			return HandleReplaceReply(ctx, executor, request, input)
 		},
 	)
 }
 
-func HandleReplaceReply(ctx context.Context, request *mcp.CallToolRequest, input ReplaceReplyInput) (*mcp.CallToolResult, any, error) {
+func HandleReplaceReply(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input ReplaceReplyInput) (*mcp.CallToolResult, *ComposeOutput, error) {
 	// 1. Input Validation and Setup
 	if input.OutgoingID == 0 || input.MessageID == 0 || input.Account == "" || len(input.MailboxPath) == 0 {
-		return nil, nil, fmt.Errorf("outgoing_id, message_id, account, and mailbox_path are required")
+		return nil, nil, missingParameters("outgoing_id, message_id, account, and mailbox_path are required")
 	}
-	if err := mac.EnsureAccessibility(); err != nil {
+	if err := pasterFor(executor).EnsureAccessibility(); err != nil {
 		return nil, nil, err
 	}
 
@@ -79,37 +81,29 @@ func HandleReplaceReply(ctx context.Context, request *mcp.CallToolRequest, input
 	}
 
 	// 3. Execute JXA to replace the reply
-	resultAny, err := jxa.Execute(ctx, replaceReplyScript, string(inputJSON))
+	resultAny, err := executor.Execute(ctx, replaceReplyScript, string(inputJSON))
 	if err != nil {
 		return nil, nil, fmt.Errorf("JXA execution failed: %w", err)
 	}
 
-	resultMap, ok := resultAny.(map[string]any)
-	if !ok {
-		return nil, nil, fmt.Errorf("invalid JXA result format")
-	}
-
 	// 4. Extract data for pasting
-	newOutgoingID, idOk := resultMap["outgoing_id"].(float64)
-	resultSubject, subjectOk := resultMap["subject"].(string)
-	mailPID, pidOk := resultMap["pid"].(float64)
-
-	if !idOk || !subjectOk || !pidOk {
-		return nil, nil, fmt.Errorf("JXA result is missing required fields (outgoing_id, subject, pid)")
+	result, err := decodeResult[composeScriptResult](resultAny)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// 5. Paste content into the new reply window
-	if err := mac.PasteIntoWindow(ctx, int(mailPID), resultSubject, 5*time.Second, htmlContent, plainContent); err != nil {
+	if err := pasterFor(executor).PasteIntoWindow(ctx, result.PID, result.Subject, 5*time.Second, htmlContent, plainContent); err != nil {
 		return nil, nil, fmt.Errorf("accessibility paste operation failed: %w", err)
 	}
 
 	time.Sleep(250 * time.Millisecond) // Allow Mail.app to process the paste event.
 
 	// 6. Return success
-	finalResult := map[string]any{
-		"outgoing_id": newOutgoingID,
-		"subject":     resultSubject,
-		"message":     "Reply replaced and content pasted.",
+	finalResult := &ComposeOutput{
+		OutgoingID: result.OutgoingID,
+		Subject:    result.Subject,
+		Message:    "Reply replaced and content pasted.",
 	}
 
 	return nil, finalResult, nil
@@ -0,0 +1,596 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/dastrobu/mail-mcp/internal/header"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Account is a Mail.app account as returned by list_accounts.
+type Account struct {
+	Name           string   `json:"name" jsonschema:"Name of the account"`
+	Enabled        bool     `json:"enabled" jsonschema:"Whether the account is enabled"`
+	EmailAddresses []string `json:"emailAddresses" jsonschema:"Email addresses of the account"`
+	MailboxCount   int      `json:"mailboxCount" jsonschema:"Number of top-level mailboxes"`
+}
+
+// ListAccountsOutput is the result of the list_accounts tool.
+type ListAccountsOutput struct {
+	Accounts []Account `json:"accounts"`
+	Count    int       `json:"count"`
+}
+
+// Mailbox is a mailbox as returned by list_mailboxes.
+type Mailbox struct {
+	Name            string   `json:"name" jsonschema:"Name of the mailbox"`
+	MailboxPath     []string `json:"mailboxPath" jsonschema:"Full path of the mailbox, usable as mailboxPath input of other tools"`
+	Account         string   `json:"account" jsonschema:"Name of the account"`
+	UnreadCount     int      `json:"unreadCount" jsonschema:"Number of unread messages"`
+	MessageCount    int      `json:"messageCount" jsonschema:"Number of messages"`
+	HasSubMailboxes bool     `json:"hasSubMailboxes" jsonschema:"Whether the mailbox has sub-mailboxes"`
+	SubMailboxCount int      `json:"subMailboxCount" jsonschema:"Number of sub-mailboxes"`
+}
+
+// ListMailboxesOutput is the result of the list_mailboxes tool.
+type ListMailboxesOutput struct {
+	Mailboxes         []Mailbox `json:"mailboxes"`
+	Count             int       `json:"count"`
+	ParentMailboxPath []string  `json:"parentMailboxPath,omitempty" jsonschema:"Path of the parent mailbox, if sub-mailboxes were listed"`
+}
+
+// Recipient is a To, Cc or Bcc recipient of a message.
+type Recipient struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// Attachment describes a mail attachment of a message.
+type Attachment struct {
+	Name       string `json:"name"`
+	FileSize   int    `json:"fileSize" jsonschema:"Size in bytes"`
+	Downloaded bool   `json:"downloaded" jsonschema:"Whether the attachment has been downloaded"`
+}
+
+// MessageDetail is a full message as returned by get_message_content.
+type MessageDetail struct {
+	ID            int           `json:"id"`
+	Subject       string        `json:"subject"`
+	Sender        string        `json:"sender"`
+	ReplyTo       string        `json:"replyTo"`
+	DateReceived  *string       `json:"dateReceived,omitempty" jsonschema:"ISO 8601 date the message was received"`
+	DateSent      *string       `json:"dateSent,omitempty" jsonschema:"ISO 8601 date the message was sent"`
+	Content       string        `json:"content"`
+	BodyFormat    string        `json:"bodyFormat,omitempty" jsonschema:"Format of content: plain, markdown or html. Plain if the message has no HTML body."`
+	Split         *ContentSplit `json:"split,omitempty" jsonschema:"Parts of the content, for content_mode split"`
+	ReadStatus    bool          `json:"readStatus"`
+	FlaggedStatus bool          `json:"flaggedStatus"`
+	MessageSize   int           `json:"messageSize" jsonschema:"Size in bytes"`
+	MessageID     string        `json:"messageId" jsonschema:"Message-ID header"`
+	AllHeaders    string        `json:"allHeaders" jsonschema:"Raw message headers"`
+	ToRecipients  []Recipient   `json:"toRecipients"`
+	CcRecipients  []Recipient   `json:"ccRecipients"`
+	BccRecipients []Recipient   `json:"bccRecipients"`
+	Attachments   []Attachment  `json:"attachments"`
+
+	// Parsed from AllHeaders, see parseHeaders
+	Headers               map[string][]string            `json:"headers,omitempty" jsonschema:"Unfolded and decoded header fields by canonical name (e.g. Message-Id), the values of repeated fields in order of occurrence"`
+	ListID                *header.ListID                 `json:"listId,omitempty" jsonschema:"List-Id header of mailing list messages"`
+	ListUnsubscribe       *header.ListUnsubscribe        `json:"listUnsubscribe,omitempty" jsonschema:"List-Unsubscribe header of mailing list messages"`
+	AutoSubmitted         string                         `json:"autoSubmitted,omitempty" jsonschema:"Auto-Submitted header, e.g. auto-generated or auto-replied for messages not sent by a person"`
+	InReplyTo             []string                       `json:"inReplyTo,omitempty" jsonschema:"Message IDs of the In-Reply-To header, without angle brackets"`
+	References            []string                       `json:"references,omitempty" jsonschema:"Message IDs of the References header, oldest first, without angle brackets"`
+	AuthenticationResults []header.AuthenticationResults `json:"authenticationResults,omitempty" jsonschema:"Authentication-Results headers with the SPF, DKIM and DMARC results, the last receiving server first"`
+	Received              []header.Received              `json:"received,omitempty" jsonschema:"Received headers, the last hop first"`
+
+	// Parsed from the source, see findInvitation
+	Invitation *Invitation `json:"invitation,omitempty" jsonschema:"Calendar invitation of the message, from a text/calendar part or an .ics attachment, missing if there is none"`
+}
+
+// Invitation is the event of a calendar invitation, an iTIP message. Like
+// all objects nested in MessageDetail, its fields are camelCase, as are the
+// properties of the messages the scripts return.
+type Invitation struct {
+	Method         string                  `json:"method,omitempty" jsonschema:"iTIP method: REQUEST for an invitation or update, CANCEL for a cancellation, REPLY for an answer to an invitation"`
+	UID            string                  `json:"uid,omitempty"`
+	Title          string                  `json:"title"`
+	Start          string                  `json:"start" jsonschema:"Start as RFC 3339 date-time in the time zone of the event, or date for all-day events"`
+	End            string                  `json:"end,omitempty" jsonschema:"End as RFC 3339 date-time, or the day after the last day for all-day events"`
+	AllDay         bool                    `json:"allDay"`
+	TimeZone       string                  `json:"timeZone,omitempty" jsonschema:"Time zone of start and end as given by the organizer, e.g. Europe/Berlin"`
+	Location       string                  `json:"location,omitempty"`
+	Description    string                  `json:"description,omitempty"`
+	Status         string                  `json:"status,omitempty" jsonschema:"Status, e.g. CONFIRMED or CANCELLED"`
+	Organizer      *InvitationParticipant  `json:"organizer,omitempty"`
+	Attendees      []InvitationParticipant `json:"attendees,omitempty"`
+	Recurrence     string                  `json:"recurrence,omitempty" jsonschema:"Summary of the recurrence of a recurring event, e.g. 'weekly on Monday, 10 times'"`
+	RecurrenceRule string                  `json:"recurrenceRule,omitempty" jsonschema:"RRULE of a recurring event, e.g. FREQ=WEEKLY;COUNT=10"`
+	EventCount     int                     `json:"eventCount,omitempty" jsonschema:"Number of events of the invitation if there is more than one, e.g. changed occurrences of a recurring event; use get_attachment_text with part to read them all"`
+	Part           string                  `json:"part" jsonschema:"Path of the MIME part of the invitation, as in the parts of get_message_source"`
+}
+
+// InvitationParticipant is the organizer or an attendee of an invitation.
+type InvitationParticipant struct {
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email"`
+	Role   string `json:"role,omitempty" jsonschema:"Role, e.g. REQ-PARTICIPANT, OPT-PARTICIPANT or CHAIR"`
+	Status string `json:"status,omitempty" jsonschema:"Participation status, e.g. NEEDS-ACTION, ACCEPTED, DECLINED or TENTATIVE"`
+	RSVP   bool   `json:"rsvp,omitempty" jsonschema:"Whether the organizer expects a reply"`
+}
+
+// ContentSplit is the content of a message split into the text the sender
+// wrote, the quoted history and the signature.
+type ContentSplit struct {
+	NewText    string `json:"newText" jsonschema:"Text the sender wrote, without quoted lines and signature"`
+	QuotedText string `json:"quotedText" jsonschema:"Quoted history of earlier messages, from the attribution line (e.g. 'On ... wrote:') or separator on, and lines quoted with '>'"`
+	Signature  string `json:"signature" jsonschema:"Signature of the sender, after the '-- ' delimiter or of a mobile client"`
+}
+
+// GetMessageContentOutput is the result of the get_message_content tool.
+// Unlike the message, the chunk fields are snake_case like those of the other
+// tools taking max_chars and offset.
+type GetMessageContentOutput struct {
+	Message     MessageDetail `json:"message"`
+	TotalLength int           `json:"total_length" jsonschema:"Length of the whole content in characters"`
+	NextOffset  *int          `json:"next_offset,omitempty" jsonschema:"Offset of the rest of the content if it was cut at max_chars, missing if content reaches the end"`
+}
+
+// MessagePart is a MIME part of a message as returned by get_message_source.
+type MessagePart struct {
+	Path             string `json:"path" jsonschema:"Position of the part in the tree: 1 for the message, 1.2 for the second child of the message and so on"`
+	ContentType      string `json:"content_type" jsonschema:"Media type, e.g. text/html or multipart/alternative"`
+	Charset          string `json:"charset,omitempty" jsonschema:"Charset of text parts"`
+	Disposition      string `json:"disposition,omitempty" jsonschema:"inline or attachment"`
+	Filename         string `json:"filename,omitempty"`
+	ContentID        string `json:"content_id,omitempty" jsonschema:"Content-ID without angle brackets, referenced by cid: URLs"`
+	TransferEncoding string `json:"transfer_encoding,omitempty" jsonschema:"Content-Transfer-Encoding, e.g. base64 or quoted-printable"`
+	Size             int    `json:"size" jsonschema:"Size in bytes of the decoded body, for multipart parts of the encoded children"`
+}
+
+// InlineImage is an image shown within the body of a message.
+type InlineImage struct {
+	ContentID   string `json:"content_id,omitempty" jsonschema:"Content-ID without angle brackets"`
+	Path        string `json:"path" jsonschema:"Path of the part of the image"`
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename,omitempty"`
+	Size        int    `json:"size" jsonschema:"Size in bytes"`
+	Referenced  bool   `json:"referenced" jsonschema:"Whether the HTML body references the image by a cid: URL"`
+}
+
+// GetMessageSourceOutput is the result of the get_message_source tool.
+type GetMessageSourceOutput struct {
+	ID           int           `json:"id"`
+	Size         int           `json:"size" jsonschema:"Size of the raw source in bytes"`
+	Parts        []MessagePart `json:"parts" jsonschema:"MIME parts in depth-first order, the message first"`
+	TextBody     string        `json:"text_body" jsonschema:"Decoded text/plain body, empty if there is none"`
+	HTMLBody     string        `json:"html_body" jsonschema:"Decoded text/html body, empty if there is none"`
+	InlineImages []InlineImage `json:"inline_images" jsonschema:"Inline images, those referenced by the HTML body first"`
+	Source       *string       `json:"source,omitempty" jsonschema:"Raw RFC 822 source, if requested"`
+
+	// Lengths in characters and offsets of the rest of bodies cut at
+	// max_chars, see chunk
+	TextBodyLength     int  `json:"text_body_total_length" jsonschema:"Length of the whole text_body in characters"`
+	TextBodyNextOffset *int `json:"text_body_next_offset,omitempty" jsonschema:"Offset of the rest of text_body if it was cut at max_chars"`
+	HTMLBodyLength     int  `json:"html_body_total_length" jsonschema:"Length of the whole html_body in characters"`
+	HTMLBodyNextOffset *int `json:"html_body_next_offset,omitempty" jsonschema:"Offset of the rest of html_body if it was cut at max_chars"`
+	SourceLength       *int `json:"source_total_length,omitempty" jsonschema:"Length of the whole source in characters, if requested"`
+	SourceNextOffset   *int `json:"source_next_offset,omitempty" jsonschema:"Offset of the rest of source if it was cut at max_chars"`
+}
+
+// AttachmentTable is a CSV file or a sheet of a spreadsheet.
+type AttachmentTable struct {
+	Name string     `json:"name,omitempty" jsonschema:"Name of the sheet, missing for CSV files"`
+	Rows [][]string `json:"rows" jsonschema:"Rows of cells, which may have different lengths"`
+}
+
+// CalendarEvent is an event of an iCalendar attachment.
+type CalendarEvent struct {
+	UID         string `json:"uid,omitempty"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Status      string `json:"status,omitempty" jsonschema:"Status, e.g. CONFIRMED or CANCELLED"`
+	Start       string `json:"start" jsonschema:"Start as RFC 3339 date-time in the time zone of the event, or date for all-day events"`
+	End         string `json:"end,omitempty" jsonschema:"End as RFC 3339 date-time, or the day after the last day for all-day events"`
+	AllDay      bool   `json:"all_day"`
+}
+
+// AttachmentContact is a contact of a vCard attachment.
+type AttachmentContact struct {
+	Name         string   `json:"name"`
+	Organization string   `json:"organization,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Emails       []string `json:"emails"`
+	Phones       []string `json:"phones"`
+}
+
+// AttachedMessage is the header of an attached message.
+type AttachedMessage struct {
+	From        string   `json:"from"`
+	To          string   `json:"to,omitempty"`
+	Cc          string   `json:"cc,omitempty"`
+	Date        string   `json:"date,omitempty"`
+	Subject     string   `json:"subject"`
+	Attachments []string `json:"attachments" jsonschema:"Names of the attachments of the attached message"`
+}
+
+// GetAttachmentTextOutput is the result of the get_attachment_text tool.
+type GetAttachmentTextOutput struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name,omitempty" jsonschema:"Name of the attachment"`
+	Part        string `json:"part" jsonschema:"Path of the MIME part of the attachment"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size" jsonschema:"Size of the attachment in bytes"`
+	Kind        string `json:"kind" jsonschema:"Kind of content: text, html, csv, calendar, contacts, message, document, spreadsheet or presentation"`
+	Text        string `json:"text" jsonschema:"Text of the attachment: Markdown for HTML, the body for attached messages, one section per sheet or slide for spreadsheets and presentations"`
+	TotalLength int    `json:"total_length" jsonschema:"Length of the whole text in characters"`
+	NextOffset  *int   `json:"next_offset,omitempty" jsonschema:"Offset of the rest of the text if it was cut at max_chars, missing if text reaches the end"`
+
+	Tables    []AttachmentTable   `json:"tables,omitempty" jsonschema:"Rows of CSV files and of the sheets of spreadsheets"`
+	Truncated bool                `json:"truncated,omitempty" jsonschema:"Whether tables have more rows than returned, the text has all of them"`
+	Method    string              `json:"method,omitempty" jsonschema:"iTIP method of a calendar, e.g. REQUEST or CANCEL"`
+	Events    []CalendarEvent     `json:"events,omitempty" jsonschema:"Events of iCalendar files"`
+	Contacts  []AttachmentContact `json:"contacts,omitempty" jsonschema:"Contacts of vCard files"`
+	Message   *AttachedMessage    `json:"message,omitempty" jsonschema:"Header of attached messages"`
+}
+
+// SavedAttachment is a file written by save_attachments.
+type SavedAttachment struct {
+	Index  int    `json:"index" jsonschema:"Position of the attachment in the message, starting at 0"`
+	Name   string `json:"name" jsonschema:"Name of the attachment in the message"`
+	Path   string `json:"path" jsonschema:"Absolute path of the file"`
+	Size   int64  `json:"size" jsonschema:"Size of the file in bytes"`
+	SHA256 string `json:"sha256" jsonschema:"Hex-encoded SHA-256 digest of the file"`
+}
+
+// SaveAttachmentsOutput is the result of the save_attachments tool.
+type SaveAttachmentsOutput struct {
+	Files     []SavedAttachment `json:"files"`
+	Count     int               `json:"count"`
+	ExportDir string            `json:"export_dir" jsonschema:"Absolute path of the directory the files were saved in"`
+}
+
+// ThreadMessage is a message of a conversation as returned by get_thread.
+type ThreadMessage struct {
+	ID                int         `json:"id"`
+	Account           string      `json:"account"`
+	MailboxPath       []string    `json:"mailbox_path"`
+	Subject           string      `json:"subject"`
+	Sender            string      `json:"sender"`
+	ToRecipients      []Recipient `json:"to_recipients"`
+	CcRecipients      []Recipient `json:"cc_recipients"`
+	DateReceived      *string     `json:"date_received,omitempty" jsonschema:"ISO 8601 date the message was received"`
+	DateSent          *string     `json:"date_sent,omitempty" jsonschema:"ISO 8601 date the message was sent"`
+	InternetMessageID string      `json:"internet_message_id" jsonschema:"Message-ID header without angle brackets"`
+	InReplyTo         *string     `json:"in_reply_to,omitempty" jsonschema:"internet_message_id of the message of the conversation this one replies to. Missing for the first message and for replies to messages that were not found."`
+	Depth             int         `json:"depth" jsonschema:"Depth of the message in the reply tree, 0 for the first message"`
+	Content           string      `json:"content"`
+	TotalLength       int         `json:"total_length" jsonschema:"Length of the whole content in characters"`
+	NextOffset        *int        `json:"next_offset,omitempty" jsonschema:"Offset of the rest of the content if it was cut at max_chars, missing if content reaches the end"`
+}
+
+// GetThreadOutput is the result of the get_thread tool.
+type GetThreadOutput struct {
+	Subject           string          `json:"subject" jsonschema:"Subject of the conversation without reply and forward prefixes"`
+	Messages          []ThreadMessage `json:"messages" jsonschema:"Messages of the conversation, oldest first"`
+	Count             int             `json:"count"`
+	MailboxesSearched int             `json:"mailboxes_searched" jsonschema:"Number of mailboxes that were searched"`
+	Truncated         bool            `json:"truncated,omitempty" jsonschema:"Set if there were too many messages with the subject to check them all"`
+}
+
+// SelectedMessage is a message selected in the Mail.app viewer.
+type SelectedMessage struct {
+	ID             int      `json:"id"`
+	Subject        string   `json:"subject"`
+	Sender         string   `json:"sender"`
+	DateReceived   string   `json:"dateReceived"`
+	DateSent       string   `json:"dateSent"`
+	ReadStatus     bool     `json:"readStatus"`
+	FlaggedStatus  bool     `json:"flaggedStatus"`
+	JunkMailStatus bool     `json:"junkMailStatus"`
+	Mailbox        string   `json:"mailbox"`
+	MailboxPath    []string `json:"mailboxPath"`
+	Account        string   `json:"account"`
+}
+
+// GetSelectedMessagesOutput is the result of the get_selected_messages tool.
+// If nothing is returned, SelectedMessagesCount is set instead of Count.
+type GetSelectedMessagesOutput struct {
+	Messages              []SelectedMessage `json:"messages"`
+	Count                 *int              `json:"count,omitempty" jsonschema:"Number of returned messages"`
+	SelectedMessagesCount *int              `json:"selectedMessagesCount,omitempty" jsonschema:"Number of selected messages, set if no messages are returned"`
+}
+
+// MessageSummary is a message as returned by find_messages.
+type MessageSummary struct {
+	ID             int      `json:"id"`
+	Subject        string   `json:"subject"`
+	Sender         string   `json:"sender"`
+	DateReceived   string   `json:"date_received"`
+	DateSent       *string  `json:"date_sent,omitempty"`
+	ReadStatus     bool     `json:"read_status"`
+	FlaggedStatus  bool     `json:"flagged_status"`
+	MessageSize    int      `json:"message_size" jsonschema:"Size in bytes"`
+	ContentPreview string   `json:"content_preview" jsonschema:"First 100 characters of the content"`
+	ContentLength  int      `json:"content_length"`
+	MailboxPath    []string `json:"mailbox_path"`
+	Account        string   `json:"account"`
+}
+
+// FindMessagesFilters echoes the filters applied by find_messages.
+type FindMessagesFilters struct {
+	Subject        *string  `json:"subject,omitempty"`
+	Sender         *string  `json:"sender,omitempty"`
+	ReadStatus     *bool    `json:"read_status,omitempty"`
+	FlaggedOnly    bool     `json:"flagged_only"`
+	DateAfter      *string  `json:"date_after,omitempty"`
+	DateBefore     *string  `json:"date_before,omitempty"`
+	Body           *string  `json:"body,omitempty"`
+	Recipient      *string  `json:"recipient,omitempty"`
+	Cc             *string  `json:"cc,omitempty"`
+	ReplyTo        *string  `json:"reply_to,omitempty"`
+	HasAttachments *bool    `json:"has_attachments,omitempty"`
+	AttachmentName *string  `json:"attachment_name,omitempty"`
+	MinSize        *int     `json:"min_size,omitempty"`
+	MaxSize        *int     `json:"max_size,omitempty"`
+	Exclude        []string `json:"exclude,omitempty"`
+}
+
+// FindMessagesOutput is the result of the find_messages tool.
+type FindMessagesOutput struct {
+	Messages       []MessageSummary    `json:"messages"`
+	Count          int                 `json:"count"`
+	TotalMatches   int                 `json:"total_matches"`
+	Limit          int                 `json:"limit"`
+	HasMore        bool                `json:"has_more"`
+	FiltersApplied FindMessagesFilters `json:"filters_applied"`
+	Sort           string              `json:"sort"`
+
+	Cursor            *string `json:"cursor,omitempty" jsonschema:"Pass as cursor to get the next page. Missing on the last page."`
+	MailboxesSearched int     `json:"mailboxes_searched,omitempty" jsonschema:"Number of mailboxes that were searched"`
+}
+
+// IndexedMessage is a message found by search_index.
+type IndexedMessage struct {
+	ID           int      `json:"id"`
+	Subject      string   `json:"subject"`
+	Sender       string   `json:"sender"`
+	DateReceived string   `json:"date_received"`
+	MailboxPath  []string `json:"mailbox_path"`
+	Account      string   `json:"account"`
+	Score        float64  `json:"score" jsonschema:"BM25 relevance score, higher is better"`
+	Snippet      string   `json:"snippet" jsonschema:"Excerpt of the body around the first matching term"`
+}
+
+// SearchIndexOutput is the result of the search_index tool.
+type SearchIndexOutput struct {
+	Messages        []IndexedMessage `json:"messages"`
+	Count           int              `json:"count"`
+	TotalMatches    int              `json:"total_matches"`
+	Limit           int              `json:"limit"`
+	IndexedMessages int              `json:"indexed_messages" jsonschema:"Number of messages in the index"`
+	SyncedAt        string           `json:"synced_at" jsonschema:"ISO 8601 time of the last sync of the index"`
+}
+
+// Draft is a message in a Drafts mailbox as returned by list_drafts.
+type Draft struct {
+	DraftID         int      `json:"draft_id"`
+	Subject         string   `json:"subject"`
+	Sender          string   `json:"sender"`
+	DateReceived    string   `json:"date_received"`
+	DateSent        *string  `json:"date_sent,omitempty"`
+	ContentPreview  string   `json:"content_preview" jsonschema:"First 100 characters of the content"`
+	ContentLength   int      `json:"content_length"`
+	ToRecipients    []string `json:"to_recipients"`
+	CcRecipients    []string `json:"cc_recipients"`
+	BccRecipients   []string `json:"bcc_recipients"`
+	ToCount         int      `json:"to_count"`
+	CcCount         int      `json:"cc_count"`
+	BccCount        int      `json:"bcc_count"`
+	TotalRecipients int      `json:"total_recipients"`
+	Mailbox         string   `json:"mailbox"`
+	Account         string   `json:"account"`
+}
+
+// ListDraftsOutput is the result of the list_drafts tool.
+type ListDraftsOutput struct {
+	Drafts      []Draft `json:"drafts"`
+	Count       int     `json:"count"`
+	TotalDrafts int     `json:"total_drafts"`
+	Limit       int     `json:"limit"`
+	HasMore     bool    `json:"has_more"`
+}
+
+// DeleteDraftOutput is the result of the delete_draft tool.
+type DeleteDraftOutput struct {
+	DraftID int    `json:"draft_id"`
+	Subject string `json:"subject"`
+	Account string `json:"account"`
+	Message string `json:"message"`
+}
+
+// OutgoingMessage is an unsent message with an open compose window.
+type OutgoingMessage struct {
+	OutgoingID      int      `json:"outgoing_id"`
+	Subject         string   `json:"subject"`
+	Sender          string   `json:"sender"`
+	ContentPreview  string   `json:"content_preview" jsonschema:"First 100 characters of the content"`
+	ContentLength   int      `json:"content_length"`
+	ToRecipients    []string `json:"to_recipients"`
+	CcRecipients    []string `json:"cc_recipients"`
+	BccRecipients   []string `json:"bcc_recipients"`
+	ToCount         int      `json:"to_count"`
+	CcCount         int      `json:"cc_count"`
+	BccCount        int      `json:"bcc_count"`
+	TotalRecipients int      `json:"total_recipients"`
+}
+
+// ListOutgoingMessagesOutput is the result of the list_outgoing_messages tool.
+type ListOutgoingMessagesOutput struct {
+	Messages      []OutgoingMessage `json:"messages"`
+	Count         int               `json:"count"`
+	TotalOutgoing int               `json:"total_outgoing"`
+}
+
+// DeleteOutgoingMessageOutput is the result of the delete_outgoing_message tool.
+type DeleteOutgoingMessageOutput struct {
+	DeletedID int    `json:"deleted_id"`
+	Subject   string `json:"subject"`
+	Message   string `json:"message"`
+}
+
+// ComposeOutput is the result of the tools creating or replacing an outgoing
+// message (create_reply, replace_reply, create_outgoing_message and
+// replace_outgoing_message).
+type ComposeOutput struct {
+	OutgoingID  int                  `json:"outgoing_id" jsonschema:"ID of the outgoing message"`
+	Subject     string               `json:"subject"`
+	Message     string               `json:"message"`
+	Attachments []OutgoingAttachment `json:"attachments,omitempty" jsonschema:"Files attached to the message"`
+}
+
+// OutgoingAttachment is a file attached to an outgoing message.
+type OutgoingAttachment struct {
+	Name string `json:"name" jsonschema:"File name"`
+	Path string `json:"path" jsonschema:"Absolute path of the file, with symbolic links resolved"`
+	Size int64  `json:"size" jsonschema:"Size in bytes"`
+	Kept bool   `json:"kept,omitempty" jsonschema:"Set for attachments of the replaced message, attached from a copy in a temporary directory"`
+}
+
+// composeScriptResult is the data returned by the scripts opening a compose
+// window. The PID of Mail.app is needed to paste the content.
+type composeScriptResult struct {
+	OutgoingID int    `json:"outgoing_id"`
+	Subject    string `json:"subject"`
+	PID        int    `json:"pid"`
+	Message    string `json:"message"`
+	// KeptAttachments are the copies of the attachments of a replaced
+	// message, see replace_outgoing_message.
+	KeptAttachments []keptAttachment `json:"kept_attachments,omitempty"`
+}
+
+// keptAttachment is a copy of an attachment of a replaced message.
+type keptAttachment struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// FiledMessage is the new location of a message filed by move_messages,
+// copy_messages or archive_messages.
+type FiledMessage struct {
+	MessageID    int      `json:"message_id" jsonschema:"ID of the message in the source mailbox"`
+	NewMessageID *int     `json:"new_message_id,omitempty" jsonschema:"ID of the message in the destination mailbox. Missing if Mail.app has not assigned it yet, e.g. before an IMAP account synchronized."`
+	Subject      string   `json:"subject"`
+	Account      string   `json:"account"`
+	MailboxPath  []string `json:"mailboxPath" jsonschema:"Path of the destination mailbox"`
+}
+
+// FileMessagesOutput is the result of the tools filing messages into another
+// mailbox (move_messages, copy_messages and archive_messages).
+type FileMessagesOutput struct {
+	Messages               []FiledMessage `json:"messages"`
+	Count                  int            `json:"count"`
+	Account                string         `json:"account"`
+	MailboxPath            []string       `json:"mailboxPath" jsonschema:"Path of the source mailbox"`
+	DestinationMailboxPath []string       `json:"destinationMailboxPath" jsonschema:"Path of the destination mailbox"`
+	Message                string         `json:"message"`
+}
+
+// MessageUpdates are the properties set by update_messages. Properties that
+// were not requested are omitted.
+type MessageUpdates struct {
+	ReadStatus      *bool   `json:"readStatus,omitempty"`
+	FlaggedStatus   *bool   `json:"flaggedStatus,omitempty"`
+	FlagIndex       *int    `json:"flagIndex,omitempty"`
+	JunkMailStatus  *bool   `json:"junkMailStatus,omitempty"`
+	BackgroundColor *string `json:"backgroundColor,omitempty"`
+}
+
+// UpdateMessagesOutput is the result of update_messages.
+type UpdateMessagesOutput struct {
+	ChangedIDs   []int          `json:"changed_ids" jsonschema:"IDs of the messages of which at least one property changed"`
+	UnchangedIDs []int          `json:"unchanged_ids" jsonschema:"IDs of the messages that already had the requested properties"`
+	Count        int            `json:"count" jsonschema:"Number of changed messages"`
+	Updates      MessageUpdates `json:"updates"`
+	Account      string         `json:"account"`
+	MailboxPath  []string       `json:"mailboxPath"`
+	Message      string         `json:"message"`
+}
+
+// TrashedMessage is a message affected by trash_messages.
+type TrashedMessage struct {
+	MessageID    int    `json:"message_id"`
+	Subject      string `json:"subject"`
+	Sender       string `json:"sender"`
+	DateReceived string `json:"date_received"`
+	NewMessageID *int   `json:"new_message_id,omitempty" jsonschema:"ID of the message in the trash mailbox. Missing for dry runs, permanently deleted messages and if Mail.app has not assigned it yet."`
+}
+
+// TrashMessagesOutput is the result of trash_messages.
+type TrashMessagesOutput struct {
+	Messages         []TrashedMessage `json:"messages"`
+	Count            int              `json:"count"`
+	DryRun           bool             `json:"dry_run" jsonschema:"Whether this was a dry run that changed nothing"`
+	Permanent        bool             `json:"permanent" jsonschema:"Whether the messages were deleted permanently"`
+	Account          string           `json:"account"`
+	MailboxPath      []string         `json:"mailboxPath"`
+	TrashMailboxPath []string         `json:"trashMailboxPath" jsonschema:"Path of the trash mailbox of the account"`
+	Message          string           `json:"message"`
+}
+
+// resultSchemas caches the resolved schemas used to validate script results.
+var resultSchemas sync.Map // reflect.Type -> *jsonschema.Resolved
+
+// decodeResult strictly decodes the data returned by a JXA script into T.
+// Missing required fields, unknown fields and type mismatches are errors, so
+// changes to a script's output do not go unnoticed.
+func decodeResult[T any](data any) (*T, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JXA result: %w", err)
+	}
+
+	resolved, err := resultSchema[T]()
+	if err != nil {
+		return nil, err
+	}
+	var instance any
+	if err := json.Unmarshal(raw, &instance); err != nil {
+		return nil, fmt.Errorf("invalid JXA result: %w", err)
+	}
+	if err := resolved.Validate(instance); err != nil {
+		return nil, fmt.Errorf("invalid JXA result: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var out T
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid JXA result: %w", err)
+	}
+	return &out, nil
+}
+
+// resultSchema returns the resolved schema validating script results of type T.
+// Unlike GenerateSchema, it keeps null as valid type of pointer and slice
+// fields, since scripts return null for absent values.
+func resultSchema[T any]() (*jsonschema.Resolved, error) {
+	t := reflect.TypeFor[T]()
+	if r, ok := resultSchemas.Load(t); ok {
+		return r.(*jsonschema.Resolved), nil
+	}
+	schema, err := jsonschema.ForType(t, &jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate result schema: %w", err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve result schema: %w", err)
+	}
+	resultSchemas.Store(t, resolved)
+	return resolved, nil
+}
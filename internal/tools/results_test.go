@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    any
+		want    DeleteOutgoingMessageOutput
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: map[string]any{"deleted_id": float64(7), "subject": "Hello", "message": "deleted"},
+			want: DeleteOutgoingMessageOutput{DeletedID: 7, Subject: "Hello", Message: "deleted"},
+		},
+		{
+			name:    "missing field",
+			data:    map[string]any{"deleted_id": float64(7), "subject": "Hello"},
+			wantErr: "message",
+		},
+		{
+			name:    "unknown field",
+			data:    map[string]any{"deleted_id": float64(7), "subject": "Hello", "message": "deleted", "extra": true},
+			wantErr: "extra",
+		},
+		{
+			name:    "wrong type",
+			data:    map[string]any{"deleted_id": "7", "subject": "Hello", "message": "deleted"},
+			wantErr: "deleted_id",
+		},
+		{
+			name:    "not an object",
+			data:    []any{},
+			wantErr: "invalid JXA result",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeResult[DeleteOutgoingMessageOutput](tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("decodeResult() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeResult() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("decodeResult() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeResult_NullableFields(t *testing.T) {
+	data := map[string]any{
+		"messages":      []any{},
+		"count":         float64(0),
+		"total_matches": float64(0),
+		"limit":         float64(50),
+		"has_more":      false,
+		"filters_applied": map[string]any{
+			"subject":      nil,
+			"sender":       "alice",
+			"read_status":  nil,
+			"flagged_only": false,
+			"date_after":   nil,
+			"date_before":  nil,
+		},
+		"sort": "date_desc",
+	}
+
+	got, err := decodeResult[FindMessagesOutput](data)
+	if err != nil {
+		t.Fatalf("decodeResult() error = %v", err)
+	}
+	if got.FiltersApplied.Subject != nil {
+		t.Errorf("FiltersApplied.Subject = %v, want nil", *got.FiltersApplied.Subject)
+	}
+	if got.FiltersApplied.Sender == nil || *got.FiltersApplied.Sender != "alice" {
+		t.Errorf("FiltersApplied.Sender = %v, want alice", got.FiltersApplied.Sender)
+	}
+}
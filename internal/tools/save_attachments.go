@@ -0,0 +1,309 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/save_attachments.js
+var saveAttachmentsSource string
+
+var saveAttachmentsScript = jxa.NewScript("save_attachments", saveAttachmentsSource)
+
+// maxFilenameLength is the maximum length of a file name in bytes on APFS
+// and most other file systems.
+const maxFilenameLength = 255
+
+// SaveAttachmentsInput defines input parameters for save_attachments tool
+type SaveAttachmentsInput struct {
+	Account      string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath  []string `json:"mailboxPath" jsonschema:"Path to the mailbox as an array (e.g. ['Inbox'] for top-level or ['Inbox','GitHub'] for nested mailbox). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path to the mailbox. Can be specified multiple times for nested paths."`
+	MessageID    int      `json:"message_id" jsonschema:"The unique ID of the message" long:"message-id" description:"The unique ID of the message"`
+	Indexes      []int    `json:"indexes,omitempty" jsonschema:"Positions of the attachments to save in the attachments of get_message_content, starting at 0" long:"index" description:"Position of an attachment to save, starting at 0. Can be specified multiple times."`
+	Names        []string `json:"names,omitempty" jsonschema:"Names of the attachments to save. If neither indexes nor names are given, all attachments are saved." long:"name" description:"Name of an attachment to save. Can be specified multiple times."`
+	Subdirectory string   `json:"subdirectory,omitempty" jsonschema:"Directory relative to the export directory to save the files in, created if missing (default: the export directory)" long:"subdirectory" description:"Directory relative to the export directory to save the files in"`
+}
+
+// savedAttachments is the result of the save_attachments script.
+type savedAttachments struct {
+	ID    int `json:"id"`
+	Saved []struct {
+		Index int    `json:"index"`
+		Path  string `json:"path"`
+	} `json:"saved"`
+}
+
+// attachmentTarget is an attachment to save and the file to save it to.
+type attachmentTarget struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+}
+
+// RegisterSaveAttachments registers the save_attachments tool with the MCP
+// server. Files are only written to exportDir.
+func RegisterSaveAttachments(srv *mcp.Server, executor jxa.Executor, exportDir string) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "save_attachments",
+			Description:  "Saves attachments of a message, chosen by position or name, as files in the export directory configured for the server. Existing files are never overwritten, file names are made unique. Returns the absolute path, size and SHA-256 digest of each file. Use the attachments of get_message_content to choose.",
+			InputSchema:  GenerateSchema[SaveAttachmentsInput](),
+			OutputSchema: GenerateSchema[SaveAttachmentsOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Save Attachments",
+				ReadOnlyHint:    false,
+				IdempotentHint:  false,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input SaveAttachmentsInput) (*mcp.CallToolResult, *SaveAttachmentsOutput, error) {
+			return HandleSaveAttachments(ctx, executor, exportDir, request, input)
+		},
+	)
+}
+
+func HandleSaveAttachments(ctx context.Context, executor jxa.Executor, exportDir string, request *mcp.CallToolRequest, input SaveAttachmentsInput) (*mcp.CallToolResult, *SaveAttachmentsOutput, error) {
+	if exportDir == "" {
+		return nil, nil, missingParameters("no export directory configured, start the server with --export-dir")
+	}
+	if len(input.MailboxPath) == 0 {
+		return nil, nil, missingParameters("mailboxPath is required and must be a non-empty array")
+	}
+	dir, err := exportSubdirectory(exportDir, input.Subdirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, content, err := HandleGetMessageContent(ctx, executor, nil, GetMessageContentInput{
+		Account:     input.Account,
+		MailboxPath: input.MailboxPath,
+		MessageID:   input.MessageID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	attachments := content.Message.Attachments
+	indexes, err := selectAttachments(attachments, input.Indexes, input.Names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets := make([]attachmentTarget, len(indexes))
+	taken := map[string]bool{}
+	for i, index := range indexes {
+		att := attachments[index]
+		if !att.Downloaded {
+			return nil, nil, jxa.NewError(jxa.ErrorCodeAttachmentNotDownloaded, "attachment %q has not been downloaded yet, open the message in Mail.app to download it", att.Name)
+		}
+		path := uniquePath(dir, sanitizeFilename(att.Name), taken)
+		taken[path] = true
+		targets[i] = attachmentTarget{Index: index, Name: att.Name, Path: path}
+	}
+
+	inputJSON, err := json.Marshal(struct {
+		SaveAttachmentsInput
+		Attachments []attachmentTarget `json:"attachments"`
+	}{input, targets})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, saveAttachmentsScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute save_attachments: %w", err)
+	}
+
+	saved, err := decodeResult[savedAttachments](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &SaveAttachmentsOutput{Files: []SavedAttachment{}, ExportDir: dir}
+	for _, s := range saved.Saved {
+		i := slices.IndexFunc(targets, func(t attachmentTarget) bool { return t.Index == s.Index && t.Path == s.Path })
+		if i < 0 {
+			return nil, nil, fmt.Errorf("save_attachments saved attachment %d to unexpected path %s", s.Index, s.Path)
+		}
+		size, digest, err := hashFile(s.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Files = append(result.Files, SavedAttachment{
+			Index:  s.Index,
+			Name:   targets[i].Name,
+			Path:   s.Path,
+			Size:   size,
+			SHA256: digest,
+		})
+	}
+	result.Count = len(result.Files)
+	return nil, result, nil
+}
+
+// exportSubdirectory returns the absolute directory to save files in,
+// creating it if needed. subdirectory must be a local path and may not leave
+// the export directory, not even by symbolic links.
+func exportSubdirectory(exportDir, subdirectory string) (string, error) {
+	root, err := filepath.Abs(exportDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid export directory: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", fmt.Errorf("invalid export directory: %w", err)
+	}
+	if subdirectory == "" {
+		return root, nil
+	}
+
+	sub := filepath.FromSlash(subdirectory)
+	if !filepath.IsLocal(sub) {
+		return "", invalidParameters("subdirectory must be a relative path inside the export directory: %s", subdirectory)
+	}
+	dir := filepath.Join(root, sub)
+
+	// Check the deepest existing directory before creating the missing ones,
+	// which could otherwise be created behind a symbolic link
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	if err := checkInside(root, existing, subdirectory); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create subdirectory: %w", err)
+	}
+	if err := checkInside(root, dir, subdirectory); err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(dir)
+}
+
+// checkInside checks that path resolves to root or a directory inside it.
+func checkInside(root, path, subdirectory string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("invalid subdirectory: %w", err)
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel != "." && !filepath.IsLocal(rel) {
+		return invalidParameters("subdirectory leaves the export directory: %s", subdirectory)
+	}
+	return nil
+}
+
+// selectAttachments returns the positions of the attachments chosen by
+// position or name, all of them if none are chosen, in the order of the
+// message.
+func selectAttachments(attachments []Attachment, indexes []int, names []string) ([]int, error) {
+	if len(attachments) == 0 {
+		return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "the message has no attachments")
+	}
+	if len(indexes) == 0 && len(names) == 0 {
+		all := make([]int, len(attachments))
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	var selected []int
+	for _, index := range indexes {
+		if index < 0 || index >= len(attachments) {
+			return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "attachment index %d out of range, the message has %d attachments", index, len(attachments))
+		}
+		selected = append(selected, index)
+	}
+	for _, name := range names {
+		index := slices.IndexFunc(attachments, func(a Attachment) bool { return a.Name == name })
+		if index < 0 {
+			available := make([]string, len(attachments))
+			for i, a := range attachments {
+				available[i] = a.Name
+			}
+			return nil, jxa.NewError(jxa.ErrorCodeAttachmentNotFound, "attachment %q not found (available: %s)", name, strings.Join(available, ", "))
+		}
+		selected = append(selected, index)
+	}
+	slices.Sort(selected)
+	return slices.Compact(selected), nil
+}
+
+// sanitizeFilename turns the name of an attachment into a file name:
+// separators, control and reserved characters are replaced, leading dots
+// removed so that the file is neither hidden nor a reference to a parent
+// directory, and overlong names shortened, keeping the extension.
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "attachment"
+	}
+	if len(name) <= maxFilenameLength {
+		return name
+	}
+	ext := filepath.Ext(name)
+	if len(ext) > 32 {
+		ext = ""
+	}
+	base := name[:maxFilenameLength-len(ext)]
+	for !utf8.ValidString(base) {
+		base = base[:len(base)-1]
+	}
+	return base + ext
+}
+
+// uniquePath returns the path of a file named name in dir that neither
+// exists nor is taken, appending " (2)", " (3)" and so on to the name.
+func uniquePath(dir, name string, taken map[string]bool) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) && !taken[path] {
+			return path
+		}
+		path = filepath.Join(dir, base+" ("+strconv.Itoa(n)+")"+ext)
+	}
+}
+
+// hashFile returns the size and hex-encoded SHA-256 digest of a saved file.
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open saved attachment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read saved attachment: %w", err)
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
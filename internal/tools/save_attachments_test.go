@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "_.._etc_passwd"},
+		{"..", "attachment"},
+		{".hidden", "hidden"},
+		{"  ", "attachment"},
+		{`a\b:c*d?"e"<f>|g.txt`, "a_b_c_d__e__f__g.txt"},
+		{"line\nbreak\x00.txt", "line_break_.txt"},
+		{"Rechnung März.pdf", "Rechnung März.pdf"},
+		{strings.Repeat("ä", 200) + ".pdf", strings.Repeat("ä", 125) + ".pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeFilename(tt.name)
+			if got != tt.want {
+				t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if len(got) > maxFilenameLength {
+				t.Errorf("sanitizeFilename(%q) has %d bytes", tt.name, len(got))
+			}
+		})
+	}
+}
+
+func TestExportSubdirectory(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "export")
+	outside := t.TempDir()
+
+	dir, err := exportSubdirectory(root, "")
+	if err != nil {
+		t.Fatalf("exportSubdirectory() error = %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() || !filepath.IsAbs(dir) {
+		t.Fatalf("exportSubdirectory() = %s, want the created absolute export directory", dir)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := exportSubdirectory(root, "2025/invoices")
+	if err != nil || sub != filepath.Join(dir, "2025", "invoices") {
+		t.Errorf("exportSubdirectory(2025/invoices) = %s, %v", sub, err)
+	}
+	for _, subdirectory := range []string{"..", "../export2", "a/../../b", "/tmp", "link", "link/nested"} {
+		if _, err := exportSubdirectory(root, subdirectory); !jxa.HasCode(err, jxa.ErrorCodeInvalidParameters) {
+			t.Errorf("exportSubdirectory(%s) error = %v, want %s", subdirectory, err, jxa.ErrorCodeInvalidParameters)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("exportSubdirectory() created %s outside the export directory", entries[0].Name())
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "menu.pdf"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	taken := map[string]bool{filepath.Join(dir, "menu (2).pdf"): true}
+	if got, want := uniquePath(dir, "menu.pdf", taken), filepath.Join(dir, "menu (3).pdf"); got != want {
+		t.Errorf("uniquePath() = %s, want %s", got, want)
+	}
+	if got, want := uniquePath(dir, "notes", taken), filepath.Join(dir, "notes"); got != want {
+		t.Errorf("uniquePath() = %s, want %s", got, want)
+	}
+}
+
+func TestSelectAttachments(t *testing.T) {
+	attachments := []Attachment{{Name: "a.pdf"}, {Name: "b.csv"}, {Name: "a.pdf"}}
+	tests := []struct {
+		name    string
+		indexes []int
+		names   []string
+		want    []int
+		code    string
+	}{
+		{name: "all", want: []int{0, 1, 2}},
+		{name: "by index and name", indexes: []int{2, 1}, names: []string{"b.csv"}, want: []int{1, 2}},
+		{name: "first of duplicate names", names: []string{"a.pdf"}, want: []int{0}},
+		{name: "index out of range", indexes: []int{3}, code: jxa.ErrorCodeAttachmentNotFound},
+		{name: "negative index", indexes: []int{-1}, code: jxa.ErrorCodeAttachmentNotFound},
+		{name: "unknown name", names: []string{"c.txt"}, code: jxa.ErrorCodeAttachmentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectAttachments(attachments, tt.indexes, tt.names)
+			if tt.code != "" {
+				if !jxa.HasCode(err, tt.code) {
+					t.Errorf("selectAttachments() error = %v, want %s", err, tt.code)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("selectAttachments() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
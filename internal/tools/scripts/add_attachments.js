@@ -0,0 +1,49 @@
+#!/usr/bin/osascript -l JavaScript
+
+/**
+ * Attach files to an outgoing message
+ *
+ * Arguments:
+ *   argv[0] - JSON string containing:
+ *     - outgoing_id (required) - ID of the outgoing message
+ *     - paths (required) - Array of absolute paths of the files to attach
+ *
+ * The paths are chosen and checked by the server. The files are appended to
+ * the content, so the body must be pasted before.
+ */
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const outgoingId = args.outgoing_id;
+      const paths = args.paths || [];
+
+      if (outgoingId === undefined || outgoingId === null) {
+        throw new ScriptError("outgoing_id is required.", "MISSING_PARAMETERS");
+      }
+
+      if (!Array.isArray(paths) || paths.length === 0) {
+        throw new ScriptError(
+          "Paths are required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const msg = findOutgoingMessage(Mail, outgoingId);
+
+      const attached = [];
+      for (const path of paths) {
+        msg.content.attachments.push(Mail.Attachment({ fileName: Path(path) }));
+        log(`Attached ${path}`);
+        attached.push(path);
+      }
+
+      return {
+        outgoing_id: msg.id(),
+        attached: attached,
+      };
+    },
+    "Failed to attach files",
+  );
+}
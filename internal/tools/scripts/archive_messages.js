@@ -0,0 +1,78 @@
+// Mail.app has no scripting property for the archive mailbox, so it is
+// resolved by the names used by common providers, in this order.
+const ARCHIVE_MAILBOX_PATHS = [
+  ["Archive"],
+  ["Archives"],
+  ["[Gmail]", "All Mail"],
+  ["[Google Mail]", "All Mail"],
+];
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageIds = args.message_ids || [];
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+      if (!Array.isArray(messageIds) || messageIds.length === 0) {
+        throw new ScriptError(
+          "At least one message ID is required",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const account = findAccount(Mail, accountName);
+      const source = findMailbox(account, mailboxPath);
+
+      let archive = null;
+      let archivePath = null;
+      for (const path of ARCHIVE_MAILBOX_PATHS) {
+        archive = findMailboxByPath(account, path);
+        if (archive) {
+          archivePath = path;
+          break;
+        }
+      }
+      if (!archive) {
+        throw new ScriptError(
+          `No archive mailbox found in account '${accountName}'. Tried: ${ARCHIVE_MAILBOX_PATHS.map((p) => p.join(" > ")).join(", ")}.`,
+          "MAILBOX_NOT_FOUND",
+        );
+      }
+      log(`Using archive mailbox '${archivePath.join(" > ")}'.`);
+
+      const inArchive =
+        mailboxPath.length === archivePath.length &&
+        mailboxPath.every((name, i) => name === archivePath[i]);
+      if (inArchive) {
+        throw new ScriptError(
+          `The messages are already in the archive mailbox '${archivePath.join(" > ")}'.`,
+          "INVALID_PARAMETERS",
+        );
+      }
+
+      const messages = findMessagesByIds(source, messageIds, mailboxPath);
+      const archived = transferMessages(Mail, messages, archive, false, log);
+
+      return {
+        messages: archived,
+        count: archived.length,
+        account: accountName,
+        mailboxPath: mailboxPath,
+        destinationMailboxPath: archivePath,
+        message: `Archived ${archived.length} message(s) to '${archivePath.join(" > ")}'.`,
+      };
+    },
+    "Failed to archive messages",
+  );
+}
@@ -1,134 +1,103 @@
-function run(argv) {
-  const Mail = Application("Mail");
-  Mail.includeStandardAdditions = true;
+const SORT_ORDERS = ["date_desc", "date_asc", "sender", "subject"];
 
-  // 1. CRITICAL: Check if running FIRST
-  if (!Mail.running()) {
-    return JSON.stringify({
-      success: false,
-      error: "Mail.app is not running. Please start Mail.app and try again.",
-      errorCode: "MAIL_APP_NOT_RUNNING",
-    });
+// compareMatches orders matches by the sort order and breaks ties by date
+// (newest first), account, mailbox path and ID, so that a cursor resumes at a
+// well-defined position. Mirrors compareMessages in find_messages.go, which
+// merges the pages of several mailboxes, so strings are compared by code
+// points as Go does, not by UTF-16 code units.
+function compareMatches(sort, a, b) {
+  let c = 0;
+  if (sort === "date_asc") c = a.date - b.date;
+  if (sort === "sender") c = compareCodePoints(a.sender, b.sender);
+  if (sort === "subject") c = compareCodePoints(a.subject, b.subject);
+  if (c === 0 && sort !== "date_asc") c = b.date - a.date;
+  if (c === 0) c = compareCodePoints(a.account, b.account);
+  for (let i = 0; c === 0 && i < a.mailboxPath.length; i++) {
+    if (i >= b.mailboxPath.length) c = 1;
+    else c = compareCodePoints(a.mailboxPath[i], b.mailboxPath[i]);
   }
+  if (c === 0 && a.mailboxPath.length < b.mailboxPath.length) c = -1;
+  if (c === 0) c = a.id - b.id;
+  return c;
+}
 
-  // 2. Logging setup
-  const logs = [];
-  const log = (msg) => logs.push(msg);
-
-  // 3. Argument parsing
-  let args;
-  try {
-    args = JSON.parse(argv[0]);
-  } catch (e) {
-    return JSON.stringify({
-      success: false,
-      error: "Failed to parse input arguments JSON",
-    });
+// compareCodePoints compares strings by code points, which orders
+// characters outside the BMP after U+E000-U+FFFF, unlike < on strings.
+function compareCodePoints(x, y) {
+  let i = 0;
+  let j = 0;
+  while (i < x.length && j < y.length) {
+    const a = x.codePointAt(i);
+    const b = y.codePointAt(j);
+    if (a !== b) return a < b ? -1 : 1;
+    i += a > 0xffff ? 2 : 1;
+    j += b > 0xffff ? 2 : 1;
   }
+  if (i < x.length) return 1;
+  if (j < y.length) return -1;
+  return 0;
+}
 
-  const {
-    account: accountName,
-    mailboxPath = [],
-    limit = 50,
-    subject,
-    sender,
-    readStatus,
-    flaggedOnly,
-    dateAfter,
-    dateBefore,
-  } = args;
-
-  if (!accountName) {
-    return JSON.stringify({
-      success: false,
-      error: "Account name is required",
-    });
-  }
-  if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
-    return JSON.stringify({ success: false, error: "Mailbox path required" });
+// foldCase lower-cases each code point on its own, so that the result does
+// not depend on the context, e.g. of a final sigma. Code points whose lower
+// case is more than one code point, i.e. U+0130, are kept. Mirrors foldCase
+// in find_messages.go.
+function foldCase(s) {
+  let folded = "";
+  for (const ch of s) {
+    const lower = ch.toLowerCase();
+    folded += lower.length === ch.length ? lower : ch;
   }
+  return folded;
+}
 
-  if (limit < 1 || limit > 1000) {
-    return JSON.stringify({
-      success: false,
-      error: "Limit must be between 1 and 1000",
-    });
-  }
+function run(argv) {
+  return runScript(argv, (Mail, args, log) => {
+    const {
+      account: accountName,
+      mailboxPath = [],
+      limit = 50,
+      subject,
+      sender,
+      readStatus,
+      flaggedOnly,
+      dateAfter,
+      dateBefore,
+      body,
+      recipient,
+      cc,
+      replyTo,
+      hasAttachments,
+      attachmentName,
+      minSize,
+      maxSize,
+      sort = "date_desc",
+      after,
+      skipContent = false,
+    } = args;
 
-  try {
-    const targetAccount = Mail.accounts[accountName];
-    try {
-      targetAccount.name();
-    } catch (e) {
-      return JSON.stringify({
-        success: false,
-        error: `Account "${accountName}" not found.`,
-      });
+    if (!accountName) {
+      throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
     }
-
-    // Robust mailbox traversal function
-    function findMailboxByPath(account, targetPath) {
-      if (!targetPath || targetPath.length === 0) return account;
-
-      try {
-        let current = account;
-        for (let i = 0; i < targetPath.length; i++) {
-          const part = targetPath[i];
-          let next = null;
-          try {
-            next = current.mailboxes.whose({ name: part })()[0];
-          } catch (e) {}
-
-          if (!next) {
-            try {
-              next = current.mailboxes[part];
-              next.name();
-            } catch (e) {}
-          }
-          if (!next) throw new Error("not found");
-          current = next;
-        }
-        return current;
-      } catch (e) {}
-
-      try {
-        const allMailboxes = account.mailboxes();
-        for (let i = 0; i < allMailboxes.length; i++) {
-          const mbx = allMailboxes[i];
-          const path = [];
-          let current = mbx;
-          while (current) {
-            try {
-              const name = current.name();
-              if (name === account.name()) break;
-              path.unshift(name);
-              current = current.container();
-            } catch (e) {
-              break;
-            }
-          }
-          if (path.length === targetPath.length) {
-            let match = true;
-            for (let j = 0; j < path.length; j++) {
-              if (path[j] !== targetPath[j]) {
-                match = false;
-                break;
-              }
-            }
-            if (match) return mbx;
-          }
-        }
-      } catch (e) {}
-      return null;
+    if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+      throw new ScriptError("Mailbox path required", "MISSING_PARAMETERS");
     }
 
-    const targetMailbox = findMailboxByPath(targetAccount, mailboxPath);
-    if (!targetMailbox) {
-      return JSON.stringify({
-        success: false,
-        error: `Mailbox "${mailboxPath.join(" > ")}" not found in account "${accountName}".`,
-      });
+    if (limit < 1 || limit > 1000) {
+      throw new ScriptError(
+        "Limit must be between 1 and 1000",
+        "INVALID_PARAMETERS",
+      );
     }
+    if (SORT_ORDERS.indexOf(sort) === -1) {
+      throw new ScriptError(
+        `Unknown sort order '${sort}'. Valid: ${SORT_ORDERS.join(", ")}`,
+        "INVALID_PARAMETERS",
+      );
+    }
+
+    const targetAccount = findAccount(Mail, accountName);
+    const targetMailbox = findMailbox(targetAccount, mailboxPath);
 
     const msgs = targetMailbox.messages;
     const count = msgs.length;
@@ -145,60 +114,160 @@ function run(argv) {
     let filterSender = sender ? sender.toLowerCase() : null;
     let filterDateAfter = dateAfter ? new Date(dateAfter) : null;
     let filterDateBefore = dateBefore ? new Date(dateBefore) : null;
+    let filterBody = body ? body.toLowerCase() : null;
+    let filterRecipient = recipient ? recipient.toLowerCase() : null;
+    let filterCc = cc ? cc.toLowerCase() : null;
+    let filterReplyTo = replyTo ? replyTo.toLowerCase() : null;
+    let filterAttachmentName = attachmentName
+      ? attachmentName.toLowerCase()
+      : null;
+    const containsText = (value, text) =>
+      !!value && value.toLowerCase().indexOf(text) !== -1;
 
-    // Fetch only the columns needed for filtering to minimize data transfer
-    const subjects = filterSubject ? msgs.subject() : null;
-    const senders = filterSender ? msgs.sender() : null;
+    // Fetch only the columns needed for filtering and sorting to minimize
+    // data transfer
+    const subjects =
+      filterSubject || sort === "subject" ? msgs.subject() : null;
+    const senders = filterSender || sort === "sender" ? msgs.sender() : null;
     const readStatuses =
       readStatus !== undefined && readStatus !== null
         ? msgs.readStatus()
         : null;
     const flaggedStatuses = flaggedOnly ? msgs.flaggedStatus() : null;
-    const datesReceived =
-      filterDateAfter || filterDateBefore ? msgs.dateReceived() : null;
+    const sizes = minSize || maxSize ? msgs.messageSize() : null;
+    const replyTos = filterReplyTo ? msgs.replyTo() : null;
+    // Element properties are fetched as one array per message
+    const toAddresses = filterRecipient ? msgs.toRecipients.address() : null;
+    const ccAddresses =
+      filterRecipient || filterCc ? msgs.ccRecipients.address() : null;
+    const attachmentNames =
+      filterAttachmentName ||
+      (hasAttachments !== undefined && hasAttachments !== null)
+        ? msgs.mailAttachments.name()
+        : null;
+    const datesReceived = msgs.dateReceived();
+    const ids = msgs.id();
 
     const matchingIndices = [];
     for (let i = 0; i < count; i++) {
       if (
-        subjects &&
+        filterSubject &&
         (!subjects[i] ||
           subjects[i].toLowerCase().indexOf(filterSubject) === -1)
       )
         continue;
       if (
-        senders &&
+        filterSender &&
         (!senders[i] || senders[i].toLowerCase().indexOf(filterSender) === -1)
       )
         continue;
       if (readStatuses && readStatuses[i] !== readStatus) continue;
       if (flaggedStatuses && flaggedStatuses[i] !== true) continue;
-      if (datesReceived) {
-        const d = datesReceived[i];
-        if (filterDateAfter && d <= filterDateAfter) continue;
-        if (filterDateBefore && d >= filterDateBefore) continue;
+      const d = datesReceived[i];
+      if (filterDateAfter && d <= filterDateAfter) continue;
+      if (filterDateBefore && d >= filterDateBefore) continue;
+      if (sizes && minSize && sizes[i] < minSize) continue;
+      if (sizes && maxSize && sizes[i] > maxSize) continue;
+      if (replyTos && !containsText(replyTos[i], filterReplyTo)) continue;
+      if (
+        filterRecipient &&
+        !(toAddresses[i] || [])
+          .concat(ccAddresses[i] || [])
+          .some((a) => containsText(a, filterRecipient))
+      )
+        continue;
+      if (
+        filterCc &&
+        !(ccAddresses[i] || []).some((a) => containsText(a, filterCc))
+      )
+        continue;
+      if (attachmentNames) {
+        const names = attachmentNames[i] || [];
+        if (hasAttachments === true && names.length === 0) continue;
+        if (hasAttachments === false && names.length > 0) continue;
+        if (
+          filterAttachmentName &&
+          !names.some((n) => containsText(n, filterAttachmentName))
+        )
+          continue;
       }
       matchingIndices.push(i);
     }
 
+    // The content cannot be fetched in bulk without transferring every body,
+    // so the body filter reads it only for messages matching all other
+    // filters.
+    const contents = {};
+    if (filterBody) {
+      log(`Reading content of ${matchingIndices.length} messages...`);
+      const candidates = matchingIndices.splice(0);
+      candidates.forEach((i) => {
+        let content = "";
+        try {
+          content = msgs[i].content() || "";
+        } catch (e) {
+          log("Error reading content for message " + i + ": " + e.toString());
+        }
+        contents[i] = content;
+        if (containsText(content, filterBody)) matchingIndices.push(i);
+      });
+    }
+
+    // Keys are computed once, as folding the case is not cheap
+    const sortKeys = {};
+    matchingIndices.forEach((i) => {
+      sortKeys[i] = {
+        date: datesReceived[i].getTime(),
+        sender: senders && senders[i] ? foldCase(senders[i]) : "",
+        subject: subjects && subjects[i] ? foldCase(subjects[i]) : "",
+        account: accountName,
+        mailboxPath: mailboxPath,
+        id: ids[i],
+      };
+    });
+    matchingIndices.sort((a, b) =>
+      compareMatches(sort, sortKeys[a], sortKeys[b]),
+    );
+
     const totalMatches = matchingIndices.length;
     log(`Found ${totalMatches} matching messages.`);
 
-    const maxProcess = Math.min(totalMatches, limit);
+    // Resume after the last message of the previous page
+    let remainingIndices = matchingIndices;
+    if (after) {
+      const afterKey = {
+        date: new Date(after.date_received).getTime(),
+        sender: foldCase(after.sender || ""),
+        subject: foldCase(after.subject || ""),
+        account: after.account,
+        mailboxPath: after.mailbox_path,
+        id: after.id,
+      };
+      remainingIndices = matchingIndices.filter(
+        (i) => compareMatches(sort, sortKeys[i], afterKey) > 0,
+      );
+    }
+
+    const maxProcess = Math.min(remainingIndices.length, limit);
     const resultMessages = [];
 
     if (maxProcess > 0) {
-      const subsetIndices = matchingIndices.slice(0, maxProcess);
+      const subsetIndices = remainingIndices.slice(0, maxProcess);
       const subsetMsgs = subsetIndices.map((idx) => msgs[idx]);
 
       for (let i = 0; i < maxProcess; i++) {
         const msg = subsetMsgs[i];
 
-        // 1. Get content safely (often fails on weird/syncing messages)
-        let content = "";
-        try {
-          content = msg.content() || "";
-        } catch (e) {
-          log("Error reading content for message " + i + ": " + e.toString());
+        // 1. Get content safely (often fails on weird/syncing messages),
+        // unless the body filter already read it or the caller skips it
+        let content = contents[subsetIndices[i]];
+        if (content === undefined) {
+          content = "";
+          try {
+            if (!skipContent) content = msg.content() || "";
+          } catch (e) {
+            log("Error reading content for message " + i + ": " + e.toString());
+          }
         }
 
         // 2. Get the rest of the properties
@@ -215,10 +284,7 @@ function run(argv) {
             read_status: msg.readStatus(),
             flagged_status: msg.flaggedStatus(),
             message_size: msg.messageSize(),
-            content_preview:
-              content.length > 100
-                ? content.substring(0, 100) + "..."
-                : content,
+            content_preview: contentPreview(content),
             content_length: content.length,
             mailbox_path: mailboxPath,
             account: accountName,
@@ -232,30 +298,29 @@ function run(argv) {
       }
     }
 
-    return JSON.stringify({
-      success: true,
-      data: {
-        messages: resultMessages,
-        count: resultMessages.length,
-        total_matches: totalMatches,
-        limit: limit,
-        has_more: totalMatches > limit,
-        filters_applied: {
-          subject: subject || null,
-          sender: sender || null,
-          read_status: readStatus !== undefined ? readStatus : null,
-          flagged_only: flaggedOnly || false,
-          date_after: dateAfter || null,
-          date_before: dateBefore || null,
-        },
+    return {
+      messages: resultMessages,
+      count: resultMessages.length,
+      total_matches: totalMatches,
+      limit: limit,
+      has_more: remainingIndices.length > limit,
+      filters_applied: {
+        subject: subject || null,
+        sender: sender || null,
+        read_status: readStatus !== undefined ? readStatus : null,
+        flagged_only: flaggedOnly || false,
+        date_after: dateAfter || null,
+        date_before: dateBefore || null,
+        body: body || null,
+        recipient: recipient || null,
+        cc: cc || null,
+        reply_to: replyTo || null,
+        has_attachments: hasAttachments !== undefined ? hasAttachments : null,
+        attachment_name: attachmentName || null,
+        min_size: minSize || null,
+        max_size: maxSize || null,
       },
-      logs: logs.join("\n"),
-    });
-  } catch (e) {
-    return JSON.stringify({
-      success: false,
-      error: e.toString(),
-      logs: logs.join("\n"),
-    });
-  }
+      sort: sort,
+    };
+  });
 }
@@ -0,0 +1,81 @@
+#!/usr/bin/osascript -l JavaScript
+
+/**
+ * Get the raw source of a message from Mail.app
+ *
+ * Arguments:
+ *   argv[0] - JSON string containing:
+ *     - account (required)
+ *     - mailboxPath (required) - Array like ["Inbox"] or ["Inbox","GitHub"]
+ *     - message_id (required) - numeric ID
+ *     - maxSize (optional) - size in bytes above which the source is not read
+ *
+ * The source is returned as is, parsing the MIME structure is left to the
+ * server. The size of the message is returned too, so that the server can
+ * tell a message larger than maxSize from one without a source.
+ */
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageId = args.message_id ? parseInt(args.message_id) : 0;
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      if (!messageId || messageId < 1) {
+        throw new ScriptError(
+          "Message ID is required and must be a positive integer",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const targetAccount = findAccount(Mail, accountName);
+      const targetMailbox = findMailbox(targetAccount, mailboxPath);
+
+      const targetMessage = findMessage(
+        targetMailbox,
+        messageId,
+        `Message with ID ${messageId} not found in mailbox "${mailboxPath.join(" > ")}". The message may have been deleted or moved.`,
+      );
+
+      // Transferring the source of huge messages takes long, so it is
+      // skipped if the caller cannot use it anyway
+      const messageSize = targetMessage.messageSize();
+      if (args.maxSize && messageSize > args.maxSize) {
+        log(`Message size ${messageSize} exceeds ${args.maxSize} bytes`);
+        return {
+          id: targetMessage.id(),
+          source: "",
+          messageSize: messageSize,
+        };
+      }
+
+      // Messages that are not downloaded yet have no source
+      let source = "";
+      try {
+        source = targetMessage.source() || "";
+      } catch (e) {
+        log("Error getting message source: " + e.toString());
+      }
+
+      return {
+        id: targetMessage.id(),
+        source: source,
+        messageSize: messageSize,
+      };
+    },
+    "Failed to retrieve message source",
+  );
+}
@@ -1,63 +1,26 @@
 #!/usr/bin/osascript -l JavaScript
 
 function run(argv) {
-  const Mail = Application("Mail");
-  Mail.includeStandardAdditions = true;
+  return runScript(argv, (Mail, args, log) => {
+    const limit = args.limit || 5;
+    const startAt = 0;
 
-  // Check if Mail.app is running
-  if (!Mail.running()) {
-    return JSON.stringify({
-      success: false,
-      error: "Mail.app is not running. Please start Mail.app and try again.",
-      errorCode: "MAIL_APP_NOT_RUNNING",
-    });
-  }
-
-  // Collect logs instead of using console.log
-  const logs = [];
-
-  // Helper function to log messages
-  function log(message) {
-    logs.push(message);
-  }
-
-  // Parse arguments
-  let args;
-  try {
-    args = JSON.parse(argv[0]);
-  } catch (e) {
-    return JSON.stringify({
-      success: false,
-      error: "Failed to parse input arguments JSON",
-    });
-  }
-
-  const limit = args.limit || 5;
-  const startAt = 0;
-
-  if (limit < 1) {
-    return JSON.stringify({
-      success: false,
-      error: "Limit must be at least 1",
-    });
-  }
+    if (limit < 1) {
+      throw new ScriptError("Limit must be at least 1", "INVALID_PARAMETERS");
+    }
 
-  if (limit > 100) {
-    return JSON.stringify({
-      success: false,
-      error: "Limit cannot exceed 100",
-    });
-  }
+    if (limit > 100) {
+      throw new ScriptError("Limit cannot exceed 100", "INVALID_PARAMETERS");
+    }
 
-  try {
     // Get the selected messages from the frontmost Mail viewer
     const viewers = Mail.messageViewers();
 
     if (!viewers || viewers.length === 0) {
-      return JSON.stringify({
-        success: false,
-        error: "No Mail viewer windows are open",
-      });
+      throw new ScriptError(
+        "No Mail viewer windows are open",
+        "NO_VIEWER_WINDOW",
+      );
     }
 
     // Get the frontmost viewer
@@ -65,59 +28,20 @@ function run(argv) {
     const selectedMessages = viewer.selectedMessages();
 
     if (!selectedMessages || selectedMessages.length === 0) {
-      return JSON.stringify({
-        success: true,
-        data: {
-          selectedMessagesCount: 0,
-          messages: [],
-        },
-      });
+      return {
+        selectedMessagesCount: 0,
+        messages: [],
+      };
     }
 
     const selectedMessagesCount = selectedMessages.length;
 
     // Check if startAt is beyond available messages
     if (startAt >= selectedMessagesCount) {
-      return JSON.stringify({
-        success: true,
-        data: {
-          selectedMessagesCount: selectedMessagesCount,
-          messages: [],
-        },
-      });
-    }
-
-    // Helper function to build mailbox path
-    // Returns array like ["Inbox", "GitHub"] for nested mailboxes
-    function getMailboxPath(mailbox, accountName) {
-      const path = [];
-      let current = mailbox;
-
-      // Walk up the mailbox tree until we reach the account
-      while (current) {
-        try {
-          const name = current.name();
-
-          // Stop if we've reached the account level
-          if (name === accountName) {
-            break;
-          }
-
-          path.unshift(name);
-
-          // Try to get container (parent mailbox or account)
-          try {
-            current = current.container();
-          } catch (e) {
-            // No container, stop
-            break;
-          }
-        } catch (e) {
-          break;
-        }
-      }
-
-      return path;
+      return {
+        selectedMessagesCount: selectedMessagesCount,
+        messages: [],
+      };
     }
 
     // Extract message details (limited by limit parameter, starting at startAt)
@@ -147,27 +71,11 @@ function run(argv) {
         account: account.name(),
       });
     }
+    log(`Read ${result.length} of ${selectedMessagesCount} selected messages.`);
 
-    return JSON.stringify({
-      success: true,
-      data: {
-        messages: result,
-        count: result.length,
-      },
-      logs: logs.join("\n"),
-    });
-  } catch (e) {
-    let errorCode = "UNKNOWN_ERROR";
-    if (
-      e.errorNumber === -1743 ||
-      e.toString().includes("Automation is not allowed")
-    ) {
-      errorCode = "MAIL_APP_NO_PERMISSIONS";
-    }
-    return JSON.stringify({
-      success: false,
-      error: e.toString(),
-      errorCode: errorCode,
-    });
-  }
+    return {
+      messages: result,
+      count: result.length,
+    };
+  });
 }
@@ -1,37 +1,5 @@
 function run(argv) {
-  try {
-    // Initialize Mail.app
-    const Mail = Application("Mail");
-    Mail.includeStandardAdditions = true;
-
-    // Check if Mail.app is running
-    if (!Mail.running()) {
-      return JSON.stringify({
-        success: false,
-        error: "Mail.app is not running. Please start Mail.app and try again.",
-        errorCode: "MAIL_APP_NOT_RUNNING",
-      });
-    }
-
-    // Collect logs instead of using console.log
-    const logs = [];
-
-    // Helper function to log messages
-    function log(message) {
-      logs.push(message);
-    }
-
-    // Parse arguments
-    let args;
-    try {
-      args = JSON.parse(argv[0]);
-    } catch (e) {
-      return JSON.stringify({
-        success: false,
-        error: "Failed to parse input arguments JSON",
-      });
-    }
-
+  return runScript(argv, (Mail, args, log) => {
     const filterEnabled = args.enabled === true;
 
     // Get accounts - use whose() for filtering if enabled filter is true
@@ -43,12 +11,10 @@ function run(argv) {
     } catch (e) {
       // If Mail.app is running but we can't access it, it's a permissions issue
       // (macOS returns generic "Error: An error occurred." for permission denials)
-      return JSON.stringify({
-        success: false,
-        error:
-          "Permission denied to access Mail.app. Please grant automation permissions in System Settings > Privacy & Security > Automation.",
-        errorCode: "MAIL_APP_NO_PERMISSIONS",
-      });
+      throw new ScriptError(
+        "Permission denied to access Mail.app. Please grant automation permissions in System Settings > Privacy & Security > Automation.",
+        "MAIL_APP_NO_PERMISSIONS",
+      );
     }
     const accountList = [];
 
@@ -82,25 +48,16 @@ function run(argv) {
         const mailboxes = account.mailboxes();
         accountInfo.mailboxCount = mailboxes ? mailboxes.length : 0;
       } catch (e) {
+        log("Error reading mailboxes: " + e.toString());
         accountInfo.mailboxCount = 0;
       }
 
       accountList.push(accountInfo);
     }
 
-    // Return success with account data
-    return JSON.stringify({
-      success: true,
-      data: {
-        accounts: accountList,
-        count: accountList.length,
-      },
-      logs: logs.join("\n"),
-    });
-  } catch (e) {
-    return JSON.stringify({
-      success: false,
-      error: e.toString(),
-    });
-  }
+    return {
+      accounts: accountList,
+      count: accountList.length,
+    };
+  });
 }
@@ -0,0 +1,50 @@
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageIds = args.message_ids || [];
+      const destinationPath = args.destinationMailboxPath || [];
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+      if (!Array.isArray(destinationPath) || destinationPath.length === 0) {
+        throw new ScriptError(
+          "Destination mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+      if (!Array.isArray(messageIds) || messageIds.length === 0) {
+        throw new ScriptError(
+          "At least one message ID is required",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const account = findAccount(Mail, accountName);
+      const source = findMailbox(account, mailboxPath);
+      const destination = findMailbox(account, destinationPath);
+      const messages = findMessagesByIds(source, messageIds, mailboxPath);
+
+      const moved = transferMessages(Mail, messages, destination, false, log);
+
+      return {
+        messages: moved,
+        count: moved.length,
+        account: accountName,
+        mailboxPath: mailboxPath,
+        destinationMailboxPath: destinationPath,
+        message: `Moved ${moved.length} message(s) to '${destinationPath.join(" > ")}'.`,
+      };
+    },
+    "Failed to move messages",
+  );
+}
@@ -1,132 +1,128 @@
 function run(argv) {
-  const Mail = Application("Mail");
-  Mail.includeStandardAdditions = true;
-  const SystemEvents = Application("System Events");
-
-  // 1. CRITICAL: Check if running FIRST
-  if (!Mail.running()) {
-    return JSON.stringify({
-      success: false,
-      error: "Mail.app is not running. Please start Mail.app and try again.",
-      errorCode: "MAIL_APP_NOT_RUNNING",
-    });
-  }
-
-  // 2. Logging setup
-  const logs = [];
-  function log(message) {
-    logs.push(message);
-  }
-
-  // 3. Argument Parsing & Validation
-  let args;
-  try {
-    args = JSON.parse(argv[0]);
-  } catch (e) {
-    return JSON.stringify({
-      success: false,
-      error: "Failed to parse input arguments JSON",
-      logs: logs.join("\n"),
-    });
-  }
-
-  const outgoingIdToReplace = args.outgoing_id;
-
-  if (outgoingIdToReplace === undefined || outgoingIdToReplace === null) {
-    return JSON.stringify({
-      success: false,
-      error: "A valid outgoing_id is required.",
-      errorCode: "MISSING_PARAMETERS",
-      logs: logs.join("\n"),
-    });
-  }
-  log(`Attempting to replace outgoing message with ID: ${outgoingIdToReplace}`);
-
-  // 4. Execution wrapped in try/catch
-  try {
-    // --- Find the Old Message ---
-    // We search directly in Mail.outgoingMessages (open windows/drafts)
-    const messages = Mail.outgoingMessages.whose({ id: outgoingIdToReplace })();
-
-    if (messages.length === 0) {
-      return JSON.stringify({
-        success: false,
-        error: `Outgoing message with ID ${outgoingIdToReplace} not found.`,
-      });
-    }
-
-    const oldMsg = messages[0];
-    log(`Found message to replace. Subject: "${oldMsg.subject()}"`);
-
-    // --- Capture State from Old Message ---
-    const oldSubject = oldMsg.subject();
-    const oldSender = oldMsg.sender();
-
-    const getAddresses = (recipients) => {
-      try {
-        return recipients().map((r) => r.address());
-      } catch (e) {
-        log(`Warning: Could not read recipients: ${e.toString()}`);
-        return [];
-      }
-    };
-    const oldTo = getAddresses(oldMsg.toRecipients);
-    const oldCc = getAddresses(oldMsg.ccRecipients);
-    const oldBcc = getAddresses(oldMsg.bccRecipients);
-
-    // --- Create a New Outgoing Message ---
-    const newMsg = Mail.OutgoingMessage({ visible: true });
-    Mail.outgoingMessages.push(newMsg);
-    log("Created new empty outgoing message window.");
-
-    // --- Apply New/Old Properties ---
-    newMsg.subject = args.subject !== undefined ? args.subject : oldSubject;
-
-    const senderToSet = args.sender !== undefined ? args.sender : oldSender;
-    if (senderToSet) {
-      newMsg.sender = senderToSet;
-    }
-
-    const updateRecipients = (collection, newRecipients, fallback) => {
-      const addresses = newRecipients !== undefined ? newRecipients : fallback;
-      if (Array.isArray(addresses)) {
-        addresses.forEach((addr) =>
-          collection.push(Mail.Recipient({ address: addr })),
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const outgoingIdToReplace = args.outgoing_id;
+
+      if (outgoingIdToReplace === undefined || outgoingIdToReplace === null) {
+        throw new ScriptError(
+          "A valid outgoing_id is required.",
+          "MISSING_PARAMETERS",
         );
       }
-    };
-
-    updateRecipients(newMsg.toRecipients, args.to_recipients, oldTo);
-    updateRecipients(newMsg.ccRecipients, args.cc_recipients, oldCc);
-    updateRecipients(newMsg.bccRecipients, args.bcc_recipients, oldBcc);
-    log("Applied properties to new message.");
-
-    // --- Delete the Old Message ---
-    Mail.delete(oldMsg);
-    log(`Deleted old outgoing message with ID ${outgoingIdToReplace}.`);
-
-    // NOTE: We are NOT saving the message here. It exists as an open window.
-
-    Mail.activate();
-    const pid = SystemEvents.processes.byName("Mail").unixId();
+      log(
+        `Attempting to replace outgoing message with ID: ${outgoingIdToReplace}`,
+      );
+
+      // --- Find the Old Message ---
+      // We search directly in Mail.outgoingMessages (open windows/drafts)
+      const oldMsg = findOutgoingMessage(Mail, outgoingIdToReplace);
+      log(`Found message to replace. Subject: "${oldMsg.subject()}"`);
+
+      // --- Capture State from Old Message ---
+      const oldSubject = oldMsg.subject();
+      const oldSender = oldMsg.sender();
+      const oldTo = recipientAddresses(oldMsg.toRecipients, log);
+      const oldCc = recipientAddresses(oldMsg.ccRecipients, log);
+      const oldBcc = recipientAddresses(oldMsg.bccRecipients, log);
+
+      // The files of the attachments are deleted with the old message, so
+      // they are copied to attachment_dir and attached again after the new
+      // content is pasted. Nothing is changed if a file cannot be copied.
+      const keptAttachments = keepAttachments(
+        oldMsg,
+        args.attachment_dir,
+        log,
+      );
+
+      // --- Create a New Outgoing Message ---
+      const newMsg = Mail.OutgoingMessage({ visible: true });
+      Mail.outgoingMessages.push(newMsg);
+      log("Created new empty outgoing message window.");
+
+      // --- Apply New/Old Properties ---
+      newMsg.subject = args.subject !== undefined ? args.subject : oldSubject;
+
+      const senderToSet = args.sender !== undefined ? args.sender : oldSender;
+      if (senderToSet) {
+        newMsg.sender = senderToSet;
+      }
 
-    // 5. CRITICAL: Return `outgoing_id` of the *new* message
-    return JSON.stringify({
-      success: true,
-      data: {
+      const pick = (newRecipients, fallback) =>
+        newRecipients !== undefined ? newRecipients : fallback;
+      addRecipients(Mail, newMsg.toRecipients, pick(args.to_recipients, oldTo));
+      addRecipients(Mail, newMsg.ccRecipients, pick(args.cc_recipients, oldCc));
+      addRecipients(
+        Mail,
+        newMsg.bccRecipients,
+        pick(args.bcc_recipients, oldBcc),
+      );
+      log("Applied properties to new message.");
+
+      // --- Delete the Old Message ---
+      Mail.delete(oldMsg);
+      log(`Deleted old outgoing message with ID ${outgoingIdToReplace}.`);
+
+      // NOTE: We are NOT saving the message here. It exists as an open window.
+      const pid = activateMail(Mail);
+
+      // CRITICAL: Return `outgoing_id` of the *new* message
+      return {
         outgoing_id: newMsg.id(),
         subject: newMsg.subject(),
         pid: pid,
+        kept_attachments: keptAttachments,
         message: "Outgoing message was successfully replaced.",
-      },
-      logs: logs.join("\n"),
-    });
-  } catch (e) {
-    log(`Error during message replacement: ${e.toString()}`);
-    return JSON.stringify({
-      success: false,
-      error: `Failed to replace outgoing message: ${e.toString()}`,
-      logs: logs.join("\n"),
-    });
+      };
+    },
+    "Failed to replace outgoing message",
+  );
+}
+
+// keepAttachments copies the files of the attachments of msg to dir, each to
+// its own numbered subdirectory to keep the file names, and returns the
+// copies.
+function keepAttachments(msg, dir, log) {
+  const attachments = msg.content.attachments();
+  if (attachments.length === 0) {
+    return [];
   }
+  if (!dir) {
+    throw new ScriptError(
+      "attachment_dir is required to keep the attachments.",
+      "MISSING_PARAMETERS",
+    );
+  }
+
+  ObjC.import("Foundation");
+  const fileManager = $.NSFileManager.defaultManager;
+  const kept = [];
+  attachments.forEach((attachment, i) => {
+    let source;
+    try {
+      source = attachment.fileName().toString();
+    } catch (e) {
+      log(`Error reading attachment ${i}: ${e.toString()}`);
+    }
+    const name = source ? source.split("/").pop() : `attachment ${i + 1}`;
+    const target = `${dir}/${i + 1}/${name}`;
+    const copied =
+      source &&
+      fileManager.createDirectoryAtPathWithIntermediateDirectoriesAttributesError(
+        `${dir}/${i + 1}`,
+        true,
+        $(),
+        null,
+      ) &&
+      fileManager.copyItemAtPathToPathError(source, target, null);
+    if (!copied) {
+      throw new ScriptError(
+        `The file of attachment ${name} cannot be read, the outgoing message was not replaced.`,
+        "ATTACHMENT_NOT_FOUND",
+      );
+    }
+    log(`Kept attachment ${name}`);
+    kept.push({ name: name, path: target });
+  });
+  return kept;
 }
@@ -0,0 +1,101 @@
+#!/usr/bin/osascript -l JavaScript
+
+/**
+ * Save attachments of a message to files
+ *
+ * Arguments:
+ *   argv[0] - JSON string containing:
+ *     - account (required)
+ *     - mailboxPath (required) - Array like ["Inbox"] or ["Inbox","GitHub"]
+ *     - message_id (required) - numeric ID
+ *     - attachments (required) - Array of {index, name, path}: the position
+ *       of the attachment in mailAttachments(), its expected name and the
+ *       absolute path of the file to save it to
+ *
+ * The paths are chosen and checked by the server, the script writes the
+ * files as given.
+ */
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageId = args.message_id ? parseInt(args.message_id) : 0;
+      const targets = args.attachments || [];
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      if (!messageId || messageId < 1) {
+        throw new ScriptError(
+          "Message ID is required and must be a positive integer",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      if (!Array.isArray(targets) || targets.length === 0) {
+        throw new ScriptError(
+          "Attachments are required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const targetAccount = findAccount(Mail, accountName);
+      const targetMailbox = findMailbox(targetAccount, mailboxPath);
+
+      const targetMessage = findMessage(
+        targetMailbox,
+        messageId,
+        `Message with ID ${messageId} not found in mailbox "${mailboxPath.join(" > ")}". The message may have been deleted or moved.`,
+      );
+
+      const attachments = targetMessage.mailAttachments();
+      const saved = [];
+      for (const target of targets) {
+        const att =
+          target.index >= 0 && target.index < attachments.length
+            ? attachments[target.index]
+            : null;
+        if (!att || att.name() !== target.name) {
+          throw new ScriptError(
+            `Attachment ${target.index} "${target.name}" not found in message ${messageId}.`,
+            "ATTACHMENT_NOT_FOUND",
+          );
+        }
+
+        let downloaded = true;
+        try {
+          downloaded = att.downloaded();
+        } catch (e) {
+          log("Error reading downloaded status: " + e.toString());
+        }
+        if (!downloaded) {
+          throw new ScriptError(
+            `Attachment "${target.name}" has not been downloaded yet. Open the message in Mail.app to download it.`,
+            "ATTACHMENT_NOT_DOWNLOADED",
+          );
+        }
+
+        Mail.save(att, { in: Path(target.path) });
+        log(`Saved attachment ${target.index} to ${target.path}`);
+        saved.push({ index: target.index, path: target.path });
+      }
+
+      return {
+        id: targetMessage.id(),
+        saved: saved,
+      };
+    },
+    "Failed to save attachments",
+  );
+}
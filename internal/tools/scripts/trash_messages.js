@@ -0,0 +1,131 @@
+// Mail.app has no scripting property for the trash mailbox of an account, so
+// it is resolved by the names used by common providers, in this order.
+const TRASH_MAILBOX_PATHS = [
+  ["Deleted Messages"],
+  ["Trash"],
+  ["Deleted Items"],
+  ["[Gmail]", "Trash"],
+  ["[Google Mail]", "Trash"],
+];
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageIds = args.message_ids || [];
+      const dryRun = args.dry_run === true;
+      const permanent = args.permanent === true;
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+      if (!Array.isArray(messageIds) || messageIds.length === 0) {
+        throw new ScriptError(
+          "At least one message ID is required",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const account = findAccount(Mail, accountName);
+      const source = findMailbox(account, mailboxPath);
+
+      let trash = null;
+      let trashPath = null;
+      for (const path of TRASH_MAILBOX_PATHS) {
+        trash = findMailboxByPath(account, path);
+        if (trash) {
+          trashPath = path;
+          break;
+        }
+      }
+      if (!trash) {
+        throw new ScriptError(
+          `No trash mailbox found in account '${accountName}'. Tried: ${TRASH_MAILBOX_PATHS.map((p) => p.join(" > ")).join(", ")}.`,
+          "MAILBOX_NOT_FOUND",
+        );
+      }
+      log(`Using trash mailbox '${trashPath.join(" > ")}'.`);
+
+      const inTrash =
+        mailboxPath.length === trashPath.length &&
+        mailboxPath.every((name, i) => name === trashPath[i]);
+      if (inTrash && !permanent) {
+        throw new ScriptError(
+          "The messages are already in the trash. Set permanent to delete them permanently.",
+          "INVALID_PARAMETERS",
+        );
+      }
+
+      const messages = findMessagesByIds(source, messageIds, mailboxPath);
+      const details = messages.map((msg) => ({
+        message_id: msg.id(),
+        subject: msg.subject(),
+        sender: msg.sender(),
+        date_received: msg.dateReceived().toISOString(),
+      }));
+
+      let remaining = 0;
+      if (!dryRun) {
+        // Messages outside the trash are moved there first, so that a
+        // permanent deletion never depends on the account's settings for
+        // deleted messages.
+        const trashed = inTrash
+          ? messages.map((msg) => ({ new_message_id: msg.id() }))
+          : transferMessages(Mail, messages, trash, false, log);
+
+        trashed.forEach((t, i) => {
+          if (!permanent) {
+            details[i].new_message_id = t.new_message_id;
+            return;
+          }
+          const found =
+            t.new_message_id !== null
+              ? trash.messages.whose({ id: t.new_message_id })()
+              : [];
+          if (found.length === 0) {
+            log(
+              `Message ${details[i].message_id} was moved to the trash but could not be found there to delete it permanently.`,
+            );
+            details[i].new_message_id = t.new_message_id;
+            remaining++;
+            return;
+          }
+          Mail.delete(found[0]);
+          log(`Permanently deleted message ${details[i].message_id}.`);
+        });
+      }
+
+      let message;
+      if (dryRun) {
+        message = `Dry run: ${details.length} message(s) would be ${permanent ? "permanently deleted" : `moved to '${trashPath.join(" > ")}'`}.`;
+      } else if (permanent) {
+        message = `Permanently deleted ${details.length - remaining} message(s).`;
+        if (remaining > 0) {
+          message += ` ${remaining} message(s) remain in '${trashPath.join(" > ")}'.`;
+        }
+      } else {
+        message = `Moved ${details.length} message(s) to '${trashPath.join(" > ")}'.`;
+      }
+
+      return {
+        messages: details,
+        count: details.length,
+        dry_run: dryRun,
+        permanent: permanent,
+        account: accountName,
+        mailboxPath: mailboxPath,
+        trashMailboxPath: trashPath,
+        message: message,
+      };
+    },
+    "Failed to trash messages",
+  );
+}
@@ -0,0 +1,83 @@
+// Properties that can be updated, in the order they are applied. flagIndex is
+// applied after flaggedStatus, since setting it also flags or unflags the
+// message.
+const UPDATABLE_PROPERTIES = [
+  "readStatus",
+  "junkMailStatus",
+  "backgroundColor",
+  "flaggedStatus",
+  "flagIndex",
+];
+
+function run(argv) {
+  return runScript(
+    argv,
+    (Mail, args, log) => {
+      const accountName = args.account || "";
+      const mailboxPath = args.mailboxPath || [];
+      const messageIds = args.message_ids || [];
+
+      if (!accountName) {
+        throw new ScriptError("Account name is required", "MISSING_PARAMETERS");
+      }
+      if (!Array.isArray(mailboxPath) || mailboxPath.length === 0) {
+        throw new ScriptError(
+          "Mailbox path is required and must be a non-empty array",
+          "MISSING_PARAMETERS",
+        );
+      }
+      if (!Array.isArray(messageIds) || messageIds.length === 0) {
+        throw new ScriptError(
+          "At least one message ID is required",
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const updates = {};
+      UPDATABLE_PROPERTIES.forEach((name) => {
+        if (args[name] !== undefined && args[name] !== null) {
+          updates[name] = args[name];
+        }
+      });
+      if (Object.keys(updates).length === 0) {
+        throw new ScriptError(
+          `At least one property to update is required (${UPDATABLE_PROPERTIES.join(", ")})`,
+          "MISSING_PARAMETERS",
+        );
+      }
+
+      const account = findAccount(Mail, accountName);
+      const mailbox = findMailbox(account, mailboxPath);
+      const messages = findMessagesByIds(mailbox, messageIds, mailboxPath);
+
+      const changedIds = [];
+      const unchangedIds = [];
+      messages.forEach((msg) => {
+        const id = msg.id();
+        let changed = false;
+        UPDATABLE_PROPERTIES.forEach((name) => {
+          if (updates[name] === undefined) return;
+          // The flag colour implies the flagged status
+          if (name === "flaggedStatus" && updates.flagIndex !== undefined) return;
+          if (msg[name]() !== updates[name]) {
+            msg[name] = updates[name];
+            changed = true;
+            log(`Set ${name} of message ${id} to ${updates[name]}.`);
+          }
+        });
+        (changed ? changedIds : unchangedIds).push(id);
+      });
+
+      return {
+        changed_ids: changedIds,
+        unchanged_ids: unchangedIds,
+        count: changedIds.length,
+        updates: updates,
+        account: accountName,
+        mailboxPath: mailboxPath,
+        message: `Updated ${changedIds.length} of ${messages.length} message(s).`,
+      };
+    },
+    "Failed to update messages",
+  );
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+// allScripts lists the script of every tool together with its embedded source.
+var allScripts = []struct {
+	script jxa.Script
+	source string
+}{
+	{listAccountsScript, listAccountsSource},
+	{listMailboxesScript, listMailboxesSource},
+	{getMessageContentScript, getMessageContentSource},
+	{getMessageSourceScript, getMessageSourceSource},
+	{getSelectedMessagesScript, getSelectedMessagesSource},
+	{findMessagesScript, findMessagesSource},
+	{listDraftsScript, listDraftsSource},
+	{deleteDraftScript, deleteDraftSource},
+	{createReplyScript, createReplySource},
+	{replaceReplyScript, replaceReplySource},
+	{createOutgoingMessageScript, createOutgoingMessageSource},
+	{listOutgoingMessagesScript, listOutgoingMessagesSource},
+	{replaceOutgoingMessageScript, replaceOutgoingMessageSource},
+	{deleteOutgoingMessageScript, deleteOutgoingMessageSource},
+	{moveMessagesScript, moveMessagesSource},
+	{copyMessagesScript, copyMessagesSource},
+	{archiveMessagesScript, archiveMessagesSource},
+	{updateMessagesScript, updateMessagesSource},
+	{trashMessagesScript, trashMessagesSource},
+	{saveAttachmentsScript, saveAttachmentsSource},
+	{addAttachmentsScript, addAttachmentsSource},
+}
+
+func TestScripts_AllCovered(t *testing.T) {
+	files, err := filepath.Glob("scripts/*.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range allScripts {
+		names = append(names, s.script.Name)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".js")
+		if !slices.Contains(names, name) {
+			t.Errorf("script %s is not listed in allScripts", file)
+		}
+	}
+}
+
+func TestScripts_IncludePrelude(t *testing.T) {
+	for _, s := range allScripts {
+		t.Run(s.script.Name, func(t *testing.T) {
+			if !strings.HasPrefix(s.script.Source, jxa.Prelude) {
+				t.Error("script source does not start with the prelude, use jxa.NewScript")
+			}
+			file, err := os.ReadFile(filepath.Join("scripts", s.script.Name+".js"))
+			if err != nil {
+				t.Fatalf("script name does not match a file: %v", err)
+			}
+			if string(file) != s.source {
+				t.Errorf("source of %s does not match scripts/%s.js", s.script.Name, s.script.Name)
+			}
+		})
+	}
+}
+
+// TestScripts_UseSharedHelpers checks that scripts do not duplicate what the
+// prelude provides, so that fixes land in one place.
+func TestScripts_UseSharedHelpers(t *testing.T) {
+	var redefinitions []*regexp.Regexp
+	for _, m := range regexp.MustCompile(`(?m)^(?:function|class) (\w+)`).FindAllStringSubmatch(jxa.Prelude, -1) {
+		redefinitions = append(redefinitions, regexp.MustCompile(`\b(?:function|class|const|let|var) `+m[1]+`\b`))
+	}
+	duplicates := map[string]string{
+		"Mail.running()":      "running check, use runScript",
+		`Application("Mail")`: "Mail.app access, use the Mail passed by runScript",
+		"JSON.parse(argv":     "argument parsing, use runScript",
+		"JSON.stringify({":    "result envelope, return data from the runScript handler",
+		"logs.join":           "log collection, use the log passed by runScript",
+		".container()":        "mailbox path computation, use getMailboxPath",
+		"Mail.accounts[":      "account lookup, use findAccount",
+		`processes.byName("`:  "PID lookup, use activateMail",
+	}
+
+	for _, s := range allScripts {
+		t.Run(s.script.Name, func(t *testing.T) {
+			if !strings.Contains(s.source, "function run(argv) {\n  return runScript(") {
+				t.Error("run does not delegate to runScript")
+			}
+			for _, re := range redefinitions {
+				if loc := re.FindString(s.source); loc != "" {
+					t.Errorf("script redefines prelude helper: %q", loc)
+				}
+			}
+			for pattern, reason := range duplicates {
+				if strings.Contains(s.source, pattern) {
+					t.Errorf("script contains %q: duplicated %s", pattern, reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/index"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// snippetLength is the length of the body excerpts returned by search_index.
+const snippetLength = 160
+
+// SearchIndexInput defines input parameters for search_index tool
+type SearchIndexInput struct {
+	Query       string   `json:"query" jsonschema:"Words to search for in subject, sender, recipients and body. Messages matching any word are returned, best match first." long:"query" description:"Words to search for in subject, sender, recipients and body"`
+	Account     string   `json:"account,omitempty" jsonschema:"Name of the email account. If omitted, all indexed accounts are searched." long:"account" description:"Name of the email account. If omitted, all indexed accounts are searched."`
+	MailboxPath []string `json:"mailboxPath,omitempty" jsonschema:"Mailbox path array (e.g., ['Inbox'] or ['Inbox', 'GitHub']). If omitted, all mailboxes are searched. Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Mailbox path (can be specified multiple times for nested mailboxes). If omitted, all mailboxes are searched."`
+	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum number of messages to return (1-100, default: 20)" long:"limit" description:"Maximum number of messages to return (1-100, default: 20)"`
+}
+
+// RegisterSearchIndex registers the search_index tool with the MCP server
+func RegisterSearchIndex(srv *mcp.Server, idx *index.Index) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "search_index",
+			Description:  "Full-text search of the local message index, ranked by relevance. Much faster than find_messages with a body filter, but only finds messages indexed by the last sync. Use the returned account, mailbox_path and id with get_message_content to read a message.",
+			InputSchema:  GenerateSchema[SearchIndexInput](),
+			OutputSchema: GenerateSchema[SearchIndexOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Search Index",
+				ReadOnlyHint:    true,
+				IdempotentHint:  true,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(false),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input SearchIndexInput) (*mcp.CallToolResult, *SearchIndexOutput, error) {
+			return HandleSearchIndex(ctx, idx, request, input)
+		},
+	)
+}
+
+func HandleSearchIndex(ctx context.Context, idx *index.Index, request *mcp.CallToolRequest, input SearchIndexInput) (*mcp.CallToolResult, *SearchIndexOutput, error) {
+	if strings.TrimSpace(input.Query) == "" {
+		return nil, nil, missingParameters("query is required")
+	}
+
+	// Apply default limit
+	if input.Limit == 0 {
+		input.Limit = 20
+	}
+	if input.Limit < 1 || input.Limit > 100 {
+		return nil, nil, invalidParameters("limit must be between 1 and 100")
+	}
+
+	syncedAt := idx.SyncedAt()
+	if syncedAt.IsZero() {
+		return nil, nil, jxa.NewError(jxa.ErrorCodeIndexNotReady, "the index has not been synced yet, retry later or run 'mail-mcp index rebuild'")
+	}
+
+	hits, total := idx.Search(input.Query, index.SearchOptions{
+		Account:     input.Account,
+		MailboxPath: input.MailboxPath,
+		Limit:       input.Limit,
+	})
+	result := &SearchIndexOutput{
+		Messages:        []IndexedMessage{},
+		TotalMatches:    total,
+		Limit:           input.Limit,
+		IndexedMessages: idx.Count(),
+		SyncedAt:        syncedAt.UTC().Format(time.RFC3339),
+	}
+	for _, h := range hits {
+		result.Messages = append(result.Messages, IndexedMessage{
+			ID:           h.Message.ID,
+			Subject:      h.Message.Subject,
+			Sender:       h.Message.Sender,
+			DateReceived: h.Message.DateReceived.UTC().Format(time.RFC3339),
+			MailboxPath:  h.Message.MailboxPath,
+			Account:      h.Message.Account,
+			Score:        h.Score,
+			Snippet:      index.Snippet(h.Message.Body, input.Query, snippetLength),
+		})
+	}
+	result.Count = len(result.Messages)
+	return nil, result, nil
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/index"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestHandleSearchIndex(t *testing.T) {
+	idx, err := index.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	// An index that was never synced is not ready
+	_, _, err = HandleSearchIndex(context.Background(), idx, nil, SearchIndexInput{Query: "report"})
+	if !jxa.HasCode(err, jxa.ErrorCodeIndexNotReady) {
+		t.Errorf("HandleSearchIndex() before sync error = %v, want %s", err, jxa.ErrorCodeIndexNotReady)
+	}
+
+	idx.Add(index.Message{
+		Account: "Work", MailboxPath: []string{"INBOX"}, ID: 7, Subject: "Quarterly report",
+		Body: "The report is attached.", DateReceived: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+	})
+	idx.SetSyncedAt(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC))
+
+	_, result, err := HandleSearchIndex(context.Background(), idx, nil, SearchIndexInput{Query: "report"})
+	if err != nil {
+		t.Fatalf("HandleSearchIndex() error = %v", err)
+	}
+	if result.Count != 1 || result.TotalMatches != 1 || result.Limit != 20 || result.IndexedMessages != 1 || result.SyncedAt != "2025-03-02T00:00:00Z" {
+		t.Errorf("HandleSearchIndex() = %+v", result)
+	}
+	m := result.Messages[0]
+	if m.ID != 7 || m.DateReceived != "2025-03-01T09:00:00Z" || m.Snippet != "The report is attached." || m.Score <= 0 {
+		t.Errorf("HandleSearchIndex() message = %+v", m)
+	}
+
+	tests := []struct {
+		name  string
+		input SearchIndexInput
+		code  string
+	}{
+		{"missing query", SearchIndexInput{Query: "  "}, jxa.ErrorCodeMissingParameters},
+		{"limit too large", SearchIndexInput{Query: "report", Limit: 101}, jxa.ErrorCodeInvalidParameters},
+		{"negative limit", SearchIndexInput{Query: "report", Limit: -1}, jxa.ErrorCodeInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := HandleSearchIndex(context.Background(), idx, nil, tt.input); !jxa.HasCode(err, tt.code) {
+				t.Errorf("HandleSearchIndex() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
@@ -1,25 +1,49 @@
 package tools
 
 import (
+	"time"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// RegisterAll registers all available tools with the MCP server.
-func RegisterAll(srv *mcp.Server) {
+// Config is the configuration of the tools registered by RegisterAll.
+type Config struct {
+	// TimeZone is the time zone of date filters without an explicit one,
+	// the local time zone if nil.
+	TimeZone *time.Location
+	// AttachmentDirs are the directories outgoing messages may attach files
+	// from. Without any, attaching files is refused.
+	AttachmentDirs []string
+}
+
+// RegisterAll registers all available tools with the MCP server. All tools run
+// their JXA scripts through the given executor.
+func RegisterAll(srv *mcp.Server, executor jxa.Executor, cfg Config) {
 	// Informational tools
-	RegisterListAccounts(srv)
-	RegisterListMailboxes(srv)
-	RegisterGetMessageContent(srv)
-	RegisterFindMessages(srv)
-	RegisterGetSelectedMessages(srv)
-	RegisterListOutgoingMessages(srv)
-	RegisterListDrafts(srv)
+	RegisterListAccounts(srv, executor)
+	RegisterListMailboxes(srv, executor)
+	RegisterGetMessageContent(srv, executor)
+	RegisterGetThread(srv, executor)
+	RegisterGetMessageSource(srv, executor)
+	RegisterGetAttachmentText(srv, executor)
+	RegisterFindMessages(srv, executor, cfg.TimeZone)
+	RegisterGetSelectedMessages(srv, executor)
+	RegisterListOutgoingMessages(srv, executor)
+	RegisterListDrafts(srv, executor)
 
 	// Message creation and manipulation tools
-	RegisterCreateReply(srv)
-	RegisterReplaceReply(srv)
-	RegisterCreateOutgoingMessage(srv)
-	RegisterReplaceOutgoingMessage(srv)
-	RegisterDeleteOutgoingMessage(srv)
-	RegisterDeleteDraft(srv)
+	RegisterCreateReply(srv, executor)
+	RegisterReplaceReply(srv, executor)
+	RegisterCreateOutgoingMessage(srv, executor, cfg.AttachmentDirs)
+	RegisterReplaceOutgoingMessage(srv, executor, cfg.AttachmentDirs)
+	RegisterDeleteOutgoingMessage(srv, executor)
+	RegisterDeleteDraft(srv, executor)
+
+	// Message filing tools
+	RegisterMoveMessages(srv, executor)
+	RegisterCopyMessages(srv, executor)
+	RegisterArchiveMessages(srv, executor)
+	RegisterUpdateMessages(srv, executor)
+	RegisterTrashMessages(srv, executor)
 }
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connect registers all tools backed by the given executor on a fresh server
+// and returns a client session connected through in-memory transports.
+func connect(t *testing.T, executor jxa.Executor) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	srv := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	RegisterAll(srv, executor, Config{})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := srv.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server Connect() error = %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+// unmarshalArg decodes the JSON argument passed to a recorded script call.
+func unmarshalArg(t *testing.T, call jxa.Call) map[string]any {
+	t.Helper()
+	if len(call.Args) != 1 {
+		t.Fatalf("script %q called with %d args, want 1", call.Script, len(call.Args))
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(call.Args[0]), &m); err != nil {
+		t.Fatalf("script %q argument is not JSON: %v", call.Script, err)
+	}
+	return m
+}
+
+// errorOutput decodes the error of a failed tool call from its last text
+// content. Error results have no structured content, as it would not match
+// the output schema of the tool.
+func errorOutput(t *testing.T, res *mcp.CallToolResult) *jxa.Error {
+	t.Helper()
+	if res.StructuredContent != nil {
+		t.Errorf("error result has structured content %v, want none", res.StructuredContent)
+	}
+	if len(res.Content) < 2 {
+		t.Fatalf("error result has %d contents, want the message and the error", len(res.Content))
+	}
+	text := res.Content[len(res.Content)-1].(*mcp.TextContent).Text
+	var out ErrorOutput
+	if err := json.Unmarshal([]byte(text), &out); err != nil || out.Error == nil {
+		t.Fatalf("text content %s is not an ErrorOutput: %v", text, err)
+	}
+	return out.Error
+}
+
+func TestRegisterAll_CallToolThroughExecutor(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("list_accounts", jxa.Result{
+		Success: true,
+		Data: map[string]any{
+			"accounts": []any{map[string]any{
+				"name":           "Work",
+				"enabled":        true,
+				"emailAddresses": []any{"jane@example.com"},
+				"mailboxCount":   3,
+			}},
+			"count": 1,
+		},
+	})
+	session := connect(t, fake)
+
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "list_accounts",
+		Arguments: map[string]any{"enabled": true},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("CallTool() returned tool error: %v", res.Content)
+	}
+
+	calls := fake.CallsTo("list_accounts")
+	if len(calls) != 1 {
+		t.Fatalf("list_accounts called %d times, want 1", len(calls))
+	}
+	if got := unmarshalArg(t, calls[0])["enabled"]; got != true {
+		t.Errorf("script argument enabled = %v, want true", got)
+	}
+}
+
+func TestRegisterAll_ScriptErrorIsToolError(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("list_mailboxes", jxa.Result{
+		Success:   false,
+		Error:     "Mail.app is not running. Please start Mail.app and try again.",
+		ErrorCode: jxa.ErrorCodeMailAppNotRunning,
+	})
+	session := connect(t, fake)
+
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "list_mailboxes",
+		Arguments: map[string]any{"account": "Work"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !res.IsError {
+		t.Errorf("CallTool() IsError = false, want true")
+	}
+	got := errorOutput(t, res)
+	if got.Code != jxa.ErrorCodeMailAppNotRunning || !got.Retryable {
+		t.Errorf("structured error = %+v, want retryable %s", got, jxa.ErrorCodeMailAppNotRunning)
+	}
+}
+
+func TestRegisterAll_ValidationErrorIsToolError(t *testing.T) {
+	fake := jxa.NewFakeExecutor()
+	session := connect(t, fake)
+
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "find_messages",
+		Arguments: map[string]any{"account": "Work", "mailboxPath": []string{"INBOX"}},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if !res.IsError {
+		t.Fatalf("CallTool() IsError = false, want true")
+	}
+	got := errorOutput(t, res)
+	if got.Code != jxa.ErrorCodeMissingParameters || got.Retryable {
+		t.Errorf("structured error = %+v, want non-retryable %s", got, jxa.ErrorCodeMissingParameters)
+	}
+	if n := len(fake.Calls()); n != 0 {
+		t.Errorf("executor called %d times, want 0", n)
+	}
+}
+
+func TestRegisterAll_PublishesOutputSchemas(t *testing.T) {
+	session := connect(t, jxa.NewFakeExecutor())
+
+	res, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	for _, tool := range res.Tools {
+		if tool.OutputSchema == nil {
+			t.Errorf("tool %s has no output schema", tool.Name)
+		}
+	}
+}
+
+func TestRegisterAll_StructuredContent(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("delete_outgoing_message", jxa.Result{
+		Success: true,
+		Data: map[string]any{
+			"deleted_id": 12,
+			"subject":    "Hello",
+			"message":    "Outgoing message deleted successfully.",
+		},
+	})
+	session := connect(t, fake)
+
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "delete_outgoing_message",
+		Arguments: map[string]any{"outgoing_id": 12},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("CallTool() returned tool error: %v", res.Content)
+	}
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got DeleteOutgoingMessageOutput
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("structured content %s does not decode: %v", data, err)
+	}
+	if got.DeletedID != 12 || got.Subject != "Hello" {
+		t.Errorf("structured content = %+v, want deleted_id 12 and subject Hello", got)
+	}
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/trash_messages.js
+var trashMessagesSource string
+
+var trashMessagesScript = jxa.NewScript("trash_messages", trashMessagesSource)
+
+// TrashMessagesInput defines input parameters for trash_messages tool
+type TrashMessagesInput struct {
+	Account     string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath []string `json:"mailboxPath" jsonschema:"Path of the mailbox containing the messages (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path of the mailbox containing the messages. Can be specified multiple times for nested paths."`
+	MessageIDs  []int    `json:"message_ids" jsonschema:"IDs of the messages to move to the trash" long:"message-id" description:"ID of a message to move to the trash. Can be specified multiple times."`
+	DryRun      bool     `json:"dry_run,omitempty" jsonschema:"Only return the subject, sender and date of the messages that would be affected, without changing anything" long:"dry-run" description:"Only show the messages that would be affected"`
+	Permanent   bool     `json:"permanent,omitempty" jsonschema:"Delete the messages permanently instead of moving them to the trash. This cannot be undone." long:"permanent" description:"Delete the messages permanently. This cannot be undone."`
+}
+
+// RegisterTrashMessages registers the trash_messages tool with the MCP server
+func RegisterTrashMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "trash_messages",
+			Description:  "Moves messages to the trash mailbox of their account. Use dry_run to see the subject, sender and date of every affected message first. With permanent set, the messages are deleted permanently, which cannot be undone.",
+			InputSchema:  GenerateSchema[TrashMessagesInput](),
+			OutputSchema: GenerateSchema[TrashMessagesOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Trash Messages",
+				ReadOnlyHint:    false,
+				IdempotentHint:  false,
+				DestructiveHint: new(true),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input TrashMessagesInput) (*mcp.CallToolResult, *TrashMessagesOutput, error) {
+			return HandleTrashMessages(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleTrashMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input TrashMessagesInput) (*mcp.CallToolResult, *TrashMessagesOutput, error) {
+	if err := validateMessageIDs(input.MailboxPath, input.MessageIDs); err != nil {
+		return nil, nil, err
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, trashMessagesScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute trash_messages: %w", err)
+	}
+
+	result, err := decodeResult[TrashMessagesOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
+}
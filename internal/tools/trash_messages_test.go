@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestHandleTrashMessages_Flags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input TrashMessagesInput
+		want  map[string]any
+	}{
+		{
+			name:  "trash",
+			input: TrashMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}},
+			want:  map[string]any{},
+		},
+		{
+			name:  "dry run",
+			input: TrashMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, DryRun: true},
+			want:  map[string]any{"dry_run": true},
+		},
+		{
+			name:  "permanent",
+			input: TrashMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, Permanent: true},
+			want:  map[string]any{"permanent": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := jxa.NewFakeExecutor().On("trash_messages", jxa.Result{
+				Success: true,
+				Data: map[string]any{
+					"messages":         []any{},
+					"count":            0,
+					"dry_run":          tt.input.DryRun,
+					"permanent":        tt.input.Permanent,
+					"account":          "Work",
+					"mailboxPath":      []any{"Inbox"},
+					"trashMailboxPath": []any{"Trash"},
+					"message":          "",
+				},
+			})
+			if _, _, err := HandleTrashMessages(context.Background(), fake, nil, tt.input); err != nil {
+				t.Fatalf("HandleTrashMessages() error = %v", err)
+			}
+
+			// Unset flags are omitted, so that the script defaults to a
+			// non-permanent move to the trash.
+			args := unmarshalArg(t, fake.CallsTo("trash_messages")[0])
+			for _, name := range []string{"dry_run", "permanent"} {
+				if args[name] != tt.want[name] {
+					t.Errorf("%s = %v, want %v", name, args[name], tt.want[name])
+				}
+			}
+		})
+	}
+}
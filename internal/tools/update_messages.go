@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+//go:embed scripts/update_messages.js
+var updateMessagesSource string
+
+var updateMessagesScript = jxa.NewScript("update_messages", updateMessagesSource)
+
+// BackgroundColors are the message background colours that can be set in
+// Mail.app. Mail.app reports "other" for colours that cannot be set by script.
+var BackgroundColors = []string{"none", "red", "orange", "yellow", "green", "blue", "purple", "gray"}
+
+// UpdateMessagesInput defines input parameters for update_messages tool
+type UpdateMessagesInput struct {
+	Account         string   `json:"account" jsonschema:"Name of the email account" long:"account" description:"Name of the email account"`
+	MailboxPath     []string `json:"mailboxPath" jsonschema:"Path of the mailbox containing the messages (e.g. ['Inbox'] or ['Inbox','GitHub']). Note: Mailbox names are case-sensitive." long:"mailbox-path" description:"Path of the mailbox containing the messages. Can be specified multiple times for nested paths."`
+	MessageIDs      []int    `json:"message_ids" jsonschema:"IDs of the messages to update" long:"message-id" description:"ID of a message to update. Can be specified multiple times."`
+	ReadStatus      *bool    `json:"readStatus,omitempty" jsonschema:"Mark the messages as read (true) or unread (false)" long:"read-status" description:"Mark the messages as read"`
+	FlaggedStatus   *bool    `json:"flaggedStatus,omitempty" jsonschema:"Flag (true) or unflag (false) the messages" long:"flagged-status" description:"Flag the messages"`
+	FlagIndex       *int     `json:"flagIndex,omitempty" jsonschema:"Flag colour: 0 red, 1 orange, 2 yellow, 3 green, 4 blue, 5 purple, 6 gray, or -1 to unflag. Setting a colour flags the messages." long:"flag-index" description:"Flag colour (0 red, 1 orange, 2 yellow, 3 green, 4 blue, 5 purple, 6 gray, -1 to unflag)"`
+	JunkMailStatus  *bool    `json:"junkMailStatus,omitempty" jsonschema:"Mark the messages as junk (true) or not junk (false)" long:"junk-mail-status" description:"Mark the messages as junk"`
+	BackgroundColor string   `json:"backgroundColor,omitempty" jsonschema:"Background colour of the messages in the message list: none, red, orange, yellow, green, blue, purple or gray" long:"background-color" description:"Background colour of the messages (none, red, orange, yellow, green, blue, purple, gray)"`
+}
+
+// RegisterUpdateMessages registers the update_messages tool with the MCP server
+func RegisterUpdateMessages(srv *mcp.Server, executor jxa.Executor) {
+	addTool(srv,
+		&mcp.Tool{
+			Name:         "update_messages",
+			Description:  "Sets the read, flagged, flag colour, junk or background colour status of messages. Only the given properties are changed. Returns the IDs of the messages that changed and of those that already had the requested status.",
+			InputSchema:  GenerateSchema[UpdateMessagesInput](),
+			OutputSchema: GenerateSchema[UpdateMessagesOutput](),
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "Update Messages",
+				ReadOnlyHint:    false,
+				IdempotentHint:  true,
+				DestructiveHint: new(false),
+				OpenWorldHint:   new(true),
+			},
+		},
+		func(ctx context.Context, request *mcp.CallToolRequest, input UpdateMessagesInput) (*mcp.CallToolResult, *UpdateMessagesOutput, error) {
+			return HandleUpdateMessages(ctx, executor, request, input)
+		},
+	)
+}
+
+func HandleUpdateMessages(ctx context.Context, executor jxa.Executor, request *mcp.CallToolRequest, input UpdateMessagesInput) (*mcp.CallToolResult, *UpdateMessagesOutput, error) {
+	if err := validateMessageIDs(input.MailboxPath, input.MessageIDs); err != nil {
+		return nil, nil, err
+	}
+
+	if input.ReadStatus == nil &&
+		input.FlaggedStatus == nil &&
+		input.FlagIndex == nil &&
+		input.JunkMailStatus == nil &&
+		input.BackgroundColor == "" {
+		return nil, nil, missingParameters("at least one property to update is required (readStatus, flaggedStatus, flagIndex, junkMailStatus, or backgroundColor)")
+	}
+
+	if input.FlagIndex != nil {
+		if *input.FlagIndex < -1 || *input.FlagIndex > 6 {
+			return nil, nil, invalidParameters("flagIndex must be between -1 and 6, got %d", *input.FlagIndex)
+		}
+		if input.FlaggedStatus != nil && *input.FlaggedStatus != (*input.FlagIndex >= 0) {
+			return nil, nil, invalidParameters("flaggedStatus %t contradicts flagIndex %d", *input.FlaggedStatus, *input.FlagIndex)
+		}
+	}
+
+	if input.BackgroundColor != "" && !slices.Contains(BackgroundColors, input.BackgroundColor) {
+		return nil, nil, invalidParameters("invalid backgroundColor: %s (valid: %v)", input.BackgroundColor, BackgroundColors)
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input for JXA: %w", err)
+	}
+
+	data, err := executor.Execute(ctx, updateMessagesScript, string(inputJSON))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute update_messages: %w", err)
+	}
+
+	result, err := decodeResult[UpdateMessagesOutput](data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, result, nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dastrobu/mail-mcp/internal/jxa"
+)
+
+func TestHandleUpdateMessages_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   UpdateMessagesInput
+		wantErr string
+	}{
+		{
+			name:    "no message IDs",
+			input:   UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, ReadStatus: new(true)},
+			wantErr: "message_ids must contain at least one message ID",
+		},
+		{
+			name:    "no property",
+			input:   UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}},
+			wantErr: "at least one property to update is required",
+		},
+		{
+			name:    "flag index out of range",
+			input:   UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, FlagIndex: new(7)},
+			wantErr: "flagIndex must be between -1 and 6, got 7",
+		},
+		{
+			name:    "unflag with colour",
+			input:   UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, FlaggedStatus: new(false), FlagIndex: new(2)},
+			wantErr: "flaggedStatus false contradicts flagIndex 2",
+		},
+		{
+			name:    "unknown background colour",
+			input:   UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, BackgroundColor: "pink"},
+			wantErr: "invalid backgroundColor: pink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := jxa.NewFakeExecutor()
+			_, _, err := HandleUpdateMessages(context.Background(), fake, nil, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("HandleUpdateMessages() error = %v, want %q", err, tt.wantErr)
+			}
+			if n := len(fake.Calls()); n != 0 {
+				t.Errorf("executor called %d times, want 0", n)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateMessages_OmitsUnsetProperties(t *testing.T) {
+	fake := jxa.NewFakeExecutor().On("update_messages", jxa.Result{
+		Success: true,
+		Data: map[string]any{
+			"changed_ids":   []any{1},
+			"unchanged_ids": []any{},
+			"count":         1,
+			"updates":       map[string]any{"flagIndex": -1},
+			"account":       "Work",
+			"mailboxPath":   []any{"Inbox"},
+			"message":       "Updated 1 of 1 message(s).",
+		},
+	})
+
+	input := UpdateMessagesInput{Account: "Work", MailboxPath: []string{"Inbox"}, MessageIDs: []int{1}, FlagIndex: new(-1)}
+	_, got, err := HandleUpdateMessages(context.Background(), fake, nil, input)
+	if err != nil {
+		t.Fatalf("HandleUpdateMessages() error = %v", err)
+	}
+	if got.Updates.FlagIndex == nil || *got.Updates.FlagIndex != -1 {
+		t.Errorf("updates.flagIndex = %v, want -1", got.Updates.FlagIndex)
+	}
+
+	args := unmarshalArg(t, fake.CallsTo("update_messages")[0])
+	for _, name := range []string{"readStatus", "flaggedStatus", "junkMailStatus", "backgroundColor"} {
+		if v, ok := args[name]; ok {
+			t.Errorf("%s = %v passed to script, want it omitted", name, v)
+		}
+	}
+}
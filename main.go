@@ -8,11 +8,18 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/dastrobu/mail-mcp/internal/completion"
+	"github.com/dastrobu/mail-mcp/internal/index"
+	"github.com/dastrobu/mail-mcp/internal/jxa"
 	"github.com/dastrobu/mail-mcp/internal/launchd"
 	applog "github.com/dastrobu/mail-mcp/internal/log"
+	"github.com/dastrobu/mail-mcp/internal/mailsim"
 	"github.com/dastrobu/mail-mcp/internal/opts"
+	"github.com/dastrobu/mail-mcp/internal/opts/typed_flags"
 
 	"github.com/dastrobu/mail-mcp/internal/tools"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -47,6 +54,12 @@ func main() {
 	opts.GlobalOpts.Launchd.Restart.Handler = func() error {
 		return restartLaunchd()
 	}
+	opts.GlobalOpts.Index.Rebuild.Handler = func() error {
+		return rebuildIndex(opts.GlobalOpts.Index.Rebuild.Dir)
+	}
+	opts.GlobalOpts.Index.Status.Handler = func() error {
+		return indexStatus(opts.GlobalOpts.Index.Status.Dir)
+	}
 
 	registerToolHandlers()
 
@@ -116,8 +129,10 @@ func debugMiddleware(debug bool) func(mcp.MethodHandler) mcp.MethodHandler {
 	}
 }
 
-// createServer creates and configures a new MCP server instance
-func createServer(debug bool) *mcp.Server {
+// createServer creates and configures a new MCP server instance. The
+// search_index tool is only registered if an index is given, the
+// save_attachments tool only if an export directory is given.
+func createServer(debug bool, executor jxa.Executor, cfg tools.Config, idx *index.Index, exportDir string) *mcp.Server {
 	srv := mcp.NewServer(&mcp.Implementation{
 		Name:    serverName,
 		Version: version,
@@ -129,7 +144,13 @@ func createServer(debug bool) *mcp.Server {
 	}
 
 	// Register all tools
-	tools.RegisterAll(srv)
+	tools.RegisterAll(srv, executor, cfg)
+	if idx != nil {
+		tools.RegisterSearchIndex(srv, idx)
+	}
+	if exportDir != "" {
+		tools.RegisterSaveAttachments(srv, executor, exportDir)
+	}
 
 	return srv
 }
@@ -151,7 +172,47 @@ func run(options *opts.RunCmd) error {
 	// Log to stderr (stdout is used for MCP communication in stdio mode)
 	log.Printf("Apple Mail MCP Server v%s (commit: %s, built: %s) initialized\n", version, commit, date)
 
-	srv := createServer(options.Debug)
+	var cfg tools.Config
+	if options.TimeZone != "" {
+		loc, err := time.LoadLocation(options.TimeZone)
+		if err != nil {
+			return fmt.Errorf("invalid time zone: %w", err)
+		}
+		cfg.TimeZone = loc
+	}
+
+	executor, err := createExecutor(options)
+	if err != nil {
+		return err
+	}
+	if closer, ok := executor.(io.Closer); ok {
+		defer func() { _ = closer.Close() }()
+	}
+
+	var idx *index.Index
+	if options.Index {
+		if options.IndexInterval <= 0 {
+			return fmt.Errorf("invalid index interval: %v", options.IndexInterval)
+		}
+		idx, err = openIndex(options.IndexDir)
+		if err != nil {
+			return err
+		}
+		if err := idx.Lock(); err != nil {
+			return fmt.Errorf("failed to use search index: %w, stop the other server or index rebuild first", err)
+		}
+		defer func() { _ = idx.Close() }()
+		log.Printf("Using search index %s\n", idx.Path())
+		go syncIndexLoop(ctx, executor, idx, options.IndexInterval)
+	}
+	if options.ExportDir != "" {
+		log.Printf("Saving attachments in %s\n", options.ExportDir)
+	}
+	if len(options.AttachmentDirs) > 0 {
+		log.Printf("Attaching files from %s\n", strings.Join(options.AttachmentDirs, ", "))
+		cfg.AttachmentDirs = options.AttachmentDirs
+	}
+	srv := createServer(options.Debug, executor, cfg, idx, options.ExportDir)
 
 	// Run the server with the selected transport
 	switch transport {
@@ -195,6 +256,116 @@ func run(options *opts.RunCmd) error {
 	return nil
 }
 
+// createExecutor creates the executor for the selected backend
+func createExecutor(options *opts.RunCmd) (jxa.Executor, error) {
+	switch options.Backend {
+	case typed_flags.BackendJXA:
+		if options.JXAWorker {
+			log.Println("Using persistent JXA worker")
+			return jxa.NewWorkerExecutor(jxa.WorkerOptions{Timeout: options.JXATimeout}), nil
+		}
+		return jxa.OsascriptExecutor{}, nil
+	case typed_flags.BackendSim:
+		fixture := mailsim.Demo()
+		if options.SimFixture != "" {
+			var err error
+			fixture, err = mailsim.LoadFixture(options.SimFixture)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sim, err := mailsim.New(fixture)
+		if err != nil {
+			return nil, fmt.Errorf("invalid simulator fixture: %w", err)
+		}
+		log.Println("Using simulated Mail.app backend")
+		return sim, nil
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", options.Backend)
+	}
+}
+
+// openIndex opens the search index in the directory, or in the default
+// directory if dir is empty.
+func openIndex(dir string) (*index.Index, error) {
+	if dir == "" {
+		var err error
+		if dir, err = index.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return index.Open(dir)
+}
+
+// syncIndexLoop syncs the search index now and then in the given interval,
+// until the context is done. Failures are logged and retried in the next
+// interval, e.g. when Mail.app is not running.
+func syncIndexLoop(ctx context.Context, executor jxa.Executor, idx *index.Index, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		result, err := tools.SyncIndex(ctx, executor, idx)
+		if err != nil {
+			log.Printf("Failed to sync search index: %v\n", err)
+		} else {
+			log.Printf("Synced search index: %d mailboxes, %d messages added, %d removed in %v\n",
+				result.Mailboxes, result.Added, result.Removed, time.Since(start).Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rebuildIndex discards the search index and indexes all mailboxes again
+func rebuildIndex(dir string) error {
+	idx, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+	// A running server would replace the rebuilt index with its own
+	if err := idx.Lock(); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w, stop the server first, e.g. with mail-mcp launchd remove", err)
+	}
+	defer func() { _ = idx.Close() }()
+	idx.Reset()
+
+	fmt.Printf("Rebuilding search index %s\n", idx.Path())
+	result, err := tools.SyncIndex(context.Background(), jxa.OsascriptExecutor{}, idx)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	fmt.Printf("✅ Indexed %d messages in %d mailboxes\n", result.Added, result.Mailboxes)
+	return nil
+}
+
+// indexStatus prints the content of the search index
+func indexStatus(dir string) error {
+	idx, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	status := idx.Status()
+	fmt.Printf("Index:     %s\n", status.Path)
+	fmt.Printf("Messages:  %d\n", status.Messages)
+	fmt.Printf("Terms:     %d\n", status.Terms)
+	if status.SyncedAt.IsZero() {
+		fmt.Println("Last sync: never")
+		return nil
+	}
+	fmt.Printf("Last sync: %s\n", status.SyncedAt.Local().Format(time.DateTime))
+	fmt.Printf("Mailboxes: %d\n", len(status.Mailboxes))
+	for _, m := range status.Mailboxes {
+		fmt.Printf("  %s/%s: %d messages\n", m.Account, strings.Join(m.MailboxPath, "/"), m.Messages)
+	}
+	return nil
+}
+
 // createLaunchd creates the launchd service
 func createLaunchd(options *opts.LaunchdCreateCmd) error {
 	cfg, err := launchd.DefaultConfig()
@@ -212,6 +383,23 @@ func createLaunchd(options *opts.LaunchdCreateCmd) error {
 	if options.Debug {
 		cfg.Debug = options.Debug
 	}
+	if options.Index {
+		cfg.Index = options.Index
+	}
+	if options.ExportDir != "" {
+		exportDir, err := filepath.Abs(options.ExportDir)
+		if err != nil {
+			return fmt.Errorf("invalid export directory: %w", err)
+		}
+		cfg.ExportDir = exportDir
+	}
+	for _, dir := range options.AttachmentDirs {
+		attachmentDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("invalid attachment directory: %w", err)
+		}
+		cfg.AttachmentDirs = append(cfg.AttachmentDirs, attachmentDir)
+	}
 	if options.DisableRunAtLoad {
 		cfg.RunAtLoad = false
 	}
@@ -229,6 +417,8 @@ func restartLaunchd() error {
 }
 
 func registerToolHandlers() {
+	executor := jxa.OsascriptExecutor{}
+
 	// Helper to handle tool execution result
 	handleResult := func(result any, err error) error {
 		if err != nil {
@@ -240,67 +430,123 @@ func registerToolHandlers() {
 	}
 
 	opts.GlobalOpts.Tool.ListAccounts.Handler = func(input tools.ListAccountsInput) error {
-		_, data, err := tools.HandleListAccounts(context.Background(), nil, input)
+		_, data, err := tools.HandleListAccounts(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.ListMailboxes.Handler = func(input tools.ListMailboxesInput) error {
-		_, data, err := tools.HandleListMailboxes(context.Background(), nil, input)
+		_, data, err := tools.HandleListMailboxes(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.GetMessageContent.Handler = func(input tools.GetMessageContentInput) error {
-		_, data, err := tools.HandleGetMessageContent(context.Background(), nil, input)
+		_, data, err := tools.HandleGetMessageContent(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.GetThread.Handler = func(input tools.GetThreadInput) error {
+		_, data, err := tools.HandleGetThread(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.GetMessageSource.Handler = func(input tools.GetMessageSourceInput) error {
+		_, data, err := tools.HandleGetMessageSource(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.GetAttachmentText.Handler = func(input tools.GetAttachmentTextInput) error {
+		_, data, err := tools.HandleGetAttachmentText(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.GetSelectedMessages.Handler = func(input tools.GetSelectedMessagesInput) error {
-		_, data, err := tools.HandleGetSelectedMessages(context.Background(), nil, input)
+		_, data, err := tools.HandleGetSelectedMessages(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.CreateReply.Handler = func(input tools.CreateReplyInput) error {
-		_, data, err := tools.HandleCreateReply(context.Background(), nil, input)
+		_, data, err := tools.HandleCreateReply(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.ReplaceReply.Handler = func(input tools.ReplaceReplyInput) error {
-		_, data, err := tools.HandleReplaceReply(context.Background(), nil, input)
+		_, data, err := tools.HandleReplaceReply(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.ListDrafts.Handler = func(input tools.ListDraftsInput) error {
-		_, data, err := tools.HandleListDrafts(context.Background(), nil, input)
+		_, data, err := tools.HandleListDrafts(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.DeleteDraft.Handler = func(input tools.DeleteDraftInput) error {
-		_, data, err := tools.HandleDeleteDraft(context.Background(), nil, input)
+		_, data, err := tools.HandleDeleteDraft(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.CreateOutgoingMessage.Handler = func(input tools.CreateOutgoingMessageInput) error {
-		_, data, err := tools.HandleCreateOutgoingMessage(context.Background(), nil, input)
+		attachmentDirs := opts.GlobalOpts.Tool.CreateOutgoingMessage.AttachmentDirs
+		_, data, err := tools.HandleCreateOutgoingMessage(context.Background(), executor, attachmentDirs, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.ListOutgoingMessages.Handler = func() error {
-		_, data, err := tools.HandleListOutgoingMessages(context.Background(), nil, struct{}{})
+		_, data, err := tools.HandleListOutgoingMessages(context.Background(), executor, nil, struct{}{})
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.ReplaceOutgoingMessage.Handler = func(input tools.ReplaceOutgoingMessageInput) error {
-		_, data, err := tools.HandleReplaceOutgoingMessage(context.Background(), nil, input)
+		attachmentDirs := opts.GlobalOpts.Tool.ReplaceOutgoingMessage.AttachmentDirs
+		_, data, err := tools.HandleReplaceOutgoingMessage(context.Background(), executor, attachmentDirs, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.DeleteOutgoingMessage.Handler = func(input tools.DeleteOutgoingMessageInput) error {
-		_, data, err := tools.HandleDeleteOutgoingMessage(context.Background(), nil, input)
+		_, data, err := tools.HandleDeleteOutgoingMessage(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 
 	opts.GlobalOpts.Tool.FindMessages.Handler = func(input tools.FindMessagesInput) error {
-		_, data, err := tools.HandleFindMessages(context.Background(), nil, input)
+		_, data, err := tools.HandleFindMessages(context.Background(), executor, nil, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.SearchIndex.Handler = func(input tools.SearchIndexInput) error {
+		idx, err := openIndex(opts.GlobalOpts.Tool.SearchIndex.IndexDir)
+		if err != nil {
+			return err
+		}
+		_, data, err := tools.HandleSearchIndex(context.Background(), idx, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.SaveAttachments.Handler = func(input tools.SaveAttachmentsInput) error {
+		_, data, err := tools.HandleSaveAttachments(context.Background(), executor, opts.GlobalOpts.Tool.SaveAttachments.ExportDir, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.MoveMessages.Handler = func(input tools.MoveMessagesInput) error {
+		_, data, err := tools.HandleMoveMessages(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.CopyMessages.Handler = func(input tools.CopyMessagesInput) error {
+		_, data, err := tools.HandleCopyMessages(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.ArchiveMessages.Handler = func(input tools.ArchiveMessagesInput) error {
+		_, data, err := tools.HandleArchiveMessages(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.UpdateMessages.Handler = func(input tools.UpdateMessagesInput) error {
+		_, data, err := tools.HandleUpdateMessages(context.Background(), executor, nil, input)
+		return handleResult(data, err)
+	}
+
+	opts.GlobalOpts.Tool.TrashMessages.Handler = func(input tools.TrashMessagesInput) error {
+		_, data, err := tools.HandleTrashMessages(context.Background(), executor, nil, input)
 		return handleResult(data, err)
 	}
 }